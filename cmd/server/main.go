@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/manuelfirman/go-API/internal/application"
@@ -23,8 +24,19 @@ func main() {
 		DBName:    "go_api_db",
 		ParseTime: true,
 	}
+	// - idempotency keys
+	idempotencyStoreCfg := "mysql"
+	idempotencyTTLCfg := 24 * time.Hour
+	// - tracking codes: {date}, {random}, {carrier} (cid) and {order} (order number)
+	trackingCodeFormatCfg := "TRK-{date}-{random}"
 	// - cfg
-	cfg := application.ConfigServer{Addr: addrCfg, MySQLDSN: mysqlCfg.FormatDSN()}
+	cfg := application.ConfigServer{
+		Addr:               addrCfg,
+		MySQLDSN:           mysqlCfg.FormatDSN(),
+		IdempotencyStore:   idempotencyStoreCfg,
+		IdempotencyTTL:     idempotencyTTLCfg,
+		TrackingCodeFormat: trackingCodeFormatCfg,
+	}
 
 	// - server
 	server := application.New(cfg)
@@ -6,6 +6,19 @@ CREATE DATABASE `go_api_db`;
 USE `go_api_db`;
 
 -- table `product_types`
+CREATE TABLE `product_types` (
+    `id` int NOT NULL AUTO_INCREMENT,
+    `name` varchar(50) NOT NULL,
+    `storage_class` varchar(25) NOT NULL,
+    `minimum_temperature` float NOT NULL,
+    `maximum_temperature` float NOT NULL,
+    `version` int NOT NULL DEFAULT 1,
+    `deleted_at` datetime(3) NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_product_types_name` (`name`)
+) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
+
+-- table `localities`
 CREATE TABLE `localities` (
     `id` int NOT NULL,
     `locality_name` varchar(50) NOT NULL,
@@ -35,6 +48,8 @@ CREATE TABLE `sellers` (
     `address` varchar(255) NOT NULL,
     `telephone` varchar(15) NOT NULL,
     `locality_id` int NOT NULL,
+    `version` int NOT NULL DEFAULT 1,
+    `deleted_at` datetime(3) NULL DEFAULT NULL,
     PRIMARY KEY (`id`),
     UNIQUE KEY `idx_sellers_cid` (`cid`),
     CONSTRAINT `fk_sellers_locality_id` FOREIGN KEY (`locality_id`) REFERENCES `localities` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
@@ -49,6 +64,8 @@ CREATE TABLE `warehouses` (
     `minimum_capacity` int NOT NULL,
     `minimum_temperature` float NOT NULL,
     `locality_id` int NULL,
+    `version` int NOT NULL DEFAULT 1,
+    `deleted_at` datetime(3) NULL DEFAULT NULL,
     PRIMARY KEY (`id`),
     UNIQUE KEY `idx_warehouses_warehouse_code` (`warehouse_code`),
     CONSTRAINT `fk_warehouses_locality_id` FOREIGN KEY (`locality_id`) REFERENCES `localities` (`id`) ON DELETE SET NULL ON UPDATE CASCADE
@@ -65,9 +82,12 @@ CREATE TABLE `sections` (
     `maximum_capacity` int NOT NULL,
     `warehouse_id` int NOT NULL,
     `product_type_id` int NOT NULL,
+    `version` int NOT NULL DEFAULT 1,
+    `deleted_at` datetime(3) NULL DEFAULT NULL,
     PRIMARY KEY (`id`),
     UNIQUE KEY `idx_sections_section_number` (`section_number`),
-    CONSTRAINT `fk_sections_warehouse_id` FOREIGN KEY (`warehouse_id`) REFERENCES `warehouses` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+    CONSTRAINT `fk_sections_warehouse_id` FOREIGN KEY (`warehouse_id`) REFERENCES `warehouses` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `fk_sections_product_type_id` FOREIGN KEY (`product_type_id`) REFERENCES `product_types` (`id`) ON UPDATE CASCADE
 ) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
 
 -- table `products`
@@ -84,9 +104,12 @@ CREATE TABLE `products` (
     `recom_freez_temp` float NOT NULL,
     `seller_id` int NULL,
     `product_type_id` int NULL,
+    `version` int NOT NULL DEFAULT 1,
+    `deleted_at` datetime(3) NULL DEFAULT NULL,
     PRIMARY KEY (`id`),
     UNIQUE KEY `idx_products_product_code` (`product_code`),
-    CONSTRAINT `fk_products_seller_id` FOREIGN KEY (`seller_id`) REFERENCES `sellers` (`id`) ON DELETE SET NULL ON UPDATE CASCADE
+    CONSTRAINT `fk_products_seller_id` FOREIGN KEY (`seller_id`) REFERENCES `sellers` (`id`) ON DELETE SET NULL ON UPDATE CASCADE,
+    CONSTRAINT `fk_products_product_type_id` FOREIGN KEY (`product_type_id`) REFERENCES `product_types` (`id`) ON UPDATE CASCADE
 ) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
 
 -- table `employees`
@@ -96,17 +119,35 @@ CREATE TABLE `employees` (
     `first_name` varchar(50) NOT NULL,
     `last_name` varchar(50) NOT NULL,
     `warehouse_id` int NULL,
+    `version` int NOT NULL DEFAULT 1,
+    `deleted_at` datetime(3) NULL DEFAULT NULL,
     PRIMARY KEY (`id`),
     UNIQUE KEY `idx_employees_card_number_id` (`card_number_id`),
     CONSTRAINT `fk_employees_warehouse_id` FOREIGN KEY (`warehouse_id`) REFERENCES `warehouses` (`id`) ON DELETE SET NULL ON UPDATE CASCADE
 ) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
 
+-- table `employee_assignments`
+CREATE TABLE `employee_assignments` (
+    `id` int NOT NULL AUTO_INCREMENT,
+    `employee_id` int NOT NULL,
+    `warehouse_id` int NOT NULL,
+    `start_date` date NOT NULL,
+    `end_date` date NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_employee_assignments_employee_id_start_date` (`employee_id`, `start_date`),
+    KEY `idx_employee_assignments_warehouse_id_start_date` (`warehouse_id`, `start_date`),
+    CONSTRAINT `fk_employee_assignments_employee_id` FOREIGN KEY (`employee_id`) REFERENCES `employees` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `fk_employee_assignments_warehouse_id` FOREIGN KEY (`warehouse_id`) REFERENCES `warehouses` (`id`) ON DELETE RESTRICT ON UPDATE CASCADE
+) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
+
 -- table `buyers`
 CREATE TABLE `buyers` (
     `id` int NOT NULL AUTO_INCREMENT,
     `card_number_id` int NOT NULL,
     `first_name` varchar(50) NOT NULL,
     `last_name` varchar(50) NOT NULL,
+    `version` int NOT NULL DEFAULT 1,
+    `deleted_at` datetime(3) NULL DEFAULT NULL,
     PRIMARY KEY (`id`),
     UNIQUE KEY `idx_buyers_card_number_id` (`card_number_id`)
 ) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
@@ -124,6 +165,7 @@ CREATE TABLE `product_batches` (
     `manufacturing_hour` int NOT NULL,
     `section_id` int NOT NULL,
     `product_id` int NOT NULL,
+    `deleted_at` datetime(3) NULL DEFAULT NULL,
     PRIMARY KEY (`id`),
     CONSTRAINT `fk_product_batches_section_id` FOREIGN KEY (`section_id`) REFERENCES `sections` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
     CONSTRAINT `fk_product_batches_product_id` FOREIGN KEY (`product_id`) REFERENCES `products` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
@@ -152,9 +194,25 @@ CREATE TABLE `product_records` (
     `sale_price` float NULL,
     `product_id` int NOT NULL,
     PRIMARY KEY (`id`),
+    KEY `idx_product_records_product_id_last_update_date` (`product_id`, `last_update_date`),
     CONSTRAINT `fk_product_records_product_id` FOREIGN KEY (`product_id`) REFERENCES `products` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
 ) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
 
+-- table `order_statuses`
+CREATE TABLE `order_statuses` (
+    `id` int NOT NULL,
+    `description` varchar(25) NOT NULL,
+    PRIMARY KEY (`id`)
+) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
+
+INSERT INTO `order_statuses` (`id`, `description`) VALUES
+(1, 'pending'),
+(2, 'confirmed'),
+(3, 'picking'),
+(4, 'shipped'),
+(5, 'delivered'),
+(6, 'cancelled');
+
 -- table `purchase_orders`
 CREATE TABLE `purchase_orders` (
     `id` int NOT NULL AUTO_INCREMENT,
@@ -164,8 +222,89 @@ CREATE TABLE `purchase_orders` (
     `buyer_id` int NOT NULL,
     `product_record_id` int NOT NULL,
     `order_status_id` int NOT NULL,
+    `carrier_id` int NULL,
     PRIMARY KEY (`id`),
     UNIQUE KEY `idx_purchase_orders_order_number` (`order_number`),
+    UNIQUE KEY `idx_purchase_orders_tracking_code` (`tracking_code`),
+    KEY `idx_purchase_orders_buyer_id_order_date` (`buyer_id`, `order_date`),
     CONSTRAINT `fk_purchase_orders_buyer_id` FOREIGN KEY (`buyer_id`) REFERENCES `buyers` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
-    CONSTRAINT `fk_purchase_orders_product_record_id` FOREIGN KEY (`product_record_id`) REFERENCES `product_records` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
-) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
\ No newline at end of file
+    CONSTRAINT `fk_purchase_orders_product_record_id` FOREIGN KEY (`product_record_id`) REFERENCES `product_records` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `fk_purchase_orders_order_status_id` FOREIGN KEY (`order_status_id`) REFERENCES `order_statuses` (`id`),
+    CONSTRAINT `fk_purchase_orders_carrier_id` FOREIGN KEY (`carrier_id`) REFERENCES `carries` (`id`) ON DELETE SET NULL ON UPDATE CASCADE
+) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
+
+-- table `idempotency_keys`
+CREATE TABLE `idempotency_keys` (
+    `idempotency_key` varchar(255) NOT NULL,
+    `fingerprint` char(64) NOT NULL,
+    `status_code` int NOT NULL,
+    `content_type` varchar(100) NOT NULL,
+    `etag` varchar(255) NOT NULL DEFAULT '',
+    `body` mediumblob NOT NULL,
+    `expires_at` datetime NOT NULL,
+    PRIMARY KEY (`idempotency_key`),
+    KEY `idx_idempotency_keys_expires_at` (`expires_at`)
+) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
+
+-- table `section_temperature_readings`
+CREATE TABLE `section_temperature_readings` (
+    `id` int NOT NULL AUTO_INCREMENT,
+    `section_id` int NOT NULL,
+    `temperature` float NOT NULL,
+    `recorded_at` datetime(3) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_section_temperature_readings_section_id_recorded_at` (`section_id`, `recorded_at`),
+    CONSTRAINT `fk_section_temperature_readings_section_id` FOREIGN KEY (`section_id`) REFERENCES `sections` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
+
+-- table `product_stock_thresholds`
+CREATE TABLE `product_stock_thresholds` (
+    `product_id` int NOT NULL,
+    `threshold` int NOT NULL,
+    PRIMARY KEY (`product_id`),
+    CONSTRAINT `fk_product_stock_thresholds_product_id` FOREIGN KEY (`product_id`) REFERENCES `products` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
+
+-- table `inventory_movements` (append-only ledger, the batch and section are not constrained so it outlives them)
+CREATE TABLE `inventory_movements` (
+    `id` int NOT NULL AUTO_INCREMENT,
+    `product_batch_id` int NOT NULL,
+    `section_id` int NOT NULL,
+    `movement_type` varchar(20) NOT NULL,
+    `quantity` int NOT NULL,
+    `employee_id` int NULL,
+    `reason` varchar(255) NOT NULL,
+    `created_at` datetime(3) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_inventory_movements_product_batch_id` (`product_batch_id`),
+    CONSTRAINT `fk_inventory_movements_employee_id` FOREIGN KEY (`employee_id`) REFERENCES `employees` (`id`) ON DELETE SET NULL ON UPDATE CASCADE
+) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
+
+-- table `purchase_order_status_history`
+CREATE TABLE `purchase_order_status_history` (
+    `id` int NOT NULL AUTO_INCREMENT,
+    `purchase_order_id` int NOT NULL,
+    `from_status_id` int NULL,
+    `to_status_id` int NOT NULL,
+    `changed_at` datetime(3) NOT NULL,
+    PRIMARY KEY (`id`),
+    CONSTRAINT `fk_purchase_order_status_history_purchase_order_id` FOREIGN KEY (`purchase_order_id`) REFERENCES `purchase_orders` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `fk_purchase_order_status_history_from_status_id` FOREIGN KEY (`from_status_id`) REFERENCES `order_statuses` (`id`),
+    CONSTRAINT `fk_purchase_order_status_history_to_status_id` FOREIGN KEY (`to_status_id`) REFERENCES `order_statuses` (`id`)
+) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
+
+-- table `audit_log`
+CREATE TABLE `audit_log` (
+    `id` int NOT NULL AUTO_INCREMENT,
+    `resource` varchar(50) NOT NULL,
+    `resource_id` int NOT NULL,
+    `action` varchar(20) NOT NULL,
+    `before` json NULL,
+    `after` json NULL,
+    `actor` varchar(255) NULL,
+    `request_id` varchar(255) NULL,
+    `created_at` datetime(3) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_audit_log_resource_resource_id` (`resource`, `resource_id`),
+    KEY `idx_audit_log_created_at` (`created_at`)
+) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
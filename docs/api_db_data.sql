@@ -9,6 +9,18 @@ USE `go_api_db`;
 -- TRUNCATE TABLE `product_batches`;
 
 -- DML
+INSERT INTO `product_types` (`name`, `storage_class`, `minimum_temperature`, `maximum_temperature`) VALUES
+('Frozen vegetables', 'frozen', -25, -5),
+('Frozen fruit', 'frozen', -25, -6),
+('Frozen bakery', 'frozen', -25, -7),
+('Frozen poultry', 'frozen', -30, -8),
+('Frozen meat', 'frozen', -30, -9),
+('Frozen fish', 'frozen', -30, -10),
+('Frozen seafood', 'frozen', -30, -11),
+('Frozen ready meals', 'frozen', -30, -12),
+('Ice cream', 'frozen', -30, -13),
+('Frozen desserts', 'frozen', -30, -14);
+
 INSERT INTO `localities` (`id`, `locality_name`, `province_name`, `country_name`) VALUES
 (100, 'City A', 'Province A', 'Country A'),
 (102, 'City B', 'Province B', 'Country A'),
@@ -93,6 +105,12 @@ INSERT INTO `employees` (`card_number_id`, `first_name`, `last_name`, `warehouse
 (1009, 'William', 'Anderson', 9),
 (1010, 'Jessica', 'Thomas', 10);
 
+-- DML `employee_assignments`: the employees start their history at their current warehouse
+INSERT INTO `employee_assignments` (`employee_id`, `warehouse_id`, `start_date`)
+SELECT `id`, `warehouse_id`, CURDATE()
+FROM `employees`
+WHERE `warehouse_id` IS NOT NULL;
+
 INSERT INTO `buyers` (`card_number_id`, `first_name`, `last_name`) VALUES
 (1001, 'Alice', 'Brown'),
 (1002, 'Mark', 'Jones'),
@@ -144,11 +162,22 @@ INSERT INTO `product_records` (`last_update_date`, `purchase_price`, `sale_price
 INSERT INTO `purchase_orders` (`order_number`, `order_date`, `tracking_code`, `buyer_id`, `product_record_id`, `order_status_id`) VALUES
 (1, '2021-01-01', 'ABC123', 1, 1, 1),
 (2, '2021-01-01', 'ABC124', 2, 2, 2),
-(3, '2021-01-01', 'ABC123', 2, 2, 1),
-(4, '2021-01-01', 'ABC123', 3, 3, 3),
+(3, '2021-01-01', 'ABC126', 2, 2, 1),
+(4, '2021-01-01', 'ABC127', 3, 3, 3),
 (5, '2021-01-01', 'ABC125', 8, 6, 2),
-(6, '2021-01-01', 'ABC125', 9, 6, 5),
+(6, '2021-01-01', 'ABC128', 9, 6, 5),
 (7, '2021-01-01', 'ABC129', 10, 7, 3),
 (8, '2021-01-01', 'ABC130', 1, 1, 2),
 (9, '2021-01-01', 'ABC131', 2, 2, 1),
-(10, '2021-01-01', 'ABC132', 3, 3, 4);
\ No newline at end of file
+(10, '2021-01-01', 'ABC132', 3, 3, 4);
+
+-- DML `inventory_movements`: opening balance of the batches
+INSERT INTO `inventory_movements` (`product_batch_id`, `section_id`, `movement_type`, `quantity`, `reason`, `created_at`)
+SELECT `id`, `section_id`, 'receipt', `current_quantity`, 'opening balance', UTC_TIMESTAMP(3)
+FROM `product_batches`
+WHERE `current_quantity` > 0;
+
+-- DML `purchase_order_status_history`: the orders start their history in their current status
+INSERT INTO `purchase_order_status_history` (`purchase_order_id`, `from_status_id`, `to_status_id`, `changed_at`)
+SELECT `id`, NULL, `order_status_id`, `order_date`
+FROM `purchase_orders`;
@@ -0,0 +1,9 @@
+-- Migration 001: version columns for optimistic concurrency (ETag / If-Match)
+USE `go_api_db`;
+
+ALTER TABLE `sellers` ADD COLUMN `version` int NOT NULL DEFAULT 1 AFTER `locality_id`;
+ALTER TABLE `warehouses` ADD COLUMN `version` int NOT NULL DEFAULT 1 AFTER `locality_id`;
+ALTER TABLE `sections` ADD COLUMN `version` int NOT NULL DEFAULT 1 AFTER `product_type_id`;
+ALTER TABLE `products` ADD COLUMN `version` int NOT NULL DEFAULT 1 AFTER `product_type_id`;
+ALTER TABLE `employees` ADD COLUMN `version` int NOT NULL DEFAULT 1 AFTER `warehouse_id`;
+ALTER TABLE `buyers` ADD COLUMN `version` int NOT NULL DEFAULT 1 AFTER `last_name`;
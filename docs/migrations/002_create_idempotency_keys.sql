@@ -0,0 +1,13 @@
+-- Migration 002: stored responses of POST requests sent with an Idempotency-Key header
+USE `go_api_db`;
+
+CREATE TABLE `idempotency_keys` (
+    `idempotency_key` varchar(255) NOT NULL,
+    `fingerprint` char(64) NOT NULL,
+    `status_code` int NOT NULL,
+    `content_type` varchar(100) NOT NULL,
+    `body` mediumblob NOT NULL,
+    `expires_at` datetime NOT NULL,
+    PRIMARY KEY (`idempotency_key`),
+    KEY `idx_idempotency_keys_expires_at` (`expires_at`)
+) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
@@ -0,0 +1,12 @@
+-- Migration 003: temperature readings sent by the sensors of the sections
+USE `go_api_db`;
+
+CREATE TABLE `section_temperature_readings` (
+    `id` int NOT NULL AUTO_INCREMENT,
+    `section_id` int NOT NULL,
+    `temperature` float NOT NULL,
+    `recorded_at` datetime(3) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_section_temperature_readings_section_id_recorded_at` (`section_id`, `recorded_at`),
+    CONSTRAINT `fk_section_temperature_readings_section_id` FOREIGN KEY (`section_id`) REFERENCES `sections` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
@@ -0,0 +1,9 @@
+-- Migration 004: low-stock thresholds of the products (reorder list)
+USE `go_api_db`;
+
+CREATE TABLE `product_stock_thresholds` (
+    `product_id` int NOT NULL,
+    `threshold` int NOT NULL,
+    PRIMARY KEY (`product_id`),
+    CONSTRAINT `fk_product_stock_thresholds_product_id` FOREIGN KEY (`product_id`) REFERENCES `products` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
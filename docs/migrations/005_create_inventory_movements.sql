@@ -0,0 +1,22 @@
+-- Migration 005: ledger of the changes of quantity of the product batches
+USE `go_api_db`;
+
+CREATE TABLE `inventory_movements` (
+    `id` int NOT NULL AUTO_INCREMENT,
+    `product_batch_id` int NOT NULL,
+    `section_id` int NOT NULL,
+    `movement_type` varchar(20) NOT NULL,
+    `quantity` int NOT NULL,
+    `employee_id` int NULL,
+    `reason` varchar(255) NOT NULL,
+    `created_at` datetime(3) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_inventory_movements_product_batch_id` (`product_batch_id`),
+    CONSTRAINT `fk_inventory_movements_employee_id` FOREIGN KEY (`employee_id`) REFERENCES `employees` (`id`) ON DELETE SET NULL ON UPDATE CASCADE
+) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
+
+-- opening balance of the existing batches, so their current quantity matches their ledger
+INSERT INTO `inventory_movements` (`product_batch_id`, `section_id`, `movement_type`, `quantity`, `reason`, `created_at`)
+SELECT `id`, `section_id`, 'receipt', `current_quantity`, 'opening balance', UTC_TIMESTAMP(3)
+FROM `product_batches`
+WHERE `current_quantity` > 0;
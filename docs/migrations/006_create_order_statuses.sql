@@ -0,0 +1,35 @@
+-- Migration 006: statuses of the purchase orders and history of their changes
+USE `go_api_db`;
+
+CREATE TABLE `order_statuses` (
+    `id` int NOT NULL,
+    `description` varchar(25) NOT NULL,
+    PRIMARY KEY (`id`)
+) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
+
+INSERT INTO `order_statuses` (`id`, `description`) VALUES
+(1, 'pending'),
+(2, 'confirmed'),
+(3, 'picking'),
+(4, 'shipped'),
+(5, 'delivered'),
+(6, 'cancelled');
+
+ALTER TABLE `purchase_orders` ADD CONSTRAINT `fk_purchase_orders_order_status_id` FOREIGN KEY (`order_status_id`) REFERENCES `order_statuses` (`id`);
+
+CREATE TABLE `purchase_order_status_history` (
+    `id` int NOT NULL AUTO_INCREMENT,
+    `purchase_order_id` int NOT NULL,
+    `from_status_id` int NULL,
+    `to_status_id` int NOT NULL,
+    `changed_at` datetime(3) NOT NULL,
+    PRIMARY KEY (`id`),
+    CONSTRAINT `fk_purchase_order_status_history_purchase_order_id` FOREIGN KEY (`purchase_order_id`) REFERENCES `purchase_orders` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `fk_purchase_order_status_history_from_status_id` FOREIGN KEY (`from_status_id`) REFERENCES `order_statuses` (`id`),
+    CONSTRAINT `fk_purchase_order_status_history_to_status_id` FOREIGN KEY (`to_status_id`) REFERENCES `order_statuses` (`id`)
+) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
+
+-- the existing orders start their history in their current status
+INSERT INTO `purchase_order_status_history` (`purchase_order_id`, `from_status_id`, `to_status_id`, `changed_at`)
+SELECT `id`, NULL, `order_status_id`, `order_date`
+FROM `purchase_orders`;
@@ -0,0 +1,9 @@
+-- Migration 007: carrier of the purchase orders and lookup by tracking code
+USE `go_api_db`;
+
+-- the carrier is assigned when the order is prepared for shipment
+ALTER TABLE `purchase_orders` ADD COLUMN `carrier_id` int NULL AFTER `order_status_id`;
+ALTER TABLE `purchase_orders` ADD CONSTRAINT `fk_purchase_orders_carrier_id` FOREIGN KEY (`carrier_id`) REFERENCES `carries` (`id`) ON DELETE SET NULL ON UPDATE CASCADE;
+
+-- not unique: the legacy orders share some tracking codes, the new ones are generated unique
+CREATE INDEX `idx_purchase_orders_tracking_code` ON `purchase_orders` (`tracking_code`);
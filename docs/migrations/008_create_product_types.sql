@@ -0,0 +1,32 @@
+-- Migration 008: product types, referenced by the products and the sections
+USE `go_api_db`;
+
+CREATE TABLE `product_types` (
+    `id` int NOT NULL AUTO_INCREMENT,
+    `name` varchar(50) NOT NULL,
+    `storage_class` varchar(25) NOT NULL,
+    `minimum_temperature` float NOT NULL,
+    `maximum_temperature` float NOT NULL,
+    `version` int NOT NULL DEFAULT 1,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_product_types_name` (`name`)
+) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
+
+-- the types already in use get a placeholder to be renamed (and classified) through the API. Its temperature range
+-- covers the recommended temperatures of its products, so the batches of the products can still be placed;
+-- the types without products get the frozen range until they are edited.
+INSERT INTO `product_types` (`id`, `name`, `storage_class`, `minimum_temperature`, `maximum_temperature`)
+SELECT t.`id`, CONCAT('Product type ', t.`id`), IF(COALESCE(p.`maximum_temperature`, 0) <= 0, 'frozen', 'refrigerated'),
+    COALESCE(p.`minimum_temperature`, -30), COALESCE(p.`maximum_temperature`, 0)
+FROM (
+    SELECT `product_type_id` AS `id` FROM `products` WHERE `product_type_id` IS NOT NULL
+    UNION
+    SELECT `product_type_id` FROM `sections`
+) AS `t`
+LEFT JOIN (
+    SELECT `product_type_id`, MIN(`recom_freez_temp`) AS `minimum_temperature`, MAX(`recom_freez_temp`) AS `maximum_temperature`
+    FROM `products` WHERE `product_type_id` IS NOT NULL GROUP BY `product_type_id`
+) AS `p` ON p.`product_type_id` = t.`id`;
+
+ALTER TABLE `sections` ADD CONSTRAINT `fk_sections_product_type_id` FOREIGN KEY (`product_type_id`) REFERENCES `product_types` (`id`) ON UPDATE CASCADE;
+ALTER TABLE `products` ADD CONSTRAINT `fk_products_product_type_id` FOREIGN KEY (`product_type_id`) REFERENCES `product_types` (`id`) ON UPDATE CASCADE;
@@ -0,0 +1,22 @@
+-- Migration 009: history of the warehouses the employees work at
+USE `go_api_db`;
+
+CREATE TABLE `employee_assignments` (
+    `id` int NOT NULL AUTO_INCREMENT,
+    `employee_id` int NOT NULL,
+    `warehouse_id` int NOT NULL,
+    `start_date` date NOT NULL,
+    `end_date` date NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_employee_assignments_employee_id_start_date` (`employee_id`, `start_date`),
+    KEY `idx_employee_assignments_warehouse_id_start_date` (`warehouse_id`, `start_date`),
+    CONSTRAINT `fk_employee_assignments_employee_id` FOREIGN KEY (`employee_id`) REFERENCES `employees` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    -- a warehouse with history can't be deleted (it would orphan its employees)
+    CONSTRAINT `fk_employee_assignments_warehouse_id` FOREIGN KEY (`warehouse_id`) REFERENCES `warehouses` (`id`) ON DELETE RESTRICT ON UPDATE CASCADE
+) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
+
+-- the existing employees start their history at their current warehouse
+INSERT INTO `employee_assignments` (`employee_id`, `warehouse_id`, `start_date`)
+SELECT `id`, `warehouse_id`, CURDATE()
+FROM `employees`
+WHERE `warehouse_id` IS NOT NULL;
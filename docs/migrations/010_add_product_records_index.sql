@@ -0,0 +1,4 @@
+-- Migration 010: the prices of a product in effect at a moment are looked up by product and date
+USE `go_api_db`;
+
+ALTER TABLE `product_records` ADD KEY `idx_product_records_product_id_last_update_date` (`product_id`, `last_update_date`);
@@ -0,0 +1,4 @@
+-- Migration 011: the purchase orders of a buyer are aggregated by date range
+USE `go_api_db`;
+
+ALTER TABLE `purchase_orders` ADD KEY `idx_purchase_orders_buyer_id_order_date` (`buyer_id`, `order_date`);
@@ -0,0 +1,11 @@
+-- Migration 012: the resources are soft deleted (marked with the moment they were deleted) and can be restored
+USE `go_api_db`;
+
+ALTER TABLE `product_types` ADD COLUMN `deleted_at` datetime(3) NULL DEFAULT NULL AFTER `version`;
+ALTER TABLE `sellers` ADD COLUMN `deleted_at` datetime(3) NULL DEFAULT NULL AFTER `version`;
+ALTER TABLE `warehouses` ADD COLUMN `deleted_at` datetime(3) NULL DEFAULT NULL AFTER `version`;
+ALTER TABLE `sections` ADD COLUMN `deleted_at` datetime(3) NULL DEFAULT NULL AFTER `version`;
+ALTER TABLE `products` ADD COLUMN `deleted_at` datetime(3) NULL DEFAULT NULL AFTER `version`;
+ALTER TABLE `employees` ADD COLUMN `deleted_at` datetime(3) NULL DEFAULT NULL AFTER `version`;
+ALTER TABLE `buyers` ADD COLUMN `deleted_at` datetime(3) NULL DEFAULT NULL AFTER `version`;
+ALTER TABLE `product_batches` ADD COLUMN `deleted_at` datetime(3) NULL DEFAULT NULL AFTER `product_id`;
@@ -0,0 +1,19 @@
+-- Migration 013: every change made to the resources is recorded in the audit log, with who made it and the request.
+-- The log has no foreign keys, so it outlives the resources it refers to.
+USE `go_api_db`;
+
+-- table `audit_log`
+CREATE TABLE `audit_log` (
+    `id` int NOT NULL AUTO_INCREMENT,
+    `resource` varchar(50) NOT NULL,
+    `resource_id` int NOT NULL,
+    `action` varchar(20) NOT NULL,
+    `before` json NULL,
+    `after` json NULL,
+    `actor` varchar(255) NULL,
+    `request_id` varchar(255) NULL,
+    `created_at` datetime(3) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_audit_log_resource_resource_id` (`resource`, `resource_id`),
+    KEY `idx_audit_log_created_at` (`created_at`)
+) ENGINE = InnoDB DEFAULT CHARSET = UTF8MB4;
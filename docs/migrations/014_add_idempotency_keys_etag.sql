@@ -0,0 +1,5 @@
+-- Migration 014: the idempotency keys are reserved (status_code 0) before their request is processed,
+-- and the ETag of the stored response is replayed with it
+USE `go_api_db`;
+
+ALTER TABLE `idempotency_keys` ADD COLUMN `etag` varchar(255) NOT NULL DEFAULT '' AFTER `content_type`;
@@ -0,0 +1,12 @@
+-- Migration 015: unique tracking codes of the purchase orders
+USE `go_api_db`;
+
+-- the legacy orders that share a tracking code keep it only on the latest one, the others get their id appended
+UPDATE `purchase_orders` AS `po`
+INNER JOIN (
+    SELECT `tracking_code`, MAX(`id`) AS `latest_id` FROM `purchase_orders` GROUP BY `tracking_code` HAVING COUNT(*) > 1
+) AS `d` ON d.`tracking_code` = po.`tracking_code` AND po.`id` <> d.`latest_id`
+SET po.`tracking_code` = CONCAT(LEFT(po.`tracking_code`, 24 - CHAR_LENGTH(po.`id`)), '-', po.`id`);
+
+DROP INDEX `idx_purchase_orders_tracking_code` ON `purchase_orders`;
+CREATE UNIQUE INDEX `idx_purchase_orders_tracking_code` ON `purchase_orders` (`tracking_code`);
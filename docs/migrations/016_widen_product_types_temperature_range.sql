@@ -0,0 +1,14 @@
+-- Migration 016: temperature ranges of the product types that cover the recommended temperatures of their products
+USE `go_api_db`;
+
+-- the placeholder types backfilled by migration 008 got a fixed range (-30 to 0), so the batches of the products
+-- recommended above it can't be placed: every range is widened to cover the products of the type
+UPDATE `product_types` AS `pt`
+INNER JOIN (
+    SELECT `product_type_id`, MIN(`recom_freez_temp`) AS `minimum_temperature`, MAX(`recom_freez_temp`) AS `maximum_temperature`
+    FROM `products` WHERE `product_type_id` IS NOT NULL GROUP BY `product_type_id`
+) AS `p` ON p.`product_type_id` = pt.`id`
+SET pt.`minimum_temperature` = LEAST(pt.`minimum_temperature`, p.`minimum_temperature`),
+    pt.`maximum_temperature` = GREATEST(pt.`maximum_temperature`, p.`maximum_temperature`),
+    pt.`version` = pt.`version` + 1
+WHERE p.`minimum_temperature` < pt.`minimum_temperature` OR p.`maximum_temperature` > pt.`maximum_temperature`;
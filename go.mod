@@ -3,6 +3,7 @@ module github.com/manuelfirman/go-API
 go 1.21.6
 
 require (
+	github.com/DATA-DOG/go-sqlmock v1.5.2
 	github.com/go-chi/chi/v5 v5.0.12
 	github.com/go-sql-driver/mysql v1.8.0
 	github.com/stretchr/testify v1.9.0
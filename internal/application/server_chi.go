@@ -3,12 +3,16 @@ package application
 import (
 	"database/sql"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/manuelfirman/go-API/internal"
 	handler "github.com/manuelfirman/go-API/internal/handler/chi"
 	"github.com/manuelfirman/go-API/internal/repository"
 	"github.com/manuelfirman/go-API/internal/service"
+	"github.com/manuelfirman/go-API/platform/tracking"
+	"github.com/manuelfirman/go-API/platform/web/idempotency"
 )
 
 // ConfigServer is the configuration for the server
@@ -17,14 +21,23 @@ type ConfigServer struct {
 	Addr string
 	// MySQLDSN is the DSN for the MySQL database
 	MySQLDSN string
+	// IdempotencyStore is the storage of the idempotency keys: "mysql" or "memory"
+	IdempotencyStore string
+	// IdempotencyTTL is how long the response of an idempotency key is replayed
+	IdempotencyTTL time.Duration
+	// TrackingCodeFormat is the format of the tracking codes of the shipments (e.g. "TRK-{date}-{random}")
+	TrackingCodeFormat string
 }
 
 // New creates a new instance of the server
 func New(cfg ConfigServer) *ServerChi {
 	// default config
 	defaultCfg := ConfigServer{
-		Addr:     ":8080",
-		MySQLDSN: "",
+		Addr:               ":8080",
+		MySQLDSN:           "",
+		IdempotencyStore:   "memory",
+		IdempotencyTTL:     24 * time.Hour,
+		TrackingCodeFormat: tracking.DefaultFormat,
 	}
 	if cfg.Addr != "" {
 		defaultCfg.Addr = cfg.Addr
@@ -32,10 +45,22 @@ func New(cfg ConfigServer) *ServerChi {
 	if cfg.MySQLDSN != "" {
 		defaultCfg.MySQLDSN = cfg.MySQLDSN
 	}
+	if cfg.IdempotencyStore != "" {
+		defaultCfg.IdempotencyStore = cfg.IdempotencyStore
+	}
+	if cfg.IdempotencyTTL != 0 {
+		defaultCfg.IdempotencyTTL = cfg.IdempotencyTTL
+	}
+	if cfg.TrackingCodeFormat != "" {
+		defaultCfg.TrackingCodeFormat = cfg.TrackingCodeFormat
+	}
 
 	return &ServerChi{
-		addr:     defaultCfg.Addr,
-		mysqlDSN: defaultCfg.MySQLDSN,
+		addr:               defaultCfg.Addr,
+		mysqlDSN:           defaultCfg.MySQLDSN,
+		idempotencyStore:   defaultCfg.IdempotencyStore,
+		idempotencyTTL:     defaultCfg.IdempotencyTTL,
+		trackingCodeFormat: defaultCfg.TrackingCodeFormat,
 	}
 }
 
@@ -45,6 +70,12 @@ type ServerChi struct {
 	addr string
 	// mysqlDSN is the DSN for the MySQL database
 	mysqlDSN string
+	// idempotencyStore is the storage of the idempotency keys: "mysql" or "memory"
+	idempotencyStore string
+	// idempotencyTTL is how long the response of an idempotency key is replayed
+	idempotencyTTL time.Duration
+	// trackingCodeFormat is the format of the tracking codes of the shipments
+	trackingCodeFormat string
 }
 
 // Run runs the server
@@ -61,28 +92,61 @@ func (s *ServerChi) Run() (err error) {
 	if err != nil {
 		return
 	}
+	// - tracking codes
+	codes, err := tracking.NewGenerator(s.trackingCodeFormat)
+	if err != nil {
+		return
+	}
+
+	// - audit log of the changes
+	audit := service.NewAuditDefault(repository.NewAuditMySQL(db))
 
 	// - router
 	router := chi.NewRouter()
 	// - middlewares
+	router.Use(middleware.RequestID)
 	router.Use(middleware.Logger)
 	router.Use(middleware.Recoverer)
+	// - who makes the changes of the requests, for the audit log
+	router.Use(handler.AuditSource)
+	// - idempotency keys on POST endpoints, scoped to the client
+	router.Use(idempotency.Middleware(s.buildIdempotencyStore(db), s.idempotencyTTL, handler.RequestClient))
 	// - ping endpoint
 	buildPing(router)
 
 	// endpoints
 	// - products
-	buildProductsRouter(router, db)
+	buildProductsRouter(router, db, audit)
 	// - sellers
-	buildSellersRouter(router, db)
+	buildSellersRouter(router, db, audit)
 	// - buyers
-	buildBuyersRouter(router, db)
+	buildBuyersRouter(router, db, audit)
 	// - warehouses
-	buildWarehousesRouter(router, db)
+	buildWarehousesRouter(router, db, audit)
 	// - employees
-	buildEmployeesRouter(router, db)
+	buildEmployeesRouter(router, db, audit)
 	// - sections
-	buildSectionsRouter(router, db)
+	buildSectionsRouter(router, db, audit)
+	// - product types
+	buildProductTypesRouter(router, db, audit)
+	// - product batches
+	buildProductBatchesRouter(router, db, audit)
+	// - transfers
+	buildTransfersRouter(router, db, audit)
+	// - inbound orders
+	buildInboundOrdersRouter(router, db, audit)
+	// - purchase orders
+	buildPurchaseOrdersRouter(router, db, codes, audit)
+	// - shipment tracking
+	buildTrackRouter(router, db, codes)
+	// - compliance
+	buildComplianceRouter(router, db)
+	// - stock
+	buildStockRouter(router, db)
+	// - reports
+	buildReportsRouter(router, db)
+	// - audit log
+	buildAuditRouter(router, audit)
 
 	// run
 	err = http.ListenAndServe(s.addr, router)
@@ -90,110 +154,365 @@ func (s *ServerChi) Run() (err error) {
 }
 
 // *buildProductsRouter builds the router for the products endpoints
-func buildProductsRouter(router *chi.Mux, db *sql.DB) {
+func buildProductsRouter(router *chi.Mux, db *sql.DB, audit internal.AuditService) {
 	// instance dependences
 	rp := repository.NewProductMySQL(db)
-	sv := service.NewProductDefault(rp)
+	sv := service.NewProductAudited(service.NewProductDefault(rp), audit)
 	hd := handler.NewProductDefault(sv)
+	// - picking from the batches of the products
+	rpBatch := repository.NewProductBatchMySQL(db)
+	svBatch := service.NewProductBatchDefault(rpBatch)
+	hdBatch := handler.NewProductBatchDefault(svBatch)
+	// - stock of the products
+	rpStock := repository.NewStockMySQL(db)
+	svStock := service.NewStockAudited(service.NewStockDefault(rpStock), audit)
+	hdStock := handler.NewStockDefault(svStock)
+	// - dependents of the products
+	rpDependents := repository.NewDependentsMySQL(db)
+	svDependents := service.NewDependentsDefault(rpDependents)
+	hdDependents := handler.NewDependentsDefault(svDependents)
 
 	// define the routes of the products
 	router.Route("/api/v1/products", func(r chi.Router) {
 		// endpoints
 		r.Post("/", hd.Create())
+		r.Post("/import", hd.Import())
+		r.Post("/bulk", hd.BulkCreate())
 		r.Get("/", hd.GetAll())
 		r.Get("/{id}", hd.GetByID())
 		r.Patch("/{id}", hd.Update())
-		r.Delete("/{id}", hd.Delete())
+		r.With(hdDependents.ConfirmCascade(internal.ResourceProducts)).Delete("/{id}", hd.Delete())
+		r.Post("/{id}/restore", hd.Restore())
+		r.Get("/{id}/dependents", hdDependents.Get(internal.ResourceProducts))
+		r.Get("/{id}/prices", hd.Prices())
+		r.Get("/{id}/pick", hdBatch.Pick())
+		r.Get("/{id}/stock", hdStock.ProductStock())
+		r.Put("/{id}/stock/threshold", hdStock.SetThreshold())
+		r.Delete("/{id}/stock/threshold", hdStock.DeleteThreshold())
 	})
 }
 
 // *buildBuyersRouter builds the router for the buyers endpoints
-func buildBuyersRouter(router *chi.Mux, db *sql.DB) {
+func buildBuyersRouter(router *chi.Mux, db *sql.DB, audit internal.AuditService) {
 	// instance dependences
 	rp := repository.NewBuyerMySQL(db)
-	sv := service.NewBuyerDefault(rp)
+	sv := service.NewBuyerAudited(service.NewBuyerDefault(rp), audit)
 	hd := handler.NewBuyerDefault(sv)
+	// - dependents of the buyers
+	rpDependents := repository.NewDependentsMySQL(db)
+	svDependents := service.NewDependentsDefault(rpDependents)
+	hdDependents := handler.NewDependentsDefault(svDependents)
 
 	// define the routes of the buyers
 	router.Route("/api/v1/buyers", func(r chi.Router) {
 		// endpoints
 		r.Post("/", hd.Save())
+		r.Post("/import", hd.Import())
 		r.Get("/", hd.GetAll())
 		r.Get("/{id}", hd.Get())
 		r.Patch("/{id}", hd.Update())
-		r.Delete("/{id}", hd.Delete())
+		r.With(hdDependents.ConfirmCascade(internal.ResourceBuyers)).Delete("/{id}", hd.Delete())
+		r.Post("/{id}/restore", hd.Restore())
+		r.Get("/{id}/dependents", hdDependents.Get(internal.ResourceBuyers))
+		r.Get("/{id}/analytics", hd.Analytics())
 	})
 }
 
 // *buildSellersRouter builds the router for the sellers endpoints
-func buildSellersRouter(router *chi.Mux, db *sql.DB) {
+func buildSellersRouter(router *chi.Mux, db *sql.DB, audit internal.AuditService) {
 	// instance dependences
 	rp := repository.NewSellerMySQL(db)
-	sv := service.NewSellerDefault(rp)
+	sv := service.NewSellerAudited(service.NewSellerDefault(rp), audit)
 	hd := handler.NewSellerDefault(sv)
+	// - dependents of the sellers
+	rpDependents := repository.NewDependentsMySQL(db)
+	svDependents := service.NewDependentsDefault(rpDependents)
+	hdDependents := handler.NewDependentsDefault(svDependents)
 
 	// define the routes of the sellers
 	router.Route("/api/v1/sellers", func(r chi.Router) {
 		// endpoints
 		r.Post("/", hd.Create())
+		r.Post("/import", hd.Import())
+		r.Post("/bulk", hd.BulkCreate())
 		r.Get("/", hd.GetAll())
 		r.Get("/{id}", hd.GetByID())
 		r.Patch("/{id}", hd.Update())
-		r.Delete("/{id}", hd.Delete())
+		r.With(hdDependents.ConfirmCascade(internal.ResourceSellers)).Delete("/{id}", hd.Delete())
+		r.Post("/{id}/restore", hd.Restore())
+		r.Get("/{id}/dependents", hdDependents.Get(internal.ResourceSellers))
+		r.Get("/{id}/performance", hd.Performance())
 	})
 }
 
 // *buildWarehousesRouter builds the router for the warehouses endpoints
-func buildWarehousesRouter(router *chi.Mux, db *sql.DB) {
+func buildWarehousesRouter(router *chi.Mux, db *sql.DB, audit internal.AuditService) {
 	// instance dependences
 	rp := repository.NewWarehouseMySQL(db)
-	sv := service.NewWarehouseDefault(rp)
+	sv := service.NewWarehouseAudited(service.NewWarehouseDefault(rp), audit)
 	hd := handler.NewWarehouseDefault(sv)
+	// - stock of the warehouses
+	rpStock := repository.NewStockMySQL(db)
+	svStock := service.NewStockDefault(rpStock)
+	hdStock := handler.NewStockDefault(svStock)
+	// - employees of the warehouses
+	rpEmployee := repository.NewEmployeeMySQL(db)
+	svEmployee := service.NewEmployeeDefault(rpEmployee)
+	hdEmployee := handler.NewEmployeeDefault(svEmployee)
+	// - dependents of the warehouses
+	rpDependents := repository.NewDependentsMySQL(db)
+	svDependents := service.NewDependentsDefault(rpDependents)
+	hdDependents := handler.NewDependentsDefault(svDependents)
 
 	// define the routes of the warehouses
 	router.Route("/api/v1/warehouses", func(r chi.Router) {
 		// endpoints
 		r.Post("/", hd.Save())
+		r.Post("/import", hd.Import())
 		r.Get("/", hd.GetAll())
 		r.Get("/{id}", hd.Get())
 		r.Patch("/{id}", hd.Update())
-		r.Delete("/{id}", hd.Delete())
+		r.With(hdDependents.ConfirmCascade(internal.ResourceWarehouses)).Delete("/{id}", hd.Delete())
+		r.Post("/{id}/restore", hd.Restore())
+		r.Get("/{id}/dependents", hdDependents.Get(internal.ResourceWarehouses))
+		r.Get("/{id}/stock", hdStock.WarehouseStock())
+		r.Get("/{id}/summary", hd.Summary())
+		r.Get("/{id}/employees", hdEmployee.WarehouseEmployees())
 	})
 }
 
 // *buildEmployeesRouter builds the router for the employees endpoints
-func buildEmployeesRouter(router *chi.Mux, db *sql.DB) {
+func buildEmployeesRouter(router *chi.Mux, db *sql.DB, audit internal.AuditService) {
 	// instance dependences
 	rp := repository.NewEmployeeMySQL(db)
-	sv := service.NewEmployeeDefault(rp)
+	sv := service.NewEmployeeAudited(service.NewEmployeeDefault(rp), audit)
 	hd := handler.NewEmployeeDefault(sv)
+	// - dependents of the employees
+	rpDependents := repository.NewDependentsMySQL(db)
+	svDependents := service.NewDependentsDefault(rpDependents)
+	hdDependents := handler.NewDependentsDefault(svDependents)
 
 	// define the routes of the employees
 	router.Route("/api/v1/employees", func(r chi.Router) {
 		// endpoints
 		r.Post("/", hd.Save())
+		r.Post("/import", hd.Import())
 		r.Get("/", hd.GetAll())
 		r.Get("/{id}", hd.Get())
 		r.Patch("/{id}", hd.Update())
-		r.Delete("/{id}", hd.Delete())
+		r.With(hdDependents.ConfirmCascade(internal.ResourceEmployees)).Delete("/{id}", hd.Delete())
+		r.Post("/{id}/restore", hd.Restore())
+		r.Get("/{id}/dependents", hdDependents.Get(internal.ResourceEmployees))
+		r.Post("/{id}/assign", hd.Assign())
+		r.Get("/{id}/assignments", hd.Assignments())
+	})
+}
+
+// *buildProductTypesRouter builds the router for the product types endpoints
+func buildProductTypesRouter(router *chi.Mux, db *sql.DB, audit internal.AuditService) {
+	// instance dependences
+	rp := repository.NewProductTypeMySQL(db)
+	sv := service.NewProductTypeAudited(service.NewProductTypeDefault(rp), audit)
+	hd := handler.NewProductTypeDefault(sv)
+	// - dependents of the product types
+	rpDependents := repository.NewDependentsMySQL(db)
+	svDependents := service.NewDependentsDefault(rpDependents)
+	hdDependents := handler.NewDependentsDefault(svDependents)
+
+	// define the routes of the product types
+	router.Route("/api/v1/product-types", func(r chi.Router) {
+		// endpoints
+		r.Post("/", hd.Save())
+		r.Get("/", hd.GetAll())
+		r.Get("/{id}", hd.Get())
+		r.Patch("/{id}", hd.Update())
+		r.With(hdDependents.ConfirmCascade(internal.ResourceProductTypes)).Delete("/{id}", hd.Delete())
+		r.Post("/{id}/restore", hd.Restore())
+		r.Get("/{id}/dependents", hdDependents.Get(internal.ResourceProductTypes))
 	})
 }
 
 // *buildSectionsRouter builds the router for the sections endpoints
-func buildSectionsRouter(router *chi.Mux, db *sql.DB) {
+func buildSectionsRouter(router *chi.Mux, db *sql.DB, audit internal.AuditService) {
 	// instance dependences
 	rp := repository.NewSectionMySQL(db)
-	sv := service.NewSectionDefault(rp)
+	sv := service.NewSectionAudited(service.NewSectionDefault(rp), audit)
 	hd := handler.NewSectionDefault(sv)
+	// - temperature readings
+	rpReading := repository.NewSectionReadingMySQL(db)
+	svReading := service.NewSectionReadingAudited(service.NewSectionReadingDefault(rpReading, rp), sv, audit)
+	hdReading := handler.NewSectionReadingDefault(svReading)
+	// - dependents of the sections
+	rpDependents := repository.NewDependentsMySQL(db)
+	svDependents := service.NewDependentsDefault(rpDependents)
+	hdDependents := handler.NewDependentsDefault(svDependents)
 
 	// define the routes of the sections
 	router.Route("/api/v1/sections", func(r chi.Router) {
 		// endpoints
 		r.Post("/", hd.Save())
+		r.Post("/import", hd.Import())
 		r.Get("/", hd.GetAll())
 		r.Get("/{id}", hd.Get())
 		r.Patch("/{id}", hd.Update())
-		r.Delete("/{id}", hd.Delete())
+		r.With(hdDependents.ConfirmCascade(internal.ResourceSections)).Delete("/{id}", hd.Delete())
+		r.Post("/{id}/restore", hd.Restore())
+		r.Get("/{id}/dependents", hdDependents.Get(internal.ResourceSections))
+		r.Post("/{id}/readings", hdReading.Save())
+		r.Get("/{id}/readings", hdReading.GetAll())
+	})
+}
+
+// buildIdempotencyStore builds the configured storage of the idempotency keys
+func (s *ServerChi) buildIdempotencyStore(db *sql.DB) idempotency.Store {
+	switch s.idempotencyStore {
+	case "mysql":
+		return repository.NewIdempotencyMySQL(db)
+	default:
+		return idempotency.NewStoreMemory()
+	}
+}
+
+// *buildProductBatchesRouter builds the router for the product batches endpoints
+func buildProductBatchesRouter(router *chi.Mux, db *sql.DB, audit internal.AuditService) {
+	// instance dependences
+	rp := repository.NewProductBatchMySQL(db)
+	sv := service.NewProductBatchAudited(service.NewProductBatchDefault(rp), audit)
+	hd := handler.NewProductBatchDefault(sv)
+	// - dependents of the product batches
+	rpDependents := repository.NewDependentsMySQL(db)
+	svDependents := service.NewDependentsDefault(rpDependents)
+	hdDependents := handler.NewDependentsDefault(svDependents)
+
+	// define the routes of the product batches
+	router.Route("/api/v1/product-batches", func(r chi.Router) {
+		// endpoints
+		r.Post("/", hd.Save())
+		r.Get("/", hd.GetAll())
+		r.Get("/expiring", hd.Expiring())
+		r.Get("/{id}", hd.Get())
+		r.Patch("/{id}", hd.Update())
+		r.With(hdDependents.ConfirmCascade(internal.ResourceProductBatches)).Delete("/{id}", hd.Delete())
+		r.Post("/{id}/restore", hd.Restore())
+		r.Get("/{id}/dependents", hdDependents.Get(internal.ResourceProductBatches))
+		r.Post("/{id}/movements", hd.AddMovement())
+		r.Get("/{id}/movements", hd.Movements())
+	})
+}
+
+// *buildTransfersRouter builds the router for the transfers endpoints
+func buildTransfersRouter(router *chi.Mux, db *sql.DB, audit internal.AuditService) {
+	// instance dependences
+	rp := repository.NewProductBatchMySQL(db)
+	sv := service.NewProductBatchAudited(service.NewProductBatchDefault(rp), audit)
+	hd := handler.NewProductBatchDefault(sv)
+
+	// define the routes of the transfers
+	router.Route("/api/v1/transfers", func(r chi.Router) {
+		// endpoints
+		r.Post("/", hd.Transfer())
+	})
+}
+
+// *buildInboundOrdersRouter builds the router for the inbound orders endpoints
+func buildInboundOrdersRouter(router *chi.Mux, db *sql.DB, audit internal.AuditService) {
+	// instance dependences
+	rp := repository.NewProductBatchMySQL(db)
+	sv := service.NewProductBatchAudited(service.NewProductBatchDefault(rp), audit)
+	hd := handler.NewProductBatchDefault(sv)
+
+	// define the routes of the inbound orders
+	router.Route("/api/v1/inbound-orders", func(r chi.Router) {
+		// endpoints
+		r.Post("/receive", hd.Receive())
+	})
+}
+
+// *buildPurchaseOrdersRouter builds the router for the purchase orders endpoints
+func buildPurchaseOrdersRouter(router *chi.Mux, db *sql.DB, codes internal.TrackingCodeGenerator, audit internal.AuditService) {
+	// instance dependences
+	rp := repository.NewPurchaseOrderMySQL(db)
+	sv := service.NewPurchaseOrderAudited(service.NewPurchaseOrderDefault(rp, codes), audit)
+	hd := handler.NewPurchaseOrderDefault(sv)
+
+	// define the routes of the purchase orders
+	router.Route("/api/v1/purchase-orders", func(r chi.Router) {
+		// endpoints
+		r.Post("/", hd.Save())
+		r.Get("/", hd.GetAll())
+		r.Get("/{id}", hd.Get())
+		r.Post("/{id}/transitions", hd.Transition())
+		r.Get("/{id}/history", hd.History())
+		r.Post("/{id}/shipment", hd.Shipment())
+	})
+}
+
+// *buildTrackRouter builds the router for the shipment tracking endpoints
+func buildTrackRouter(router *chi.Mux, db *sql.DB, codes internal.TrackingCodeGenerator) {
+	// instance dependences
+	rp := repository.NewPurchaseOrderMySQL(db)
+	sv := service.NewPurchaseOrderDefault(rp, codes)
+	hd := handler.NewPurchaseOrderDefault(sv)
+
+	// define the routes of the shipment tracking
+	router.Route("/api/v1/track", func(r chi.Router) {
+		// endpoints
+		r.Get("/{tracking_code}", hd.Track())
+	})
+}
+
+// *buildComplianceRouter builds the router for the compliance endpoints
+func buildComplianceRouter(router *chi.Mux, db *sql.DB) {
+	// instance dependences
+	rp := repository.NewProductBatchMySQL(db)
+	sv := service.NewProductBatchDefault(rp)
+	hd := handler.NewProductBatchDefault(sv)
+
+	// define the routes of the compliance checks
+	router.Route("/api/v1/compliance", func(r chi.Router) {
+		// endpoints
+		r.Get("/temperature", hd.TemperatureCompliance())
+	})
+}
+
+// *buildStockRouter builds the router for the stock endpoints
+func buildStockRouter(router *chi.Mux, db *sql.DB) {
+	// instance dependences
+	rp := repository.NewStockMySQL(db)
+	sv := service.NewStockDefault(rp)
+	hd := handler.NewStockDefault(sv)
+
+	// define the routes of the stock
+	router.Route("/api/v1/stock", func(r chi.Router) {
+		// endpoints
+		r.Get("/reorder", hd.Reorder())
+	})
+}
+
+// *buildReportsRouter builds the router for the reports endpoints
+func buildReportsRouter(router *chi.Mux, db *sql.DB) {
+	// instance dependences
+	// - margins of the products
+	rpProduct := repository.NewProductMySQL(db)
+	svProduct := service.NewProductDefault(rpProduct)
+	hdProduct := handler.NewProductDefault(svProduct)
+
+	// define the routes of the reports
+	router.Route("/api/v1/reports", func(r chi.Router) {
+		// endpoints
+		r.Get("/margins", hdProduct.Margins())
+	})
+}
+
+// *buildAuditRouter builds the router for the audit log endpoints
+func buildAuditRouter(router *chi.Mux, audit internal.AuditService) {
+	// instance dependences
+	hd := handler.NewAuditDefault(audit)
+
+	// define the routes of the audit log
+	router.Route("/api/v1/audit", func(r chi.Router) {
+		// endpoints
+		r.Get("/", hd.GetAll())
 	})
 }
 
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"context"
+	"time"
+)
+
+// Actions recorded in the audit log
+const (
+	// AuditCreate is the creation of a resource
+	AuditCreate = "create"
+	// AuditUpdate is a change of the fields of a resource
+	AuditUpdate = "update"
+	// AuditDelete is the (soft) delete of a resource
+	AuditDelete = "delete"
+	// AuditRestore is the restore of a deleted resource
+	AuditRestore = "restore"
+	// AuditPurge is the permanent delete of a resource
+	AuditPurge = "purge"
+)
+
+// AuditEntry is a struct that contains a change made to a resource
+type AuditEntry struct {
+	// ID is the unique identifier of the entry
+	ID int
+	// Resource is the kind of the resource that changed
+	Resource string
+	// ResourceID is the unique identifier of the resource that changed
+	ResourceID int
+	// Action is what was done to the resource
+	Action string
+	// Before are the fields that changed with their values before the change (nil on creates and restores)
+	Before map[string]any
+	// After are the fields that changed with their values after the change (nil on deletes and purges)
+	After map[string]any
+	// Actor is who the request that made the change declared to be, unverified (empty if unknown)
+	Actor string
+	// RequestID is the unique identifier of the request that made the change (empty if unknown)
+	RequestID string
+	// CreatedAt is the moment at which the change was made
+	CreatedAt time.Time
+}
+
+// AuditFilter is a struct that contains the conditions the audit entries must meet (every zero field matches all)
+type AuditFilter struct {
+	// Resource is the kind of the resource
+	Resource string
+	// ResourceID is the unique identifier of the resource
+	ResourceID int
+	// Since is the moment from which the changes were made
+	Since time.Time
+}
+
+// AuditSource is a struct that contains who makes the changes of a request and the request itself
+type AuditSource struct {
+	// Actor is who the request declares to be, unverified
+	Actor string
+	// RequestID is the unique identifier of the request
+	RequestID string
+}
+
+// auditSourceKey is the key of the audit source in a context
+type auditSourceKey struct{}
+
+// WithAuditSource returns a copy of the context that carries the audit source
+func WithAuditSource(ctx context.Context, src AuditSource) context.Context {
+	return context.WithValue(ctx, auditSourceKey{}, src)
+}
+
+// AuditSourceFrom returns the audit source carried by the context, zero if it carries none
+func AuditSourceFrom(ctx context.Context) AuditSource {
+	src, _ := ctx.Value(auditSourceKey{}).(AuditSource)
+	return src
+}
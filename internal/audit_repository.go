@@ -0,0 +1,16 @@
+package internal
+
+import "errors"
+
+var (
+	// ErrAuditRepository is the generic error of the repository
+	ErrAuditRepository = errors.New("repository: internal error")
+)
+
+// AuditRepository is an interface that contains the methods that the audit repository should support
+type AuditRepository interface {
+	// Save saves an entry of the audit log
+	Save(e *AuditEntry) error
+	// GetAll returns the entries of the audit log that meet the filter, the oldest first
+	GetAll(filter AuditFilter) ([]AuditEntry, error)
+}
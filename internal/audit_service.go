@@ -0,0 +1,22 @@
+package internal
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrAuditService is the generic error of the service
+	ErrAuditService = errors.New("service: internal error")
+	// ErrAuditServiceUnknown is returned when the repository returns an unknown error
+	ErrAuditServiceUnknown = errors.New("service: unknown error")
+)
+
+// AuditService is an interface that contains the methods that the audit service should support
+type AuditService interface {
+	// Record saves the change made to the resource with the given ID in the audit log, by the source carried by the context.
+	// before and after are the resource before and after the change (nil when it didn't exist or no longer exists).
+	Record(ctx context.Context, resource string, id int, action string, before any, after any) error
+	// GetAll returns the entries of the audit log that meet the filter, the oldest first
+	GetAll(filter AuditFilter) ([]AuditEntry, error)
+}
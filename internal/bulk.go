@@ -0,0 +1,18 @@
+package internal
+
+import "errors"
+
+var (
+	// ErrBulkNotSaved is set on the items of an all-or-nothing bulk operation that were not saved because another item failed
+	ErrBulkNotSaved = errors.New("bulk: item not saved because another item failed")
+)
+
+// BulkResult is a struct that contains the outcome of one item of a bulk operation
+type BulkResult struct {
+	// Index is the position of the item in the bulk request
+	Index int
+	// ID is the unique identifier assigned to the item (zero if it was not saved)
+	ID int
+	// Err is the error of the item (nil if it was saved)
+	Err error
+}
@@ -1,5 +1,7 @@
 package internal
 
+import "time"
+
 // Buyer is a struct that contains the buyer's information
 type Buyer struct {
 	// ID is the unique identifier of the buyer
@@ -10,4 +12,42 @@ type Buyer struct {
 	FirstName string
 	// LastName is the last name of the buyer
 	LastName string
+	// Version is the version of the buyer, incremented on every update (optimistic concurrency)
+	Version int
+	// DeletedAt is the moment the buyer was deleted, zero unless it is
+	DeletedAt time.Time
+}
+
+// BuyerAnalytics is a struct that contains the purchase activity of a buyer in a period (cancelled orders excluded)
+type BuyerAnalytics struct {
+	// BuyerID is the unique identifier of the buyer
+	BuyerID int
+	// From is the first day of the period, zero when unbounded
+	From time.Time
+	// To is the last day of the period, zero when unbounded
+	To time.Time
+	// Orders is the number of purchase orders
+	Orders int
+	// Spend is the sum of the sale prices of the orders
+	Spend float64
+	// AverageBasket is the spend per order
+	AverageBasket float64
+	// LastOrderDate is the date of the latest order, zero when there are none
+	LastOrderDate time.Time
+	// TopProducts is the most ordered products, the most ordered first
+	TopProducts []BuyerProduct
+}
+
+// BuyerProduct is a struct that contains the purchases of a product by a buyer
+type BuyerProduct struct {
+	// ProductID is the unique identifier of the product
+	ProductID int
+	// ProductCode is the unique code of the product
+	ProductCode string
+	// Description is the description of the product
+	Description string
+	// Orders is the number of purchase orders of the product
+	Orders int
+	// Spend is the sum of the sale prices of the orders of the product
+	Spend float64
 }
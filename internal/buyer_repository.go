@@ -1,6 +1,9 @@
 package internal
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	// ErrBuyerRepositoryNotFound is returned when the buyer is not found
@@ -13,20 +16,32 @@ var (
 	ErrBuyerRepositoryFK = errors.New("repository: buyer has purchase orders")
 	// ErrBuyerRepositoryNoData is returned when the buyer has no data
 	ErrBuyerRepositoryNoData = errors.New("repository: buyer table has no data")
+	// ErrBuyerRepositoryVersionConflict is returned when the buyer was modified since it was read
+	ErrBuyerRepositoryVersionConflict = errors.New("repository: buyer version conflict")
 )
 
 // BuyerRepository is an interface that contains the methods that the buyer repository should support
 type BuyerRepository interface {
-	// FindAll returns all the buyers
-	GetAll() ([]Buyer, error)
+	// FindAll returns all the buyers, the deleted ones only if includeDeleted
+	GetAll(includeDeleted bool) ([]Buyer, error)
+	// ForEach calls fn with every buyer (the deleted ones only if includeDeleted) as it is read, it stops at the first error returned by fn
+	ForEach(includeDeleted bool, fn func(buyer Buyer) error) error
 	// FindByID returns the buyer with the given ID
 	Get(id int) (Buyer, error)
 	// Save saves the given buyer
 	Save(buyer *Buyer) error
-	// Update updates the given buyer
+	// Update updates the given buyer if its version matches the stored one
 	Update(buyer *Buyer) error
-	// Delete deletes the buyer with the given ID
-	Delete(id int) error
+	// Delete marks the buyer with the given ID as deleted, at the given version (any if zero)
+	Delete(id int, version int) error
+	// Restore unmarks the deleted buyer with the given ID
+	Restore(id int) error
+	// Purge deletes the buyer with the given ID permanently, deleted or not, at the given version (any if zero),
+	// if nothing blocks it and, when rows depend on it, cascade confirms they are deleted or nulled with it
+	Purge(id int, version int, cascade bool) error
+	// GetAnalytics returns the purchase activity of the buyer with the given ID between the given days (unbounded when zero),
+	// with its top most ordered products
+	GetAnalytics(id int, from time.Time, to time.Time, top int) (BuyerAnalytics, error)
 	// ReportPurchaseOrders returns the report of the purchase orders of the buyer with the given ID or all the buyers if the ID is 0
 	// ReportPurchaseOrders(id int) (report []PurchaseOrderReport, err error)
 }
@@ -1,6 +1,10 @@
 package internal
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"time"
+)
 
 var (
 	//ErrBuyerFieldRequired is returned when the buyer field is required
@@ -15,20 +19,36 @@ var (
 	ErrBuyerServiceFK = errors.New("service: buyer has purchase orders")
 	// ErrBuyerServiceUnkown is returned when the repository returns an unknown error (not defined in repository errors)
 	ErrBuyerServiceUnkown = errors.New("service: unknown error")
+	// ErrBuyerServiceVersionConflict is returned when the buyer was modified since it was read
+	ErrBuyerServiceVersionConflict = errors.New("service: buyer version conflict")
+	// ErrBuyerServiceInvalidFilter is returned when the filter of the analytics is invalid
+	ErrBuyerServiceInvalidFilter = errors.New("service: invalid filter")
 )
 
 // BuyerService is an interface that contains the methods that the buyer service should support
 type BuyerService interface {
-	// FindAll returns all the buyers
-	GetAll() ([]Buyer, error)
+	// FindAll returns all the buyers, the deleted ones only if includeDeleted
+	GetAll(includeDeleted bool) ([]Buyer, error)
+	// ForEach calls fn with every buyer (the deleted ones only if includeDeleted) without loading all of them in memory, it stops at the first error returned by fn
+	ForEach(includeDeleted bool, fn func(buyer Buyer) error) error
 	// FindByID returns the buyer with the given ID
 	Get(id int) (Buyer, error)
 	// Save saves the given buyer
-	Save(buyer *Buyer) error
+	Save(ctx context.Context, buyer *Buyer) error
+	// Validate checks the given buyer as Save does, without saving it
+	Validate(buyer *Buyer) error
 	// Update updates the given buyer
-	Update(buyer *Buyer) error
-	// Delete deletes the buyer with the given ID
-	Delete(id int) error
+	Update(ctx context.Context, buyer *Buyer) error
+	// Delete marks the buyer with the given ID as deleted, at the given version (any if zero)
+	Delete(ctx context.Context, id int, version int) error
+	// Restore unmarks the deleted buyer with the given ID
+	Restore(ctx context.Context, id int) error
+	// Purge deletes the buyer with the given ID permanently, deleted or not, at the given version (any if zero).
+	// The rows that depend on it are deleted or nulled with it only if the context confirms the cascade (WithCascadeConfirmed)
+	Purge(ctx context.Context, id int, version int) error
+	// GetAnalytics returns the purchase activity of the buyer with the given ID between the given days (unbounded when zero),
+	// with its top most ordered products
+	GetAnalytics(id int, from time.Time, to time.Time, top int) (BuyerAnalytics, error)
 	// ReportPurchaseOrders returns the report of the purchase orders of the buyer with the given ID or all the buyers if the ID is 0
 	// ReportPurchaseOrders(id int) (report []PurchaseOrderReport, err error)
 }
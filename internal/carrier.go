@@ -0,0 +1,17 @@
+package internal
+
+// Carrier is a struct that contains the information of a company that ships the purchase orders
+type Carrier struct {
+	// ID is the unique identifier of the carrier
+	ID int
+	// CID is the unique company identifier of the carrier
+	CID int
+	// CompanyName is the name of the company
+	CompanyName string
+	// Address is the address of the carrier
+	Address string
+	// Telephone is the telephone number of the carrier
+	Telephone string
+	// LocalityID is the id of the locality where the carrier is located
+	LocalityID int
+}
@@ -0,0 +1,63 @@
+package internal
+
+import "context"
+
+// Effects of deleting a resource permanently on the rows that depend on it
+const (
+	// DependentDeleted means the rows are deleted with the resource (cascade)
+	DependentDeleted = "deleted"
+	// DependentNulled means the reference of the rows to the resource is set to null
+	DependentNulled = "nulled"
+	// DependentBlocking means the rows prevent the resource from being deleted
+	DependentBlocking = "blocking"
+)
+
+// Dependent is a struct that contains the rows of a kind that depend on a resource
+type Dependent struct {
+	// Kind is the kind of the rows (the table they are stored in)
+	Kind string
+	// Effect is what deleting the resource permanently does to the rows: DependentDeleted, DependentNulled or DependentBlocking
+	Effect string
+	// Count is the number of rows
+	Count int
+}
+
+// Dependents is a struct that contains the rows that depend on a resource, directly or through cascades
+type Dependents struct {
+	// Resource is the kind of the resource (e.g. ResourceWarehouses)
+	Resource string
+	// ID is the unique identifier of the resource
+	ID int
+	// Items are the kinds of rows that depend on the resource, only the ones with rows
+	Items []Dependent
+}
+
+// Any returns true if any row depends on the resource
+func (d Dependents) Any() bool {
+	return len(d.Items) > 0
+}
+
+// Blocked returns true if any row prevents the resource from being deleted permanently
+func (d Dependents) Blocked() bool {
+	for _, item := range d.Items {
+		if item.Effect == DependentBlocking {
+			return true
+		}
+	}
+	return false
+}
+
+// cascadeKey is the key of the confirmation of the cascade in a context
+type cascadeKey struct{}
+
+// WithCascadeConfirmed returns a copy of the context that carries the confirmation that the rows that depend on
+// the resource can be deleted or nulled with it
+func WithCascadeConfirmed(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cascadeKey{}, true)
+}
+
+// CascadeConfirmed returns true if the context carries the confirmation of the cascade
+func CascadeConfirmed(ctx context.Context) bool {
+	confirmed, _ := ctx.Value(cascadeKey{}).(bool)
+	return confirmed
+}
@@ -0,0 +1,22 @@
+package internal
+
+import "errors"
+
+var (
+	// ErrDependentsRepositoryNotFound is returned when the resource is not found
+	ErrDependentsRepositoryNotFound = errors.New("repository: resource not found")
+	// ErrDependentsRepositoryResource is returned when the kind of resource is unknown
+	ErrDependentsRepositoryResource = errors.New("repository: unknown resource")
+	// ErrDependentsRepositoryUnconfirmed is returned when a resource with dependents is deleted permanently without
+	// confirming the cascade
+	ErrDependentsRepositoryUnconfirmed = errors.New("repository: resource has dependents, cascade not confirmed")
+	// ErrDependentsRepository is the generic error of the repository
+	ErrDependentsRepository = errors.New("repository: internal error")
+)
+
+// DependentsRepository is an interface that contains the methods that the dependents repository should support.
+// The dependents of a resource are the rows that deleting it permanently deletes, nulls or is blocked by, following the foreign keys.
+type DependentsRepository interface {
+	// Get returns the rows that depend on the resource with the given ID (deleted or not)
+	Get(resource string, id int) (Dependents, error)
+}
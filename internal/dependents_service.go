@@ -0,0 +1,21 @@
+package internal
+
+import "errors"
+
+var (
+	// ErrDependentsServiceNotFound is returned when the resource is not found
+	ErrDependentsServiceNotFound = errors.New("service: resource not found")
+	// ErrDependentsServiceUnconfirmed is returned when a resource with dependents is deleted permanently without
+	// confirming the cascade
+	ErrDependentsServiceUnconfirmed = errors.New("service: resource has dependents, cascade not confirmed")
+	// ErrDependentsService is the generic error of the service
+	ErrDependentsService = errors.New("service: internal error")
+	// ErrDependentsServiceUnknown is returned when the repository returns an unknown error
+	ErrDependentsServiceUnknown = errors.New("service: unknown error")
+)
+
+// DependentsService is an interface that contains the methods that the dependents service should support
+type DependentsService interface {
+	// Get returns the rows that depend on the resource with the given ID, what deleting it permanently would remove or null
+	Get(resource string, id int) (Dependents, error)
+}
@@ -1,5 +1,7 @@
 package internal
 
+import "time"
+
 // Employee is a struct that contains the employee's information
 type Employee struct {
 	// ID is the unique identifier of the employee
@@ -12,4 +14,22 @@ type Employee struct {
 	LastName string
 	// WarehouseID is the unique identifier of the warehouse to which the employee belongs
 	WarehouseID int
+	// Version is the version of the employee, incremented on every update (optimistic concurrency)
+	Version int
+	// DeletedAt is the moment the employee was deleted, zero unless it is
+	DeletedAt time.Time
+}
+
+// EmployeeAssignment is a struct that contains a period an employee worked (or works) at a warehouse
+type EmployeeAssignment struct {
+	// ID is the unique identifier of the assignment
+	ID int
+	// EmployeeID is the unique identifier of the employee
+	EmployeeID int
+	// WarehouseID is the unique identifier of the warehouse
+	WarehouseID int
+	// StartDate is the day the employee started working at the warehouse
+	StartDate time.Time
+	// EndDate is the day the employee moved to another warehouse (excluded), zero while the assignment is current
+	EndDate time.Time
 }
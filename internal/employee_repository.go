@@ -1,6 +1,9 @@
 package internal
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	// ErrEmployeeRepository is returned when an internal error occurs
@@ -13,20 +16,41 @@ var (
 	ErrEmployeeRepositoryInvalidField = errors.New("repository: field invalid")
 	// ErrInvalidForeingKey is returned when the foreing key is invalid
 	ErrEmployeeRepositoryForeignKey = errors.New("repository: invalid foreing key restriction")
+	// ErrEmployeeRepositoryVersionConflict is returned when the employee was modified since it was read
+	ErrEmployeeRepositoryVersionConflict = errors.New("repository: employee version conflict")
+	// ErrEmployeeRepositoryWarehouseNotFound is returned when the warehouse of an assignment is not found
+	ErrEmployeeRepositoryWarehouseNotFound = errors.New("repository: warehouse not found")
+	// ErrEmployeeRepositoryAlreadyAssigned is returned when the employee already works at the warehouse
+	ErrEmployeeRepositoryAlreadyAssigned = errors.New("repository: employee already assigned to the warehouse")
+	// ErrEmployeeRepositoryAssignmentDate is returned when an assignment starts before the current one
+	ErrEmployeeRepositoryAssignmentDate = errors.New("repository: assignment starts before the current one")
 )
 
 // EmployeeRepository is an interface that contains the methods that the employee repository should support
 type EmployeeRepository interface {
-	// FindAll returns all the employees
-	GetAll() ([]Employee, error)
+	// FindAll returns all the employees, the deleted ones only if includeDeleted
+	GetAll(includeDeleted bool) ([]Employee, error)
+	// ForEach calls fn with every employee (the deleted ones only if includeDeleted) as it is read, it stops at the first error returned by fn
+	ForEach(includeDeleted bool, fn func(employee Employee) error) error
 	// FindByID returns the employee with the given ID
 	Get(id int) (Employee, error)
 	// Save saves the given employee
 	Save(employee *Employee) error
-	// Update updates the given employee
+	// Update updates the given employee if its version matches the stored one
 	Update(employee *Employee) error
-	// Delete deletes the employee with the given ID
-	Delete(id int) error
+	// Delete marks the employee with the given ID as deleted, at the given version (any if zero)
+	Delete(id int, version int) error
+	// Restore unmarks the deleted employee with the given ID
+	Restore(id int) error
+	// Purge deletes the employee with the given ID permanently, deleted or not, at the given version (any if zero),
+	// if nothing blocks it and, when rows depend on it, cascade confirms they are deleted or nulled with it
+	Purge(id int, version int, cascade bool) error
+	// Assign moves the employee to the warehouse of the assignment from its start date, closing the current assignment
+	Assign(assignment *EmployeeAssignment) error
+	// GetAssignments returns the assignments of the employee, the oldest first
+	GetAssignments(employeeID int) ([]EmployeeAssignment, error)
+	// GetByWarehouseAt returns the employees that worked at the warehouse on the given date
+	GetByWarehouseAt(warehouseID int, at time.Time) ([]Employee, error)
 
 	// getReportInboudOrder returns the inbound order report for the given employee id or all orders for all employees
 	// GetReportInboudOrders(id int) (iorr []InboundOrderReport, err error)
@@ -1,6 +1,10 @@
 package internal
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"time"
+)
 
 var (
 	// ErrEmployeeServiceInvalidID is returned when the employee ID is invalid
@@ -17,20 +21,45 @@ var (
 	ErrEmployeeServiceUnknown = errors.New("service: unknown error")
 	// ErrEmployeeServiceDuplicated is returned when the employee already exists
 	ErrEmployeeServiceDuplicated = errors.New("service: employee already exists")
+	// ErrEmployeeServiceVersionConflict is returned when the employee was modified since it was read
+	ErrEmployeeServiceVersionConflict = errors.New("service: employee version conflict")
+	// ErrEmployeeServiceWarehouseNotFound is returned when the warehouse of an assignment is not found
+	ErrEmployeeServiceWarehouseNotFound = errors.New("service: warehouse not found")
+	// ErrEmployeeServiceAlreadyAssigned is returned when the employee already works at the warehouse
+	ErrEmployeeServiceAlreadyAssigned = errors.New("service: employee already assigned to the warehouse")
+	// ErrEmployeeServiceInvalidDate is returned when the date of an assignment is in the future
+	ErrEmployeeServiceInvalidDate = errors.New("service: date can't be in the future")
+	// ErrEmployeeServiceAssignmentDate is returned when an assignment starts before the current one
+	ErrEmployeeServiceAssignmentDate = errors.New("service: assignment starts before the current one")
 )
 
 // EmployeeService is an interface that contains the methods that the employee service should support
 type EmployeeService interface {
-	// FindAll returns all the employees
-	GetAll() ([]Employee, error)
+	// FindAll returns all the employees, the deleted ones only if includeDeleted
+	GetAll(includeDeleted bool) ([]Employee, error)
+	// ForEach calls fn with every employee (the deleted ones only if includeDeleted) without loading all of them in memory, it stops at the first error returned by fn
+	ForEach(includeDeleted bool, fn func(employee Employee) error) error
 	// FindByID returns the employee with the given ID
 	Get(id int) (Employee, error)
 	// Save saves the given employee
-	Save(employee *Employee) error
+	Save(ctx context.Context, employee *Employee) error
+	// Validate checks the given employee as Save does, without saving it
+	Validate(employee *Employee) error
 	// Update updates the given employee
-	Update(employee *Employee) error
-	// Delete deletes the employee with the given ID
-	Delete(id int) error
+	Update(ctx context.Context, employee *Employee) error
+	// Delete marks the employee with the given ID as deleted, at the given version (any if zero)
+	Delete(ctx context.Context, id int, version int) error
+	// Restore unmarks the deleted employee with the given ID
+	Restore(ctx context.Context, id int) error
+	// Purge deletes the employee with the given ID permanently, deleted or not, at the given version (any if zero).
+	// The rows that depend on it are deleted or nulled with it only if the context confirms the cascade (WithCascadeConfirmed)
+	Purge(ctx context.Context, id int, version int) error
+	// Assign moves the employee to the warehouse of the assignment from its start date (today when zero)
+	Assign(ctx context.Context, assignment *EmployeeAssignment) error
+	// GetAssignments returns the assignments of the employee, the oldest first
+	GetAssignments(employeeID int) ([]EmployeeAssignment, error)
+	// GetByWarehouseAt returns the employees that worked at the warehouse on the given date (today when zero)
+	GetByWarehouseAt(warehouseID int, at time.Time) ([]Employee, error)
 	// getReportInboudOrder returns the inbound order report for the given employee id or all orders for all employees
 	// GetReportInboudOrders(id int) (iorr []InboundOrderReport, err error)
 }
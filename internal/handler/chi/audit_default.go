@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/manuelfirman/go-API/internal"
+	"github.com/manuelfirman/go-API/platform/web/response"
+)
+
+// HeaderActor is the header that names who makes the changes of a request. The API has no authentication yet,
+// so the actor recorded in the audit log is the one the client declares, unverified. It is not an identity:
+// an authenticated one must be recorded apart from it, never in its place.
+const HeaderActor = "X-Actor"
+
+// AuditEntryJSON is the JSON representation of an entry of the audit log
+type AuditEntryJSON struct {
+	// ID is the unique identifier of the entry
+	ID int `json:"id"`
+	// Resource is the kind of the resource that changed
+	Resource string `json:"resource"`
+	// ResourceID is the unique identifier of the resource that changed
+	ResourceID int `json:"resource_id"`
+	// Action is what was done to the resource: create, update, delete, restore or purge
+	Action string `json:"action"`
+	// Before are the fields that changed with their values before the change
+	Before map[string]any `json:"before"`
+	// After are the fields that changed with their values after the change
+	After map[string]any `json:"after"`
+	// Actor is who the request that made the change declared to be (HeaderActor), unverified
+	Actor *string `json:"actor"`
+	// RequestID is the unique identifier of the request that made the change
+	RequestID *string `json:"request_id"`
+	// CreatedAt is the moment at which the change was made
+	CreatedAt string `json:"created_at"`
+}
+
+// AuditSource is a middleware that puts who makes the changes of the request (HeaderActor) and the id of the request
+// (set by the RequestID middleware of chi) in its context, so the services record them in the audit log
+func AuditSource(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := internal.WithAuditSource(r.Context(), internal.AuditSource{
+			Actor:     r.Header.Get(HeaderActor),
+			RequestID: middleware.GetReqID(r.Context()),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestClient returns the client that sends the request, to scope what belongs to it (e.g. its idempotency keys):
+// the actor it declares (HeaderActor) or, if none, its network address
+func RequestClient(r *http.Request) string {
+	if actor := r.Header.Get(HeaderActor); actor != "" {
+		return "actor:" + actor
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "addr:" + host
+}
+
+// NewAuditDefault creates a new instance of the audit handler
+func NewAuditDefault(sv internal.AuditService) *AuditDefault {
+	return &AuditDefault{
+		sv: sv,
+	}
+}
+
+// AuditDefault is the default implementation of the audit handler
+type AuditDefault struct {
+	sv internal.AuditService
+}
+
+// GetAll returns the entries of the audit log, the oldest first, optionally only the ones of a resource
+// (?resource= and ?id=) and the ones made since a moment (?since=, RFC 3339 or YYYY-MM-DD)
+func (h *AuditDefault) GetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get the filter from the query
+		filter := internal.AuditFilter{
+			Resource: r.URL.Query().Get("resource"),
+		}
+		if v := r.URL.Query().Get("id"); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil || id <= 0 {
+				response.Error(w, http.StatusBadRequest, "invalid id")
+				return
+			}
+			filter.ResourceID = id
+		}
+		if v := r.URL.Query().Get("since"); v != "" {
+			since, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				since, err = time.Parse(DateLayout, v)
+			}
+			if err != nil {
+				response.Error(w, http.StatusBadRequest, "invalid since, expected RFC 3339 or YYYY-MM-DD")
+				return
+			}
+			filter.Since = since
+		}
+
+		// process
+		entries, err := h.sv.GetAll(filter)
+		if err != nil {
+			switch {
+			case errors.Is(err, internal.ErrAuditService):
+				response.Error(w, http.StatusInternalServerError, "internal server error")
+			case errors.Is(err, internal.ErrAuditServiceUnknown):
+				response.Error(w, http.StatusInternalServerError, "unknown service error")
+			default:
+				response.Error(w, http.StatusInternalServerError, "unknown server error")
+			}
+			return
+		}
+
+		// response
+		data := make([]AuditEntryJSON, len(entries))
+		for i, e := range entries {
+			data[i] = AuditEntryJSON{
+				ID:         e.ID,
+				Resource:   e.Resource,
+				ResourceID: e.ResourceID,
+				Action:     e.Action,
+				Before:     e.Before,
+				After:      e.After,
+				Actor:      optionalString(e.Actor),
+				RequestID:  optionalString(e.RequestID),
+				CreatedAt:  e.CreatedAt.Format(time.RFC3339),
+			}
+		}
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    data,
+		})
+	}
+}
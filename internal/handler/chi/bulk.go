@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/manuelfirman/go-API/internal"
+	"github.com/manuelfirman/go-API/platform/web/response"
+)
+
+const (
+	// bulkMaxItems is the maximum number of items accepted by a bulk request
+	bulkMaxItems = 10000
+	// bulkModeAtomic saves either all the items or none
+	bulkModeAtomic = "atomic"
+	// bulkModePerItem saves every valid item independently
+	bulkModePerItem = "per_item"
+)
+
+var (
+	// ErrHandlerBulkContentType is the error returned when the bulk body is neither a json array nor ndjson
+	ErrHandlerBulkContentType = errors.New("unsupported bulk content type")
+	// ErrHandlerBulkTooLarge is the error returned when the bulk body has too many items
+	ErrHandlerBulkTooLarge = errors.New("too many bulk items")
+	// ErrHandlerBulkMode is the error returned when the bulk mode is unknown
+	ErrHandlerBulkMode = errors.New("invalid bulk mode")
+)
+
+// BulkResultJSON is the result of a single item of a bulk request
+type BulkResultJSON struct {
+	// Index is the position of the item in the request
+	Index int `json:"index"`
+	// ID is the unique identifier of the created item
+	ID int `json:"id,omitempty"`
+	// Error is the reason why the item was not created
+	Error string `json:"error,omitempty"`
+}
+
+// bulkAtomic returns whether the bulk request must be saved atomically (mode query param, atomic by default)
+func bulkAtomic(r *http.Request) (bool, error) {
+	switch r.URL.Query().Get("mode") {
+	case "", bulkModeAtomic:
+		return true, nil
+	case bulkModePerItem:
+		return false, nil
+	default:
+		return false, ErrHandlerBulkMode
+	}
+}
+
+// readBulkItems reads the items of a bulk request, either a json array or ndjson (one item per line)
+func readBulkItems(r *http.Request) (items []json.RawMessage, err error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		err = ErrHandlerBulkContentType
+		return
+	}
+
+	switch mediaType {
+	case "application/json":
+		err = json.NewDecoder(r.Body).Decode(&items)
+	case "application/x-ndjson":
+		reader := bufio.NewReader(r.Body)
+		for {
+			line, rdErr := reader.ReadBytes('\n')
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				if !json.Valid(line) {
+					err = errors.New("invalid ndjson line")
+					return
+				}
+				items = append(items, json.RawMessage(line))
+				if len(items) > bulkMaxItems {
+					break
+				}
+			}
+			if rdErr == io.EOF {
+				break
+			}
+			if rdErr != nil {
+				err = rdErr
+				return
+			}
+		}
+	default:
+		err = ErrHandlerBulkContentType
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	switch {
+	case len(items) == 0:
+		err = errors.New("empty bulk body")
+	case len(items) > bulkMaxItems:
+		err = ErrHandlerBulkTooLarge
+	}
+	return
+}
+
+// bulkRequestError writes the error response for an error returned while reading a bulk request
+func bulkRequestError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrHandlerBulkContentType):
+		response.Error(w, http.StatusUnsupportedMediaType, "unsupported content type")
+	case errors.Is(err, ErrHandlerBulkTooLarge):
+		response.Error(w, http.StatusRequestEntityTooLarge, "too many items")
+	case errors.Is(err, ErrHandlerBulkMode):
+		response.Error(w, http.StatusBadRequest, "invalid mode")
+	default:
+		response.Error(w, http.StatusBadRequest, "invalid body")
+	}
+}
+
+// bulkResults merges the validation errors and the results of the saved items into the results of the request.
+// - invalid holds the validation error of each invalid item by its index in the request
+// - indices holds the index in the request of each item sent to the service
+// - message returns the error message of a service error
+func bulkResults(n int, invalid map[int]error, indices []int, saved []internal.BulkResult, message func(error) string) (results []BulkResultJSON) {
+	results = make([]BulkResultJSON, n)
+	for i := range results {
+		results[i].Index = i
+		// items not sent to the service (atomic request with invalid items)
+		results[i].Error = internal.ErrBulkNotSaved.Error()
+	}
+	for i, err := range invalid {
+		results[i].Error = err.Error()
+	}
+	for _, s := range saved {
+		idx := indices[s.Index]
+		switch {
+		case s.Err == nil:
+			results[idx].ID = s.ID
+			results[idx].Error = ""
+		case errors.Is(s.Err, internal.ErrBulkNotSaved):
+			results[idx].Error = s.Err.Error()
+		default:
+			results[idx].Error = message(s.Err)
+		}
+	}
+
+	return
+}
+
+// writeBulkResults writes the response of a bulk request
+// - 201 if every item was created
+// - 207 if only some of them were created
+// - 422 if none of them was created
+func writeBulkResults(w http.ResponseWriter, results []BulkResultJSON) {
+	created := 0
+	for _, r := range results {
+		if r.Error == "" {
+			created++
+		}
+	}
+
+	code, message := http.StatusMultiStatus, "some items were not created"
+	switch {
+	case created == len(results):
+		code, message = http.StatusCreated, "success"
+	case created == 0:
+		code, message = http.StatusUnprocessableEntity, "no items were created"
+	}
+
+	response.JSON(w, code, Response{
+		Message: message,
+		Data: map[string]any{
+			"created": created,
+			"failed":  len(results) - created,
+			"results": results,
+		},
+	})
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/manuelfirman/go-API/internal"
+	"github.com/manuelfirman/go-API/platform/web/etag"
 	"github.com/manuelfirman/go-API/platform/web/request"
 	"github.com/manuelfirman/go-API/platform/web/response"
 )
@@ -24,6 +25,29 @@ type BuyerJSON struct {
 	FirstName string `json:"first_name"`
 	// LastName is the last name of the buyer
 	LastName string `json:"last_name"`
+	// DeletedAt is the moment the buyer was deleted, only listed with ?include_deleted=true (read only)
+	DeletedAt *string `json:"deleted_at,omitempty"`
+}
+
+// BuyerAnalyticsJSON is a struct that contains the purchase activity of a buyer as JSON
+type BuyerAnalyticsJSON struct {
+	BuyerID       int                `json:"buyer_id"`
+	From          *string            `json:"from"`
+	To            *string            `json:"to"`
+	Orders        int                `json:"orders"`
+	Spend         float64            `json:"spend"`
+	AverageBasket float64            `json:"average_basket"`
+	LastOrderDate *string            `json:"last_order_date"`
+	TopProducts   []BuyerProductJSON `json:"top_products"`
+}
+
+// BuyerProductJSON is a struct that contains the purchases of a product by a buyer as JSON
+type BuyerProductJSON struct {
+	ProductID   int     `json:"product_id"`
+	ProductCode string  `json:"product_code"`
+	Description string  `json:"description"`
+	Orders      int     `json:"orders"`
+	Spend       float64 `json:"spend"`
 }
 
 // NewBuyerDefault creates a new instance of the buyer handler
@@ -42,30 +66,40 @@ type BuyerDefault struct {
 // GetAll returns all buyers
 func (h *BuyerDefault) GetAll() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		buyers, err := h.sv.GetAll()
+		// request
+		// - include the deleted buyers with ?include_deleted=true
+		includeDeleted, err := queryBool(r, "include_deleted")
 		if err != nil {
-			switch {
-			case errors.Is(err, internal.ErrBuyerService):
-				response.Error(w, http.StatusInternalServerError, "internal server error")
-			case errors.Is(err, internal.ErrBuyerServiceUnkown):
-				response.Error(w, http.StatusInternalServerError, "unknown service error")
-			default:
-				response.Error(w, http.StatusInternalServerError, "unknown server error")
-			}
-
+			response.Error(w, http.StatusBadRequest, "invalid include_deleted")
 			return
 		}
 
-		// serialize buyers
-		data := make([]BuyerJSON, len(buyers))
-		for i, b := range buyers {
-			data[i] = serializeBuyer(b)
-		}
+		// response
+		// - writer of the format requested by the client
+		sw := newStreamWriter(w, r, http.StatusOK, BuyerJSON{}, "success")
 
-		response.JSON(w, http.StatusOK, Response{
-			Message: "success",
-			Data:    data,
+		// process
+		// - write every buyer as it is read
+		err = h.sv.ForEach(includeDeleted, func(buyer internal.Buyer) error {
+			return sw.Write(serializeBuyer(buyer))
 		})
+		if err != nil {
+			// - nothing sent yet: regular error response, otherwise the response is left truncated
+			if !sw.Started() {
+				switch {
+				case errors.Is(err, internal.ErrBuyerService):
+					response.Error(w, http.StatusInternalServerError, "internal server error")
+				case errors.Is(err, internal.ErrBuyerServiceUnkown):
+					response.Error(w, http.StatusInternalServerError, "unknown service error")
+				default:
+					response.Error(w, http.StatusInternalServerError, "unknown server error")
+				}
+			}
+			return
+		}
+
+		// - end of the list
+		sw.Close()
 	}
 }
 
@@ -97,6 +131,12 @@ func (h *BuyerDefault) Get() http.HandlerFunc {
 
 			return
 		}
+		// - the client already has the current version
+		etag.Set(w, buyer.Version)
+		if etag.IfNoneMatch(r, buyer.Version) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
 		// - serialize buyer
 		data := serializeBuyer(buyer)
 
@@ -147,7 +187,7 @@ func (h *BuyerDefault) Save() http.HandlerFunc {
 		// - deserialize the BuyerJSON to an internal Buyer
 		buyer := deserializeBuyer(buyerJSON)
 		// - save buyer
-		err = h.sv.Save(&buyer)
+		err = h.sv.Save(r.Context(), &buyer)
 		if err != nil {
 			switch {
 			case errors.Is(err, internal.ErrBuyerServiceDuplicated):
@@ -174,6 +214,57 @@ func (h *BuyerDefault) Save() http.HandlerFunc {
 	}
 }
 
+// Import creates the buyers of a csv document, one per row (with ?dry_run=true the rows are only validated)
+func (h *BuyerDefault) Import() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - options
+		dryRun, mapping, err := importOptions(r)
+		if err != nil {
+			importRequestError(w, err)
+			return
+		}
+
+		// - read the rows
+		records, err := request.CSV(r, BuyerJSON{}, mapping)
+		if err != nil {
+			importRequestError(w, err)
+			return
+		}
+
+		// process
+		// - validate and save every row on its own
+		results := make([]ImportResultJSON, len(records))
+		for i, record := range records {
+			results[i].Line = record.Line
+
+			// - parse the row
+			err := record.Err
+			var buyer internal.Buyer
+			if err == nil {
+				buyer, err = parseBuyerItem(record.Body)
+			}
+
+			// - validate or save the buyer
+			if err == nil {
+				if dryRun {
+					err = h.sv.Validate(&buyer)
+				} else {
+					err = h.sv.Save(r.Context(), &buyer)
+				}
+			}
+			if err != nil {
+				results[i].Error = buyerItemError(err)
+				continue
+			}
+			results[i].ID = buyer.ID
+		}
+
+		// response
+		writeImportResults(w, dryRun, results)
+	}
+}
+
 // Update updates the given buyer
 func (h *BuyerDefault) Update() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -203,15 +294,23 @@ func (h *BuyerDefault) Update() http.HandlerFunc {
 			return
 		}
 
+		// - check the buyer was not modified since the client read it
+		if !etag.IfMatch(r, buyer.Version) {
+			response.Error(w, http.StatusPreconditionFailed, "buyer has been modified")
+			return
+		}
+		version := buyer.Version
+
 		// - serialize internal buyer
 		buyerJSON := serializeBuyer(buyer)
-		// - map the body json to the buyer
-		if err := request.JSON(r, &buyerJSON); err != nil {
-			response.Error(w, http.StatusBadRequest, "invalid body")
+		// - apply the body (merge patch or json patch) to the buyer
+		if err := request.Patch(r, &buyerJSON); err != nil {
+			patchError(w, err)
 			return
 		}
 
-		// - validate the buyer
+		// - validate the buyer (the id comes from the URL)
+		buyerJSON.ID = 0
 		if err := validateBuyerZeroValues(buyerJSON); err != nil {
 			response.Error(w, http.StatusBadRequest, err.Error())
 			return
@@ -219,13 +318,17 @@ func (h *BuyerDefault) Update() http.HandlerFunc {
 
 		// - deserialize buyerJSON to internal buyer
 		buyer = deserializeBuyer(buyerJSON)
+		buyer.ID = id
+		buyer.Version = version
 
 		// - update buyer
-		err = h.sv.Update(&buyer)
+		err = h.sv.Update(r.Context(), &buyer)
 		if err != nil {
 			switch {
 			case errors.Is(err, internal.ErrBuyerServiceNotFound):
 				response.Error(w, http.StatusNotFound, "buyer not found")
+			case errors.Is(err, internal.ErrBuyerServiceVersionConflict):
+				response.Error(w, http.StatusPreconditionFailed, "buyer has been modified")
 			case errors.Is(err, internal.ErrBuyerService):
 				response.Error(w, http.StatusInternalServerError, "internal server error")
 			case errors.Is(err, internal.ErrBuyerServiceUnkown):
@@ -238,6 +341,7 @@ func (h *BuyerDefault) Update() http.HandlerFunc {
 		}
 
 		// - serialize buyer to response
+		etag.Set(w, buyer.Version)
 		data := serializeBuyer(buyer)
 
 		// response
@@ -248,7 +352,7 @@ func (h *BuyerDefault) Update() http.HandlerFunc {
 	}
 }
 
-// Delete deletes the buyer by ID
+// Delete marks the buyer as deleted by ID, or deletes it permanently with ?hard=true
 func (h *BuyerDefault) Delete() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// request
@@ -258,14 +362,48 @@ func (h *BuyerDefault) Delete() http.HandlerFunc {
 			response.Error(w, http.StatusBadRequest, "invalid id")
 			return
 		}
+		// - delete it permanently with ?hard=true, otherwise it is only marked as deleted
+		hard, err := queryBool(r, "hard")
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid hard")
+			return
+		}
+
+		// - check the buyer was not modified since the client read it (the delete only applies to that version)
+		version := 0
+		if r.Header.Get("If-Match") != "" {
+			buyer, err := h.sv.Get(id)
+			if err != nil {
+				switch {
+				case errors.Is(err, internal.ErrBuyerServiceNotFound):
+					response.Error(w, http.StatusNotFound, "buyer not found")
+				default:
+					response.Error(w, http.StatusInternalServerError, "internal server error")
+				}
+				return
+			}
+			if !etag.IfMatch(r, buyer.Version) {
+				response.Error(w, http.StatusPreconditionFailed, "buyer has been modified")
+				return
+			}
+			version = buyer.Version
+		}
 
 		// process
 		// - delete buyer by id
-		err = h.sv.Delete(id)
+		if hard {
+			err = h.sv.Purge(r.Context(), id, version)
+		} else {
+			err = h.sv.Delete(r.Context(), id, version)
+		}
 		if err != nil {
 			switch {
 			case errors.Is(err, internal.ErrBuyerServiceNotFound):
 				response.Error(w, http.StatusNotFound, "buyer not found")
+			case errors.Is(err, internal.ErrBuyerServiceVersionConflict):
+				response.Error(w, http.StatusPreconditionFailed, "buyer has been modified")
+			case errors.Is(err, internal.ErrDependentsServiceUnconfirmed):
+				response.Error(w, http.StatusConflict, "resource has dependents, confirm the delete with ?confirm=cascade")
 			case errors.Is(err, internal.ErrBuyerServiceFK):
 				response.Error(w, http.StatusConflict, "buyer has purchase orders")
 			case errors.Is(err, internal.ErrBuyerService):
@@ -286,6 +424,126 @@ func (h *BuyerDefault) Delete() http.HandlerFunc {
 	}
 }
 
+// Restore restores the deleted buyer with the given ID and returns it
+func (h *BuyerDefault) Restore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		// process
+		// - unmark the buyer
+		err = h.sv.Restore(r.Context(), id)
+		if err != nil {
+			switch {
+			case errors.Is(err, internal.ErrBuyerServiceNotFound):
+				response.Error(w, http.StatusNotFound, "deleted buyer not found")
+			case errors.Is(err, internal.ErrBuyerService):
+				response.Error(w, http.StatusInternalServerError, "internal server error")
+			case errors.Is(err, internal.ErrBuyerServiceUnkown):
+				response.Error(w, http.StatusInternalServerError, "unknown service error")
+			default:
+				response.Error(w, http.StatusInternalServerError, "unknown server error")
+			}
+			return
+		}
+		// - get it back
+		buyer, err := h.sv.Get(id)
+		if err != nil {
+			response.Error(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		// response
+		etag.Set(w, buyer.Version)
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    serializeBuyer(buyer),
+		})
+	}
+}
+
+// Analytics returns the purchase activity of a buyer between the days of ?from= and ?to= (unbounded when missing),
+// with its ?top= most ordered products
+func (h *BuyerDefault) Analytics() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from URL
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+		// - get the period from the query
+		from, err := queryDate(r, "from")
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		to, err := queryDate(r, "to")
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		// - get the number of top products from the query
+		var top int
+		if v := r.URL.Query().Get("top"); v != "" {
+			if top, err = strconv.Atoi(v); err != nil {
+				response.Error(w, http.StatusBadRequest, "invalid top")
+				return
+			}
+		}
+
+		// process
+		a, err := h.sv.GetAnalytics(id, from, to, top)
+		if err != nil {
+			switch {
+			case errors.Is(err, internal.ErrBuyerServiceInvalidFilter):
+				response.Error(w, http.StatusBadRequest, err.Error())
+			case errors.Is(err, internal.ErrBuyerServiceNotFound):
+				response.Error(w, http.StatusNotFound, "buyer not found")
+			case errors.Is(err, internal.ErrBuyerService):
+				response.Error(w, http.StatusInternalServerError, "internal server error")
+			case errors.Is(err, internal.ErrBuyerServiceUnkown):
+				response.Error(w, http.StatusInternalServerError, "unknown service error")
+			default:
+				response.Error(w, http.StatusInternalServerError, "unknown server error")
+			}
+
+			return
+		}
+
+		// response
+		data := BuyerAnalyticsJSON{
+			BuyerID:       a.BuyerID,
+			From:          optionalDate(a.From),
+			To:            optionalDate(a.To),
+			Orders:        a.Orders,
+			Spend:         a.Spend,
+			AverageBasket: a.AverageBasket,
+			LastOrderDate: optionalDate(a.LastOrderDate),
+			TopProducts:   make([]BuyerProductJSON, len(a.TopProducts)),
+		}
+		for i, p := range a.TopProducts {
+			data.TopProducts[i] = BuyerProductJSON{
+				ProductID:   p.ProductID,
+				ProductCode: p.ProductCode,
+				Description: p.Description,
+				Orders:      p.Orders,
+				Spend:       p.Spend,
+			}
+		}
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    data,
+		})
+	}
+}
+
 // serializeBuyer converts an internal Buyer to a BuyerJSON
 func serializeBuyer(b internal.Buyer) BuyerJSON {
 	return BuyerJSON{
@@ -293,6 +551,7 @@ func serializeBuyer(b internal.Buyer) BuyerJSON {
 		CardNumberID: b.CardNumberID,
 		FirstName:    b.FirstName,
 		LastName:     b.LastName,
+		DeletedAt:    optionalTime(b.DeletedAt),
 	}
 }
 
@@ -306,6 +565,45 @@ func deserializeBuyer(b BuyerJSON) internal.Buyer {
 	}
 }
 
+// parseBuyerItem validates a buyer item of an import request as Save does
+func parseBuyerItem(body []byte) (buyer internal.Buyer, err error) {
+	// - validate the body keys
+	var bodyMap map[string]any
+	if err = json.Unmarshal(body, &bodyMap); err != nil {
+		err = errors.New("invalid body: cannot unmarshal to map")
+		return
+	}
+	if err = validateKeyExistance(bodyMap, "card_number_id", "first_name", "last_name"); err != nil {
+		return
+	}
+
+	// - validate the body values
+	var buyerJSON BuyerJSON
+	if err = json.Unmarshal(body, &buyerJSON); err != nil {
+		err = errors.New("invalid body: cannot unmarshal to struct")
+		return
+	}
+	if err = validateBuyerZeroValues(buyerJSON); err != nil {
+		return
+	}
+
+	buyer = deserializeBuyer(buyerJSON)
+	return
+}
+
+// buyerItemError returns the message of an error of a buyer item of an import request
+func buyerItemError(err error) string {
+	switch {
+	case errors.Is(err, internal.ErrBuyerServiceDuplicated):
+		return "buyer already exists"
+	case errors.Is(err, internal.ErrBuyerService), errors.Is(err, internal.ErrBuyerServiceUnkown):
+		return "unknown error"
+	default:
+		// validation error
+		return err.Error()
+	}
+}
+
 // validateBuyerZeroValues validates if the buyer has zero values
 func validateBuyerZeroValues(b BuyerJSON) error {
 	// validate id
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/manuelfirman/go-API/internal"
+	"github.com/manuelfirman/go-API/platform/web/response"
+)
+
+// DependentsJSON is the JSON representation of the rows that depend on a resource
+type DependentsJSON struct {
+	// Resource is the kind of the resource
+	Resource string `json:"resource"`
+	// ID is the unique identifier of the resource
+	ID int `json:"id"`
+	// Dependents are the kinds of rows that depend on the resource
+	Dependents []DependentJSON `json:"dependents"`
+}
+
+// DependentJSON is the JSON representation of the rows of a kind that depend on a resource
+type DependentJSON struct {
+	// Kind is the kind of the rows
+	Kind string `json:"kind"`
+	// Effect is what deleting the resource permanently does to the rows: deleted, nulled or blocking
+	Effect string `json:"effect"`
+	// Count is the number of rows
+	Count int `json:"count"`
+}
+
+// NewDependentsDefault creates a new instance of the dependents handler
+func NewDependentsDefault(sv internal.DependentsService) *DependentsDefault {
+	return &DependentsDefault{
+		sv: sv,
+	}
+}
+
+// DependentsDefault is the default implementation of the dependents handler
+type DependentsDefault struct {
+	sv internal.DependentsService
+}
+
+// Get returns the rows that depend on a resource of the given kind: what deleting it permanently would remove or null
+func (h *DependentsDefault) Get(resource string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		// process
+		d, err := h.sv.Get(resource, id)
+		if err != nil {
+			writeDependentsError(w, err)
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    serializeDependents(d),
+		})
+	}
+}
+
+// ConfirmCascade returns a middleware that guards the permanent deletes (?hard=true) of the resources of the given kind:
+// if rows that block the delete depend on the resource it is rejected with 409, confirmed or not, and if other rows
+// depend on it the delete must be confirmed with ?confirm=cascade, otherwise it is rejected with 409. Both responses
+// carry the dependents. The confirmation goes on in the context, so the delete checks the dependents again in its
+// own transaction. The other requests go through as they are.
+func (h *DependentsDefault) ConfirmCascade(resource string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// request
+			// - only permanent deletes are checked (an invalid flag is rejected by the handler)
+			hard, err := queryBool(r, "hard")
+			if err != nil || !hard {
+				next.ServeHTTP(w, r)
+				return
+			}
+			confirmed := r.URL.Query().Get("confirm") == "cascade"
+			// - get id from url
+			id, err := strconv.Atoi(chi.URLParam(r, "id"))
+			if err != nil {
+				response.Error(w, http.StatusBadRequest, "invalid id")
+				return
+			}
+
+			// process
+			d, err := h.sv.Get(resource, id)
+			if err != nil {
+				writeDependentsError(w, err)
+				return
+			}
+			switch {
+			case d.Blocked():
+				response.JSON(w, http.StatusConflict, Response{
+					Message: "resource has blocking dependents, it can't be deleted permanently",
+					Data:    serializeDependents(d),
+				})
+				return
+			case d.Any() && !confirmed:
+				response.JSON(w, http.StatusConflict, Response{
+					Message: "resource has dependents, confirm the delete with ?confirm=cascade",
+					Data:    serializeDependents(d),
+				})
+				return
+			}
+
+			// - nothing blocks the delete: pass the confirmation on
+			if confirmed {
+				r = r.WithContext(internal.WithCascadeConfirmed(r.Context()))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeDependentsError writes the error response for an error returned by the dependents service
+func writeDependentsError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, internal.ErrDependentsServiceNotFound):
+		response.Error(w, http.StatusNotFound, "resource not found")
+	case errors.Is(err, internal.ErrDependentsService):
+		response.Error(w, http.StatusInternalServerError, "internal server error")
+	case errors.Is(err, internal.ErrDependentsServiceUnknown):
+		response.Error(w, http.StatusInternalServerError, "unknown service error")
+	default:
+		response.Error(w, http.StatusInternalServerError, "unknown server error")
+	}
+}
+
+// serializeDependents serializes the dependents of a resource into a DependentsJSON
+func serializeDependents(d internal.Dependents) DependentsJSON {
+	data := DependentsJSON{
+		Resource:   d.Resource,
+		ID:         d.ID,
+		Dependents: make([]DependentJSON, len(d.Items)),
+	}
+	for i, item := range d.Items {
+		data.Dependents[i] = DependentJSON{
+			Kind:   item.Kind,
+			Effect: item.Effect,
+			Count:  item.Count,
+		}
+	}
+	return data
+}
@@ -7,20 +7,38 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/manuelfirman/go-API/internal"
+	"github.com/manuelfirman/go-API/platform/web/etag"
 	"github.com/manuelfirman/go-API/platform/web/request"
 	"github.com/manuelfirman/go-API/platform/web/response"
 )
 
 // EmployeeJSON is the json response of a employee
 type EmployeeJSON struct {
-	ID           int    `json:"id" example:"1"`
-	CardNumberID int    `json:"card_number_id" example:"1234"`
-	FirstName    string `json:"first_name" example:"John"`
-	LastName     string `json:"last_name" example:"Doe"`
-	WarehouseID  int    `json:"warehouse_id" example:"1"`
+	ID           int     `json:"id" example:"1"`
+	CardNumberID int     `json:"card_number_id" example:"1234"`
+	FirstName    string  `json:"first_name" example:"John"`
+	LastName     string  `json:"last_name" example:"Doe"`
+	WarehouseID  int     `json:"warehouse_id" example:"1"`
+	DeletedAt    *string `json:"deleted_at,omitempty"`
+}
+
+// EmployeeAssignmentJSON is the json response of an assignment of an employee to a warehouse
+type EmployeeAssignmentJSON struct {
+	ID          int     `json:"id" example:"1"`
+	EmployeeID  int     `json:"employee_id" example:"1"`
+	WarehouseID int     `json:"warehouse_id" example:"1"`
+	StartDate   string  `json:"start_date" example:"2024-01-01"`
+	EndDate     *string `json:"end_date" example:"2024-06-01"`
+}
+
+// AssignJSON is the json request to move an employee to a warehouse
+type AssignJSON struct {
+	WarehouseID   int    `json:"warehouse_id" example:"2"`
+	EffectiveDate string `json:"effective_date" example:"2024-06-01"`
 }
 
 // NewEmployeeDefault creates a new instance of the employee handler
@@ -37,29 +55,40 @@ type EmployeeDefault struct {
 
 func (h *EmployeeDefault) GetAll() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		employees, err := h.sv.GetAll()
+		// request
+		// - include the deleted employees with ?include_deleted=true
+		includeDeleted, err := queryBool(r, "include_deleted")
 		if err != nil {
-			switch err {
-			case internal.ErrEmployeeServiceInternalError:
-				response.Error(w, http.StatusInternalServerError, "internal server error")
-			case internal.ErrEmployeeServiceUnknown:
-				response.Error(w, http.StatusInternalServerError, "unknown service error")
-			default:
-				response.Error(w, http.StatusInternalServerError, "unknown server error")
-			}
-
+			response.Error(w, http.StatusBadRequest, "invalid include_deleted")
 			return
 		}
 
-		data := make([]EmployeeJSON, len(employees))
-		for i, v := range employees {
-			data[i] = serializeEmployee(v)
-		}
+		// response
+		// - writer of the format requested by the client
+		sw := newStreamWriter(w, r, http.StatusOK, EmployeeJSON{}, "success")
 
-		response.JSON(w, http.StatusOK, Response{
-			Message: "success",
-			Data:    data,
+		// process
+		// - write every employee as it is read
+		err = h.sv.ForEach(includeDeleted, func(employee internal.Employee) error {
+			return sw.Write(serializeEmployee(employee))
 		})
+		if err != nil {
+			// - nothing sent yet: regular error response, otherwise the response is left truncated
+			if !sw.Started() {
+				switch err {
+				case internal.ErrEmployeeServiceInternalError:
+					response.Error(w, http.StatusInternalServerError, "internal server error")
+				case internal.ErrEmployeeServiceUnknown:
+					response.Error(w, http.StatusInternalServerError, "unknown service error")
+				default:
+					response.Error(w, http.StatusInternalServerError, "unknown server error")
+				}
+			}
+			return
+		}
+
+		// - end of the list
+		sw.Close()
 	}
 }
 
@@ -92,6 +121,12 @@ func (h *EmployeeDefault) Get() http.HandlerFunc {
 			return
 		}
 
+		// - the client already has the current version
+		etag.Set(w, employee.Version)
+		if etag.IfNoneMatch(r, employee.Version) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
 		// - serialize
 		data := serializeEmployee(employee)
 
@@ -142,11 +177,13 @@ func (h *EmployeeDefault) Save() http.HandlerFunc {
 		// - deserialize EmployeeJSON to an internal employee
 		employee := deserializeEmployee(employeeJSON)
 		// - save employee
-		err = h.sv.Save(&employee)
+		err = h.sv.Save(r.Context(), &employee)
 		if err != nil {
 			switch {
 			case errors.Is(err, internal.ErrEmployeeServiceDuplicated):
 				response.Error(w, http.StatusConflict, "employee already exists")
+			case errors.Is(err, internal.ErrEmployeeServiceWarehouseNotFound):
+				response.Error(w, http.StatusConflict, "warehouse not found")
 			case errors.Is(err, internal.ErrEmployeeServiceInternalError):
 				response.Error(w, http.StatusInternalServerError, "internal server error")
 			case errors.Is(err, internal.ErrEmployeeServiceUnknown):
@@ -169,6 +206,57 @@ func (h *EmployeeDefault) Save() http.HandlerFunc {
 	}
 }
 
+// Import creates the employees of a csv document, one per row (with ?dry_run=true the rows are only validated)
+func (h *EmployeeDefault) Import() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - options
+		dryRun, mapping, err := importOptions(r)
+		if err != nil {
+			importRequestError(w, err)
+			return
+		}
+
+		// - read the rows
+		records, err := request.CSV(r, EmployeeJSON{}, mapping)
+		if err != nil {
+			importRequestError(w, err)
+			return
+		}
+
+		// process
+		// - validate and save every row on its own
+		results := make([]ImportResultJSON, len(records))
+		for i, record := range records {
+			results[i].Line = record.Line
+
+			// - parse the row
+			err := record.Err
+			var employee internal.Employee
+			if err == nil {
+				employee, err = parseEmployeeItem(record.Body)
+			}
+
+			// - validate or save the employee
+			if err == nil {
+				if dryRun {
+					err = h.sv.Validate(&employee)
+				} else {
+					err = h.sv.Save(r.Context(), &employee)
+				}
+			}
+			if err != nil {
+				results[i].Error = employeeItemError(err)
+				continue
+			}
+			results[i].ID = employee.ID
+		}
+
+		// response
+		writeImportResults(w, dryRun, results)
+	}
+}
+
 // Update updates the given employee
 func (h *EmployeeDefault) Update() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -197,29 +285,43 @@ func (h *EmployeeDefault) Update() http.HandlerFunc {
 			return
 		}
 
+		// - check the employee was not modified since the client read it
+		if !etag.IfMatch(r, employee.Version) {
+			response.Error(w, http.StatusPreconditionFailed, "employee has been modified")
+			return
+		}
+		version := employee.Version
+
 		// - serialize the employee
 		employeeJSON := serializeEmployee(employee)
 
-		// - map the json to employee
-		if err = request.JSON(r, &employeeJSON); err != nil {
-			response.Error(w, http.StatusBadRequest, "invalid body")
+		// - apply the body (merge patch or json patch) to the employee
+		if err = request.Patch(r, &employeeJSON); err != nil {
+			patchError(w, err)
 			return
 		}
 
 		// process
-		// - validate the employee
+		// - validate the employee (the id comes from the URL)
+		employeeJSON.ID = 0
 		if err = validateEmployeeZeroValues(employeeJSON); err != nil {
 			response.Error(w, http.StatusBadRequest, err.Error())
 			return
 		}
 		// - deserialize the employee
 		employee = deserializeEmployee(employeeJSON)
+		employee.ID = id
+		employee.Version = version
 		// - update the employee
-		err = h.sv.Update(&employee)
+		err = h.sv.Update(r.Context(), &employee)
 		if err != nil {
 			switch {
+			case errors.Is(err, internal.ErrEmployeeServiceVersionConflict):
+				response.Error(w, http.StatusPreconditionFailed, "employee has been modified")
 			case errors.Is(err, internal.ErrEmployeeServiceDuplicated):
 				response.Error(w, http.StatusConflict, "employee already exists")
+			case errors.Is(err, internal.ErrEmployeeServiceWarehouseNotFound):
+				response.Error(w, http.StatusConflict, "warehouse not found")
 			case errors.Is(err, internal.ErrEmployeeServiceInternalError):
 				response.Error(w, http.StatusInternalServerError, "internal server error")
 			case errors.Is(err, internal.ErrEmployeeServiceUnknown):
@@ -232,6 +334,7 @@ func (h *EmployeeDefault) Update() http.HandlerFunc {
 		}
 
 		// - serialize the employee to response
+		etag.Set(w, employee.Version)
 		data := serializeEmployee(employee)
 
 		// response
@@ -242,7 +345,7 @@ func (h *EmployeeDefault) Update() http.HandlerFunc {
 	}
 }
 
-// Delete deletes the employee with the given ID
+// Delete marks the employee with the given ID as deleted, or deletes it permanently with ?hard=true
 func (h *EmployeeDefault) Delete() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// request
@@ -252,14 +355,48 @@ func (h *EmployeeDefault) Delete() http.HandlerFunc {
 			response.Error(w, http.StatusBadRequest, "invalid id")
 			return
 		}
+		// - delete it permanently with ?hard=true, otherwise it is only marked as deleted
+		hard, err := queryBool(r, "hard")
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid hard")
+			return
+		}
+
+		// - check the employee was not modified since the client read it (the delete only applies to that version)
+		version := 0
+		if r.Header.Get("If-Match") != "" {
+			employee, err := h.sv.Get(id)
+			if err != nil {
+				switch {
+				case errors.Is(err, internal.ErrEmployeeServiceNotFound):
+					response.Error(w, http.StatusNotFound, "employee not found")
+				default:
+					response.Error(w, http.StatusInternalServerError, "internal server error")
+				}
+				return
+			}
+			if !etag.IfMatch(r, employee.Version) {
+				response.Error(w, http.StatusPreconditionFailed, "employee has been modified")
+				return
+			}
+			version = employee.Version
+		}
 
 		// process
 		// - delete employee by id
-		err = h.sv.Delete(id)
+		if hard {
+			err = h.sv.Purge(r.Context(), id, version)
+		} else {
+			err = h.sv.Delete(r.Context(), id, version)
+		}
 		if err != nil {
 			switch {
 			case errors.Is(err, internal.ErrEmployeeServiceNotFound):
 				response.Error(w, http.StatusNotFound, "employee not found")
+			case errors.Is(err, internal.ErrEmployeeServiceVersionConflict):
+				response.Error(w, http.StatusPreconditionFailed, "employee has been modified")
+			case errors.Is(err, internal.ErrDependentsServiceUnconfirmed):
+				response.Error(w, http.StatusConflict, "resource has dependents, confirm the delete with ?confirm=cascade")
 			case errors.Is(err, internal.ErrEmployeeServiceInternalError):
 				response.Error(w, http.StatusInternalServerError, "internal server error")
 			case errors.Is(err, internal.ErrEmployeeServiceUnknown):
@@ -280,6 +417,218 @@ func (h *EmployeeDefault) Delete() http.HandlerFunc {
 	}
 }
 
+// Restore restores the deleted employee with the given ID and returns it
+func (h *EmployeeDefault) Restore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		// process
+		// - unmark the employee
+		err = h.sv.Restore(r.Context(), id)
+		if err != nil {
+			switch {
+			case errors.Is(err, internal.ErrEmployeeServiceNotFound):
+				response.Error(w, http.StatusNotFound, "deleted employee not found")
+			case errors.Is(err, internal.ErrEmployeeServiceInternalError):
+				response.Error(w, http.StatusInternalServerError, "internal server error")
+			case errors.Is(err, internal.ErrEmployeeServiceUnknown):
+				response.Error(w, http.StatusInternalServerError, "unknown service error")
+			default:
+				response.Error(w, http.StatusInternalServerError, "unknown server error")
+			}
+			return
+		}
+		// - get it back
+		employee, err := h.sv.Get(id)
+		if err != nil {
+			response.Error(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		// response
+		etag.Set(w, employee.Version)
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    serializeEmployee(employee),
+		})
+	}
+}
+
+// Assign moves the employee to a warehouse from the effective date of the body (today when missing)
+func (h *EmployeeDefault) Assign() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from request
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+		// - read body
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot read")
+			return
+		}
+		// - unmarshal to map for validation
+		var bodyMap map[string]any
+		if err = json.Unmarshal(body, &bodyMap); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot unmarshal to map")
+			return
+		}
+		// - validate the body keys
+		if err = validateKeyExistance(bodyMap, "warehouse_id"); err != nil {
+			response.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		// - unmarshal the body to an AssignJSON
+		var aJSON AssignJSON
+		if err = json.Unmarshal(body, &aJSON); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot unmarshal to struct")
+			return
+		}
+		// - deserialize
+		assignment := internal.EmployeeAssignment{
+			EmployeeID:  id,
+			WarehouseID: aJSON.WarehouseID,
+		}
+		if aJSON.EffectiveDate != "" {
+			if assignment.StartDate, err = time.Parse(DateLayout, aJSON.EffectiveDate); err != nil {
+				response.Error(w, http.StatusBadRequest, ErrHandlerInvalidDate.Error()+": effective_date")
+				return
+			}
+		}
+
+		// process
+		if err = h.sv.Assign(r.Context(), &assignment); err != nil {
+			writeEmployeeAssignmentError(w, err)
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusCreated, Response{
+			Message: "success",
+			Data:    serializeEmployeeAssignment(assignment),
+		})
+	}
+}
+
+// Assignments returns the history of the warehouses the employee worked at, the oldest first
+func (h *EmployeeDefault) Assignments() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from request
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		// process
+		assignments, err := h.sv.GetAssignments(id)
+		if err != nil {
+			writeEmployeeAssignmentError(w, err)
+			return
+		}
+
+		// response
+		data := make([]EmployeeAssignmentJSON, len(assignments))
+		for i, a := range assignments {
+			data[i] = serializeEmployeeAssignment(a)
+		}
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    data,
+		})
+	}
+}
+
+// WarehouseEmployees returns the employees that worked at the warehouse on the date of ?at= (today when missing)
+func (h *EmployeeDefault) WarehouseEmployees() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get the warehouse id from request
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+		// - get the date from the query
+		var at time.Time
+		if v := r.URL.Query().Get("at"); v != "" {
+			if at, err = time.Parse(DateLayout, v); err != nil {
+				response.Error(w, http.StatusBadRequest, ErrHandlerInvalidDate.Error()+": at")
+				return
+			}
+		}
+
+		// process
+		employees, err := h.sv.GetByWarehouseAt(id, at)
+		if err != nil {
+			if errors.Is(err, internal.ErrEmployeeServiceWarehouseNotFound) {
+				response.Error(w, http.StatusNotFound, "warehouse not found")
+				return
+			}
+			writeEmployeeAssignmentError(w, err)
+			return
+		}
+
+		// response
+		data := make([]EmployeeJSON, len(employees))
+		for i, v := range employees {
+			data[i] = serializeEmployee(v)
+		}
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    data,
+		})
+	}
+}
+
+// writeEmployeeAssignmentError writes the response of an error of the employee assignments
+func writeEmployeeAssignmentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, internal.ErrEmployeeServiceFieldRequired), errors.Is(err, internal.ErrEmployeeServiceInvalidDate):
+		response.Error(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, internal.ErrEmployeeServiceNotFound):
+		response.Error(w, http.StatusNotFound, "employee not found")
+	case errors.Is(err, internal.ErrEmployeeServiceWarehouseNotFound):
+		response.Error(w, http.StatusConflict, "warehouse not found")
+	case errors.Is(err, internal.ErrEmployeeServiceAlreadyAssigned):
+		response.Error(w, http.StatusConflict, "employee already assigned to the warehouse")
+	case errors.Is(err, internal.ErrEmployeeServiceAssignmentDate):
+		response.Error(w, http.StatusConflict, "effective date before the start of the current assignment")
+	case errors.Is(err, internal.ErrEmployeeServiceInternalError):
+		response.Error(w, http.StatusInternalServerError, "internal server error")
+	case errors.Is(err, internal.ErrEmployeeServiceUnknown):
+		response.Error(w, http.StatusInternalServerError, "unknown service error")
+	default:
+		response.Error(w, http.StatusInternalServerError, "unknown server error")
+	}
+}
+
+// serializeEmployeeAssignment creates a new json from the given assignment
+func serializeEmployeeAssignment(a internal.EmployeeAssignment) EmployeeAssignmentJSON {
+	data := EmployeeAssignmentJSON{
+		ID:          a.ID,
+		EmployeeID:  a.EmployeeID,
+		WarehouseID: a.WarehouseID,
+		StartDate:   a.StartDate.Format(DateLayout),
+	}
+	if !a.EndDate.IsZero() {
+		endDate := a.EndDate.Format(DateLayout)
+		data.EndDate = &endDate
+	}
+
+	return data
+}
+
 // serializeEmployee creates a new json from the given employee
 func serializeEmployee(e internal.Employee) EmployeeJSON {
 	return EmployeeJSON{
@@ -288,6 +637,7 @@ func serializeEmployee(e internal.Employee) EmployeeJSON {
 		FirstName:    e.FirstName,
 		LastName:     e.LastName,
 		WarehouseID:  e.WarehouseID,
+		DeletedAt:    optionalTime(e.DeletedAt),
 	}
 }
 
@@ -302,6 +652,47 @@ func deserializeEmployee(e EmployeeJSON) internal.Employee {
 	}
 }
 
+// parseEmployeeItem validates an employee item of an import request as Save does
+func parseEmployeeItem(body []byte) (employee internal.Employee, err error) {
+	// - validate the body keys
+	var bodyMap map[string]any
+	if err = json.Unmarshal(body, &bodyMap); err != nil {
+		err = errors.New("invalid body: cannot unmarshal to map")
+		return
+	}
+	if err = validateKeyExistance(bodyMap, "card_number_id", "first_name", "last_name", "warehouse_id"); err != nil {
+		return
+	}
+
+	// - validate the body values
+	var employeeJSON EmployeeJSON
+	if err = json.Unmarshal(body, &employeeJSON); err != nil {
+		err = errors.New("invalid body: cannot unmarshal to struct")
+		return
+	}
+	if err = validateEmployeeZeroValues(employeeJSON); err != nil {
+		return
+	}
+
+	employee = deserializeEmployee(employeeJSON)
+	return
+}
+
+// employeeItemError returns the message of an error of an employee item of an import request
+func employeeItemError(err error) string {
+	switch {
+	case errors.Is(err, internal.ErrEmployeeServiceDuplicated):
+		return "employee already exists"
+	case errors.Is(err, internal.ErrEmployeeServiceWarehouseNotFound):
+		return "warehouse not found"
+	case errors.Is(err, internal.ErrEmployeeServiceInternalError), errors.Is(err, internal.ErrEmployeeServiceUnknown):
+		return "unknown error"
+	default:
+		// validation error
+		return err.Error()
+	}
+}
+
 // validateEmployee validates the employee fields
 func validateEmployeeZeroValues(e EmployeeJSON) error {
 	// - validate id
@@ -3,6 +3,12 @@ package handler
 import (
 	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/manuelfirman/go-API/platform/web/request"
+	"github.com/manuelfirman/go-API/platform/web/response"
 )
 
 var (
@@ -12,8 +18,13 @@ var (
 	ErrHandlerMissingField = errors.New("missing field")
 	// ErrHandlerIdInRequest is the error returned when the ID is in the request
 	ErrHandlerIdInRequest = errors.New("id in request")
+	// ErrHandlerInvalidDate is the error returned when a date is not in the DateLayout format
+	ErrHandlerInvalidDate = errors.New("invalid date, expected YYYY-MM-DD")
 )
 
+// DateLayout is the layout of the dates in the JSON representations
+const DateLayout = "2006-01-02"
+
 // Response is a struct that contains the response message and data
 type Response struct {
 	Message string `json:"message"`
@@ -30,3 +41,95 @@ func validateKeyExistance(m map[string]any, keys ...string) error {
 
 	return nil
 }
+
+// queryDate returns the date (DateLayout) of the query parameter key, zero when it is missing
+func queryDate(r *http.Request, key string) (date time.Time, err error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return
+	}
+
+	date, err = time.Parse(DateLayout, v)
+	if err != nil {
+		err = fmt.Errorf("%w: %s", ErrHandlerInvalidDate, key)
+	}
+	return
+}
+
+// queryBool returns the flag of the query parameter key, false when it is missing
+func queryBool(r *http.Request, key string) (flag bool, err error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return
+	}
+
+	flag, err = strconv.ParseBool(v)
+	return
+}
+
+// optionalID returns the id of a reference that may be missing, nil (null in the JSON) when zero
+func optionalID(id int) *int {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+// idOf returns the id of a reference that may be missing, zero when nil
+func idOf(id *int) int {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+// optionalString returns a text that may be missing, nil (null in the JSON) when empty
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// optionalDate returns the date (DateLayout) of a moment that may be missing, nil (null in the JSON) when zero
+func optionalDate(t time.Time) *string {
+	if t.IsZero() {
+		return nil
+	}
+	date := t.Format(DateLayout)
+	return &date
+}
+
+// optionalTime returns the moment (RFC 3339) that may be missing, nil (null in the JSON) when zero
+func optionalTime(t time.Time) *string {
+	if t.IsZero() {
+		return nil
+	}
+	moment := t.Format(time.RFC3339)
+	return &moment
+}
+
+// newStreamWriter returns the writer of a list response in the format requested by the client:
+// csv (with the columns of schema), ndjson or json ({message, data}) by default
+func newStreamWriter(w http.ResponseWriter, r *http.Request, code int, schema any, message string) response.StreamWriter {
+	switch {
+	case request.WantsCSV(r):
+		return response.NewCSVWriter(w, code, schema)
+	case request.WantsNDJSON(r):
+		return response.NewNDJSONWriter(w, code)
+	default:
+		return response.NewJSONArrayWriter(w, code, message)
+	}
+}
+
+// patchError writes the error response for an error returned while applying a patch to a resource
+func patchError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, request.ErrRequestPatchTestFailed):
+		response.Error(w, http.StatusConflict, "patch test operation failed")
+	case errors.Is(err, request.ErrRequestContentTypeNotPatch):
+		response.Error(w, http.StatusUnsupportedMediaType, "unsupported patch content type")
+	default:
+		response.Error(w, http.StatusBadRequest, "invalid body")
+	}
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/manuelfirman/go-API/platform/web/request"
+	"github.com/manuelfirman/go-API/platform/web/response"
+)
+
+// ImportResultJSON is the result of a single row of a csv import
+type ImportResultJSON struct {
+	// Line is the line of the row in the csv document
+	Line int `json:"line"`
+	// ID is the unique identifier of the created item (empty on dry runs)
+	ID int `json:"id,omitempty"`
+	// Error is the reason why the row is invalid or was not imported
+	Error string `json:"error,omitempty"`
+}
+
+// importOptions returns the options of an import request
+// - dry_run: validate the rows without saving them
+// - mapping: rename the csv columns to the json fields, e.g. "Code:product_code,Seller:seller_id"
+func importOptions(r *http.Request) (dryRun bool, mapping map[string]string, err error) {
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			return
+		}
+	}
+
+	mapping, err = request.ParseCSVMapping(r.URL.Query().Get("mapping"))
+	return
+}
+
+// importRequestError writes the error response for an error returned while reading an import request
+func importRequestError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, request.ErrRequestContentTypeNotCSV):
+		response.Error(w, http.StatusUnsupportedMediaType, "unsupported content type")
+	case errors.Is(err, request.ErrRequestCSVInvalid):
+		response.Error(w, http.StatusBadRequest, err.Error())
+	default:
+		response.Error(w, http.StatusBadRequest, "invalid request")
+	}
+}
+
+// writeImportResults writes the row-level report of an import request
+func writeImportResults(w http.ResponseWriter, dryRun bool, results []ImportResultJSON) {
+	failed := 0
+	for _, r := range results {
+		if r.Error != "" {
+			failed++
+		}
+	}
+
+	// rows that passed are "valid" on a dry run and "imported" otherwise
+	passed := "imported"
+	if dryRun {
+		passed = "valid"
+	}
+
+	response.JSON(w, http.StatusOK, Response{
+		Message: "success",
+		Data: map[string]any{
+			"dry_run": dryRun,
+			"total":   len(results),
+			passed:    len(results) - failed,
+			"failed":  failed,
+			"rows":    results,
+		},
+	})
+}
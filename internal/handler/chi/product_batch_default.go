@@ -0,0 +1,878 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/manuelfirman/go-API/internal"
+	"github.com/manuelfirman/go-API/platform/web/request"
+	"github.com/manuelfirman/go-API/platform/web/response"
+)
+
+// ProductBatchJSON is the JSON representation of a product batch
+type ProductBatchJSON struct {
+	// ID is the unique identifier of the product batch
+	ID int `json:"id"`
+	// BatchNumber is the number of the batch
+	BatchNumber int `json:"batch_number"`
+	// DueDate is the date on which the batch expires (YYYY-MM-DD)
+	DueDate string `json:"due_date"`
+	// MinimumTemperature is the minimum temperature at which the batch can be stored
+	MinimumTemperature float64 `json:"minimum_temperature"`
+	// CurrentTemperature is the current temperature of the batch
+	CurrentTemperature float64 `json:"current_temperature"`
+	// InitialQuantity is the quantity of units the batch had when it was received
+	InitialQuantity int `json:"initial_quantity"`
+	// CurrentQuantity is the quantity of units left in the batch
+	CurrentQuantity int `json:"current_quantity"`
+	// ManufacturingDate is the date on which the batch was manufactured (YYYY-MM-DD)
+	ManufacturingDate string `json:"manufacturing_date"`
+	// ManufacturingHour is the hour of the day at which the batch was manufactured
+	ManufacturingHour int `json:"manufacturing_hour"`
+	// SectionID is the unique identifier of the section where the batch is stored
+	SectionID int `json:"section_id"`
+	// ProductID is the unique identifier of the product of the batch
+	ProductID int `json:"product_id"`
+	// DeletedAt is the moment the product batch was deleted, only listed with ?include_deleted=true (read only)
+	DeletedAt *string `json:"deleted_at,omitempty"`
+}
+
+// InventoryMovementJSON is the JSON representation of a change of the quantity of a product batch
+type InventoryMovementJSON struct {
+	// ID is the unique identifier of the movement
+	ID int `json:"id"`
+	// ProductBatchID is the unique identifier of the product batch whose quantity changed
+	ProductBatchID int `json:"product_batch_id"`
+	// SectionID is the unique identifier of the section where the units were added or removed
+	SectionID int `json:"section_id"`
+	// Type is the type of the movement: receipt, pick, adjustment, transfer or write_off
+	Type string `json:"type"`
+	// Quantity is the number of units added to the batch (negative if removed)
+	Quantity int `json:"quantity"`
+	// EmployeeID is the unique identifier of the employee who made the movement (omitted if unknown)
+	EmployeeID int `json:"employee_id,omitempty"`
+	// Reason is why the movement was made
+	Reason string `json:"reason"`
+	// CreatedAt is the moment at which the movement was made (RFC 3339)
+	CreatedAt string `json:"created_at"`
+}
+
+// BatchTransferJSON is the JSON representation of the moving of units of a product batch to another section
+type BatchTransferJSON struct {
+	// ProductBatchID is the unique identifier of the product batch the units are moved from
+	ProductBatchID int `json:"product_batch_id"`
+	// SectionID is the unique identifier of the section the units are moved to
+	SectionID int `json:"section_id"`
+	// Quantity is the number of units moved
+	Quantity int `json:"quantity"`
+	// SourceEmployeeID is the unique identifier of the employee who sends the units
+	SourceEmployeeID int `json:"source_employee_id"`
+	// TargetEmployeeID is the unique identifier of the employee who receives the units
+	TargetEmployeeID int `json:"target_employee_id"`
+	// Reason is why the units are moved
+	Reason string `json:"reason"`
+	// FromSectionID is the unique identifier of the section the units were moved from (read only)
+	FromSectionID int `json:"from_section_id"`
+	// TargetBatchID is the unique identifier of the product batch the units were moved to (read only)
+	TargetBatchID int `json:"target_batch_id"`
+	// Split is set when only part of the units were moved and a new batch was created (read only)
+	Split bool `json:"split"`
+}
+
+// InboundOrderJSON is the JSON representation of an order received at a warehouse
+type InboundOrderJSON struct {
+	// ID is the unique identifier of the inbound order (read only)
+	ID int `json:"id"`
+	// OrderNumber is the unique number of the inbound order
+	OrderNumber int `json:"order_number"`
+	// OrderDate is the date on which the order was received (YYYY-MM-DD)
+	OrderDate string `json:"order_date"`
+	// WarehouseID is the unique identifier of the warehouse receiving the order
+	WarehouseID int `json:"warehouse_id"`
+	// EmployeeID is the unique identifier of the employee receiving the order
+	EmployeeID int `json:"employee_id"`
+	// ProductBatchID is the unique identifier of the product batch brought in by the order (read only)
+	ProductBatchID int `json:"product_batch_id"`
+}
+
+// InboundReceiptJSON is the JSON representation of the receiving of an inbound order with the product batch it brings in
+type InboundReceiptJSON struct {
+	InboundOrderJSON
+	// ProductBatch is the product batch brought in by the order
+	ProductBatch ProductBatchJSON `json:"product_batch"`
+}
+
+// ExpiringBatchJSON is the JSON representation of a product batch nearing its due date
+type ExpiringBatchJSON struct {
+	ProductBatchJSON
+	// DaysLeft is the number of days until the batch expires (negative if it already expired)
+	DaysLeft int `json:"days_left"`
+}
+
+// BatchPickJSON is the JSON representation of the units to pick from a product batch
+type BatchPickJSON struct {
+	// BatchID is the unique identifier of the product batch
+	BatchID int `json:"product_batch_id"`
+	// BatchNumber is the number of the batch
+	BatchNumber int `json:"batch_number"`
+	// SectionID is the unique identifier of the section where the batch is stored
+	SectionID int `json:"section_id"`
+	// DueDate is the date on which the batch expires (YYYY-MM-DD)
+	DueDate string `json:"due_date"`
+	// Quantity is the number of units to pick from the batch
+	Quantity int `json:"quantity"`
+}
+
+// BatchTemperatureJSON is the JSON representation of the temperatures of a stored product batch that is out of range
+type BatchTemperatureJSON struct {
+	// BatchID is the unique identifier of the product batch
+	BatchID int `json:"product_batch_id"`
+	// BatchNumber is the number of the batch
+	BatchNumber int `json:"batch_number"`
+	// ProductID is the unique identifier of the product of the batch
+	ProductID int `json:"product_id"`
+	// SectionID is the unique identifier of the section where the batch is stored
+	SectionID int `json:"section_id"`
+	// WarehouseID is the unique identifier of the warehouse of the section
+	WarehouseID int `json:"warehouse_id"`
+	// RecommendedTemperature is the recommended freezing temperature of the product
+	RecommendedTemperature float64 `json:"recommended_temperature"`
+	// BatchMinimumTemperature is the minimum temperature at which the batch can be stored
+	BatchMinimumTemperature float64 `json:"batch_minimum_temperature"`
+	// BatchCurrentTemperature is the current temperature of the batch
+	BatchCurrentTemperature float64 `json:"batch_current_temperature"`
+	// SectionCurrentTemperature is the current temperature of the section
+	SectionCurrentTemperature float64 `json:"section_current_temperature"`
+	// SectionMinimumTemperature is the minimum temperature that can be maintained in the section
+	SectionMinimumTemperature float64 `json:"section_minimum_temperature"`
+	// WarehouseMinimumTemperature is the minimum temperature that can be maintained in the warehouse
+	WarehouseMinimumTemperature float64 `json:"warehouse_minimum_temperature"`
+	// Reasons are the reasons why the batch is out of range
+	Reasons []string `json:"reasons"`
+}
+
+// NewProductBatchDefault creates a new instance of the product batch handler
+func NewProductBatchDefault(sv internal.ProductBatchService) *ProductBatchDefault {
+	return &ProductBatchDefault{
+		sv: sv,
+	}
+}
+
+// ProductBatchDefault is the default implementation of the product batch handler
+type ProductBatchDefault struct {
+	sv internal.ProductBatchService
+}
+
+// GetAll returns all product batches. The list is streamed as the batches are read (json by default, ndjson or csv if requested)
+func (h *ProductBatchDefault) GetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - include the deleted product batches with ?include_deleted=true
+		includeDeleted, err := queryBool(r, "include_deleted")
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid include_deleted")
+			return
+		}
+
+		// response
+		// - writer of the format requested by the client
+		sw := newStreamWriter(w, r, http.StatusOK, ProductBatchJSON{}, "success")
+
+		// process
+		// - write every batch as it is read
+		err = h.sv.ForEach(includeDeleted, func(pb internal.ProductBatch) error {
+			return sw.Write(serializeProductBatch(pb))
+		})
+		if err != nil {
+			// - nothing sent yet: regular error response, otherwise the response is left truncated
+			if !sw.Started() {
+				writeProductBatchError(w, err)
+			}
+			return
+		}
+
+		// - end of the list
+		sw.Close()
+	}
+}
+
+// Get returns a product batch by ID
+func (h *ProductBatchDefault) Get() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		// process
+		pb, err := h.sv.Get(id)
+		if err != nil {
+			writeProductBatchError(w, err)
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    serializeProductBatch(pb),
+		})
+	}
+}
+
+// Save places a new product batch in a section, taking up its capacity
+func (h *ProductBatchDefault) Save() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - read the body in []byte
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot read")
+			return
+		}
+		// - unmarshal body to map for validations
+		var bodyMap map[string]any
+		if err = json.Unmarshal(body, &bodyMap); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot unmarshal to map")
+			return
+		}
+		// - validate
+		if err = validateKeyExistance(bodyMap, "batch_number", "due_date", "minimum_temperature", "current_temperature", "initial_quantity", "current_quantity", "manufacturing_date", "manufacturing_hour", "section_id", "product_id"); err != nil {
+			response.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// - unmarshal to struct
+		var pbJSON ProductBatchJSON
+		if err = json.Unmarshal(body, &pbJSON); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot unmarshal to struct")
+			return
+		}
+		if pbJSON.ID != 0 {
+			response.Error(w, http.StatusBadRequest, ErrHandlerIdInRequest.Error())
+			return
+		}
+
+		// - deserialize
+		pb, err := deserializeProductBatch(pbJSON)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// process
+		if err = h.sv.Save(r.Context(), &pb); err != nil {
+			writeProductBatchError(w, err)
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusCreated, Response{
+			Message: "success",
+			Data:    serializeProductBatch(pb),
+		})
+	}
+}
+
+// Update updates a product batch: changing its section moves it and changing its current quantity consumes units
+func (h *ProductBatchDefault) Update() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		// - get product batch by id
+		pb, err := h.sv.Get(id)
+		if err != nil {
+			writeProductBatchError(w, err)
+			return
+		}
+
+		// - apply the body (merge patch or json patch) to the product batch
+		pbJSON := serializeProductBatch(pb)
+		if err := request.Patch(r, &pbJSON); err != nil {
+			patchError(w, err)
+			return
+		}
+
+		// - deserialize (the id comes from the URL)
+		pb, err = deserializeProductBatch(pbJSON)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		pb.ID = id
+
+		// process
+		if err = h.sv.Update(r.Context(), &pb); err != nil {
+			writeProductBatchError(w, err)
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    serializeProductBatch(pb),
+		})
+	}
+}
+
+// Delete deletes a product batch (permanently with ?hard=true), releasing the capacity it took up in its section
+func (h *ProductBatchDefault) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+		// - delete it permanently with ?hard=true, otherwise it is only marked as deleted
+		hard, err := queryBool(r, "hard")
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid hard")
+			return
+		}
+
+		// process
+		if hard {
+			err = h.sv.Purge(r.Context(), id)
+		} else {
+			err = h.sv.Delete(r.Context(), id)
+		}
+		if err != nil {
+			writeProductBatchError(w, err)
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusNoContent, nil)
+	}
+}
+
+// Restore restores the deleted product batch with the given ID and returns it
+func (h *ProductBatchDefault) Restore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		// process
+		// - unmark the product batch
+		err = h.sv.Restore(r.Context(), id)
+		if err != nil {
+			writeProductBatchError(w, err)
+			return
+		}
+		// - get it back
+		pb, err := h.sv.Get(id)
+		if err != nil {
+			response.Error(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    serializeProductBatch(pb),
+		})
+	}
+}
+
+// AddMovement changes the quantity of a product batch with a movement of its ledger (receipt, pick, adjustment or write-off)
+func (h *ProductBatchDefault) AddMovement() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+		// - read the body in []byte
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot read")
+			return
+		}
+		// - unmarshal body to map for validations
+		var bodyMap map[string]any
+		if err = json.Unmarshal(body, &bodyMap); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot unmarshal to map")
+			return
+		}
+		// - validate
+		if err = validateKeyExistance(bodyMap, "type", "quantity"); err != nil {
+			response.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		// - unmarshal to struct
+		var mJSON InventoryMovementJSON
+		if err = json.Unmarshal(body, &mJSON); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot unmarshal to struct")
+			return
+		}
+		if mJSON.ID != 0 {
+			response.Error(w, http.StatusBadRequest, ErrHandlerIdInRequest.Error())
+			return
+		}
+
+		// process
+		m := internal.InventoryMovement{
+			ProductBatchID: id,
+			Type:           mJSON.Type,
+			Quantity:       mJSON.Quantity,
+			EmployeeID:     mJSON.EmployeeID,
+			Reason:         mJSON.Reason,
+		}
+		if err = h.sv.AddMovement(r.Context(), &m); err != nil {
+			writeProductBatchError(w, err)
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusCreated, Response{
+			Message: "success",
+			Data:    serializeInventoryMovement(m),
+		})
+	}
+}
+
+// Movements returns the ledger of a product batch: its movements, oldest first, and the quantity they add up to
+func (h *ProductBatchDefault) Movements() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		// process
+		movements, err := h.sv.GetMovements(id)
+		if err != nil {
+			writeProductBatchError(w, err)
+			return
+		}
+
+		// response
+		balance := 0
+		data := make([]InventoryMovementJSON, 0, len(movements))
+		for _, m := range movements {
+			balance += m.Quantity
+			data = append(data, serializeInventoryMovement(m))
+		}
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data: map[string]any{
+				"product_batch_id": id,
+				"balance":          balance,
+				"movements":        data,
+			},
+		})
+	}
+}
+
+// Transfer moves a quantity of a product batch to a target section (of any warehouse) in a single transaction,
+// splitting the batch when only part of its units are moved
+func (h *ProductBatchDefault) Transfer() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - read the body in []byte
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot read")
+			return
+		}
+		// - unmarshal body to map for validations
+		var bodyMap map[string]any
+		if err = json.Unmarshal(body, &bodyMap); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot unmarshal to map")
+			return
+		}
+		// - validate
+		if err = validateKeyExistance(bodyMap, "product_batch_id", "section_id", "quantity", "source_employee_id", "target_employee_id"); err != nil {
+			response.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		// - unmarshal to struct
+		var tJSON BatchTransferJSON
+		if err = json.Unmarshal(body, &tJSON); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot unmarshal to struct")
+			return
+		}
+
+		// process
+		t := internal.BatchTransfer{
+			ProductBatchID:   tJSON.ProductBatchID,
+			ToSectionID:      tJSON.SectionID,
+			Quantity:         tJSON.Quantity,
+			SourceEmployeeID: tJSON.SourceEmployeeID,
+			TargetEmployeeID: tJSON.TargetEmployeeID,
+			Reason:           tJSON.Reason,
+		}
+		if err = h.sv.Transfer(r.Context(), &t); err != nil {
+			writeProductBatchError(w, err)
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusCreated, Response{
+			Message: "success",
+			Data: BatchTransferJSON{
+				ProductBatchID:   t.ProductBatchID,
+				SectionID:        t.ToSectionID,
+				Quantity:         t.Quantity,
+				SourceEmployeeID: t.SourceEmployeeID,
+				TargetEmployeeID: t.TargetEmployeeID,
+				Reason:           t.Reason,
+				FromSectionID:    t.FromSectionID,
+				TargetBatchID:    t.TargetBatchID,
+				Split:            t.Split,
+			},
+		})
+	}
+}
+
+// Receive receives an inbound order: the product batch it brings in is placed in its section and the order saved,
+// all or nothing. The current quantity of the batch defaults to its initial quantity.
+func (h *ProductBatchDefault) Receive() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - read the body in []byte
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot read")
+			return
+		}
+		// - unmarshal body to map for validations
+		var bodyMap map[string]any
+		if err = json.Unmarshal(body, &bodyMap); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot unmarshal to map")
+			return
+		}
+		// - validate the order and its batch
+		if err = validateKeyExistance(bodyMap, "order_number", "order_date", "warehouse_id", "employee_id", "product_batch"); err != nil {
+			response.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		batchMap, ok := bodyMap["product_batch"].(map[string]any)
+		if !ok {
+			response.Error(w, http.StatusBadRequest, "invalid body: product_batch must be an object")
+			return
+		}
+		if err = validateKeyExistance(batchMap, "batch_number", "due_date", "minimum_temperature", "current_temperature", "initial_quantity", "manufacturing_date", "manufacturing_hour", "section_id", "product_id"); err != nil {
+			response.Error(w, http.StatusBadRequest, "product_batch: "+err.Error())
+			return
+		}
+		// - unmarshal to struct
+		var rJSON InboundReceiptJSON
+		if err = json.Unmarshal(body, &rJSON); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot unmarshal to struct")
+			return
+		}
+		if rJSON.ID != 0 || rJSON.ProductBatch.ID != 0 {
+			response.Error(w, http.StatusBadRequest, ErrHandlerIdInRequest.Error())
+			return
+		}
+		if _, ok := batchMap["current_quantity"]; !ok {
+			rJSON.ProductBatch.CurrentQuantity = rJSON.ProductBatch.InitialQuantity
+		}
+		// - deserialize
+		orderDate, err := time.Parse(DateLayout, rJSON.OrderDate)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, ErrHandlerInvalidDate.Error()+": order_date")
+			return
+		}
+		o := internal.InboundOrder{
+			OrderNumber: rJSON.OrderNumber,
+			OrderDate:   orderDate,
+			WarehouseID: rJSON.WarehouseID,
+			EmployeeID:  rJSON.EmployeeID,
+		}
+		pb, err := deserializeProductBatch(rJSON.ProductBatch)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// process
+		if err = h.sv.Receive(r.Context(), &o, &pb); err != nil {
+			writeProductBatchError(w, err)
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusCreated, Response{
+			Message: "success",
+			Data: InboundReceiptJSON{
+				InboundOrderJSON: InboundOrderJSON{
+					ID:             o.ID,
+					OrderNumber:    o.OrderNumber,
+					OrderDate:      o.OrderDate.Format(DateLayout),
+					WarehouseID:    o.WarehouseID,
+					EmployeeID:     o.EmployeeID,
+					ProductBatchID: o.ProductBatchID,
+				},
+				ProductBatch: serializeProductBatch(pb),
+			},
+		})
+	}
+}
+
+// Expiring returns the product batches with units left that expire within ?days= days (7 by default, expired ones included),
+// optionally only the ones stored in the warehouse ?warehouse_id=
+func (h *ProductBatchDefault) Expiring() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get the filters from the query
+		query := r.URL.Query()
+		days := 7
+		if v := query.Get("days"); v != "" {
+			var err error
+			if days, err = strconv.Atoi(v); err != nil {
+				response.Error(w, http.StatusBadRequest, "invalid days")
+				return
+			}
+		}
+		warehouseID := 0
+		if v := query.Get("warehouse_id"); v != "" {
+			var err error
+			if warehouseID, err = strconv.Atoi(v); err != nil {
+				response.Error(w, http.StatusBadRequest, "invalid warehouse_id")
+				return
+			}
+		}
+
+		// process
+		batches, err := h.sv.GetExpiring(days, warehouseID)
+		if err != nil {
+			writeProductBatchError(w, err)
+			return
+		}
+
+		// response
+		today := time.Now().UTC().Truncate(24 * time.Hour)
+		data := make([]ExpiringBatchJSON, 0, len(batches))
+		for _, pb := range batches {
+			data = append(data, ExpiringBatchJSON{
+				ProductBatchJSON: serializeProductBatch(pb),
+				DaysLeft:         int(pb.DueDate.Sub(today).Hours() / 24),
+			})
+		}
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    data,
+		})
+	}
+}
+
+// Pick returns the batches (and their sections) to pick ?quantity= units of the product from, first expired first out.
+// If the product doesn't have enough units, the picks cover the units there are and the shortfall is reported.
+func (h *ProductBatchDefault) Pick() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+		// - get the quantity from the query
+		quantity, err := strconv.Atoi(r.URL.Query().Get("quantity"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid quantity")
+			return
+		}
+
+		// process
+		picks, err := h.sv.Pick(id, quantity)
+		if err != nil {
+			if errors.Is(err, internal.ErrProductBatchServiceProductNotFound) {
+				response.Error(w, http.StatusNotFound, "product not found")
+				return
+			}
+			writeProductBatchError(w, err)
+			return
+		}
+
+		// response
+		picked := 0
+		data := make([]BatchPickJSON, 0, len(picks))
+		for _, p := range picks {
+			picked += p.Quantity
+			data = append(data, BatchPickJSON{
+				BatchID:     p.BatchID,
+				BatchNumber: p.BatchNumber,
+				SectionID:   p.SectionID,
+				DueDate:     p.DueDate.Format(DateLayout),
+				Quantity:    p.Quantity,
+			})
+		}
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data: map[string]any{
+				"product_id": id,
+				"requested":  quantity,
+				"picked":     picked,
+				"shortfall":  quantity - picked,
+				"picks":      data,
+			},
+		})
+	}
+}
+
+// TemperatureCompliance returns the stored product batches that are out of the temperature range of their product
+func (h *ProductBatchDefault) TemperatureCompliance() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// process
+		batches, err := h.sv.GetTemperatureCompliance()
+		if err != nil {
+			writeProductBatchError(w, err)
+			return
+		}
+
+		// response
+		data := make([]BatchTemperatureJSON, 0, len(batches))
+		for _, bt := range batches {
+			data = append(data, BatchTemperatureJSON{
+				BatchID:                     bt.BatchID,
+				BatchNumber:                 bt.BatchNumber,
+				ProductID:                   bt.ProductID,
+				SectionID:                   bt.SectionID,
+				WarehouseID:                 bt.WarehouseID,
+				RecommendedTemperature:      bt.RecommendedTemperature,
+				BatchMinimumTemperature:     bt.BatchMinimumTemperature,
+				BatchCurrentTemperature:     bt.BatchCurrentTemperature,
+				SectionCurrentTemperature:   bt.SectionCurrentTemperature,
+				SectionMinimumTemperature:   bt.SectionMinimumTemperature,
+				WarehouseMinimumTemperature: bt.WarehouseMinimumTemperature,
+				Reasons:                     bt.Reasons,
+			})
+		}
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    data,
+		})
+	}
+}
+
+// writeProductBatchError writes the error response for an error returned by the product batch service
+func writeProductBatchError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, internal.ErrProductBatchServiceNotFound):
+		response.Error(w, http.StatusNotFound, "product batch not found")
+	case errors.Is(err, internal.ErrProductBatchServiceCapacityExceeded):
+		response.Error(w, http.StatusConflict, "section maximum capacity exceeded")
+	case errors.Is(err, internal.ErrProductBatchServiceProductType):
+		response.Error(w, http.StatusConflict, "section doesn't store the product type")
+	case errors.Is(err, internal.ErrProductBatchServiceTemperature):
+		response.Error(w, http.StatusConflict, "section can't keep the recommended temperature of the product")
+	case errors.Is(err, internal.ErrProductBatchServiceEmployeeNotFound):
+		response.Error(w, http.StatusConflict, "employee not found")
+	case errors.Is(err, internal.ErrProductBatchServiceQuantityOutOfRange):
+		response.Error(w, http.StatusConflict, "quantity out of range of the batch")
+	case errors.Is(err, internal.ErrProductBatchServiceLedgerMismatch):
+		response.Error(w, http.StatusConflict, "batch quantity doesn't match its movements")
+	case errors.Is(err, internal.ErrProductBatchServiceEmployeeWarehouse):
+		response.Error(w, http.StatusConflict, "employee doesn't work at the warehouse")
+	case errors.Is(err, internal.ErrProductBatchServiceSectionWarehouse):
+		response.Error(w, http.StatusConflict, "section is not in the warehouse")
+	case errors.Is(err, internal.ErrProductBatchServiceOrderDuplicated):
+		response.Error(w, http.StatusConflict, "inbound order already exists")
+	case errors.Is(err, internal.ErrDependentsServiceUnconfirmed):
+		response.Error(w, http.StatusConflict, "resource has dependents, confirm the delete with ?confirm=cascade")
+	case errors.Is(err, internal.ErrProductBatchServiceFK):
+		response.Error(w, http.StatusConflict, "resource has blocking dependents, it can't be deleted permanently")
+	case errors.Is(err, internal.ErrProductBatchServiceSectionNotFound):
+		response.Error(w, http.StatusConflict, "section not found")
+	case errors.Is(err, internal.ErrProductBatchServiceProductNotFound):
+		response.Error(w, http.StatusConflict, "product not found")
+	case errors.Is(err, internal.ErrProductBatchServiceInvalidField):
+		response.Error(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, internal.ErrProductBatchService):
+		response.Error(w, http.StatusInternalServerError, "internal server error")
+	case errors.Is(err, internal.ErrProductBatchServiceUnknown):
+		response.Error(w, http.StatusInternalServerError, "unknown service error")
+	default:
+		response.Error(w, http.StatusInternalServerError, "unknown server error")
+	}
+}
+
+// serializeProductBatch serializes a product batch into a ProductBatchJSON
+func serializeProductBatch(pb internal.ProductBatch) ProductBatchJSON {
+	return ProductBatchJSON{
+		ID:                 pb.ID,
+		BatchNumber:        pb.BatchNumber,
+		DueDate:            pb.DueDate.Format(DateLayout),
+		MinimumTemperature: pb.MinimumTemperature,
+		CurrentTemperature: pb.CurrentTemperature,
+		InitialQuantity:    pb.InitialQuantity,
+		CurrentQuantity:    pb.CurrentQuantity,
+		ManufacturingDate:  pb.ManufacturingDate.Format(DateLayout),
+		ManufacturingHour:  pb.ManufacturingHour,
+		SectionID:          pb.SectionID,
+		ProductID:          pb.ProductID,
+		DeletedAt:          optionalTime(pb.DeletedAt),
+	}
+}
+
+// serializeInventoryMovement serializes an inventory movement into an InventoryMovementJSON
+func serializeInventoryMovement(m internal.InventoryMovement) InventoryMovementJSON {
+	return InventoryMovementJSON{
+		ID:             m.ID,
+		ProductBatchID: m.ProductBatchID,
+		SectionID:      m.SectionID,
+		Type:           m.Type,
+		Quantity:       m.Quantity,
+		EmployeeID:     m.EmployeeID,
+		Reason:         m.Reason,
+		CreatedAt:      m.CreatedAt.Format(time.RFC3339Nano),
+	}
+}
+
+// deserializeProductBatch deserializes a ProductBatchJSON into a product batch
+func deserializeProductBatch(pbJSON ProductBatchJSON) (pb internal.ProductBatch, err error) {
+	dueDate, err := time.Parse(DateLayout, pbJSON.DueDate)
+	if err != nil {
+		err = fmt.Errorf("%w: due_date", ErrHandlerInvalidDate)
+		return
+	}
+	manufacturingDate, err := time.Parse(DateLayout, pbJSON.ManufacturingDate)
+	if err != nil {
+		err = fmt.Errorf("%w: manufacturing_date", ErrHandlerInvalidDate)
+		return
+	}
+
+	pb = internal.ProductBatch{
+		ID:                 pbJSON.ID,
+		BatchNumber:        pbJSON.BatchNumber,
+		DueDate:            dueDate,
+		MinimumTemperature: pbJSON.MinimumTemperature,
+		CurrentTemperature: pbJSON.CurrentTemperature,
+		InitialQuantity:    pbJSON.InitialQuantity,
+		CurrentQuantity:    pbJSON.CurrentQuantity,
+		ManufacturingDate:  manufacturingDate,
+		ManufacturingHour:  pbJSON.ManufacturingHour,
+		SectionID:          pbJSON.SectionID,
+		ProductID:          pbJSON.ProductID,
+	}
+	return
+}
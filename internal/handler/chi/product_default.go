@@ -7,8 +7,10 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/manuelfirman/go-API/platform/validate"
+	"github.com/manuelfirman/go-API/platform/web/etag"
 	"github.com/manuelfirman/go-API/platform/web/request"
 	"github.com/manuelfirman/go-API/platform/web/response"
 
@@ -38,10 +40,12 @@ type ProductJSON struct {
 	FreezingRate float64 `json:"freezing_rate"`
 	// RecomFreezTemp is the recommended freezing temperature for the product
 	RecomFreezTemp float64 `json:"recommended_freezing_temperature"`
-	// ProductTypeID is the unique identifier of the product type
-	ProductTypeID int `json:"product_type_id"`
+	// ProductTypeID is the unique identifier of the product type, null if it has none
+	ProductTypeID *int `json:"product_type_id"`
 	// SellerID is the unique identifier of the seller
 	SellerID int `json:"seller_id"`
+	// DeletedAt is the moment the product was deleted, only listed with ?include_deleted=true (read only)
+	DeletedAt *string `json:"deleted_at,omitempty"`
 }
 
 // ProductJSON is a struct that contains the product's information as JSON
@@ -64,8 +68,8 @@ type ProductRequestJSON struct {
 	FreezingRate float64 `json:"freezing_rate"`
 	// RecomFreezTemp is the recommended freezing temperature for the product
 	RecomFreezTemp float64 `json:"recommended_freezing_temperature"`
-	// ProductTypeID is the unique identifier of the product type
-	ProductTypeID int `json:"product_type_id"`
+	// ProductTypeID is the unique identifier of the product type (optional, without it the product can't be stored in any section)
+	ProductTypeID int `json:"product_type_id,omitempty"`
 	// SellerID is the unique identifier of the seller
 	SellerID int `json:"seller_id"`
 }
@@ -80,6 +84,59 @@ type ProductRecordReportJSON struct {
 	RecordCount int `json:"records_count"`
 }
 
+// ProductRecordJSON is a struct that contains the prices of a product from a moment on as JSON
+type ProductRecordJSON struct {
+	// ID is the unique identifier of the product record
+	ID int `json:"id"`
+	// LastUpdateDate is the moment the prices took effect
+	LastUpdateDate string `json:"last_update_date"`
+	// PurchasePrice is the price the product is bought at
+	PurchasePrice float64 `json:"purchase_price"`
+	// SalePrice is the price the product is sold at, null when it isn't for sale
+	SalePrice *float64 `json:"sale_price"`
+}
+
+// ProductPricesJSON is a struct that contains the price timeline of a product as JSON
+type ProductPricesJSON struct {
+	// ProductID is the unique identifier of the product
+	ProductID int `json:"product_id"`
+	// At is the moment the current prices are taken at
+	At string `json:"at"`
+	// Current is the record in effect at At, null when the product had no prices yet
+	Current *ProductRecordJSON `json:"current"`
+	// Timeline is the records of the product, the oldest first
+	Timeline []ProductRecordJSON `json:"timeline"`
+}
+
+// ProductMarginJSON is a struct that contains the margin of a product as JSON
+type ProductMarginJSON struct {
+	ProductID       int     `json:"product_id"`
+	ProductCode     string  `json:"product_code"`
+	Description     string  `json:"description"`
+	SellerID        *int    `json:"seller_id"`
+	ProductRecordID int     `json:"product_record_id"`
+	LastUpdateDate  string  `json:"last_update_date"`
+	PurchasePrice   float64 `json:"purchase_price"`
+	SalePrice       float64 `json:"sale_price"`
+	Margin          float64 `json:"margin"`
+	MarginRate      float64 `json:"margin_rate"`
+}
+
+// SellerMarginJSON is a struct that contains the margins of the products of a seller as JSON
+type SellerMarginJSON struct {
+	SellerID          *int    `json:"seller_id"`
+	Products          int     `json:"products"`
+	AverageMargin     float64 `json:"average_margin"`
+	AverageMarginRate float64 `json:"average_margin_rate"`
+}
+
+// MarginReportJSON is a struct that contains the margins of the products for sale as JSON
+type MarginReportJSON struct {
+	At       string              `json:"at"`
+	Products []ProductMarginJSON `json:"products"`
+	Sellers  []SellerMarginJSON  `json:"sellers"`
+}
+
 // NewProductDefault creates a new instance of the product handler
 func NewProductDefault(sv internal.ProductService) *ProductDefault {
 	return &ProductDefault{
@@ -93,35 +150,36 @@ type ProductDefault struct {
 	sv internal.ProductService
 }
 
-// GetAll returns all products
+// GetAll returns all products. The list is streamed as the products are read (json by default, ndjson or csv if requested)
 func (h *ProductDefault) GetAll() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// process
-		// - get all products from the service
-		products, err := h.sv.GetAll()
+		// request
+		// - include the deleted products with ?include_deleted=true
+		includeDeleted, err := queryBool(r, "include_deleted")
 		if err != nil {
-			switch {
-			case errors.Is(err, internal.ErrProductServiceNotFound):
-				response.Error(w, http.StatusNotFound, "products not found")
-			default:
-				response.Error(w, http.StatusInternalServerError, "unknown error")
-			}
+			response.Error(w, http.StatusBadRequest, "invalid include_deleted")
 			return
 		}
 
 		// response
-		// - serialize products
-		var productsResponseJSON []ProductJSON
-		for _, p := range products {
-			jsonData := deserializeProduct(p)
-			productsResponseJSON = append(productsResponseJSON, jsonData)
-		}
+		// - writer of the format requested by the client
+		sw := newStreamWriter(w, r, http.StatusOK, ProductJSON{}, "products found")
 
-		// - return the products in JSON format
-		response.JSON(w, http.StatusOK, map[string]any{
-			"message": "products found",
-			"data":    productsResponseJSON,
+		// process
+		// - write every product as it is read
+		err = h.sv.ForEach(includeDeleted, func(p internal.Product) error {
+			return sw.Write(deserializeProduct(p))
 		})
+		if err != nil {
+			// - nothing sent yet: regular error response, otherwise the response is left truncated
+			if !sw.Started() {
+				response.Error(w, http.StatusInternalServerError, "unknown error")
+			}
+			return
+		}
+
+		// - end of the list
+		sw.Close()
 	}
 }
 
@@ -150,6 +208,13 @@ func (h *ProductDefault) GetByID() http.HandlerFunc {
 			return
 		}
 
+		// - the client already has the current version
+		etag.Set(w, product.Version)
+		if etag.IfNoneMatch(r, product.Version) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
 		data := deserializeProduct(product)
 
 		//response
@@ -206,7 +271,7 @@ func (h *ProductDefault) Create() http.HandlerFunc {
 			ExpirationRate: productRequest.ExpirationRate,
 			FreezingRate:   productRequest.FreezingRate,
 			RecomFreezTemp: productRequest.RecomFreezTemp,
-			ProductTypeID:  productRequest.ProductTypeID,
+			ProductTypeID:  optionalID(productRequest.ProductTypeID),
 			SellerID:       productRequest.SellerID,
 		}
 
@@ -220,13 +285,15 @@ func (h *ProductDefault) Create() http.HandlerFunc {
 
 		// process
 		// - create a new product
-		p, err = h.sv.Save(&p)
+		p, err = h.sv.Save(r.Context(), &p)
 		if err != nil {
 			switch {
 			case errors.Is(err, internal.ErrProductServiceDuplicated):
 				response.Error(w, http.StatusConflict, "duplicated product code")
 			case errors.Is(err, internal.ErrSellerServiceNotFound):
 				response.Error(w, http.StatusConflict, "seller not found")
+			case errors.Is(err, internal.ErrProductTypeServiceNotFound):
+				response.Error(w, http.StatusConflict, "product type not found")
 			default:
 				response.Error(w, http.StatusInternalServerError, "unknown error")
 			}
@@ -245,6 +312,105 @@ func (h *ProductDefault) Create() http.HandlerFunc {
 	}
 }
 
+// BulkCreate creates several products at once from a json array or ndjson body
+func (h *ProductDefault) BulkCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - mode (atomic by default, or per_item)
+		atomic, err := bulkAtomic(r)
+		if err != nil {
+			bulkRequestError(w, err)
+			return
+		}
+
+		// - read the items
+		items, err := readBulkItems(r)
+		if err != nil {
+			bulkRequestError(w, err)
+			return
+		}
+
+		// - validate every item as in Create
+		invalid := make(map[int]error)
+		indices := make([]int, 0, len(items))
+		products := make([]internal.Product, 0, len(items))
+		for i, item := range items {
+			p, err := parseProductItem(item)
+			if err != nil {
+				invalid[i] = err
+				continue
+			}
+			indices = append(indices, i)
+			products = append(products, p)
+		}
+
+		// process
+		// - save the valid products (none if the request is atomic and some item is invalid)
+		var saved []internal.BulkResult
+		if len(products) > 0 && (!atomic || len(invalid) == 0) {
+			saved, err = h.sv.SaveBulk(r.Context(), products, atomic)
+			if err != nil {
+				response.Error(w, http.StatusInternalServerError, "unknown error")
+				return
+			}
+		}
+
+		// response
+		writeBulkResults(w, bulkResults(len(items), invalid, indices, saved, productItemError))
+	}
+}
+
+// Import creates the products of a csv document, one per row (with ?dry_run=true the rows are only validated)
+func (h *ProductDefault) Import() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - options
+		dryRun, mapping, err := importOptions(r)
+		if err != nil {
+			importRequestError(w, err)
+			return
+		}
+
+		// - read the rows
+		records, err := request.CSV(r, ProductRequestJSON{}, mapping)
+		if err != nil {
+			importRequestError(w, err)
+			return
+		}
+
+		// process
+		// - validate and save every row on its own
+		results := make([]ImportResultJSON, len(records))
+		for i, record := range records {
+			results[i].Line = record.Line
+
+			// - parse the row
+			err := record.Err
+			var p internal.Product
+			if err == nil {
+				p, err = parseProductItem(record.Body)
+			}
+
+			// - validate or save the product
+			if err == nil {
+				if dryRun {
+					err = h.sv.Validate(&p)
+				} else {
+					p, err = h.sv.Save(r.Context(), &p)
+				}
+			}
+			if err != nil {
+				results[i].Error = productItemError(err)
+				continue
+			}
+			results[i].ID = p.ID
+		}
+
+		// response
+		writeImportResults(w, dryRun, results)
+	}
+}
+
 // Update updates a product
 func (h *ProductDefault) Update() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -268,27 +434,35 @@ func (h *ProductDefault) Update() http.HandlerFunc {
 			return
 		}
 
+		// - check the product was not modified since the client read it
+		if !etag.IfMatch(r, p.Version) {
+			response.Error(w, http.StatusPreconditionFailed, "product has been modified")
+			return
+		}
+
 		// - deserialize to ProductJSON
 		productJSONData := deserializeProduct(p)
 
-		// - map JSON to productJSON
-		if err := request.JSON(r, &productJSONData); err != nil {
-			response.Error(w, http.StatusBadRequest, "invalid body")
+		// - apply the body (merge patch or json patch) to productJSON
+		if err := request.Patch(r, &productJSONData); err != nil {
+			patchError(w, err)
 			return
 		}
 
-		// - serialize to internal product
+		// - serialize to internal product (the id comes from the URL)
+		productJSONData.ID = 0
 		updatedProduct := serializeProduct(productJSONData)
-		updatedProduct.ID = id
 		// - validate required fields
 		err = validateProductZeroValues(&updatedProduct)
 		if err != nil {
 			response.Error(w, http.StatusUnprocessableEntity, err.Error())
 			return
 		}
+		updatedProduct.ID = id
+		updatedProduct.Version = p.Version
 
 		// - update the product
-		err = h.sv.Update(&updatedProduct)
+		err = h.sv.Update(r.Context(), &updatedProduct)
 		if err != nil {
 			switch {
 			case errors.Is(err, internal.ErrProductServiceNotFound):
@@ -297,6 +471,14 @@ func (h *ProductDefault) Update() http.HandlerFunc {
 				response.Error(w, http.StatusConflict, "duplicated product code")
 			case errors.Is(err, internal.ErrProductServiceNothingToUpdate):
 				response.Error(w, http.StatusConflict, "nothing to update")
+			case errors.Is(err, internal.ErrProductServiceVersionConflict):
+				response.Error(w, http.StatusPreconditionFailed, "product has been modified")
+			case errors.Is(err, internal.ErrSellerServiceNotFound):
+				response.Error(w, http.StatusConflict, "seller not found")
+			case errors.Is(err, internal.ErrProductTypeServiceNotFound):
+				response.Error(w, http.StatusConflict, "product type not found")
+			case errors.Is(err, internal.ErrProductServiceProductType):
+				response.Error(w, http.StatusConflict, "product has batches in sections of another product type")
 			default:
 				response.Error(w, http.StatusInternalServerError, "unknown error")
 			}
@@ -304,6 +486,7 @@ func (h *ProductDefault) Update() http.HandlerFunc {
 		}
 
 		// serialize to JSON
+		etag.Set(w, updatedProduct.Version)
 		responseJSONData := deserializeProduct(updatedProduct)
 
 		// response
@@ -316,7 +499,7 @@ func (h *ProductDefault) Update() http.HandlerFunc {
 	}
 }
 
-// Delete deletes a product
+// Delete marks a product as deleted, or deletes it permanently with ?hard=true
 func (h *ProductDefault) Delete() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// request
@@ -326,14 +509,48 @@ func (h *ProductDefault) Delete() http.HandlerFunc {
 			response.Error(w, http.StatusBadRequest, "invalid id")
 			return
 		}
+		// - delete it permanently with ?hard=true, otherwise it is only marked as deleted
+		hard, err := queryBool(r, "hard")
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid hard")
+			return
+		}
+
+		// - check the product was not modified since the client read it (the delete only applies to that version)
+		version := 0
+		if r.Header.Get("If-Match") != "" {
+			p, err := h.sv.Get(id)
+			if err != nil {
+				switch {
+				case errors.Is(err, internal.ErrProductServiceNotFound):
+					response.Error(w, http.StatusNotFound, "product not found")
+				default:
+					response.Error(w, http.StatusInternalServerError, "unknown error")
+				}
+				return
+			}
+			if !etag.IfMatch(r, p.Version) {
+				response.Error(w, http.StatusPreconditionFailed, "product has been modified")
+				return
+			}
+			version = p.Version
+		}
 
 		// process
-		err = h.sv.Delete(id)
+		if hard {
+			err = h.sv.Purge(r.Context(), id, version)
+		} else {
+			err = h.sv.Delete(r.Context(), id, version)
+		}
 
 		if err != nil {
 			switch {
 			case errors.Is(err, internal.ErrProductServiceNotFound):
 				response.Error(w, http.StatusNotFound, "product not found")
+			case errors.Is(err, internal.ErrProductServiceVersionConflict):
+				response.Error(w, http.StatusPreconditionFailed, "product has been modified")
+			case errors.Is(err, internal.ErrDependentsServiceUnconfirmed):
+				response.Error(w, http.StatusConflict, "resource has dependents, confirm the delete with ?confirm=cascade")
 			case errors.Is(err, internal.ErrProductServiceForeignKey):
 				response.Error(w, http.StatusConflict, "product has dependencies")
 			default:
@@ -349,6 +566,45 @@ func (h *ProductDefault) Delete() http.HandlerFunc {
 	}
 }
 
+// Restore restores the deleted product with the given ID and returns it
+func (h *ProductDefault) Restore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		// process
+		// - unmark the product
+		err = h.sv.Restore(r.Context(), id)
+		if err != nil {
+			switch {
+			case errors.Is(err, internal.ErrProductServiceNotFound):
+				response.Error(w, http.StatusNotFound, "deleted product not found")
+			default:
+				response.Error(w, http.StatusInternalServerError, "unknown error")
+			}
+			return
+		}
+		// - get it back
+		p, err := h.sv.Get(id)
+		if err != nil {
+			response.Error(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		// response
+		etag.Set(w, p.Version)
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    deserializeProduct(p),
+		})
+	}
+}
+
 // // GetReport returns the information of the product record report
 // func (h *ProductDefault) GetReport() http.HandlerFunc {
 // 	return func(w http.ResponseWriter, r *http.Request) {
@@ -393,6 +649,146 @@ func (h *ProductDefault) Delete() http.HandlerFunc {
 // 	}
 // }
 
+// Prices returns the price timeline of a product and its prices as of the date of ?at= (now when missing)
+func (h *ProductDefault) Prices() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get the id from the request
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+		// - get the date from the query
+		at, err := queryDate(r, "at")
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// process
+		prices, err := h.sv.GetPrices(id, endOfDay(at))
+		if err != nil {
+			switch {
+			case errors.Is(err, internal.ErrProductServiceNotFound):
+				response.Error(w, http.StatusNotFound, "product not found")
+			default:
+				response.Error(w, http.StatusInternalServerError, "unknown error")
+			}
+			return
+		}
+
+		// response
+		data := ProductPricesJSON{
+			ProductID: prices.ProductID,
+			At:        prices.At.Format(time.RFC3339),
+			Timeline:  make([]ProductRecordJSON, len(prices.Timeline)),
+		}
+		for i, pr := range prices.Timeline {
+			data.Timeline[i] = serializeProductRecord(pr)
+		}
+		if prices.Current.ID != 0 {
+			current := serializeProductRecord(prices.Current)
+			data.Current = &current
+		}
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    data,
+		})
+	}
+}
+
+// Margins returns the margins of the products for sale as of the date of ?at= (now when missing),
+// of the seller of ?seller_id= (all when missing), per product and per seller
+func (h *ProductDefault) Margins() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get the seller from the query
+		var sellerID int
+		if v := r.URL.Query().Get("seller_id"); v != "" {
+			var err error
+			if sellerID, err = strconv.Atoi(v); err != nil || sellerID <= 0 {
+				response.Error(w, http.StatusBadRequest, "invalid seller_id")
+				return
+			}
+		}
+		// - get the date from the query
+		at, err := queryDate(r, "at")
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// process
+		report, err := h.sv.GetMargins(sellerID, endOfDay(at))
+		if err != nil {
+			switch {
+			case errors.Is(err, internal.ErrSellerServiceNotFound):
+				response.Error(w, http.StatusNotFound, "seller not found")
+			default:
+				response.Error(w, http.StatusInternalServerError, "unknown error")
+			}
+			return
+		}
+
+		// response
+		data := MarginReportJSON{
+			At:       report.At.Format(time.RFC3339),
+			Products: make([]ProductMarginJSON, len(report.Products)),
+			Sellers:  make([]SellerMarginJSON, len(report.Sellers)),
+		}
+		for i, m := range report.Products {
+			data.Products[i] = ProductMarginJSON{
+				ProductID:       m.ProductID,
+				ProductCode:     m.ProductCode,
+				Description:     m.Description,
+				SellerID:        optionalID(m.SellerID),
+				ProductRecordID: m.Record.ID,
+				LastUpdateDate:  m.Record.LastUpdateDate.Format(time.RFC3339),
+				PurchasePrice:   m.Record.PurchasePrice,
+				SalePrice:       m.Record.SalePrice,
+				Margin:          m.Margin,
+				MarginRate:      m.MarginRate,
+			}
+		}
+		for i, sm := range report.Sellers {
+			data.Sellers[i] = SellerMarginJSON{
+				SellerID:          optionalID(sm.SellerID),
+				Products:          sm.Products,
+				AverageMargin:     sm.AverageMargin,
+				AverageMarginRate: sm.AverageMarginRate,
+			}
+		}
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    data,
+		})
+	}
+}
+
+// serializeProductRecord converts a product record to a ProductRecordJSON
+func serializeProductRecord(pr internal.ProductRecord) ProductRecordJSON {
+	data := ProductRecordJSON{
+		ID:             pr.ID,
+		LastUpdateDate: pr.LastUpdateDate.Format(time.RFC3339),
+		PurchasePrice:  pr.PurchasePrice,
+	}
+	if pr.SalePrice != 0 {
+		salePrice := pr.SalePrice
+		data.SalePrice = &salePrice
+	}
+
+	return data
+}
+
+// endOfDay returns the last second of the given date, so everything that took effect during the day is included (zero stays zero)
+func endOfDay(date time.Time) time.Time {
+	if date.IsZero() {
+		return date
+	}
+	return date.Add(24*time.Hour - time.Second)
+}
+
 // deserializeProduct converts a internal Product to a ProductJSON
 func deserializeProduct(p internal.Product) ProductJSON {
 	return ProductJSON{
@@ -406,8 +802,9 @@ func deserializeProduct(p internal.Product) ProductJSON {
 		ExpirationRate: p.ExpirationRate,
 		FreezingRate:   p.FreezingRate,
 		RecomFreezTemp: p.RecomFreezTemp,
-		ProductTypeID:  p.ProductTypeID,
+		ProductTypeID:  optionalID(p.ProductTypeID),
 		SellerID:       p.SellerID,
+		DeletedAt:      optionalTime(p.DeletedAt),
 	}
 }
 
@@ -424,7 +821,7 @@ func serializeProduct(p ProductJSON) internal.Product {
 		ExpirationRate: p.ExpirationRate,
 		FreezingRate:   p.FreezingRate,
 		RecomFreezTemp: p.RecomFreezTemp,
-		ProductTypeID:  p.ProductTypeID,
+		ProductTypeID:  idOf(p.ProductTypeID),
 		SellerID:       p.SellerID,
 	}
 }
@@ -437,6 +834,58 @@ func serializeProduct(p ProductJSON) internal.Product {
 // 	}
 // }
 
+// parseProductItem validates a product item of a bulk or import request as Create does
+func parseProductItem(body []byte) (p internal.Product, err error) {
+	// - validate the body
+	bodyMap := map[string]any{}
+	if err = json.Unmarshal(body, &bodyMap); err != nil {
+		err = errors.New("invalid body")
+		return
+	}
+	productRequest := ProductRequestJSON{}
+	if err = validate.CheckFieldExistance(productRequest, bodyMap); err != nil {
+		return
+	}
+	if err = json.Unmarshal(body, &productRequest); err != nil {
+		err = errors.New("invalid body")
+		return
+	}
+
+	// - map the body to a product and validate required fields
+	p = serializeProduct(ProductJSON{
+		ProductCode:    productRequest.ProductCode,
+		Description:    productRequest.Description,
+		Height:         productRequest.Height,
+		Length:         productRequest.Length,
+		Width:          productRequest.Width,
+		Weight:         productRequest.Weight,
+		ExpirationRate: productRequest.ExpirationRate,
+		FreezingRate:   productRequest.FreezingRate,
+		RecomFreezTemp: productRequest.RecomFreezTemp,
+		ProductTypeID:  optionalID(productRequest.ProductTypeID),
+		SellerID:       productRequest.SellerID,
+	})
+	err = validateProductZeroValues(&p)
+	return
+}
+
+// productItemError returns the message of an error of a product item of a bulk or import request
+func productItemError(err error) string {
+	switch {
+	case errors.Is(err, internal.ErrProductServiceDuplicated):
+		return "duplicated product code"
+	case errors.Is(err, internal.ErrSellerServiceNotFound):
+		return "seller not found"
+	case errors.Is(err, internal.ErrProductTypeServiceNotFound):
+		return "product type not found"
+	case errors.Is(err, internal.ErrProductServiceUnkown), errors.Is(err, internal.ErrProductServiceDBError):
+		return "unknown error"
+	default:
+		// validation error
+		return err.Error()
+	}
+}
+
 // validateProductZeroValues validates if the product has fields in zero value
 func validateProductZeroValues(product *internal.Product) error {
 	if product.ID != 0 {
@@ -473,6 +922,9 @@ func validateProductZeroValues(product *internal.Product) error {
 	if product.SellerID == 0 {
 		return fmt.Errorf("%w: seller_id", ErrHandlerMissingField)
 	}
+	if product.ProductTypeID < 0 {
+		return fmt.Errorf("%w: product_type_id", ErrHandlerMissingField)
+	}
 
 	return nil
 }
@@ -0,0 +1,331 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/manuelfirman/go-API/internal"
+	"github.com/manuelfirman/go-API/platform/web/etag"
+	"github.com/manuelfirman/go-API/platform/web/request"
+	"github.com/manuelfirman/go-API/platform/web/response"
+)
+
+// ProductTypeJSON is the JSON representation of a product type
+type ProductTypeJSON struct {
+	// ID is the unique identifier of the product type
+	ID int `json:"id"`
+	// Name is the unique name of the product type
+	Name string `json:"name"`
+	// StorageClass is how the products of the type are stored: ambient, refrigerated or frozen
+	StorageClass string `json:"storage_class"`
+	// MinimumTemperature is the lowest temperature the products of the type can be stored at
+	MinimumTemperature float64 `json:"minimum_temperature"`
+	// MaximumTemperature is the highest temperature the products of the type can be stored at
+	MaximumTemperature float64 `json:"maximum_temperature"`
+	// DeletedAt is the moment the product type was deleted, only listed with ?include_deleted=true (read only)
+	DeletedAt *string `json:"deleted_at,omitempty"`
+}
+
+// NewProductTypeDefault creates a new instance of the product type handler
+func NewProductTypeDefault(sv internal.ProductTypeService) *ProductTypeDefault {
+	return &ProductTypeDefault{
+		sv: sv,
+	}
+}
+
+// ProductTypeDefault is the default implementation of the product type handler
+type ProductTypeDefault struct {
+	sv internal.ProductTypeService
+}
+
+// GetAll returns all product types
+func (h *ProductTypeDefault) GetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - include the deleted product types with ?include_deleted=true
+		includeDeleted, err := queryBool(r, "include_deleted")
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid include_deleted")
+			return
+		}
+
+		// process
+		types, err := h.sv.GetAll(includeDeleted)
+		if err != nil {
+			writeProductTypeError(w, err)
+			return
+		}
+
+		// response
+		data := make([]ProductTypeJSON, 0, len(types))
+		for _, pt := range types {
+			data = append(data, serializeProductType(pt))
+		}
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    data,
+		})
+	}
+}
+
+// Get returns a product type by ID
+func (h *ProductTypeDefault) Get() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		// process
+		pt, err := h.sv.Get(id)
+		if err != nil {
+			writeProductTypeError(w, err)
+			return
+		}
+		// - the client already has the current version
+		etag.Set(w, pt.Version)
+		if etag.IfNoneMatch(r, pt.Version) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    serializeProductType(pt),
+		})
+	}
+}
+
+// Save creates a new product type
+func (h *ProductTypeDefault) Save() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - read the body in []byte
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot read")
+			return
+		}
+		// - unmarshal body to map for validations
+		var bodyMap map[string]any
+		if err = json.Unmarshal(body, &bodyMap); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot unmarshal to map")
+			return
+		}
+		// - validate
+		if err = validateKeyExistance(bodyMap, "name", "storage_class", "minimum_temperature", "maximum_temperature"); err != nil {
+			response.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		// - unmarshal to struct
+		var ptJSON ProductTypeJSON
+		if err = json.Unmarshal(body, &ptJSON); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot unmarshal to struct")
+			return
+		}
+		if ptJSON.ID != 0 {
+			response.Error(w, http.StatusBadRequest, ErrHandlerIdInRequest.Error())
+			return
+		}
+
+		// process
+		pt := deserializeProductType(ptJSON)
+		if err = h.sv.Save(r.Context(), &pt); err != nil {
+			writeProductTypeError(w, err)
+			return
+		}
+
+		// response
+		etag.Set(w, pt.Version)
+		response.JSON(w, http.StatusCreated, Response{
+			Message: "success",
+			Data:    serializeProductType(pt),
+		})
+	}
+}
+
+// Update updates a product type with a merge patch or json patch, if it was not modified since the client read it
+func (h *ProductTypeDefault) Update() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+		// - get product type by id
+		pt, err := h.sv.Get(id)
+		if err != nil {
+			writeProductTypeError(w, err)
+			return
+		}
+		// - check the product type was not modified since the client read it
+		if !etag.IfMatch(r, pt.Version) {
+			response.Error(w, http.StatusPreconditionFailed, "product type has been modified")
+			return
+		}
+		version := pt.Version
+		// - apply the body to the product type (the id comes from the url)
+		ptJSON := serializeProductType(pt)
+		if err = request.Patch(r, &ptJSON); err != nil {
+			patchError(w, err)
+			return
+		}
+		pt = deserializeProductType(ptJSON)
+		pt.ID = id
+		pt.Version = version
+
+		// process
+		if err = h.sv.Update(r.Context(), &pt); err != nil {
+			writeProductTypeError(w, err)
+			return
+		}
+
+		// response
+		etag.Set(w, pt.Version)
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    serializeProductType(pt),
+		})
+	}
+}
+
+// Delete marks a product type as deleted by ID, or deletes it permanently with ?hard=true unless products or sections are of the type
+func (h *ProductTypeDefault) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+		// - delete it permanently with ?hard=true, otherwise it is only marked as deleted
+		hard, err := queryBool(r, "hard")
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid hard")
+			return
+		}
+		// - check the product type was not modified since the client read it (the delete only applies to that version)
+		version := 0
+		if r.Header.Get("If-Match") != "" {
+			pt, err := h.sv.Get(id)
+			if err != nil {
+				writeProductTypeError(w, err)
+				return
+			}
+			if !etag.IfMatch(r, pt.Version) {
+				response.Error(w, http.StatusPreconditionFailed, "product type has been modified")
+				return
+			}
+			version = pt.Version
+		}
+
+		// process
+		if hard {
+			err = h.sv.Purge(r.Context(), id, version)
+		} else {
+			err = h.sv.Delete(r.Context(), id, version)
+		}
+		if err != nil {
+			writeProductTypeError(w, err)
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusNoContent, Response{
+			Message: "success",
+			Data:    nil,
+		})
+	}
+}
+
+// Restore restores the deleted product type with the given ID and returns it
+func (h *ProductTypeDefault) Restore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		// process
+		// - unmark the product type
+		err = h.sv.Restore(r.Context(), id)
+		if err != nil {
+			writeProductTypeError(w, err)
+			return
+		}
+		// - get it back
+		pt, err := h.sv.Get(id)
+		if err != nil {
+			response.Error(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		// response
+		etag.Set(w, pt.Version)
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    serializeProductType(pt),
+		})
+	}
+}
+
+// writeProductTypeError writes the error response for an error returned by the product type service
+func writeProductTypeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, internal.ErrProductTypeServiceNotFound):
+		response.Error(w, http.StatusNotFound, "product type not found")
+	case errors.Is(err, internal.ErrProductTypeServiceDuplicated):
+		response.Error(w, http.StatusConflict, "product type already exists")
+	case errors.Is(err, internal.ErrProductTypeServiceFK):
+		response.Error(w, http.StatusConflict, "product type has products or sections")
+	case errors.Is(err, internal.ErrProductTypeServiceVersionConflict):
+		response.Error(w, http.StatusPreconditionFailed, "product type has been modified")
+	case errors.Is(err, internal.ErrDependentsServiceUnconfirmed):
+		response.Error(w, http.StatusConflict, "resource has dependents, confirm the delete with ?confirm=cascade")
+	case errors.Is(err, internal.ErrProductTypeServiceInvalidField):
+		response.Error(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, internal.ErrProductTypeService):
+		response.Error(w, http.StatusInternalServerError, "internal server error")
+	case errors.Is(err, internal.ErrProductTypeServiceUnknown):
+		response.Error(w, http.StatusInternalServerError, "unknown service error")
+	default:
+		response.Error(w, http.StatusInternalServerError, "unknown server error")
+	}
+}
+
+// serializeProductType serializes a product type into a ProductTypeJSON
+func serializeProductType(pt internal.ProductType) ProductTypeJSON {
+	return ProductTypeJSON{
+		ID:                 pt.ID,
+		Name:               pt.Name,
+		StorageClass:       pt.StorageClass,
+		MinimumTemperature: pt.MinimumTemperature,
+		MaximumTemperature: pt.MaximumTemperature,
+		DeletedAt:          optionalTime(pt.DeletedAt),
+	}
+}
+
+// deserializeProductType deserializes a ProductTypeJSON into a product type
+func deserializeProductType(ptJSON ProductTypeJSON) internal.ProductType {
+	return internal.ProductType{
+		ID:                 ptJSON.ID,
+		Name:               ptJSON.Name,
+		StorageClass:       ptJSON.StorageClass,
+		MinimumTemperature: ptJSON.MinimumTemperature,
+		MaximumTemperature: ptJSON.MaximumTemperature,
+	}
+}
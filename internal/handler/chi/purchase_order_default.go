@@ -0,0 +1,431 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/manuelfirman/go-API/internal"
+	"github.com/manuelfirman/go-API/platform/web/response"
+)
+
+// PurchaseOrderJSON is the JSON representation of a purchase order
+type PurchaseOrderJSON struct {
+	// ID is the unique identifier of the purchase order
+	ID int `json:"id"`
+	// OrderNumber is the unique number of the purchase order
+	OrderNumber int `json:"order_number"`
+	// OrderDate is the date on which the order was placed (YYYY-MM-DD)
+	OrderDate string `json:"order_date"`
+	// TrackingCode is the code to track the shipment of the order (optional on create, generated if missing)
+	TrackingCode string `json:"tracking_code"`
+	// BuyerID is the unique identifier of the buyer who placed the order
+	BuyerID int `json:"buyer_id"`
+	// ProductRecordID is the unique identifier of the product record ordered
+	ProductRecordID int `json:"product_record_id"`
+	// OrderStatusID is the id of the current status of the order (read only)
+	OrderStatusID int `json:"order_status_id"`
+	// Status is the name of the current status of the order (read only)
+	Status string `json:"status"`
+	// CarrierID is the unique identifier of the carrier that ships the order (read only, null if not assigned)
+	CarrierID *int `json:"carrier_id"`
+}
+
+// ShipmentJSON is the JSON representation of a request to assign a carrier to a purchase order
+type ShipmentJSON struct {
+	// CarrierID is the unique identifier of the carrier (optional, chosen by the service if missing)
+	CarrierID int `json:"carrier_id"`
+	// WarehouseID is the unique identifier of the warehouse the order ships from (optional)
+	WarehouseID int `json:"warehouse_id"`
+}
+
+// CarrierJSON is the JSON representation of a carrier
+type CarrierJSON struct {
+	// ID is the unique identifier of the carrier
+	ID int `json:"id"`
+	// CID is the unique company identifier of the carrier
+	CID int `json:"cid"`
+	// CompanyName is the name of the company
+	CompanyName string `json:"company_name"`
+	// Address is the address of the carrier
+	Address string `json:"address"`
+	// Telephone is the telephone number of the carrier
+	Telephone string `json:"telephone"`
+	// LocalityID is the id of the locality where the carrier is located
+	LocalityID int `json:"locality_id"`
+}
+
+// TrackingJSON is the JSON representation of the tracking of the shipment of a purchase order
+type TrackingJSON struct {
+	// TrackingCode is the code to track the shipment
+	TrackingCode string `json:"tracking_code"`
+	// OrderNumber is the number of the purchase order
+	OrderNumber int `json:"order_number"`
+	// Status is the name of the current status of the order
+	Status string `json:"status"`
+	// Carrier is the carrier that ships the order
+	Carrier CarrierJSON `json:"carrier"`
+	// Timeline are the status changes of the order, oldest first
+	Timeline []OrderStatusChangeJSON `json:"timeline"`
+}
+
+// OrderTransitionJSON is the JSON representation of a request to change the status of a purchase order
+type OrderTransitionJSON struct {
+	// Status is the name of the status to go to
+	Status string `json:"status"`
+}
+
+// OrderStatusChangeJSON is the JSON representation of a change of the status of a purchase order
+type OrderStatusChangeJSON struct {
+	// From is the name of the status before the change (null when the order was created)
+	From *string `json:"from"`
+	// To is the name of the status after the change
+	To string `json:"to"`
+	// ChangedAt is the moment at which the status changed (RFC 3339)
+	ChangedAt string `json:"changed_at"`
+}
+
+// NewPurchaseOrderDefault creates a new instance of the purchase order handler
+func NewPurchaseOrderDefault(sv internal.PurchaseOrderService) *PurchaseOrderDefault {
+	return &PurchaseOrderDefault{
+		sv: sv,
+	}
+}
+
+// PurchaseOrderDefault is the default implementation of the purchase order handler
+type PurchaseOrderDefault struct {
+	sv internal.PurchaseOrderService
+}
+
+// GetAll returns all purchase orders
+func (h *PurchaseOrderDefault) GetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// process
+		orders, err := h.sv.GetAll()
+		if err != nil {
+			writePurchaseOrderError(w, err)
+			return
+		}
+
+		// response
+		data := make([]PurchaseOrderJSON, 0, len(orders))
+		for _, po := range orders {
+			data = append(data, serializePurchaseOrder(po))
+		}
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    data,
+		})
+	}
+}
+
+// Get returns a purchase order by ID
+func (h *PurchaseOrderDefault) Get() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		// process
+		po, err := h.sv.Get(id)
+		if err != nil {
+			writePurchaseOrderError(w, err)
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    serializePurchaseOrder(po),
+		})
+	}
+}
+
+// Save creates a new purchase order, which starts pending (with a generated tracking code if the body has none)
+func (h *PurchaseOrderDefault) Save() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - read the body in []byte
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot read")
+			return
+		}
+		// - unmarshal body to map for validations
+		var bodyMap map[string]any
+		if err = json.Unmarshal(body, &bodyMap); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot unmarshal to map")
+			return
+		}
+		// - validate
+		if err = validateKeyExistance(bodyMap, "order_number", "order_date", "buyer_id", "product_record_id"); err != nil {
+			response.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		// - unmarshal to struct
+		var poJSON PurchaseOrderJSON
+		if err = json.Unmarshal(body, &poJSON); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot unmarshal to struct")
+			return
+		}
+		if poJSON.ID != 0 {
+			response.Error(w, http.StatusBadRequest, ErrHandlerIdInRequest.Error())
+			return
+		}
+		// - deserialize
+		orderDate, err := time.Parse(DateLayout, poJSON.OrderDate)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, ErrHandlerInvalidDate.Error()+": order_date")
+			return
+		}
+		po := internal.PurchaseOrder{
+			OrderNumber:     poJSON.OrderNumber,
+			OrderDate:       orderDate,
+			TrackingCode:    poJSON.TrackingCode,
+			BuyerID:         poJSON.BuyerID,
+			ProductRecordID: poJSON.ProductRecordID,
+		}
+
+		// process
+		if err = h.sv.Save(r.Context(), &po); err != nil {
+			writePurchaseOrderError(w, err)
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusCreated, Response{
+			Message: "success",
+			Data:    serializePurchaseOrder(po),
+		})
+	}
+}
+
+// Transition moves a purchase order to another status: pending -> confirmed -> picking -> shipped -> delivered,
+// and cancelled from any status before shipped. Illegal transitions are rejected with 409.
+func (h *PurchaseOrderDefault) Transition() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+		// - read the body
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot read")
+			return
+		}
+		var tJSON OrderTransitionJSON
+		if err = json.Unmarshal(body, &tJSON); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot unmarshal to struct")
+			return
+		}
+		to, ok := internal.ParseOrderStatus(tJSON.Status)
+		if !ok {
+			response.Error(w, http.StatusUnprocessableEntity, "invalid status")
+			return
+		}
+
+		// process
+		po, err := h.sv.Transition(r.Context(), id, to)
+		if err != nil {
+			writePurchaseOrderError(w, err)
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    serializePurchaseOrder(po),
+		})
+	}
+}
+
+// History returns the status changes of a purchase order, oldest first
+func (h *PurchaseOrderDefault) History() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		// process
+		changes, err := h.sv.GetHistory(id)
+		if err != nil {
+			writePurchaseOrderError(w, err)
+			return
+		}
+
+		// response
+		data := make([]OrderStatusChangeJSON, 0, len(changes))
+		for _, change := range changes {
+			data = append(data, serializeOrderStatusChange(change))
+		}
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    data,
+		})
+	}
+}
+
+// Shipment assigns a carrier to a purchase order and generates its tracking code.
+// The body is optional: without carrier_id the carrier is chosen preferring the locality of the warehouse.
+func (h *PurchaseOrderDefault) Shipment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+		// - read the body (optional)
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot read")
+			return
+		}
+		var sJSON ShipmentJSON
+		if len(body) > 0 {
+			if err = json.Unmarshal(body, &sJSON); err != nil {
+				response.Error(w, http.StatusBadRequest, "invalid body: cannot unmarshal to struct")
+				return
+			}
+		}
+
+		// process
+		po, err := h.sv.AssignShipment(r.Context(), id, sJSON.CarrierID, sJSON.WarehouseID)
+		if err != nil {
+			writePurchaseOrderError(w, err)
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    serializePurchaseOrder(po),
+		})
+	}
+}
+
+// Track returns the shipment of the purchase order with the given tracking code and its status timeline
+func (h *PurchaseOrderDefault) Track() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get tracking code from url
+		code := chi.URLParam(r, "tracking_code")
+		if code == "" {
+			response.Error(w, http.StatusBadRequest, "invalid tracking code")
+			return
+		}
+
+		// process
+		t, err := h.sv.Track(code)
+		if err != nil {
+			if errors.Is(err, internal.ErrPurchaseOrderServiceNotFound) {
+				response.Error(w, http.StatusNotFound, "tracking code not found")
+				return
+			}
+			writePurchaseOrderError(w, err)
+			return
+		}
+
+		// response
+		data := TrackingJSON{
+			TrackingCode: t.Order.TrackingCode,
+			OrderNumber:  t.Order.OrderNumber,
+			Status:       t.Order.Status.String(),
+			Carrier: CarrierJSON{
+				ID:          t.Carrier.ID,
+				CID:         t.Carrier.CID,
+				CompanyName: t.Carrier.CompanyName,
+				Address:     t.Carrier.Address,
+				Telephone:   t.Carrier.Telephone,
+				LocalityID:  t.Carrier.LocalityID,
+			},
+			Timeline: make([]OrderStatusChangeJSON, 0, len(t.History)),
+		}
+		for _, change := range t.History {
+			data.Timeline = append(data.Timeline, serializeOrderStatusChange(change))
+		}
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    data,
+		})
+	}
+}
+
+// writePurchaseOrderError writes the error response for an error returned by the purchase order service
+func writePurchaseOrderError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, internal.ErrPurchaseOrderServiceNotFound):
+		response.Error(w, http.StatusNotFound, "purchase order not found")
+	case errors.Is(err, internal.ErrPurchaseOrderServiceDuplicated):
+		response.Error(w, http.StatusConflict, "purchase order already exists")
+	case errors.Is(err, internal.ErrPurchaseOrderServiceTrackingCodeDuplicated):
+		response.Error(w, http.StatusConflict, "tracking code already in use")
+	case errors.Is(err, internal.ErrPurchaseOrderServiceFK):
+		response.Error(w, http.StatusConflict, "buyer or product record not found")
+	case errors.Is(err, internal.ErrPurchaseOrderServiceCarrierNotFound):
+		response.Error(w, http.StatusNotFound, "carrier not found")
+	case errors.Is(err, internal.ErrPurchaseOrderServiceNotShippable):
+		response.Error(w, http.StatusConflict, err.Error())
+	case errors.Is(err, internal.ErrPurchaseOrderServiceInvalidTransition):
+		response.Error(w, http.StatusConflict, err.Error())
+	case errors.Is(err, internal.ErrPurchaseOrderServiceStatusConflict):
+		response.Error(w, http.StatusConflict, "purchase order status changed, try again")
+	case errors.Is(err, internal.ErrPurchaseOrderServiceInvalidField):
+		response.Error(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, internal.ErrPurchaseOrderService):
+		response.Error(w, http.StatusInternalServerError, "internal server error")
+	case errors.Is(err, internal.ErrPurchaseOrderServiceUnknown):
+		response.Error(w, http.StatusInternalServerError, "unknown service error")
+	default:
+		response.Error(w, http.StatusInternalServerError, "unknown server error")
+	}
+}
+
+// serializePurchaseOrder serializes a purchase order into a PurchaseOrderJSON
+func serializePurchaseOrder(po internal.PurchaseOrder) PurchaseOrderJSON {
+	poJSON := PurchaseOrderJSON{
+		ID:              po.ID,
+		OrderNumber:     po.OrderNumber,
+		OrderDate:       po.OrderDate.Format(DateLayout),
+		TrackingCode:    po.TrackingCode,
+		BuyerID:         po.BuyerID,
+		ProductRecordID: po.ProductRecordID,
+		OrderStatusID:   int(po.Status),
+		Status:          po.Status.String(),
+	}
+	if po.CarrierID != 0 {
+		carrierID := po.CarrierID
+		poJSON.CarrierID = &carrierID
+	}
+	return poJSON
+}
+
+// serializeOrderStatusChange serializes a status change of a purchase order into an OrderStatusChangeJSON
+func serializeOrderStatusChange(change internal.OrderStatusChange) OrderStatusChangeJSON {
+	changeJSON := OrderStatusChangeJSON{
+		To:        change.To.String(),
+		ChangedAt: change.ChangedAt.Format(time.RFC3339Nano),
+	}
+	if change.From != 0 {
+		from := change.From.String()
+		changeJSON.From = &from
+	}
+	return changeJSON
+}
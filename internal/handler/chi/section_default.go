@@ -10,6 +10,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/manuelfirman/go-API/internal"
+	"github.com/manuelfirman/go-API/platform/web/etag"
 	"github.com/manuelfirman/go-API/platform/web/request"
 	"github.com/manuelfirman/go-API/platform/web/response"
 )
@@ -24,7 +25,7 @@ type SectionJSON struct {
 	CurrentTemperature float64 `json:"current_temperature"`
 	// MinimumTemperature is the minimum temperature that can be maintained in the section
 	MinimumTemperature float64 `json:"minimum_temperature"`
-	// CurrentCapacity is the current capacity of the section
+	// CurrentCapacity is the current capacity of the section (read-only, it changes with the batches stored in it)
 	CurrentCapacity int `json:"current_capacity"`
 	// MinimumCapacity is the minimum capacity of the section
 	MinimumCapacity int `json:"minimum_capacity"`
@@ -34,6 +35,10 @@ type SectionJSON struct {
 	WarehouseID int `json:"warehouse_id"`
 	// ProductTypeID is the unique identifier of the type of product stored in the section
 	ProductTypeID int `json:"product_type_id"`
+	// BelowMinimumCapacity is set when the current capacity dropped below the minimum capacity (read only)
+	BelowMinimumCapacity bool `json:"below_minimum_capacity"`
+	// DeletedAt is the moment the section was deleted, only listed with ?include_deleted=true (read only)
+	DeletedAt *string `json:"deleted_at,omitempty"`
 }
 
 // NewSectionDefault creates a new instance of the section handler
@@ -51,27 +56,50 @@ type SectionDefault struct {
 // GetAll returns all sections. Returns an error if the operation fails.
 func (h *SectionDefault) GetAll() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		sections, err := h.sv.GetAll()
+		// request
+		// - include the deleted sections with ?include_deleted=true
+		includeDeleted, err := queryBool(r, "include_deleted")
 		if err != nil {
-			switch {
-			case errors.Is(err, internal.ErrSectionService):
-				response.Error(w, http.StatusInternalServerError, "internal server error")
-			case errors.Is(err, internal.ErrSectionServiceUnkown):
-				response.Error(w, http.StatusInternalServerError, "unknown service error")
-			default:
-				response.Error(w, http.StatusInternalServerError, "unknown server error")
-			}
+			response.Error(w, http.StatusBadRequest, "invalid include_deleted")
+			return
 		}
-		// serialize the sections
-		data := make([]SectionJSON, len(sections))
-		for i, section := range sections {
-			data[i] = serializeSection(section)
+		// - only the sections below their minimum capacity with ?below_minimum=true
+		belowMinimum, err := queryBool(r, "below_minimum")
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid below_minimum")
+			return
 		}
-		// return the response
-		response.JSON(w, http.StatusOK, Response{
-			Message: "success",
-			Data:    data,
+
+		// response
+		// - writer of the format requested by the client
+		sw := newStreamWriter(w, r, http.StatusOK, SectionJSON{}, "success")
+
+		// process
+		// - write every section as it is read
+		err = h.sv.ForEach(includeDeleted, func(section internal.Section) error {
+			sectionJSON := serializeSection(section)
+			if belowMinimum && !sectionJSON.BelowMinimumCapacity {
+				return nil
+			}
+			return sw.Write(sectionJSON)
 		})
+		if err != nil {
+			// - nothing sent yet: regular error response, otherwise the response is left truncated
+			if !sw.Started() {
+				switch {
+				case errors.Is(err, internal.ErrSectionService):
+					response.Error(w, http.StatusInternalServerError, "internal server error")
+				case errors.Is(err, internal.ErrSectionServiceUnkown):
+					response.Error(w, http.StatusInternalServerError, "unknown service error")
+				default:
+					response.Error(w, http.StatusInternalServerError, "unknown server error")
+				}
+			}
+			return
+		}
+
+		// - end of the list
+		sw.Close()
 	}
 }
 
@@ -102,6 +130,13 @@ func (h *SectionDefault) Get() http.HandlerFunc {
 			return
 		}
 
+		// - the client already has the current version
+		etag.Set(w, section.Version)
+		if etag.IfNoneMatch(r, section.Version) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
 		// - serialize data to response
 		data := serializeSection(section)
 
@@ -129,7 +164,7 @@ func (h *SectionDefault) Save() http.HandlerFunc {
 			return
 		}
 		// - validate
-		if err = validateKeyExistance(bodyMap, "section_number", "current_temperature", "minimum_temperature", "current_capacity", "minimum_capacity", "maximum_capacity", "warehouse_id", "product_type_id"); err != nil {
+		if err = validateKeyExistance(bodyMap, "section_number", "current_temperature", "minimum_temperature", "minimum_capacity", "maximum_capacity", "warehouse_id", "product_type_id"); err != nil {
 			response.Error(w, http.StatusBadRequest, err.Error())
 			return
 		}
@@ -150,11 +185,15 @@ func (h *SectionDefault) Save() http.HandlerFunc {
 		section := deserializeSection(sectionJSON)
 
 		// process
-		err = h.sv.Save(&section)
+		err = h.sv.Save(r.Context(), &section)
 		if err != nil {
 			switch {
 			case errors.Is(err, internal.ErrSectionServiceDuplicated):
 				response.Error(w, http.StatusBadRequest, "section already exists")
+			case errors.Is(err, internal.ErrSectionServiceFK):
+				response.Error(w, http.StatusConflict, "warehouse not found")
+			case errors.Is(err, internal.ErrProductTypeServiceNotFound):
+				response.Error(w, http.StatusConflict, "product type not found")
 			case errors.Is(err, internal.ErrSectionService):
 				response.Error(w, http.StatusInternalServerError, "internal server error")
 			case errors.Is(err, internal.ErrSectionServiceUnkown):
@@ -177,6 +216,57 @@ func (h *SectionDefault) Save() http.HandlerFunc {
 	}
 }
 
+// Import creates the sections of a csv document, one per row (with ?dry_run=true the rows are only validated)
+func (h *SectionDefault) Import() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - options
+		dryRun, mapping, err := importOptions(r)
+		if err != nil {
+			importRequestError(w, err)
+			return
+		}
+
+		// - read the rows
+		records, err := request.CSV(r, SectionJSON{}, mapping)
+		if err != nil {
+			importRequestError(w, err)
+			return
+		}
+
+		// process
+		// - validate and save every row on its own
+		results := make([]ImportResultJSON, len(records))
+		for i, record := range records {
+			results[i].Line = record.Line
+
+			// - parse the row
+			err := record.Err
+			var section internal.Section
+			if err == nil {
+				section, err = parseSectionItem(record.Body)
+			}
+
+			// - validate or save the section
+			if err == nil {
+				if dryRun {
+					err = h.sv.Validate(&section)
+				} else {
+					err = h.sv.Save(r.Context(), &section)
+				}
+			}
+			if err != nil {
+				results[i].Error = sectionItemError(err)
+				continue
+			}
+			results[i].ID = section.ID
+		}
+
+		// response
+		writeImportResults(w, dryRun, results)
+	}
+}
+
 func (h *SectionDefault) Update() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// request
@@ -204,16 +294,25 @@ func (h *SectionDefault) Update() http.HandlerFunc {
 			return
 		}
 
+		// - check the section was not modified since the client read it
+		if !etag.IfMatch(r, section.Version) {
+			response.Error(w, http.StatusPreconditionFailed, "section has been modified")
+			return
+		}
+		version := section.Version
+		capacity := section.CurrentCapacity
+
 		// - deserialize section to JSON
 		sectionJSON := serializeSection(section)
 
-		// - map JSON to the section
-		if err := request.JSON(r, &sectionJSON); err != nil {
-			response.Error(w, http.StatusBadRequest, "invalid body")
+		// - apply the body (merge patch or json patch) to the section
+		if err := request.Patch(r, &sectionJSON); err != nil {
+			patchError(w, err)
 			return
 		}
 
-		// - validate zero values
+		// - validate zero values (the id comes from the URL)
+		sectionJSON.ID = 0
 		if err := validateSectionZeroValues(sectionJSON); err != nil {
 			response.Error(w, http.StatusBadRequest, err.Error())
 			return
@@ -221,13 +320,27 @@ func (h *SectionDefault) Update() http.HandlerFunc {
 
 		// - deserialize sectionJSON to internal section
 		section = deserializeSection(sectionJSON)
+		section.ID = id
+		section.Version = version
+		// - the current capacity is read-only
+		section.CurrentCapacity = capacity
 
 		// process
-		err = h.sv.Update(&section)
+		err = h.sv.Update(r.Context(), &section)
 		if err != nil {
 			switch {
+			case errors.Is(err, internal.ErrSectionServiceVersionConflict):
+				response.Error(w, http.StatusPreconditionFailed, "section has been modified")
+			case errors.Is(err, internal.ErrSectionServiceNotFound):
+				response.Error(w, http.StatusNotFound, "section not found")
+			case errors.Is(err, internal.ErrSectionServiceProductType):
+				response.Error(w, http.StatusConflict, "section stores batches of another product type")
 			case errors.Is(err, internal.ErrSectionServiceDuplicated):
 				response.Error(w, http.StatusBadRequest, "section already exists")
+			case errors.Is(err, internal.ErrSectionServiceFK):
+				response.Error(w, http.StatusConflict, "warehouse not found")
+			case errors.Is(err, internal.ErrProductTypeServiceNotFound):
+				response.Error(w, http.StatusConflict, "product type not found")
 			case errors.Is(err, internal.ErrSectionService):
 				response.Error(w, http.StatusInternalServerError, "internal server error")
 			case errors.Is(err, internal.ErrSectionServiceUnkown):
@@ -240,6 +353,7 @@ func (h *SectionDefault) Update() http.HandlerFunc {
 		}
 
 		// - serialize data to response
+		etag.Set(w, section.Version)
 		data := serializeSection(section)
 
 		// response
@@ -250,6 +364,7 @@ func (h *SectionDefault) Update() http.HandlerFunc {
 	}
 }
 
+// Delete marks a section as deleted by ID, or deletes it permanently with ?hard=true
 func (h *SectionDefault) Delete() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// request
@@ -259,13 +374,49 @@ func (h *SectionDefault) Delete() http.HandlerFunc {
 			response.Error(w, http.StatusBadRequest, "invalid id")
 			return
 		}
+		// - delete it permanently with ?hard=true, otherwise it is only marked as deleted
+		hard, err := queryBool(r, "hard")
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid hard")
+			return
+		}
+
+		// - check the section was not modified since the client read it (the delete only applies to that version)
+		version := 0
+		if r.Header.Get("If-Match") != "" {
+			section, err := h.sv.Get(id)
+			if err != nil {
+				switch {
+				case errors.Is(err, internal.ErrSectionServiceNotFound):
+					response.Error(w, http.StatusNotFound, "section not found")
+				default:
+					response.Error(w, http.StatusInternalServerError, "internal server error")
+				}
+				return
+			}
+			if !etag.IfMatch(r, section.Version) {
+				response.Error(w, http.StatusPreconditionFailed, "section has been modified")
+				return
+			}
+			version = section.Version
+		}
 
 		// process
-		err = h.sv.Delete(id)
+		if hard {
+			err = h.sv.Purge(r.Context(), id, version)
+		} else {
+			err = h.sv.Delete(r.Context(), id, version)
+		}
 		if err != nil {
 			switch {
 			case errors.Is(err, internal.ErrSectionServiceNotFound):
 				response.Error(w, http.StatusNotFound, "section not found")
+			case errors.Is(err, internal.ErrSectionServiceVersionConflict):
+				response.Error(w, http.StatusPreconditionFailed, "section has been modified")
+			case errors.Is(err, internal.ErrDependentsServiceUnconfirmed):
+				response.Error(w, http.StatusConflict, "resource has dependents, confirm the delete with ?confirm=cascade")
+			case errors.Is(err, internal.ErrSectionServiceFK):
+				response.Error(w, http.StatusConflict, "section has product batches")
 			case errors.Is(err, internal.ErrSectionService):
 				response.Error(w, http.StatusInternalServerError, "internal server error")
 			case errors.Is(err, internal.ErrSectionServiceUnkown):
@@ -284,6 +435,49 @@ func (h *SectionDefault) Delete() http.HandlerFunc {
 	}
 }
 
+// Restore restores the deleted section with the given ID and returns it
+func (h *SectionDefault) Restore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		// process
+		// - unmark the section
+		err = h.sv.Restore(r.Context(), id)
+		if err != nil {
+			switch {
+			case errors.Is(err, internal.ErrSectionServiceNotFound):
+				response.Error(w, http.StatusNotFound, "deleted section not found")
+			case errors.Is(err, internal.ErrSectionService):
+				response.Error(w, http.StatusInternalServerError, "internal server error")
+			case errors.Is(err, internal.ErrSectionServiceUnkown):
+				response.Error(w, http.StatusInternalServerError, "unknown service error")
+			default:
+				response.Error(w, http.StatusInternalServerError, "unknown server error")
+			}
+			return
+		}
+		// - get it back
+		section, err := h.sv.Get(id)
+		if err != nil {
+			response.Error(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		// response
+		etag.Set(w, section.Version)
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    serializeSection(section),
+		})
+	}
+}
+
 // serializeSection serializes a section into a SectionJSON
 func serializeSection(section internal.Section) SectionJSON {
 	return SectionJSON{
@@ -296,6 +490,9 @@ func serializeSection(section internal.Section) SectionJSON {
 		MaximumCapacity:    section.MaximumCapacity,
 		WarehouseID:        section.WarehouseID,
 		ProductTypeID:      section.ProductTypeID,
+		// - flag sections that need to be restocked
+		BelowMinimumCapacity: section.CurrentCapacity < section.MinimumCapacity,
+		DeletedAt:            optionalTime(section.DeletedAt),
 	}
 }
 
@@ -314,6 +511,49 @@ func deserializeSection(section SectionJSON) internal.Section {
 	}
 }
 
+// parseSectionItem validates a section item of an import request as Save does
+func parseSectionItem(body []byte) (section internal.Section, err error) {
+	// - validate the body keys
+	var bodyMap map[string]any
+	if err = json.Unmarshal(body, &bodyMap); err != nil {
+		err = errors.New("invalid body: cannot unmarshal to map")
+		return
+	}
+	if err = validateKeyExistance(bodyMap, "section_number", "current_temperature", "minimum_temperature", "minimum_capacity", "maximum_capacity", "warehouse_id", "product_type_id"); err != nil {
+		return
+	}
+
+	// - validate zero values
+	var sectionJSON SectionJSON
+	if err = json.Unmarshal(body, &sectionJSON); err != nil {
+		err = errors.New("invalid body: cannot unmarshal to struct")
+		return
+	}
+	if err = validateSectionZeroValues(sectionJSON); err != nil {
+		return
+	}
+
+	section = deserializeSection(sectionJSON)
+	return
+}
+
+// sectionItemError returns the message of an error of a section item of an import request
+func sectionItemError(err error) string {
+	switch {
+	case errors.Is(err, internal.ErrSectionServiceDuplicated):
+		return "section already exists"
+	case errors.Is(err, internal.ErrSectionServiceFK):
+		return "warehouse not found"
+	case errors.Is(err, internal.ErrProductTypeServiceNotFound):
+		return "product type not found"
+	case errors.Is(err, internal.ErrSectionService), errors.Is(err, internal.ErrSectionServiceUnkown):
+		return "unknown error"
+	default:
+		// validation error
+		return err.Error()
+	}
+}
+
 // validateSectionZeroValues
 func validateSectionZeroValues(section SectionJSON) error {
 	// validate that id does has send in the request
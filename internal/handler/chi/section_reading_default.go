@@ -0,0 +1,277 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/manuelfirman/go-API/internal"
+	"github.com/manuelfirman/go-API/platform/web/response"
+)
+
+// readingsDefaultRange is the time range of the readings returned when the request doesn't set it
+const readingsDefaultRange = 24 * time.Hour
+
+// SectionReadingJSON is the JSON representation of a temperature reading of a section
+type SectionReadingJSON struct {
+	// ID is the unique identifier of the reading
+	ID int `json:"id"`
+	// SectionID is the unique identifier of the section where the temperature was read
+	SectionID int `json:"section_id"`
+	// Temperature is the temperature read
+	Temperature float64 `json:"temperature"`
+	// RecordedAt is the moment at which the temperature was read (RFC 3339), now if not sent
+	RecordedAt string `json:"recorded_at"`
+	// Excursion is set when the temperature is below the minimum temperature of the section (read only)
+	Excursion bool `json:"excursion"`
+}
+
+// SectionReadingBucketJSON is the JSON representation of the summary of the readings of a section in an interval
+type SectionReadingBucketJSON struct {
+	// Start is the beginning of the interval (RFC 3339, inclusive)
+	Start string `json:"start"`
+	// End is the end of the interval (RFC 3339, exclusive)
+	End string `json:"end"`
+	// Count is the number of readings in the interval
+	Count int `json:"count"`
+	// Minimum is the lowest temperature read in the interval
+	Minimum float64 `json:"min"`
+	// Maximum is the highest temperature read in the interval
+	Maximum float64 `json:"max"`
+	// Average is the average temperature read in the interval
+	Average float64 `json:"avg"`
+	// Excursions is the number of readings below the minimum temperature of the section in the interval
+	Excursions int `json:"excursions"`
+}
+
+// NewSectionReadingDefault creates a new instance of the section reading handler
+func NewSectionReadingDefault(sv internal.SectionReadingService) *SectionReadingDefault {
+	return &SectionReadingDefault{
+		sv: sv,
+	}
+}
+
+// SectionReadingDefault is the default implementation of the section reading handler
+type SectionReadingDefault struct {
+	sv internal.SectionReadingService
+}
+
+// Save saves the temperature readings sent by the sensors of a section: a single reading (json object)
+// or a batch of them (json array or ndjson). The current temperature of the section is set to the latest one read.
+func (h *SectionReadingDefault) Save() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		// - read the readings
+		items, err := readSectionReadings(r)
+		if err != nil {
+			bulkRequestError(w, err)
+			return
+		}
+
+		// - parse every reading
+		readings := make([]internal.SectionReading, len(items))
+		for i, item := range items {
+			readings[i], err = parseSectionReading(item)
+			if err != nil {
+				response.Error(w, http.StatusBadRequest, fmt.Sprintf("reading %d: %s", i, err.Error()))
+				return
+			}
+		}
+
+		// process
+		if err = h.sv.Save(r.Context(), id, readings); err != nil {
+			writeSectionReadingError(w, err)
+			return
+		}
+
+		// response
+		excursions := 0
+		for _, reading := range readings {
+			if reading.Excursion {
+				excursions++
+			}
+		}
+		response.JSON(w, http.StatusCreated, Response{
+			Message: "success",
+			Data: map[string]any{
+				"saved":      len(readings),
+				"excursions": excursions,
+			},
+		})
+	}
+}
+
+// GetAll returns the temperature readings of a section recorded in [from, to) (RFC 3339, the last 24 hours by default).
+// With ?interval= (e.g. 15m, 1h) the readings are downsampled to their min/max/avg per interval,
+// otherwise they are streamed one by one (json by default, ndjson or csv if requested).
+func (h *SectionReadingDefault) GetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		// - time range
+		query := r.URL.Query()
+		to := time.Now().UTC()
+		if v := query.Get("to"); v != "" {
+			if to, err = time.Parse(time.RFC3339, v); err != nil {
+				response.Error(w, http.StatusBadRequest, "invalid to, expected RFC 3339")
+				return
+			}
+		}
+		from := to.Add(-readingsDefaultRange)
+		if v := query.Get("from"); v != "" {
+			if from, err = time.Parse(time.RFC3339, v); err != nil {
+				response.Error(w, http.StatusBadRequest, "invalid from, expected RFC 3339")
+				return
+			}
+		}
+
+		// process
+		// - downsampled
+		if v := query.Get("interval"); v != "" {
+			interval, err := time.ParseDuration(v)
+			if err != nil {
+				response.Error(w, http.StatusBadRequest, "invalid interval")
+				return
+			}
+
+			buckets, err := h.sv.Downsample(id, from, to, interval)
+			if err != nil {
+				writeSectionReadingError(w, err)
+				return
+			}
+
+			data := make([]SectionReadingBucketJSON, 0, len(buckets))
+			for _, b := range buckets {
+				data = append(data, SectionReadingBucketJSON{
+					Start:      b.Start.Format(time.RFC3339),
+					End:        b.End.Format(time.RFC3339),
+					Count:      b.Count,
+					Minimum:    b.Minimum,
+					Maximum:    b.Maximum,
+					Average:    b.Average,
+					Excursions: b.Excursions,
+				})
+			}
+			response.JSON(w, http.StatusOK, Response{
+				Message: "success",
+				Data:    data,
+			})
+			return
+		}
+
+		// - one by one, as they are read
+		sw := newStreamWriter(w, r, http.StatusOK, SectionReadingJSON{}, "success")
+		err = h.sv.ForEach(id, from, to, func(reading internal.SectionReading) error {
+			return sw.Write(serializeSectionReading(reading))
+		})
+		if err != nil {
+			// - nothing sent yet: regular error response, otherwise the response is left truncated
+			if !sw.Started() {
+				writeSectionReadingError(w, err)
+			}
+			return
+		}
+
+		// - end of the list
+		sw.Close()
+	}
+}
+
+// readSectionReadings reads the readings of a request: a single json object, or a batch as a bulk request
+func readSectionReadings(r *http.Request) (items []json.RawMessage, err error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return
+	}
+
+	if trimmed := bytes.TrimSpace(body); bytes.HasPrefix(trimmed, []byte("{")) && json.Valid(trimmed) {
+		items = []json.RawMessage{trimmed}
+		return
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return readBulkItems(r)
+}
+
+// parseSectionReading validates and deserializes a reading of a request
+func parseSectionReading(body []byte) (reading internal.SectionReading, err error) {
+	// - unmarshal body to map for validations
+	var bodyMap map[string]any
+	if err = json.Unmarshal(body, &bodyMap); err != nil {
+		err = errors.New("invalid body: cannot unmarshal to map")
+		return
+	}
+	// - validate
+	if err = validateKeyExistance(bodyMap, "temperature"); err != nil {
+		return
+	}
+
+	// - unmarshal to struct
+	var readingJSON SectionReadingJSON
+	if err = json.Unmarshal(body, &readingJSON); err != nil {
+		err = errors.New("invalid body: cannot unmarshal to struct")
+		return
+	}
+	if readingJSON.ID != 0 {
+		err = ErrHandlerIdInRequest
+		return
+	}
+
+	// - deserialize
+	reading.Temperature = readingJSON.Temperature
+	if readingJSON.RecordedAt != "" {
+		reading.RecordedAt, err = time.Parse(time.RFC3339, readingJSON.RecordedAt)
+		if err != nil {
+			err = errors.New("invalid recorded_at, expected RFC 3339")
+			return
+		}
+	}
+
+	return
+}
+
+// writeSectionReadingError writes the error response for an error returned by the section reading service
+func writeSectionReadingError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, internal.ErrSectionReadingServiceSectionNotFound):
+		response.Error(w, http.StatusNotFound, "section not found")
+	case errors.Is(err, internal.ErrSectionReadingServiceInvalidField):
+		response.Error(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, internal.ErrSectionReadingService):
+		response.Error(w, http.StatusInternalServerError, "internal server error")
+	case errors.Is(err, internal.ErrSectionReadingServiceUnknown):
+		response.Error(w, http.StatusInternalServerError, "unknown service error")
+	default:
+		response.Error(w, http.StatusInternalServerError, "unknown server error")
+	}
+}
+
+// serializeSectionReading serializes a reading into a SectionReadingJSON
+func serializeSectionReading(reading internal.SectionReading) SectionReadingJSON {
+	return SectionReadingJSON{
+		ID:          reading.ID,
+		SectionID:   reading.SectionID,
+		Temperature: reading.Temperature,
+		RecordedAt:  reading.RecordedAt.Format(time.RFC3339Nano),
+		Excursion:   reading.Excursion,
+	}
+}
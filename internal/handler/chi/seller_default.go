@@ -7,10 +7,12 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/manuelfirman/go-API/internal"
 	"github.com/manuelfirman/go-API/platform/validate"
+	"github.com/manuelfirman/go-API/platform/web/etag"
 	"github.com/manuelfirman/go-API/platform/web/request"
 	"github.com/manuelfirman/go-API/platform/web/response"
 )
@@ -29,6 +31,8 @@ type SellerJSON struct {
 	Telephone string `json:"telephone"`
 	// LocalityID is the seller's locality id
 	LocalityID string `json:"locality_id"`
+	// DeletedAt is the moment the seller was deleted, only listed with ?include_deleted=true (read only)
+	DeletedAt *string `json:"deleted_at,omitempty"`
 }
 
 // SellerJSON is a struct that contains the seller's information as JSON
@@ -45,6 +49,27 @@ type SellerRequestJSON struct {
 	LocalityID string `json:"locality_id"`
 }
 
+// SellerPerformanceJSON is a struct that contains the activity of the products of a seller as JSON
+type SellerPerformanceJSON struct {
+	SellerID     int               `json:"seller_id"`
+	From         *string           `json:"from"`
+	To           *string           `json:"to"`
+	Products     int               `json:"products"`
+	UnitsInStock int               `json:"units_in_stock"`
+	UnitsSold    int               `json:"units_sold"`
+	Revenue      float64           `json:"revenue"`
+	IdleSince    string            `json:"idle_since"`
+	IdleProducts []IdleProductJSON `json:"idle_products"`
+}
+
+// IdleProductJSON is a struct that contains a product without activity as JSON
+type IdleProductJSON struct {
+	ProductID    int     `json:"product_id"`
+	ProductCode  string  `json:"product_code"`
+	Description  string  `json:"description"`
+	LastActivity *string `json:"last_activity"`
+}
+
 // NewProductDefault creates a new instance of the product handler
 func NewSellerDefault(sv internal.SellerService) *SellerDefault {
 	return &SellerDefault{
@@ -61,30 +86,38 @@ type SellerDefault struct {
 // GetAll returns all products
 func (h *SellerDefault) GetAll() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Get all the sellers
-		sellers, err := h.sv.GetAll()
+		// request
+		// - include the deleted sellers with ?include_deleted=true
+		includeDeleted, err := queryBool(r, "include_deleted")
 		if err != nil {
-			switch {
-			case errors.Is(err, internal.ErrSellerServiceNotFound):
-				response.Error(w, http.StatusNotFound, "sellers not found")
-			default:
-				response.Error(w, http.StatusInternalServerError, "unknown error")
-			}
+			response.Error(w, http.StatusBadRequest, "invalid include_deleted")
 			return
 		}
 
-		// Serialize the sellers to JSON
-		var sellersJSON []SellerJSON
-		for _, seller := range sellers {
-			sellersJSON = append(sellersJSON, deserializeSellerToJSON(seller))
-		}
+		// response
+		// - writer of the format requested by the client
+		sw := newStreamWriter(w, r, http.StatusOK, SellerJSON{}, "success")
 
-		// Return the sellers as JSON
-		response.JSON(w, http.StatusOK, Response{
-			Message: "success",
-			Data:    sellersJSON,
+		// process
+		// - write every seller as it is read
+		err = h.sv.ForEach(includeDeleted, func(seller internal.Seller) error {
+			return sw.Write(deserializeSellerToJSON(seller))
 		})
+		if err != nil {
+			// - nothing sent yet: regular error response, otherwise the response is left truncated
+			if !sw.Started() {
+				switch {
+				case errors.Is(err, internal.ErrSellerServiceNotFound):
+					response.Error(w, http.StatusNotFound, "sellers not found")
+				default:
+					response.Error(w, http.StatusInternalServerError, "unknown error")
+				}
+			}
+			return
+		}
 
+		// - end of the list
+		sw.Close()
 	}
 }
 
@@ -111,6 +144,13 @@ func (h *SellerDefault) GetByID() http.HandlerFunc {
 			return
 		}
 
+		// The client already has the current version
+		etag.Set(w, seller.Version)
+		if etag.IfNoneMatch(r, seller.Version) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
 		// Serialize the seller to JSON
 		sellerJSON := deserializeSellerToJSON(seller)
 
@@ -175,7 +215,7 @@ func (h *SellerDefault) Create() http.HandlerFunc {
 		}
 
 		// - save the seller
-		s, err := h.sv.Save(&seller)
+		s, err := h.sv.Save(r.Context(), &seller)
 		if err != nil {
 			switch {
 			case errors.Is(err, internal.ErrSellerServiceDuplicated):
@@ -200,6 +240,105 @@ func (h *SellerDefault) Create() http.HandlerFunc {
 	}
 }
 
+// BulkCreate creates several sellers at once from a json array or ndjson body
+func (h *SellerDefault) BulkCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - mode (atomic by default, or per_item)
+		atomic, err := bulkAtomic(r)
+		if err != nil {
+			bulkRequestError(w, err)
+			return
+		}
+
+		// - read the items
+		items, err := readBulkItems(r)
+		if err != nil {
+			bulkRequestError(w, err)
+			return
+		}
+
+		// - validate every item as in Create
+		invalid := make(map[int]error)
+		indices := make([]int, 0, len(items))
+		sellers := make([]internal.Seller, 0, len(items))
+		for i, item := range items {
+			seller, err := parseSellerItem(item)
+			if err != nil {
+				invalid[i] = err
+				continue
+			}
+			indices = append(indices, i)
+			sellers = append(sellers, seller)
+		}
+
+		// process
+		// - save the valid sellers (none if the request is atomic and some item is invalid)
+		var saved []internal.BulkResult
+		if len(sellers) > 0 && (!atomic || len(invalid) == 0) {
+			saved, err = h.sv.SaveBulk(r.Context(), sellers, atomic)
+			if err != nil {
+				response.Error(w, http.StatusInternalServerError, "unknown error")
+				return
+			}
+		}
+
+		// response
+		writeBulkResults(w, bulkResults(len(items), invalid, indices, saved, sellerItemError))
+	}
+}
+
+// Import creates the sellers of a csv document, one per row (with ?dry_run=true the rows are only validated)
+func (h *SellerDefault) Import() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - options
+		dryRun, mapping, err := importOptions(r)
+		if err != nil {
+			importRequestError(w, err)
+			return
+		}
+
+		// - read the rows
+		records, err := request.CSV(r, SellerRequestJSON{}, mapping)
+		if err != nil {
+			importRequestError(w, err)
+			return
+		}
+
+		// process
+		// - validate and save every row on its own
+		results := make([]ImportResultJSON, len(records))
+		for i, record := range records {
+			results[i].Line = record.Line
+
+			// - parse the row
+			err := record.Err
+			var seller internal.Seller
+			if err == nil {
+				seller, err = parseSellerItem(record.Body)
+			}
+
+			// - validate or save the seller
+			if err == nil {
+				if dryRun {
+					err = h.sv.Validate(&seller)
+				} else {
+					seller, err = h.sv.Save(r.Context(), &seller)
+				}
+			}
+			if err != nil {
+				results[i].Error = sellerItemError(err)
+				continue
+			}
+			results[i].ID = seller.ID
+		}
+
+		// response
+		writeImportResults(w, dryRun, results)
+	}
+}
+
 // Update updates a product
 func (h *SellerDefault) Update() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -224,17 +363,24 @@ func (h *SellerDefault) Update() http.HandlerFunc {
 			return
 		}
 
+		// - check the seller was not modified since the client read it
+		if !etag.IfMatch(r, s.Version) {
+			response.Error(w, http.StatusPreconditionFailed, "seller has been modified")
+			return
+		}
+		version := s.Version
+
 		// - deserialize the seller to JSON
 		sellerJSONData := deserializeSellerToJSON(s)
-		sellerJSONData.ID = id
 
-		// - map JSON to the seller
-		if err := request.JSON(r, &sellerJSONData); err != nil {
-			response.Error(w, http.StatusBadRequest, "invalid body")
+		// - apply the body (merge patch or json patch) to the seller
+		if err := request.Patch(r, &sellerJSONData); err != nil {
+			patchError(w, err)
 			return
 		}
 
-		// - serialize to internal seller
+		// - serialize to internal seller (the id comes from the URL)
+		sellerJSONData.ID = 0
 		s = serializeSellerFromJSON(sellerJSONData)
 
 		err = validateSellerFields(&s)
@@ -242,15 +388,20 @@ func (h *SellerDefault) Update() http.HandlerFunc {
 			response.Error(w, http.StatusUnprocessableEntity, err.Error())
 			return
 		}
+		s.ID = id
+		s.Version = version
+		sellerJSONData.ID = id
 
 		// - update the seller
-		err = h.sv.Update(&s)
+		err = h.sv.Update(r.Context(), &s)
 		if err != nil {
 			switch {
 			case errors.Is(err, internal.ErrSellerServiceDuplicated):
 				response.Error(w, http.StatusConflict, "seller already exists")
 			case errors.Is(err, internal.ErrSellerServiceNotFound):
 				response.Error(w, http.StatusNotFound, "seller not found")
+			case errors.Is(err, internal.ErrSellerServiceVersionConflict):
+				response.Error(w, http.StatusPreconditionFailed, "seller has been modified")
 			default:
 				response.Error(w, http.StatusInternalServerError, "unknown error")
 			}
@@ -259,6 +410,7 @@ func (h *SellerDefault) Update() http.HandlerFunc {
 
 		// response
 		// - return the updated seller
+		etag.Set(w, s.Version)
 		response.JSON(w, http.StatusOK, Response{
 			Message: "success",
 			Data:    sellerJSONData,
@@ -267,7 +419,7 @@ func (h *SellerDefault) Update() http.HandlerFunc {
 	}
 }
 
-// Delete deletes a product
+// Delete marks a seller as deleted, or deletes it permanently with ?hard=true
 func (h *SellerDefault) Delete() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// request
@@ -277,14 +429,48 @@ func (h *SellerDefault) Delete() http.HandlerFunc {
 			response.Error(w, http.StatusBadRequest, "invalid id")
 			return
 		}
+		// - delete it permanently with ?hard=true, otherwise it is only marked as deleted
+		hard, err := queryBool(r, "hard")
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid hard")
+			return
+		}
+
+		// - check the seller was not modified since the client read it (the delete only applies to that version)
+		version := 0
+		if r.Header.Get("If-Match") != "" {
+			s, err := h.sv.Get(id)
+			if err != nil {
+				switch {
+				case errors.Is(err, internal.ErrSellerServiceNotFound):
+					response.Error(w, http.StatusNotFound, "seller not found")
+				default:
+					response.Error(w, http.StatusInternalServerError, "unknown error")
+				}
+				return
+			}
+			if !etag.IfMatch(r, s.Version) {
+				response.Error(w, http.StatusPreconditionFailed, "seller has been modified")
+				return
+			}
+			version = s.Version
+		}
 
 		// process
 		// - delete the seller
-		err = h.sv.Delete(id)
+		if hard {
+			err = h.sv.Purge(r.Context(), id, version)
+		} else {
+			err = h.sv.Delete(r.Context(), id, version)
+		}
 		if err != nil {
 			switch {
 			case errors.Is(err, internal.ErrSellerServiceNotFound):
 				response.Error(w, http.StatusNotFound, "seller not found")
+			case errors.Is(err, internal.ErrSellerServiceVersionConflict):
+				response.Error(w, http.StatusPreconditionFailed, "seller has been modified")
+			case errors.Is(err, internal.ErrDependentsServiceUnconfirmed):
+				response.Error(w, http.StatusConflict, "resource has dependents, confirm the delete with ?confirm=cascade")
 			default:
 				response.Error(w, http.StatusInternalServerError, "unknown error")
 			}
@@ -299,6 +485,117 @@ func (h *SellerDefault) Delete() http.HandlerFunc {
 	}
 }
 
+// Restore restores the deleted seller with the given ID and returns it
+func (h *SellerDefault) Restore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		// process
+		// - unmark the seller
+		err = h.sv.Restore(r.Context(), id)
+		if err != nil {
+			switch {
+			case errors.Is(err, internal.ErrSellerServiceNotFound):
+				response.Error(w, http.StatusNotFound, "deleted seller not found")
+			default:
+				response.Error(w, http.StatusInternalServerError, "unknown error")
+			}
+			return
+		}
+		// - get it back
+		s, err := h.sv.Get(id)
+		if err != nil {
+			response.Error(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		// response
+		etag.Set(w, s.Version)
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    deserializeSellerToJSON(s),
+		})
+	}
+}
+
+// Performance returns the activity of the products of a seller: the sales between the days of ?from= and ?to=
+// (unbounded when missing) and the products without activity in the last ?days= (90 by default)
+func (h *SellerDefault) Performance() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get the id from the request
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+		// - get the period of the sales from the query
+		from, err := queryDate(r, "from")
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		to, err := queryDate(r, "to")
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		// - get the idle days from the query
+		days := 90
+		if v := r.URL.Query().Get("days"); v != "" {
+			if days, err = strconv.Atoi(v); err != nil {
+				response.Error(w, http.StatusBadRequest, "invalid days")
+				return
+			}
+		}
+
+		// process
+		p, err := h.sv.GetPerformance(id, from, to, days)
+		if err != nil {
+			switch {
+			case errors.Is(err, internal.ErrSellerServiceInvalidField):
+				response.Error(w, http.StatusBadRequest, err.Error())
+			case errors.Is(err, internal.ErrSellerServiceNotFound):
+				response.Error(w, http.StatusNotFound, "seller not found")
+			default:
+				response.Error(w, http.StatusInternalServerError, "unknown error")
+			}
+			return
+		}
+
+		// response
+		data := SellerPerformanceJSON{
+			SellerID:     p.SellerID,
+			From:         optionalDate(p.From),
+			To:           optionalDate(p.To),
+			Products:     p.Products,
+			UnitsInStock: p.UnitsInStock,
+			UnitsSold:    p.UnitsSold,
+			Revenue:      p.Revenue,
+			IdleSince:    p.IdleSince.Format(time.RFC3339),
+			IdleProducts: make([]IdleProductJSON, len(p.IdleProducts)),
+		}
+		for i, ip := range p.IdleProducts {
+			data.IdleProducts[i] = IdleProductJSON{
+				ProductID:    ip.ProductID,
+				ProductCode:  ip.ProductCode,
+				Description:  ip.Description,
+				LastActivity: optionalTime(ip.LastActivity),
+			}
+		}
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    data,
+		})
+	}
+}
+
 // GetReport returns the information of the product record report
 func (h *SellerDefault) GetRecordsByProductReport() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -315,6 +612,7 @@ func deserializeSellerToJSON(seller internal.Seller) (sellerJSON SellerJSON) {
 		Address:     seller.Address,
 		Telephone:   seller.Telephone,
 		LocalityID:  seller.LocalityID,
+		DeletedAt:   optionalTime(seller.DeletedAt),
 	}
 	return
 }
@@ -332,6 +630,50 @@ func serializeSellerFromJSON(sellerJSON SellerJSON) (seller internal.Seller) {
 	return
 }
 
+// parseSellerItem validates a seller item of a bulk or import request as Create does
+func parseSellerItem(body []byte) (seller internal.Seller, err error) {
+	// - validate the body
+	bodyMap := map[string]any{}
+	if err = json.Unmarshal(body, &bodyMap); err != nil {
+		err = errors.New("invalid body")
+		return
+	}
+	sellerRequest := SellerRequestJSON{}
+	if err = validate.CheckFieldExistance(sellerRequest, bodyMap); err != nil {
+		return
+	}
+	if err = json.Unmarshal(body, &sellerRequest); err != nil {
+		err = errors.New("invalid body")
+		return
+	}
+
+	// - map the body to a seller and validate required fields
+	seller = serializeSellerFromJSON(SellerJSON{
+		CID:         sellerRequest.CID,
+		CompanyName: sellerRequest.CompanyName,
+		Address:     sellerRequest.Address,
+		Telephone:   sellerRequest.Telephone,
+		LocalityID:  sellerRequest.LocalityID,
+	})
+	err = validateSellerFields(&seller)
+	return
+}
+
+// sellerItemError returns the message of an error of a seller item of a bulk or import request
+func sellerItemError(err error) string {
+	switch {
+	case errors.Is(err, internal.ErrSellerServiceDuplicated):
+		return "seller already exists"
+	case errors.Is(err, internal.ErrSellerServiceForeignKey):
+		return "foreign key error"
+	case errors.Is(err, internal.ErrSellerServiceUnknown), errors.Is(err, internal.ErrSellerServiceDB):
+		return "unknown error"
+	default:
+		// validation error
+		return err.Error()
+	}
+}
+
 func validateSellerFields(seller *internal.Seller) error {
 	if seller.ID != 0 {
 		return ErrHandlerIdInRequest
@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/manuelfirman/go-API/internal"
+	"github.com/manuelfirman/go-API/platform/web/response"
+)
+
+// SectionStockJSON is the JSON representation of the units of a product held in a section
+type SectionStockJSON struct {
+	// SectionID is the unique identifier of the section
+	SectionID int `json:"section_id"`
+	// Quantity is the number of units held in the section
+	Quantity int `json:"quantity"`
+	// Batches is the number of batches with units left in the section
+	Batches int `json:"batches"`
+}
+
+// WarehouseProductStockJSON is the JSON representation of the units of a product held in a warehouse
+type WarehouseProductStockJSON struct {
+	// WarehouseID is the unique identifier of the warehouse (when listing the warehouses of a product)
+	WarehouseID int `json:"warehouse_id,omitempty"`
+	// ProductID is the unique identifier of the product (when listing the products of a warehouse)
+	ProductID int `json:"product_id,omitempty"`
+	// Quantity is the number of units held in the warehouse
+	Quantity int `json:"quantity"`
+	// Sections are the units held in each section of the warehouse
+	Sections []SectionStockJSON `json:"sections"`
+}
+
+// ProductStockJSON is the JSON representation of the units of a product held across the warehouses
+type ProductStockJSON struct {
+	// ProductID is the unique identifier of the product
+	ProductID int `json:"product_id"`
+	// Quantity is the total number of units of the product
+	Quantity int `json:"quantity"`
+	// LowStockThreshold is the quantity below which the product has to be reordered (null if it has no threshold)
+	LowStockThreshold *int `json:"low_stock_threshold"`
+	// LowStock is set when the quantity is below the low-stock threshold
+	LowStock bool `json:"low_stock"`
+	// Warehouses are the units held in each warehouse (not listed in the reorder list)
+	Warehouses []WarehouseProductStockJSON `json:"warehouses,omitempty"`
+}
+
+// WarehouseStockJSON is the JSON representation of the units of every product held in a warehouse
+type WarehouseStockJSON struct {
+	// WarehouseID is the unique identifier of the warehouse
+	WarehouseID int `json:"warehouse_id"`
+	// Quantity is the total number of units held in the warehouse
+	Quantity int `json:"quantity"`
+	// Products are the units of each product held in the warehouse
+	Products []WarehouseProductStockJSON `json:"products"`
+}
+
+// StockThresholdJSON is the JSON representation of the low-stock threshold of a product
+type StockThresholdJSON struct {
+	// LowStockThreshold is the quantity below which the product has to be reordered
+	LowStockThreshold int `json:"low_stock_threshold"`
+}
+
+// NewStockDefault creates a new instance of the stock handler
+func NewStockDefault(sv internal.StockService) *StockDefault {
+	return &StockDefault{
+		sv: sv,
+	}
+}
+
+// StockDefault is the default implementation of the stock handler
+type StockDefault struct {
+	sv internal.StockService
+}
+
+// ProductStock returns the units of a product held in each warehouse and section
+func (h *StockDefault) ProductStock() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		// process
+		stock, err := h.sv.GetProductStock(id)
+		if err != nil {
+			writeStockError(w, err)
+			return
+		}
+
+		// response
+		// - group the sections by warehouse (the levels come ordered by warehouse)
+		data := serializeProductStock(stock)
+		data.Warehouses = groupStockLevels(stock.Levels, func(l internal.StockLevel) WarehouseProductStockJSON {
+			return WarehouseProductStockJSON{WarehouseID: l.WarehouseID}
+		})
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    data,
+		})
+	}
+}
+
+// WarehouseStock returns the units of every product held in each section of a warehouse
+func (h *StockDefault) WarehouseStock() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		// process
+		stock, err := h.sv.GetWarehouseStock(id)
+		if err != nil {
+			writeStockError(w, err)
+			return
+		}
+
+		// response
+		// - group the sections by product (the levels come ordered by product)
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data: WarehouseStockJSON{
+				WarehouseID: stock.WarehouseID,
+				Quantity:    stock.Quantity,
+				Products: groupStockLevels(stock.Levels, func(l internal.StockLevel) WarehouseProductStockJSON {
+					return WarehouseProductStockJSON{ProductID: l.ProductID}
+				}),
+			},
+		})
+	}
+}
+
+// SetThreshold sets the low-stock threshold of a product
+func (h *StockDefault) SetThreshold() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+		// - read the body in []byte
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot read")
+			return
+		}
+		// - unmarshal body to map for validations
+		var bodyMap map[string]any
+		if err = json.Unmarshal(body, &bodyMap); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot unmarshal to map")
+			return
+		}
+		// - validate
+		if err = validateKeyExistance(bodyMap, "low_stock_threshold"); err != nil {
+			response.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		// - unmarshal to struct
+		var thresholdJSON StockThresholdJSON
+		if err = json.Unmarshal(body, &thresholdJSON); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid body: cannot unmarshal to struct")
+			return
+		}
+
+		// process
+		if err = h.sv.SetThreshold(r.Context(), id, thresholdJSON.LowStockThreshold); err != nil {
+			writeStockError(w, err)
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    thresholdJSON,
+		})
+	}
+}
+
+// DeleteThreshold removes the low-stock threshold of a product
+func (h *StockDefault) DeleteThreshold() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		// process
+		if err = h.sv.DeleteThreshold(r.Context(), id); err != nil {
+			writeStockError(w, err)
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusNoContent, nil)
+	}
+}
+
+// Reorder returns the products whose stock is below their low-stock threshold
+func (h *StockDefault) Reorder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// process
+		stocks, err := h.sv.GetReorder()
+		if err != nil {
+			writeStockError(w, err)
+			return
+		}
+
+		// response
+		data := make([]ProductStockJSON, 0, len(stocks))
+		for _, stock := range stocks {
+			data = append(data, serializeProductStock(stock))
+		}
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    data,
+		})
+	}
+}
+
+// writeStockError writes the error response for an error returned by the stock service
+func writeStockError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, internal.ErrStockServiceProductNotFound):
+		response.Error(w, http.StatusNotFound, "product not found")
+	case errors.Is(err, internal.ErrStockServiceWarehouseNotFound):
+		response.Error(w, http.StatusNotFound, "warehouse not found")
+	case errors.Is(err, internal.ErrStockServiceThresholdNotFound):
+		response.Error(w, http.StatusNotFound, "low-stock threshold not found")
+	case errors.Is(err, internal.ErrStockServiceInvalidField):
+		response.Error(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, internal.ErrStockService):
+		response.Error(w, http.StatusInternalServerError, "internal server error")
+	case errors.Is(err, internal.ErrStockServiceUnknown):
+		response.Error(w, http.StatusInternalServerError, "unknown service error")
+	default:
+		response.Error(w, http.StatusInternalServerError, "unknown server error")
+	}
+}
+
+// serializeProductStock serializes the stock of a product into a ProductStockJSON (without the warehouses)
+func serializeProductStock(stock internal.ProductStock) ProductStockJSON {
+	data := ProductStockJSON{
+		ProductID: stock.ProductID,
+		Quantity:  stock.Quantity,
+		LowStock:  stock.LowStock(),
+	}
+	if stock.LowStockThreshold > 0 {
+		threshold := stock.LowStockThreshold
+		data.LowStockThreshold = &threshold
+	}
+
+	return data
+}
+
+// groupStockLevels groups consecutive stock levels that share the group returned by newGroup, adding up their quantity
+func groupStockLevels(levels []internal.StockLevel, newGroup func(l internal.StockLevel) WarehouseProductStockJSON) (groups []WarehouseProductStockJSON) {
+	groups = []WarehouseProductStockJSON{}
+	for _, l := range levels {
+		group := newGroup(l)
+		last := len(groups) - 1
+		if last < 0 || groups[last].WarehouseID != group.WarehouseID || groups[last].ProductID != group.ProductID {
+			group.Sections = []SectionStockJSON{}
+			groups = append(groups, group)
+			last++
+		}
+
+		groups[last].Quantity += l.Quantity
+		groups[last].Sections = append(groups[last].Sections, SectionStockJSON{
+			SectionID: l.SectionID,
+			Quantity:  l.Quantity,
+			Batches:   l.Batches,
+		})
+	}
+
+	return
+}
@@ -7,10 +7,12 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/manuelfirman/go-API/internal"
 	"github.com/manuelfirman/go-API/platform/validate"
+	"github.com/manuelfirman/go-API/platform/web/etag"
 	"github.com/manuelfirman/go-API/platform/web/request"
 	"github.com/manuelfirman/go-API/platform/web/response"
 )
@@ -31,6 +33,8 @@ type WarehouseJSON struct {
 	MinimumTemperature float64 `json:"minimum_temperature"`
 	// LocalityID is the id of the locality where the warehouse is located
 	LocalityId string `json:"locality_id"`
+	// DeletedAt is the moment the warehouse was deleted, only listed with ?include_deleted=true (read only)
+	DeletedAt *string `json:"deleted_at,omitempty"`
 }
 
 // WarehouseRequestJSON is the JSON representation of a warehouse request
@@ -49,6 +53,34 @@ type WarehouseRequestJSON struct {
 	LocalityId string `json:"locality_id"`
 }
 
+// WarehouseSummaryJSON is the JSON representation of the utilisation of a warehouse
+type WarehouseSummaryJSON struct {
+	// WarehouseID is the identifier of the warehouse
+	WarehouseID int `json:"warehouse_id"`
+	// Sections is the number of sections of the warehouse
+	Sections int `json:"sections"`
+	// TotalCapacity is the sum of the maximum capacity of the sections
+	TotalCapacity int `json:"total_capacity"`
+	// UsedCapacity is the sum of the current capacity of the sections
+	UsedCapacity int `json:"used_capacity"`
+	// FreeCapacity is the capacity left in the sections
+	FreeCapacity int `json:"free_capacity"`
+	// Employees is the number of employees working at the warehouse
+	Employees int `json:"employees"`
+	// Batches is the number of product batches with units left stored in the warehouse
+	Batches int `json:"batches"`
+	// ExpiringBatches is the number of those batches that expire on or before expiring_until
+	ExpiringBatches int `json:"expiring_batches"`
+	// ExpiringProducts is the number of different products of the expiring batches
+	ExpiringProducts int `json:"expiring_products"`
+	// ExpiringUntil is the date up to which the batches are counted as expiring (YYYY-MM-DD)
+	ExpiringUntil string `json:"expiring_until"`
+	// TemperatureExcursions is the number of temperature readings below the minimum of their section since excursions_since
+	TemperatureExcursions int `json:"temperature_excursions"`
+	// ExcursionsSince is the moment from which the temperature excursions are counted (RFC 3339)
+	ExcursionsSince string `json:"excursions_since"`
+}
+
 type WarehouseDefault struct {
 	// rp is the repository used by the service
 	sv internal.WarehouseService
@@ -64,30 +96,38 @@ func NewWarehouseDefault(sv internal.WarehouseService) *WarehouseDefault {
 // GetAll returns all products. Returns an error if the operation fails.
 func (wd *WarehouseDefault) GetAll() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// process
-		wh, err := wd.sv.GetAll()
+		// request
+		// - include the deleted warehouses with ?include_deleted=true
+		includeDeleted, err := queryBool(r, "include_deleted")
 		if err != nil {
-			switch err {
-			case internal.ErrWarehouseServiceNotFound:
-				response.Error(w, http.StatusNotFound, "warehouse not found")
-			default:
-				response.Error(w, http.StatusInternalServerError, "unknown error")
-			}
+			response.Error(w, http.StatusBadRequest, "invalid include_deleted")
 			return
 		}
 
 		// response
-		// - serialize the response
-		var data []WarehouseJSON
-		for _, w := range wh {
-			data = append(data, deserializeWarehouse(w))
-		}
+		// - writer of the format requested by the client
+		sw := newStreamWriter(w, r, http.StatusOK, WarehouseJSON{}, "success")
 
-		// - write the response
-		response.JSON(w, http.StatusOK, Response{
-			Message: "success",
-			Data:    data,
+		// process
+		// - write every warehouse as it is read
+		err = wd.sv.ForEach(includeDeleted, func(wh internal.Warehouse) error {
+			return sw.Write(deserializeWarehouse(wh))
 		})
+		if err != nil {
+			// - nothing sent yet: regular error response, otherwise the response is left truncated
+			if !sw.Started() {
+				switch err {
+				case internal.ErrWarehouseServiceNotFound:
+					response.Error(w, http.StatusNotFound, "warehouse not found")
+				default:
+					response.Error(w, http.StatusInternalServerError, "unknown error")
+				}
+			}
+			return
+		}
+
+		// - end of the list
+		sw.Close()
 	}
 }
 
@@ -114,6 +154,12 @@ func (wd *WarehouseDefault) Get() http.HandlerFunc {
 		}
 
 		// response
+		// - the client already has the current version
+		etag.Set(w, wh.Version)
+		if etag.IfNoneMatch(r, wh.Version) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
 		// - serialize the response
 		data := deserializeWarehouse(wh)
 		// - write the response
@@ -178,7 +224,7 @@ func (wd *WarehouseDefault) Save() http.HandlerFunc {
 		}
 
 		// - save the warehouse
-		wh, err = wd.sv.Save(&wh)
+		wh, err = wd.sv.Save(r.Context(), &wh)
 		if err != nil {
 			switch {
 			case errors.Is(err, internal.ErrWarehouseServiceDuplicated):
@@ -202,6 +248,57 @@ func (wd *WarehouseDefault) Save() http.HandlerFunc {
 	}
 }
 
+// Import creates the warehouses of a csv document, one per row (with ?dry_run=true the rows are only validated)
+func (wd *WarehouseDefault) Import() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - options
+		dryRun, mapping, err := importOptions(r)
+		if err != nil {
+			importRequestError(w, err)
+			return
+		}
+
+		// - read the rows
+		records, err := request.CSV(r, WarehouseRequestJSON{}, mapping)
+		if err != nil {
+			importRequestError(w, err)
+			return
+		}
+
+		// process
+		// - validate and save every row on its own
+		results := make([]ImportResultJSON, len(records))
+		for i, record := range records {
+			results[i].Line = record.Line
+
+			// - parse the row
+			err := record.Err
+			var wh internal.Warehouse
+			if err == nil {
+				wh, err = parseWarehouseItem(record.Body)
+			}
+
+			// - validate or save the warehouse
+			if err == nil {
+				if dryRun {
+					err = wd.sv.Validate(&wh)
+				} else {
+					wh, err = wd.sv.Save(r.Context(), &wh)
+				}
+			}
+			if err != nil {
+				results[i].Error = warehouseItemError(err)
+				continue
+			}
+			results[i].ID = wh.ID
+		}
+
+		// response
+		writeImportResults(w, dryRun, results)
+	}
+}
+
 // Update receives a product and updates it. Returns an error if the product is not found.
 func (wd *WarehouseDefault) Update() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -225,18 +322,25 @@ func (wd *WarehouseDefault) Update() http.HandlerFunc {
 			return
 		}
 
+		// - check the warehouse was not modified since the client read it
+		if !etag.IfMatch(r, wh.Version) {
+			response.Error(w, http.StatusPreconditionFailed, "warehouse has been modified")
+			return
+		}
+		version := wh.Version
+
 		// - deserialize the warehouse
 		warehouseJSON := deserializeWarehouse(wh)
 
-		// - read the body
-		if err := request.JSON(r, &warehouseJSON); err != nil {
-			response.Error(w, http.StatusBadRequest, "invalid request")
+		// - apply the body (merge patch or json patch) to the warehouse
+		if err := request.Patch(r, &warehouseJSON); err != nil {
+			patchError(w, err)
 			return
 		}
 
 		// process
-		// - set id (for cases where the id is different from the one in the URL)
-		warehouseJSON.ID = id
+		// - clear the id, it comes from the URL and is not part of the validation
+		warehouseJSON.ID = 0
 		// - serialize the warehouse
 		wh = serializeWarehouse(warehouseJSON)
 		// - validate the warehouse
@@ -245,15 +349,20 @@ func (wd *WarehouseDefault) Update() http.HandlerFunc {
 			response.Error(w, http.StatusBadRequest, err.Error())
 			return
 		}
+		// - set id (for cases where the id is different from the one in the URL) and the version read
+		wh.ID = id
+		wh.Version = version
 
 		// - update the warehouse
-		err = wd.sv.Update(&wh)
+		err = wd.sv.Update(r.Context(), &wh)
 		if err != nil {
 			switch {
 			case errors.Is(err, internal.ErrWarehouseServiceNotFound):
 				response.Error(w, http.StatusNotFound, "warehouse not found")
 			case errors.Is(err, internal.ErrWarehouseServiceNothingToUpdate):
 				response.Error(w, http.StatusConflict, "nothing to update")
+			case errors.Is(err, internal.ErrWarehouseServiceVersionConflict):
+				response.Error(w, http.StatusPreconditionFailed, "warehouse has been modified")
 			default:
 				response.Error(w, http.StatusInternalServerError, "unknown error")
 			}
@@ -262,6 +371,7 @@ func (wd *WarehouseDefault) Update() http.HandlerFunc {
 
 		// response
 		// - deserialize the warehouse to JSON
+		etag.Set(w, wh.Version)
 		data := deserializeWarehouse(wh)
 		// - return the warehouse as JSON
 		response.JSON(w, http.StatusOK, Response{
@@ -272,7 +382,7 @@ func (wd *WarehouseDefault) Update() http.HandlerFunc {
 	}
 }
 
-// Delete deletes a product by ID. Returns an error if the product is not found.
+// Delete marks a warehouse as deleted by ID, or deletes it permanently with ?hard=true. Returns an error if the warehouse is not found.
 func (wd *WarehouseDefault) Delete() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// request
@@ -282,14 +392,50 @@ func (wd *WarehouseDefault) Delete() http.HandlerFunc {
 			response.Error(w, http.StatusBadRequest, "invalid id")
 			return
 		}
+		// - delete it permanently with ?hard=true, otherwise it is only marked as deleted
+		hard, err := queryBool(r, "hard")
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid hard")
+			return
+		}
+
+		// - check the warehouse was not modified since the client read it (the delete only applies to that version)
+		version := 0
+		if r.Header.Get("If-Match") != "" {
+			wh, err := wd.sv.Get(id)
+			if err != nil {
+				switch {
+				case errors.Is(err, internal.ErrWarehouseServiceNotFound):
+					response.Error(w, http.StatusNotFound, "warehouse not found")
+				default:
+					response.Error(w, http.StatusInternalServerError, "unknown error")
+				}
+				return
+			}
+			if !etag.IfMatch(r, wh.Version) {
+				response.Error(w, http.StatusPreconditionFailed, "warehouse has been modified")
+				return
+			}
+			version = wh.Version
+		}
 
 		// process
 		// - delete the warehouse
-		err = wd.sv.Delete(id)
+		if hard {
+			err = wd.sv.Purge(r.Context(), id, version)
+		} else {
+			err = wd.sv.Delete(r.Context(), id, version)
+		}
 		if err != nil {
 			switch {
 			case errors.Is(err, internal.ErrWarehouseServiceNotFound):
 				response.Error(w, http.StatusNotFound, "warehouse not found")
+			case errors.Is(err, internal.ErrWarehouseServiceVersionConflict):
+				response.Error(w, http.StatusPreconditionFailed, "warehouse has been modified")
+			case errors.Is(err, internal.ErrDependentsServiceUnconfirmed):
+				response.Error(w, http.StatusConflict, "resource has dependents, confirm the delete with ?confirm=cascade")
+			case errors.Is(err, internal.ErrWarehouseServiceForeignKey):
+				response.Error(w, http.StatusConflict, "warehouse is in use")
 			default:
 				response.Error(w, http.StatusInternalServerError, "unknown error")
 			}
@@ -305,6 +451,100 @@ func (wd *WarehouseDefault) Delete() http.HandlerFunc {
 	}
 }
 
+// Restore restores the deleted warehouse with the given ID and returns it
+func (wd *WarehouseDefault) Restore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from url
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		// process
+		// - unmark the warehouse
+		err = wd.sv.Restore(r.Context(), id)
+		if err != nil {
+			switch {
+			case errors.Is(err, internal.ErrWarehouseServiceNotFound):
+				response.Error(w, http.StatusNotFound, "deleted warehouse not found")
+			default:
+				response.Error(w, http.StatusInternalServerError, "unknown error")
+			}
+			return
+		}
+		// - get it back
+		wh, err := wd.sv.Get(id)
+		if err != nil {
+			response.Error(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		// response
+		etag.Set(w, wh.Version)
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data:    deserializeWarehouse(wh),
+		})
+	}
+}
+
+// Summary returns the utilisation of a warehouse: its sections and their capacity, employees, batches, the batches
+// expiring within ?days= days (7 by default, expired ones included) and the temperature excursions of the last 24 hours
+func (wd *WarehouseDefault) Summary() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get the id from the request
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+		// - get the expiry horizon from the query
+		days := 7
+		if v := r.URL.Query().Get("days"); v != "" {
+			if days, err = strconv.Atoi(v); err != nil {
+				response.Error(w, http.StatusBadRequest, "invalid days")
+				return
+			}
+		}
+
+		// process
+		s, err := wd.sv.GetSummary(id, days)
+		if err != nil {
+			switch {
+			case errors.Is(err, internal.ErrWarehouseServiceNotFound):
+				response.Error(w, http.StatusNotFound, "warehouse not found")
+			case errors.Is(err, internal.ErrWarehouseServiceInvalidField):
+				response.Error(w, http.StatusBadRequest, "invalid days")
+			default:
+				response.Error(w, http.StatusInternalServerError, "unknown error")
+			}
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, Response{
+			Message: "success",
+			Data: WarehouseSummaryJSON{
+				WarehouseID:           s.WarehouseID,
+				Sections:              s.Sections,
+				TotalCapacity:         s.TotalCapacity,
+				UsedCapacity:          s.UsedCapacity,
+				FreeCapacity:          s.FreeCapacity,
+				Employees:             s.Employees,
+				Batches:               s.Batches,
+				ExpiringBatches:       s.ExpiringBatches,
+				ExpiringProducts:      s.ExpiringProducts,
+				ExpiringUntil:         s.ExpiringUntil.Format(DateLayout),
+				TemperatureExcursions: s.TemperatureExcursions,
+				ExcursionsSince:       s.ExcursionsSince.Format(time.RFC3339),
+			},
+		})
+	}
+}
+
 // deserialize warehouse
 func deserializeWarehouse(w internal.Warehouse) WarehouseJSON {
 	return WarehouseJSON{
@@ -315,6 +555,7 @@ func deserializeWarehouse(w internal.Warehouse) WarehouseJSON {
 		MinimumCapacity:    w.MinimumCapacity,
 		MinimumTemperature: w.MinimumTemperature,
 		LocalityId:         w.LocalityId,
+		DeletedAt:          optionalTime(w.DeletedAt),
 	}
 }
 
@@ -331,6 +572,51 @@ func serializeWarehouse(w WarehouseJSON) internal.Warehouse {
 	}
 }
 
+// parseWarehouseItem validates a warehouse item of an import request as Save does
+func parseWarehouseItem(body []byte) (wh internal.Warehouse, err error) {
+	// - validate the body
+	bodyMap := map[string]any{}
+	if err = json.Unmarshal(body, &bodyMap); err != nil {
+		err = errors.New("invalid body")
+		return
+	}
+	warehouseRequest := WarehouseRequestJSON{}
+	if err = validate.CheckFieldExistance(warehouseRequest, bodyMap); err != nil {
+		return
+	}
+	if err = json.Unmarshal(body, &warehouseRequest); err != nil {
+		err = errors.New("invalid body")
+		return
+	}
+
+	// - map the body to a warehouse and validate required fields
+	wh = serializeWarehouse(WarehouseJSON{
+		WarehouseCode:      warehouseRequest.WarehouseCode,
+		Address:            warehouseRequest.Address,
+		Telephone:          warehouseRequest.Telephone,
+		MinimumCapacity:    warehouseRequest.MinimumCapacity,
+		MinimumTemperature: warehouseRequest.MinimumTemperature,
+		LocalityId:         warehouseRequest.LocalityId,
+	})
+	err = validateWarehouseFields(&wh)
+	return
+}
+
+// warehouseItemError returns the message of an error of a warehouse item of an import request
+func warehouseItemError(err error) string {
+	switch {
+	case errors.Is(err, internal.ErrWarehouseServiceDuplicated):
+		return "warehouse already exists"
+	case errors.Is(err, internal.ErrWarehouseServiceForeignKey):
+		return "foreign key error"
+	case errors.Is(err, internal.ErrWarehouseServiceUnknown):
+		return "unknown error"
+	default:
+		// validation error
+		return err.Error()
+	}
+}
+
 // Validate zero values fields
 func validateWarehouseFields(wh *internal.Warehouse) error {
 	if wh.ID != 0 {
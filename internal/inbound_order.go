@@ -0,0 +1,19 @@
+package internal
+
+import "time"
+
+// InboundOrder is a struct that contains the information of an order received at a warehouse, which brings in a product batch
+type InboundOrder struct {
+	// ID is the unique identifier of the inbound order
+	ID int
+	// OrderNumber is the unique number of the inbound order
+	OrderNumber int
+	// OrderDate is the date on which the order was received
+	OrderDate time.Time
+	// WarehouseID is the unique identifier of the warehouse receiving the order
+	WarehouseID int
+	// EmployeeID is the unique identifier of the employee receiving the order
+	EmployeeID int
+	// ProductBatchID is the unique identifier of the product batch brought in by the order
+	ProductBatchID int
+}
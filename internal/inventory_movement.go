@@ -0,0 +1,38 @@
+package internal
+
+import "time"
+
+// Types of inventory movements
+const (
+	// MovementReceipt is the reception of units of a batch
+	MovementReceipt = "receipt"
+	// MovementPick is the picking of units of a batch to fulfill an order
+	MovementPick = "pick"
+	// MovementAdjustment is a correction of the units of a batch (e.g. after a stock count)
+	MovementAdjustment = "adjustment"
+	// MovementTransfer is the moving of units of a batch out of a section (negative) or into a section (positive)
+	MovementTransfer = "transfer"
+	// MovementWriteOff is the discarding of units of a batch (expired, damaged or lost)
+	MovementWriteOff = "write_off"
+)
+
+// InventoryMovement is a struct that contains a change of the quantity of a product batch.
+// The movements are an append-only ledger: the current quantity of a batch is the sum of the quantities of its movements.
+type InventoryMovement struct {
+	// ID is the unique identifier of the movement
+	ID int
+	// ProductBatchID is the unique identifier of the product batch whose quantity changed
+	ProductBatchID int
+	// SectionID is the unique identifier of the section where the units were added or removed
+	SectionID int
+	// Type is the type of the movement (Movement* constants)
+	Type string
+	// Quantity is the number of units added to the batch (negative if removed)
+	Quantity int
+	// EmployeeID is the unique identifier of the employee who made the movement (0 if unknown)
+	EmployeeID int
+	// Reason is why the movement was made
+	Reason string
+	// CreatedAt is the moment at which the movement was made
+	CreatedAt time.Time
+}
@@ -1,5 +1,7 @@
 package internal
 
+import "time"
+
 // Product is a struct that contains the product's information
 type Product struct {
 	// ID is the unique identifier of the product
@@ -26,4 +28,8 @@ type Product struct {
 	ProductTypeID int
 	// SellerID is the unique identifier of the seller
 	SellerID int
+	// Version is the version of the product, incremented on every update (optimistic concurrency)
+	Version int
+	// DeletedAt is the moment the product was deleted, zero unless it is
+	DeletedAt time.Time
 }
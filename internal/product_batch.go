@@ -0,0 +1,112 @@
+package internal
+
+import "time"
+
+// ProductBatch is a struct that contains the information of a batch of a product stored in a section
+type ProductBatch struct {
+	// ID is the unique identifier of the product batch
+	ID int
+	// BatchNumber is the number of the batch
+	BatchNumber int
+	// DueDate is the date on which the batch expires
+	DueDate time.Time
+	// MinimumTemperature is the minimum temperature at which the batch can be stored
+	MinimumTemperature float64
+	// CurrentTemperature is the current temperature of the batch
+	CurrentTemperature float64
+	// InitialQuantity is the quantity of units the batch had when it was received
+	InitialQuantity int
+	// CurrentQuantity is the quantity of units left in the batch (the capacity it takes up in its section)
+	CurrentQuantity int
+	// ManufacturingDate is the date on which the batch was manufactured
+	ManufacturingDate time.Time
+	// ManufacturingHour is the hour of the day at which the batch was manufactured
+	ManufacturingHour int
+	// SectionID is the unique identifier of the section where the batch is stored
+	SectionID int
+	// ProductID is the unique identifier of the product of the batch
+	ProductID int
+	// DeletedAt is the moment the product batch was deleted, zero unless it is
+	DeletedAt time.Time
+}
+
+// Reasons why a stored product batch is out of the temperature range of its product
+const (
+	// TemperatureBatchAboveRecommended means the batch is warmer than the recommended temperature of its product
+	TemperatureBatchAboveRecommended = "batch_above_recommended"
+	// TemperatureBatchBelowMinimum means the batch is colder than its own minimum temperature
+	TemperatureBatchBelowMinimum = "batch_below_minimum"
+	// TemperatureSectionAboveRecommended means the section is warmer than the recommended temperature of the product
+	TemperatureSectionAboveRecommended = "section_above_recommended"
+	// TemperatureSectionBelowMinimum means the section is colder than the minimum temperature of the batch
+	TemperatureSectionBelowMinimum = "section_below_minimum"
+	// TemperatureSectionUnreachable means the section can't get as cold as the recommended temperature of the product
+	TemperatureSectionUnreachable = "section_unreachable"
+	// TemperatureWarehouseUnreachable means the warehouse can't get as cold as the recommended temperature of the product
+	TemperatureWarehouseUnreachable = "warehouse_unreachable"
+)
+
+// BatchTemperature is a struct that contains the temperatures that apply to a stored product batch
+type BatchTemperature struct {
+	// BatchID is the unique identifier of the product batch
+	BatchID int
+	// BatchNumber is the number of the batch
+	BatchNumber int
+	// ProductID is the unique identifier of the product of the batch
+	ProductID int
+	// SectionID is the unique identifier of the section where the batch is stored
+	SectionID int
+	// WarehouseID is the unique identifier of the warehouse of the section
+	WarehouseID int
+	// RecommendedTemperature is the recommended freezing temperature of the product
+	RecommendedTemperature float64
+	// BatchMinimumTemperature is the minimum temperature at which the batch can be stored
+	BatchMinimumTemperature float64
+	// BatchCurrentTemperature is the current temperature of the batch
+	BatchCurrentTemperature float64
+	// SectionCurrentTemperature is the current temperature of the section
+	SectionCurrentTemperature float64
+	// SectionMinimumTemperature is the minimum temperature that can be maintained in the section
+	SectionMinimumTemperature float64
+	// WarehouseMinimumTemperature is the minimum temperature that can be maintained in the warehouse
+	WarehouseMinimumTemperature float64
+	// Reasons are the reasons why the batch is out of range (Temperature* constants)
+	Reasons []string
+}
+
+// BatchPick is a struct that contains the units to pick from a product batch to fulfill a quantity of its product
+type BatchPick struct {
+	// BatchID is the unique identifier of the product batch
+	BatchID int
+	// BatchNumber is the number of the batch
+	BatchNumber int
+	// SectionID is the unique identifier of the section where the batch is stored
+	SectionID int
+	// DueDate is the date on which the batch expires
+	DueDate time.Time
+	// Quantity is the number of units to pick from the batch
+	Quantity int
+}
+
+// BatchTransfer is a struct that contains the moving of units of a product batch to another section (of any warehouse).
+// Moving part of the units splits the batch: the units moved make up a new batch in the target section.
+type BatchTransfer struct {
+	// ProductBatchID is the unique identifier of the product batch the units are moved from
+	ProductBatchID int
+	// TargetBatchID is the unique identifier of the product batch the units are moved to (the same one if not split)
+	TargetBatchID int
+	// FromSectionID is the unique identifier of the section the units are moved from
+	FromSectionID int
+	// ToSectionID is the unique identifier of the section the units are moved to
+	ToSectionID int
+	// Quantity is the number of units moved
+	Quantity int
+	// SourceEmployeeID is the unique identifier of the employee who sends the units
+	SourceEmployeeID int
+	// TargetEmployeeID is the unique identifier of the employee who receives the units
+	TargetEmployeeID int
+	// Reason is why the units are moved
+	Reason string
+	// Split is set when only part of the units were moved and a new batch was created
+	Split bool
+}
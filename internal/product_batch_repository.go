@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrProductBatchRepositoryNotFound is returned when the product batch is not found
+	ErrProductBatchRepositoryNotFound = errors.New("repository: product batch not found")
+	// ErrProductBatchRepositorySectionNotFound is returned when the section of the product batch is not found
+	ErrProductBatchRepositorySectionNotFound = errors.New("repository: product batch section not found")
+	// ErrProductBatchRepositoryProductNotFound is returned when the product of the product batch is not found
+	ErrProductBatchRepositoryProductNotFound = errors.New("repository: product batch product not found")
+	// ErrProductBatchRepositoryCapacityExceeded is returned when the section can't hold the quantity of the product batch
+	ErrProductBatchRepositoryCapacityExceeded = errors.New("repository: section maximum capacity exceeded")
+	// ErrProductBatchRepositoryProductType is returned when the section doesn't store the type of the product
+	// (or the recommended temperature of the product is out of the temperature range of its type)
+	ErrProductBatchRepositoryProductType = errors.New("repository: section doesn't store the product type")
+	// ErrProductBatchRepositoryTemperature is returned when the section can't keep the recommended temperature of the product
+	ErrProductBatchRepositoryTemperature = errors.New("repository: section can't keep the product temperature")
+	// ErrProductBatchRepositoryEmployeeNotFound is returned when the employee of a movement is not found
+	ErrProductBatchRepositoryEmployeeNotFound = errors.New("repository: movement employee not found")
+	// ErrProductBatchRepositoryQuantityOutOfRange is returned when a movement leaves the batch with less than 0 units or more than its initial quantity
+	ErrProductBatchRepositoryQuantityOutOfRange = errors.New("repository: product batch quantity out of range")
+	// ErrProductBatchRepositoryLedgerMismatch is returned when the current quantity of the batch doesn't match the sum of its movements
+	ErrProductBatchRepositoryLedgerMismatch = errors.New("repository: product batch quantity doesn't match its movements")
+	// ErrProductBatchRepositorySameSection is returned when a batch is transferred to the section it is in
+	ErrProductBatchRepositorySameSection = errors.New("repository: product batch already in the target section")
+	// ErrProductBatchRepositoryEmployeeWarehouse is returned when the employee receiving an inbound order doesn't work at its warehouse
+	ErrProductBatchRepositoryEmployeeWarehouse = errors.New("repository: employee doesn't work at the warehouse")
+	// ErrProductBatchRepositorySectionWarehouse is returned when the section of a received batch is not in the warehouse of the inbound order
+	ErrProductBatchRepositorySectionWarehouse = errors.New("repository: section is not in the warehouse")
+	// ErrProductBatchRepositoryOrderDuplicated is returned when an inbound order with the same order number already exists
+	ErrProductBatchRepositoryOrderDuplicated = errors.New("repository: inbound order already exists")
+	// ErrProductBatchRepositoryFK is returned when rows that block the delete depend on the product batch
+	ErrProductBatchRepositoryFK = errors.New("repository: product batch has blocking dependents")
+	// ErrProductBatchRepository is the generic error of the repository
+	ErrProductBatchRepository = errors.New("repository: internal error")
+)
+
+// ProductBatchRepository is an interface that contains the methods that the product batch repository should support.
+// The current quantity of a batch takes up capacity in its section: saving, moving, consuming and deleting batches
+// update the current capacity of the sections in the same transaction. A batch can only be placed in a section
+// that stores the type of its product and (with its warehouse) can get as cold as the recommended temperature of the product.
+// Every change of the quantity of a batch is recorded in its ledger of inventory movements, which outlives the batch.
+type ProductBatchRepository interface {
+	// GetAll returns all the product batches, the deleted ones only if includeDeleted
+	GetAll(includeDeleted bool) ([]ProductBatch, error)
+	// ForEach calls fn with every product batch (the deleted ones only if includeDeleted) as it is read, it stops at the first error returned by fn
+	ForEach(includeDeleted bool, fn func(pb ProductBatch) error) error
+	// Get returns the product batch with the given ID
+	Get(id int) (ProductBatch, error)
+	// Save places the given product batch in its section
+	Save(pb *ProductBatch) error
+	// Update updates the given product batch, moving its quantity to its (new) section
+	Update(pb *ProductBatch) error
+	// Delete marks the product batch with the given ID as deleted
+	Delete(id int) error
+	// Restore unmarks the deleted product batch with the given ID
+	Restore(id int) error
+	// Purge deletes the product batch with the given ID permanently, deleted or not, and what depends on it if cascade is true
+	Purge(id int, cascade bool) error
+	// AddMovement applies the movement to the quantity of its batch (and the capacity of its section) and records it
+	AddMovement(m *InventoryMovement) error
+	// GetMovements returns the movements of the product batch, oldest first
+	GetMovements(batchID int) ([]InventoryMovement, error)
+	// Transfer moves units of a batch to the target section, splitting the batch if only part of its units are moved
+	Transfer(t *BatchTransfer) error
+	// Receive places the batch brought in by the inbound order in its section and saves the order, in one transaction.
+	// The employee must work at the warehouse of the order and the section must be in it.
+	Receive(o *InboundOrder, pb *ProductBatch) error
+	// GetExpiring returns the batches with units left that expire on or before the given date, the ones that expire first first.
+	// If warehouseID is not 0, only the batches stored in the sections of that warehouse are returned.
+	GetExpiring(until time.Time, warehouseID int) ([]ProductBatch, error)
+	// ForEachPickable calls fn with every batch of the product with units left that doesn't expire before the given date,
+	// the ones that expire first first. It stops at the first error returned by fn.
+	ForEachPickable(productID int, from time.Time, fn func(pb ProductBatch) error) error
+	// GetOutOfTemperature returns the temperatures of the stored batches (with units left) that are out of range
+	GetOutOfTemperature() ([]BatchTemperature, error)
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrProductBatchServiceNotFound is returned when the product batch is not found
+	ErrProductBatchServiceNotFound = errors.New("service: product batch not found")
+	// ErrProductBatchServiceSectionNotFound is returned when the section of the product batch is not found
+	ErrProductBatchServiceSectionNotFound = errors.New("service: product batch section not found")
+	// ErrProductBatchServiceProductNotFound is returned when the product of the product batch is not found
+	ErrProductBatchServiceProductNotFound = errors.New("service: product batch product not found")
+	// ErrProductBatchServiceCapacityExceeded is returned when the section can't hold the quantity of the product batch
+	ErrProductBatchServiceCapacityExceeded = errors.New("service: section maximum capacity exceeded")
+	// ErrProductBatchServiceProductType is returned when the section doesn't store the type of the product
+	// (or the recommended temperature of the product is out of the temperature range of its type)
+	ErrProductBatchServiceProductType = errors.New("service: section doesn't store the product type")
+	// ErrProductBatchServiceTemperature is returned when the section can't keep the recommended temperature of the product
+	ErrProductBatchServiceTemperature = errors.New("service: section can't keep the product temperature")
+	// ErrProductBatchServiceEmployeeNotFound is returned when the employee of a movement is not found
+	ErrProductBatchServiceEmployeeNotFound = errors.New("service: movement employee not found")
+	// ErrProductBatchServiceQuantityOutOfRange is returned when a movement leaves the batch with less than 0 units or more than its initial quantity
+	ErrProductBatchServiceQuantityOutOfRange = errors.New("service: product batch quantity out of range")
+	// ErrProductBatchServiceLedgerMismatch is returned when the current quantity of the batch doesn't match the sum of its movements
+	ErrProductBatchServiceLedgerMismatch = errors.New("service: product batch quantity doesn't match its movements")
+	// ErrProductBatchServiceEmployeeWarehouse is returned when the employee receiving an inbound order doesn't work at its warehouse
+	ErrProductBatchServiceEmployeeWarehouse = errors.New("service: employee doesn't work at the warehouse")
+	// ErrProductBatchServiceSectionWarehouse is returned when the section of a received batch is not in the warehouse of the inbound order
+	ErrProductBatchServiceSectionWarehouse = errors.New("service: section is not in the warehouse")
+	// ErrProductBatchServiceOrderDuplicated is returned when an inbound order with the same order number already exists
+	ErrProductBatchServiceOrderDuplicated = errors.New("service: inbound order already exists")
+	// ErrProductBatchServiceFK is returned when rows that block the delete depend on the product batch
+	ErrProductBatchServiceFK = errors.New("service: product batch has blocking dependents")
+	// ErrProductBatchServiceInvalidField is returned when a field of the product batch is invalid
+	ErrProductBatchServiceInvalidField = errors.New("service: invalid field")
+	// ErrProductBatchService is the generic error of the service
+	ErrProductBatchService = errors.New("service: internal error")
+	// ErrProductBatchServiceUnknown is returned when the repository returns an unknown error
+	ErrProductBatchServiceUnknown = errors.New("service: unknown error")
+)
+
+// ProductBatchService is an interface that contains the methods that the product batch service should support
+type ProductBatchService interface {
+	// GetAll returns all the product batches, the deleted ones only if includeDeleted
+	GetAll(includeDeleted bool) ([]ProductBatch, error)
+	// ForEach calls fn with every product batch (the deleted ones only if includeDeleted) without loading all of them in memory, it stops at the first error returned by fn
+	ForEach(includeDeleted bool, fn func(pb ProductBatch) error) error
+	// Get returns the product batch with the given ID
+	Get(id int) (ProductBatch, error)
+	// Save places the given product batch in its section
+	Save(ctx context.Context, pb *ProductBatch) error
+	// Update updates the given product batch (moving it to another section or consuming units)
+	Update(ctx context.Context, pb *ProductBatch) error
+	// Delete marks the product batch with the given ID as deleted
+	Delete(ctx context.Context, id int) error
+	// Restore unmarks the deleted product batch with the given ID
+	Restore(ctx context.Context, id int) error
+	// Purge deletes the product batch with the given ID permanently, deleted or not
+	Purge(ctx context.Context, id int) error
+	// AddMovement applies the movement (receipt, pick, adjustment or write-off) to the quantity of its batch and records it
+	AddMovement(ctx context.Context, m *InventoryMovement) error
+	// GetMovements returns the movements of the product batch, oldest first
+	GetMovements(batchID int) ([]InventoryMovement, error)
+	// Transfer moves units of a batch to the target section, splitting the batch if only part of its units are moved
+	Transfer(ctx context.Context, t *BatchTransfer) error
+	// Receive places the batch brought in by the inbound order in its section and saves the order, all or nothing
+	Receive(ctx context.Context, o *InboundOrder, pb *ProductBatch) error
+	// GetExpiring returns the batches with units left that expire within the given number of days (or already expired),
+	// optionally only the ones of a warehouse (warehouseID not 0)
+	GetExpiring(days int, warehouseID int) ([]ProductBatch, error)
+	// Pick returns the batches to pick the given quantity of the product from, first expired first out.
+	// If there are not enough units, the picks cover the units there are.
+	Pick(productID int, quantity int) ([]BatchPick, error)
+	// GetTemperatureCompliance returns the stored batches that are out of the temperature range of their product, with the reasons
+	GetTemperatureCompliance() ([]BatchTemperature, error)
+}
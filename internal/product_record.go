@@ -0,0 +1,69 @@
+package internal
+
+import "time"
+
+// ProductRecord is a struct that contains the prices of a product from a moment on
+type ProductRecord struct {
+	// ID is the unique identifier of the product record
+	ID int
+	// LastUpdateDate is the moment the prices took effect
+	LastUpdateDate time.Time
+	// PurchasePrice is the price the product is bought at
+	PurchasePrice float64
+	// SalePrice is the price the product is sold at, zero when it isn't for sale
+	SalePrice float64
+	// ProductID is the unique identifier of the product
+	ProductID int
+}
+
+// ProductPrices is a struct that contains the price timeline of a product
+type ProductPrices struct {
+	// ProductID is the unique identifier of the product
+	ProductID int
+	// At is the moment the current prices are taken at
+	At time.Time
+	// Current is the record with a sale price in effect at At, zero when the product had no sale price yet
+	Current ProductRecord
+	// Timeline is the records of the product, the oldest first
+	Timeline []ProductRecord
+}
+
+// ProductMargin is a struct that contains the margin of a product from its prices in effect at a moment
+type ProductMargin struct {
+	// ProductID is the unique identifier of the product
+	ProductID int
+	// ProductCode is the unique code of the product
+	ProductCode string
+	// Description is the description of the product
+	Description string
+	// SellerID is the unique identifier of the seller of the product, zero when it has none
+	SellerID int
+	// Record is the record in effect
+	Record ProductRecord
+	// Margin is the sale price minus the purchase price
+	Margin float64
+	// MarginRate is the margin over the sale price
+	MarginRate float64
+}
+
+// SellerMargin is a struct that contains the margins of the products of a seller
+type SellerMargin struct {
+	// SellerID is the unique identifier of the seller, zero for the products without one
+	SellerID int
+	// Products is the number of products for sale of the seller
+	Products int
+	// AverageMargin is the average margin of the products
+	AverageMargin float64
+	// AverageMarginRate is the average margin rate of the products
+	AverageMarginRate float64
+}
+
+// MarginReport is a struct that contains the margins of the products for sale at a moment, per product and per seller
+type MarginReport struct {
+	// At is the moment the prices are taken at
+	At time.Time
+	// Products is the margins of the products, ordered by seller and product
+	Products []ProductMargin
+	// Sellers is the margins per seller
+	Sellers []SellerMargin
+}
@@ -1,6 +1,9 @@
 package internal
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 // Errors
 var (
@@ -18,20 +21,37 @@ var (
 	ErrProductRepositoryNothingToUpdate = errors.New("products repository: nothing to update")
 	// ErrProductRepositoryForeignKey is returned when a product couldn't be deleted because of a foreign key constraint.
 	ErrProductRepositoryForeignKey = errors.New("products repository: product couldn't be deleted because foreign key constraint")
+	// ErrProductRepositoryVersionConflict is returned when the product was modified since it was read.
+	ErrProductRepositoryVersionConflict = errors.New("products repository: version conflict")
 )
 
 // Repository encapsulates the storage of a Product.
 type ProductRepository interface {
-	// GetAll returns all the products.
-	GetAll() ([]Product, error)
+	// GetAll returns all the products, the deleted ones only if includeDeleted.
+	GetAll(includeDeleted bool) ([]Product, error)
+	// ForEach calls fn with every product (the deleted ones only if includeDeleted) as it is read from the storage, it stops at the first error returned by fn.
+	ForEach(includeDeleted bool, fn func(p Product) error) error
 	// Get returns the product with the given id.
 	Get(id int) (Product, error)
 	// Save saves the product in the storage.
 	Save(p *Product) (int, error)
-	// Update updates the product in the storage.
+	// SaveBulk saves the products in the storage in batches. If atomic, either all of them are saved or none.
+	SaveBulk(products []Product, atomic bool) ([]BulkResult, error)
+	// Update updates the product in the storage if its version matches the stored one.
 	Update(p *Product) error
-	// Delete deletes the product with the given id from the storage.
-	Delete(id int) error
+	// HasBatchesInOtherType returns whether the product has batches (with units left) stored in sections of another product type.
+	HasBatchesInOtherType(id int, productTypeID int) (bool, error)
+	// Delete marks the product with the given ID as deleted, at the given version (any if zero)
+	Delete(id int, version int) error
+	// Restore unmarks the deleted product with the given ID.
+	Restore(id int) error
+	// Purge deletes the product with the given ID permanently, deleted or not, at the given version (any if zero),
+	// if nothing blocks it and, when rows depend on it, cascade confirms they are deleted or nulled with it
+	Purge(id int, version int, cascade bool) error
 	// GetRecordsByProductReport returns the product records.
 	GetRecordsByProductReport(id int) ([]Product, error)
+	// GetRecords returns the records (prices over time) of the product with the given id, the oldest first.
+	GetRecords(id int) ([]ProductRecord, error)
+	// GetMargins returns the margins of the products for sale at the given moment, of the given seller (all when zero).
+	GetMargins(sellerID int, at time.Time) ([]ProductMargin, error)
 }
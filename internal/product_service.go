@@ -1,6 +1,10 @@
 package internal
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"time"
+)
 
 // Errors
 var (
@@ -20,19 +24,38 @@ var (
 	ErrProductServiceNothingToUpdate = errors.New("products service: nothing to update")
 
 	ErrProductServiceForeignKey = errors.New("products service: product couldn't be deleted because foreign key constraint")
+	// ErrProductServiceProductType is returned when the product type of a product changes while it has batches stored in sections of another type.
+	ErrProductServiceProductType = errors.New("products service: product has batches in sections of another product type")
+	// ErrProductServiceVersionConflict is returned when the product was modified since it was read.
+	ErrProductServiceVersionConflict = errors.New("products service: version conflict")
 )
 
 type ProductService interface {
-	// GetAll returns all products.
-	GetAll() ([]Product, error)
+	// GetAll returns all products, the deleted ones only if includeDeleted.
+	GetAll(includeDeleted bool) ([]Product, error)
+	// ForEach calls fn with every product (the deleted ones only if includeDeleted) without loading all of them in memory, it stops at the first error returned by fn.
+	ForEach(includeDeleted bool, fn func(p Product) error) error
 	// Get returns a product by ID.
 	Get(id int) (Product, error)
 	// Save saves a new product.
-	Save(p *Product) (Product, error)
+	Save(ctx context.Context, p *Product) (Product, error)
+	// Validate checks a new product as Save does, without saving it.
+	Validate(p *Product) error
+	// SaveBulk saves new products. If atomic, either all of them are saved or none.
+	SaveBulk(ctx context.Context, products []Product, atomic bool) ([]BulkResult, error)
 	// Update updates a product by ID.
-	Update(p *Product) error
-	// Delete deletes a product by ID.
-	Delete(id int) error
+	Update(ctx context.Context, p *Product) error
+	// Delete marks the product with the given ID as deleted, at the given version (any if zero)
+	Delete(ctx context.Context, id int, version int) error
+	// Restore unmarks the deleted product with the given ID.
+	Restore(ctx context.Context, id int) error
+	// Purge deletes the product with the given ID permanently, deleted or not, at the given version (any if zero).
+	// The rows that depend on it are deleted or nulled with it only if the context confirms the cascade (WithCascadeConfirmed)
+	Purge(ctx context.Context, id int, version int) error
 	// GetRecordsByProductReport returns a report of the product records.
 	GetRecordsByProductReport(id int) ([]Product, error)
+	// GetPrices returns the price timeline of a product and its prices at the given moment (now when zero).
+	GetPrices(id int, at time.Time) (ProductPrices, error)
+	// GetMargins returns the margins of the products for sale at the given moment (now when zero), of the given seller (all when zero).
+	GetMargins(sellerID int, at time.Time) (MarginReport, error)
 }
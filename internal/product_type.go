@@ -0,0 +1,31 @@
+package internal
+
+import "time"
+
+// Storage classes of the product types
+const (
+	// StorageClassAmbient is the storage class of the products kept at room temperature
+	StorageClassAmbient = "ambient"
+	// StorageClassRefrigerated is the storage class of the products kept cold but above freezing
+	StorageClassRefrigerated = "refrigerated"
+	// StorageClassFrozen is the storage class of the products kept frozen
+	StorageClassFrozen = "frozen"
+)
+
+// ProductType is a struct that contains the information of a type of product, which sections are dedicated to
+type ProductType struct {
+	// ID is the unique identifier of the product type
+	ID int
+	// Name is the unique name of the product type
+	Name string
+	// StorageClass is how the products of the type are stored: ambient, refrigerated or frozen
+	StorageClass string
+	// MinimumTemperature is the lowest temperature the products of the type can be stored at
+	MinimumTemperature float64
+	// MaximumTemperature is the highest temperature the products of the type can be stored at
+	MaximumTemperature float64
+	// Version is the version of the product type, incremented on every update (optimistic concurrency)
+	Version int
+	// DeletedAt is the moment the product type was deleted, zero unless it is
+	DeletedAt time.Time
+}
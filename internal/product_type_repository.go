@@ -0,0 +1,35 @@
+package internal
+
+import "errors"
+
+var (
+	// ErrProductTypeRepositoryNotFound is returned when the product type is not found
+	ErrProductTypeRepositoryNotFound = errors.New("repository: product type not found")
+	// ErrProductTypeRepositoryDuplicated is returned when a product type with the same name already exists
+	ErrProductTypeRepositoryDuplicated = errors.New("repository: product type already exists")
+	// ErrProductTypeRepositoryFK is returned when the product type has products or sections
+	ErrProductTypeRepositoryFK = errors.New("repository: product type has products or sections")
+	// ErrProductTypeRepositoryVersionConflict is returned when the product type was modified since it was read
+	ErrProductTypeRepositoryVersionConflict = errors.New("repository: product type version conflict")
+	// ErrProductTypeRepository is the generic error of the repository
+	ErrProductTypeRepository = errors.New("repository: internal error")
+)
+
+// ProductTypeRepository is an interface that contains the methods that the product type repository should support
+type ProductTypeRepository interface {
+	// GetAll returns all the product types, the deleted ones only if includeDeleted
+	GetAll(includeDeleted bool) ([]ProductType, error)
+	// Get returns the product type with the given ID
+	Get(id int) (ProductType, error)
+	// Save saves the given product type
+	Save(pt *ProductType) error
+	// Update updates the given product type if its version matches the stored one
+	Update(pt *ProductType) error
+	// Delete marks the product type with the given ID as deleted, at the given version (any if zero)
+	Delete(id int, version int) error
+	// Restore unmarks the deleted product type with the given ID
+	Restore(id int) error
+	// Purge deletes the product type with the given ID permanently, deleted or not, at the given version (any if zero),
+	// if nothing blocks it and, when rows depend on it, cascade confirms they are deleted or nulled with it
+	Purge(id int, version int, cascade bool) error
+}
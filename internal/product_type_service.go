@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrProductTypeServiceNotFound is returned when the product type is not found
+	ErrProductTypeServiceNotFound = errors.New("service: product type not found")
+	// ErrProductTypeServiceDuplicated is returned when a product type with the same name already exists
+	ErrProductTypeServiceDuplicated = errors.New("service: product type already exists")
+	// ErrProductTypeServiceFK is returned when the product type has products or sections
+	ErrProductTypeServiceFK = errors.New("service: product type has products or sections")
+	// ErrProductTypeServiceVersionConflict is returned when the product type was modified since it was read
+	ErrProductTypeServiceVersionConflict = errors.New("service: product type version conflict")
+	// ErrProductTypeServiceInvalidField is returned when a field of the product type is invalid
+	ErrProductTypeServiceInvalidField = errors.New("service: invalid field")
+	// ErrProductTypeService is the generic error of the service
+	ErrProductTypeService = errors.New("service: internal error")
+	// ErrProductTypeServiceUnknown is returned when the repository returns an unknown error
+	ErrProductTypeServiceUnknown = errors.New("service: unknown error")
+)
+
+// ProductTypeService is an interface that contains the methods that the product type service should support
+type ProductTypeService interface {
+	// GetAll returns all the product types, the deleted ones only if includeDeleted
+	GetAll(includeDeleted bool) ([]ProductType, error)
+	// Get returns the product type with the given ID
+	Get(id int) (ProductType, error)
+	// Save saves the given product type
+	Save(ctx context.Context, pt *ProductType) error
+	// Update updates the given product type
+	Update(ctx context.Context, pt *ProductType) error
+	// Delete marks the product type with the given ID as deleted, at the given version (any if zero)
+	Delete(ctx context.Context, id int, version int) error
+	// Restore unmarks the deleted product type with the given ID
+	Restore(ctx context.Context, id int) error
+	// Purge deletes the product type with the given ID permanently, deleted or not, at the given version (any if zero).
+	// The rows that depend on it are deleted or nulled with it only if the context confirms the cascade (WithCascadeConfirmed)
+	Purge(ctx context.Context, id int, version int) error
+}
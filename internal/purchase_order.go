@@ -0,0 +1,94 @@
+package internal
+
+import "time"
+
+// OrderStatus is the status of a purchase order (its id in the order_statuses table)
+type OrderStatus int
+
+// Statuses of a purchase order
+const (
+	// OrderStatusPending is the status of a new order
+	OrderStatusPending OrderStatus = iota + 1
+	// OrderStatusConfirmed is the status of an order accepted by the seller
+	OrderStatusConfirmed
+	// OrderStatusPicking is the status of an order whose units are being picked
+	OrderStatusPicking
+	// OrderStatusShipped is the status of an order handed over to the carrier
+	OrderStatusShipped
+	// OrderStatusDelivered is the status of an order received by the buyer
+	OrderStatusDelivered
+	// OrderStatusCancelled is the status of an order that won't be fulfilled
+	OrderStatusCancelled
+)
+
+// orderStatusNames are the names of the statuses, as exposed by the API
+var orderStatusNames = map[OrderStatus]string{
+	OrderStatusPending:   "pending",
+	OrderStatusConfirmed: "confirmed",
+	OrderStatusPicking:   "picking",
+	OrderStatusShipped:   "shipped",
+	OrderStatusDelivered: "delivered",
+	OrderStatusCancelled: "cancelled",
+}
+
+// String returns the name of the status
+func (s OrderStatus) String() string {
+	if name, ok := orderStatusNames[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseOrderStatus returns the status with the given name
+func ParseOrderStatus(name string) (s OrderStatus, ok bool) {
+	for status, statusName := range orderStatusNames {
+		if statusName == name {
+			return status, true
+		}
+	}
+	return
+}
+
+// PurchaseOrder is a struct that contains the information of an order of a buyer
+type PurchaseOrder struct {
+	// ID is the unique identifier of the purchase order
+	ID int
+	// OrderNumber is the unique number of the purchase order
+	OrderNumber int
+	// OrderDate is the date on which the order was placed
+	OrderDate time.Time
+	// TrackingCode is the code to track the shipment of the order
+	TrackingCode string
+	// BuyerID is the unique identifier of the buyer who placed the order
+	BuyerID int
+	// ProductRecordID is the unique identifier of the product record (product and price) ordered
+	ProductRecordID int
+	// Status is the current status of the order
+	Status OrderStatus
+	// CarrierID is the unique identifier of the carrier that ships the order (0 if not assigned yet)
+	CarrierID int
+}
+
+// OrderStatusChange is a struct that contains a change of the status of a purchase order
+type OrderStatusChange struct {
+	// ID is the unique identifier of the change
+	ID int
+	// PurchaseOrderID is the unique identifier of the purchase order
+	PurchaseOrderID int
+	// From is the status before the change (0 when the order was created)
+	From OrderStatus
+	// To is the status after the change
+	To OrderStatus
+	// ChangedAt is the moment at which the status changed
+	ChangedAt time.Time
+}
+
+// Tracking is a struct that contains what a buyer sees when tracking the shipment of a purchase order
+type Tracking struct {
+	// Order is the purchase order
+	Order PurchaseOrder
+	// Carrier is the carrier that ships the order
+	Carrier Carrier
+	// History are the status changes of the order, oldest first
+	History []OrderStatusChange
+}
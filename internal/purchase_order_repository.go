@@ -0,0 +1,45 @@
+package internal
+
+import "errors"
+
+var (
+	// ErrPurchaseOrderRepositoryNotFound is returned when the purchase order is not found
+	ErrPurchaseOrderRepositoryNotFound = errors.New("repository: purchase order not found")
+	// ErrPurchaseOrderRepositoryDuplicated is returned when a purchase order with the same order number already exists
+	ErrPurchaseOrderRepositoryDuplicated = errors.New("repository: purchase order already exists")
+	// ErrPurchaseOrderRepositoryTrackingCodeDuplicated is returned when another purchase order already has the tracking code
+	ErrPurchaseOrderRepositoryTrackingCodeDuplicated = errors.New("repository: tracking code already in use")
+	// ErrPurchaseOrderRepositoryFK is returned when the buyer or the product record of the purchase order is not found
+	ErrPurchaseOrderRepositoryFK = errors.New("repository: purchase order buyer or product record not found")
+	// ErrPurchaseOrderRepositoryCarrierNotFound is returned when the carrier is not found (or there are no carriers)
+	ErrPurchaseOrderRepositoryCarrierNotFound = errors.New("repository: carrier not found")
+	// ErrPurchaseOrderRepositoryStatusConflict is returned when the status of the purchase order changed since it was read
+	ErrPurchaseOrderRepositoryStatusConflict = errors.New("repository: purchase order status conflict")
+	// ErrPurchaseOrderRepository is the generic error of the repository
+	ErrPurchaseOrderRepository = errors.New("repository: internal error")
+)
+
+// PurchaseOrderRepository is an interface that contains the methods that the purchase order repository should support
+type PurchaseOrderRepository interface {
+	// GetAll returns all the purchase orders
+	GetAll() ([]PurchaseOrder, error)
+	// Get returns the purchase order with the given ID
+	Get(id int) (PurchaseOrder, error)
+	// Save saves the given purchase order and the first entry of its status history
+	Save(po *PurchaseOrder) error
+	// UpdateStatus changes the status of the purchase order if it is still the given one, recording the change
+	UpdateStatus(change *OrderStatusChange) error
+	// GetHistory returns the status changes of the purchase order, oldest first
+	GetHistory(id int) ([]OrderStatusChange, error)
+	// GetByTrackingCode returns the purchase order with the given tracking code
+	GetByTrackingCode(code string) (PurchaseOrder, error)
+	// GetCarrier returns the carrier with the given ID
+	GetCarrier(id int) (Carrier, error)
+	// SuggestCarrier returns the carrier with the fewest orders in transit, preferring the ones in the locality
+	// of the given warehouse (0 for any locality)
+	SuggestCarrier(warehouseID int) (Carrier, error)
+	// GetShippingWarehouse returns the warehouse holding the most units of the product of the record (0 if none holds it)
+	GetShippingWarehouse(productRecordID int) (int, error)
+	// AssignShipment sets the carrier and tracking code of the purchase order if its status is still the given one
+	AssignShipment(id int, status OrderStatus, carrierID int, trackingCode string) error
+}
@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrPurchaseOrderServiceNotFound is returned when the purchase order is not found
+	ErrPurchaseOrderServiceNotFound = errors.New("service: purchase order not found")
+	// ErrPurchaseOrderServiceDuplicated is returned when a purchase order with the same order number already exists
+	ErrPurchaseOrderServiceDuplicated = errors.New("service: purchase order already exists")
+	// ErrPurchaseOrderServiceTrackingCodeDuplicated is returned when another purchase order already has the tracking code
+	ErrPurchaseOrderServiceTrackingCodeDuplicated = errors.New("service: tracking code already in use")
+	// ErrPurchaseOrderServiceFK is returned when the buyer or the product record of the purchase order is not found
+	ErrPurchaseOrderServiceFK = errors.New("service: purchase order buyer or product record not found")
+	// ErrPurchaseOrderServiceInvalidTransition is returned when the purchase order can't go from its status to the requested one
+	ErrPurchaseOrderServiceInvalidTransition = errors.New("service: invalid purchase order status transition")
+	// ErrPurchaseOrderServiceCarrierNotFound is returned when the carrier is not found (or there are no carriers)
+	ErrPurchaseOrderServiceCarrierNotFound = errors.New("service: carrier not found")
+	// ErrPurchaseOrderServiceNotShippable is returned when a carrier is assigned to an order already shipped, delivered or cancelled
+	ErrPurchaseOrderServiceNotShippable = errors.New("service: purchase order can't be assigned a carrier in its status")
+	// ErrPurchaseOrderServiceStatusConflict is returned when the status of the purchase order changed while it was being transitioned
+	ErrPurchaseOrderServiceStatusConflict = errors.New("service: purchase order status conflict")
+	// ErrPurchaseOrderServiceInvalidField is returned when a field of the purchase order is invalid
+	ErrPurchaseOrderServiceInvalidField = errors.New("service: invalid field")
+	// ErrPurchaseOrderService is the generic error of the service
+	ErrPurchaseOrderService = errors.New("service: internal error")
+	// ErrPurchaseOrderServiceUnknown is returned when the repository returns an unknown error
+	ErrPurchaseOrderServiceUnknown = errors.New("service: unknown error")
+)
+
+// PurchaseOrderService is an interface that contains the methods that the purchase order service should support
+type PurchaseOrderService interface {
+	// GetAll returns all the purchase orders
+	GetAll() ([]PurchaseOrder, error)
+	// Get returns the purchase order with the given ID
+	Get(id int) (PurchaseOrder, error)
+	// Save saves the given purchase order, which starts pending
+	Save(ctx context.Context, po *PurchaseOrder) error
+	// Transition moves the purchase order to the given status, if the status workflow allows it
+	Transition(ctx context.Context, id int, to OrderStatus) (PurchaseOrder, error)
+	// GetHistory returns the status changes of the purchase order, oldest first
+	GetHistory(id int) ([]OrderStatusChange, error)
+	// AssignShipment assigns a carrier to the purchase order with a new tracking code. If carrierID is 0 the carrier is
+	// chosen by the service, preferring the ones in the locality of the warehouse (0 for the one holding the product)
+	AssignShipment(ctx context.Context, id int, carrierID int, warehouseID int) (PurchaseOrder, error)
+	// Track returns the purchase order with the given tracking code, its carrier and its status timeline
+	Track(code string) (Tracking, error)
+}
+
+// TrackingCodeGenerator is an interface that contains the methods that a generator of tracking codes should support
+type TrackingCodeGenerator interface {
+	// Generate returns a new tracking code, the values fill the placeholders of its format (e.g. carrier, order)
+	Generate(values map[string]string) (string, error)
+}
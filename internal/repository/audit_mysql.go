@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// NewAuditMySQL creates a new instance of the audit repository for MySQL
+func NewAuditMySQL(db *sql.DB) *AuditMySQL {
+	return &AuditMySQL{
+		db: db,
+	}
+}
+
+// AuditMySQL is the default implementation of the audit repository for MySQL
+type AuditMySQL struct {
+	db *sql.DB
+}
+
+// Save saves an entry of the audit log. The changed fields are stored as JSON objects, NULL when missing.
+func (r *AuditMySQL) Save(e *internal.AuditEntry) (err error) {
+	before, err := marshalAuditFields(e.Before)
+	if err != nil {
+		err = internal.ErrAuditRepository
+		return
+	}
+	after, err := marshalAuditFields(e.After)
+	if err != nil {
+		err = internal.ErrAuditRepository
+		return
+	}
+
+	// execute the query
+	query := "INSERT INTO `audit_log` (`resource`, `resource_id`, `action`, `before`, `after`, `actor`, `request_id`, `created_at`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	result, err := r.db.Exec(query,
+		e.Resource, e.ResourceID, e.Action, before, after,
+		sql.NullString{String: e.Actor, Valid: e.Actor != ""},
+		sql.NullString{String: e.RequestID, Valid: e.RequestID != ""},
+		e.CreatedAt.UTC(),
+	)
+	if err != nil {
+		err = internal.ErrAuditRepository
+		return
+	}
+
+	// get the last inserted id
+	id, err := result.LastInsertId()
+	if err != nil {
+		err = internal.ErrAuditRepository
+		return
+	}
+	e.ID = int(id)
+
+	return
+}
+
+// GetAll returns the entries of the audit log that meet the filter, the oldest first
+func (r *AuditMySQL) GetAll(filter internal.AuditFilter) (entries []internal.AuditEntry, err error) {
+	// build the query with the conditions of the filter
+	query := "SELECT a.`id`, a.`resource`, a.`resource_id`, a.`action`, a.`before`, a.`after`, a.`actor`, a.`request_id`, a.`created_at` FROM `audit_log` AS `a` WHERE 1 = 1"
+	var args []any
+	if filter.Resource != "" {
+		query += " AND a.`resource` = ?"
+		args = append(args, filter.Resource)
+	}
+	if filter.ResourceID != 0 {
+		query += " AND a.`resource_id` = ?"
+		args = append(args, filter.ResourceID)
+	}
+	if !filter.Since.IsZero() {
+		query += " AND a.`created_at` >= ?"
+		args = append(args, filter.Since.UTC())
+	}
+	query += " ORDER BY a.`id`"
+
+	// execute the query
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		err = internal.ErrAuditRepository
+		return
+	}
+	defer rows.Close()
+
+	// scan the rows
+	for rows.Next() {
+		var e internal.AuditEntry
+		var before, after []byte
+		var actor, requestID sql.NullString
+		if err = rows.Scan(&e.ID, &e.Resource, &e.ResourceID, &e.Action, &before, &after, &actor, &requestID, &e.CreatedAt); err != nil {
+			err = internal.ErrAuditRepository
+			return
+		}
+		if e.Before, err = unmarshalAuditFields(before); err != nil {
+			err = internal.ErrAuditRepository
+			return
+		}
+		if e.After, err = unmarshalAuditFields(after); err != nil {
+			err = internal.ErrAuditRepository
+			return
+		}
+		e.Actor = actor.String
+		e.RequestID = requestID.String
+
+		entries = append(entries, e)
+	}
+	if err = rows.Err(); err != nil {
+		err = internal.ErrAuditRepository
+		return
+	}
+
+	return
+}
+
+// marshalAuditFields returns the JSON object of the fields, nil (NULL) if there are none
+func marshalAuditFields(fields map[string]any) (data []byte, err error) {
+	if fields == nil {
+		return
+	}
+
+	data, err = json.Marshal(fields)
+	return
+}
+
+// unmarshalAuditFields returns the fields of the JSON object, nil if it is NULL
+func unmarshalAuditFields(data []byte) (fields map[string]any, err error) {
+	if data == nil {
+		return
+	}
+
+	err = json.Unmarshal(data, &fields)
+	return
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// bulkBatchSize is the number of rows inserted by each multi-row statement
+const bulkBatchSize = 500
+
+// execer is implemented by *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// bulkInsert inserts rows into table using multi-row statements of bulkBatchSize rows.
+// - atomic: all the rows are inserted in a single transaction and if any row fails none is saved
+// - otherwise: each batch is inserted on its own and a failing batch is retried row by row so only the failing rows are lost
+// mapErr converts a driver error into the repository error reported for the row.
+// The returned error is only set when the operation itself fails (e.g. the transaction cannot be committed).
+func bulkInsert(db *sql.DB, table string, columns []string, rows [][]any, atomic bool, mapErr func(error) error) (results []internal.BulkResult, err error) {
+	results = make([]internal.BulkResult, len(rows))
+	for i := range results {
+		results[i].Index = i
+	}
+
+	// executor: the transaction in atomic mode, the connection otherwise
+	var ex execer = db
+	var tx *sql.Tx
+	if atomic {
+		tx, err = db.Begin()
+		if err != nil {
+			return
+		}
+		ex = tx
+	}
+
+	failed := false
+	for start := 0; start < len(rows); start += bulkBatchSize {
+		end := min(start+bulkBatchSize, len(rows))
+
+		// insert the batch in a single statement
+		firstID, e := insertRows(ex, table, columns, rows[start:end])
+		if e == nil {
+			// the ids of a multi-row insert are consecutive, starting at the one returned by LastInsertId
+			for i := start; i < end; i++ {
+				results[i].ID = firstID + (i - start)
+			}
+			continue
+		}
+
+		// the batch failed: insert row by row to find out which rows are at fault
+		// (a failed statement only rolls back itself, the transaction is still usable)
+		for i := start; i < end; i++ {
+			id, e := insertRows(ex, table, columns, rows[i:i+1])
+			if e != nil {
+				results[i].Err = mapErr(e)
+				failed = true
+				continue
+			}
+			results[i].ID = id
+		}
+	}
+
+	if !atomic {
+		return
+	}
+
+	// all or nothing
+	if failed {
+		_ = tx.Rollback()
+		for i := range results {
+			results[i].ID = 0
+			if results[i].Err == nil {
+				results[i].Err = internal.ErrBulkNotSaved
+			}
+		}
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		for i := range results {
+			results[i].ID = 0
+		}
+	}
+
+	return
+}
+
+// insertRows inserts the rows with a single statement and returns the id of the first one
+func insertRows(ex execer, table string, columns []string, rows [][]any) (firstID int, err error) {
+	placeholder := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	placeholders := make([]string, len(rows))
+	args := make([]any, 0, len(rows)*len(columns))
+	for i, row := range rows {
+		placeholders[i] = placeholder
+		args = append(args, row...)
+	}
+
+	query := "INSERT INTO `" + table + "` (`" + strings.Join(columns, "`, `") + "`) VALUES " + strings.Join(placeholders, ", ")
+	result, err := ex.Exec(query, args...)
+	if err != nil {
+		return
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return
+	}
+	firstID = int(id)
+
+	return
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/manuelfirman/go-API/internal"
@@ -19,25 +20,39 @@ func NewBuyerMySQL(db *sql.DB) *BuyerMySQL {
 	}
 }
 
-// GetAll returns all buyers. Returns an error if the operation fails.
-func (r *BuyerMySQL) GetAll() (buyers []internal.Buyer, err error) {
+// GetAll returns all the buyers, the deleted ones only if includeDeleted
+func (r *BuyerMySQL) GetAll(includeDeleted bool) (buyers []internal.Buyer, err error) {
+	err = r.ForEach(includeDeleted, func(buyer internal.Buyer) error {
+		buyers = append(buyers, buyer)
+		return nil
+	})
+	return
+}
+
+// ForEach calls fn with every buyer as the rows are scanned, so the buyers are never held in memory.
+// The deleted buyers are only included if includeDeleted. It stops at the first error returned by fn and returns it as is.
+func (r *BuyerMySQL) ForEach(includeDeleted bool, fn func(buyer internal.Buyer) error) (err error) {
 	// execute the query
-	query := "SELECT b.`id`, b.`card_number_id`, b.`first_name`, b.`last_name` FROM `buyers` AS `b`"
+	query := "SELECT b.`id`, b.`card_number_id`, b.`first_name`, b.`last_name`, b.`version`, b.`deleted_at` FROM `buyers` AS `b`" + notDeleted("b", includeDeleted) + " ORDER BY b.`id`"
 	rows, err := r.db.Query(query)
 	if err != nil {
 		return
 	}
 	defer rows.Close()
 
-	// iterate over the rows
+	// iterate over the rows and yield the buyers
 	for rows.Next() {
 		var buyer internal.Buyer
-		err = rows.Scan(&buyer.ID, &buyer.CardNumberID, &buyer.FirstName, &buyer.LastName)
+		var deletedAt sql.NullTime
+		err = rows.Scan(&buyer.ID, &buyer.CardNumberID, &buyer.FirstName, &buyer.LastName, &buyer.Version, &deletedAt)
 		if err != nil {
 			return
 		}
+		buyer.DeletedAt = deletedAt.Time
 
-		buyers = append(buyers, buyer)
+		if err = fn(buyer); err != nil {
+			return
+		}
 	}
 
 	err = rows.Err()
@@ -51,10 +66,10 @@ func (r *BuyerMySQL) GetAll() (buyers []internal.Buyer, err error) {
 // Get returns a buyer by ID. Returns an error if the buyer is not found.
 func (r *BuyerMySQL) Get(id int) (b internal.Buyer, err error) {
 	// execute the query
-	query := "SELECT b.`id`, b.`card_number_id`, b.`first_name`, b.`last_name` FROM `buyers` AS `b` WHERE b.`id` = ?"
+	query := "SELECT b.`id`, b.`card_number_id`, b.`first_name`, b.`last_name`, b.`version` FROM `buyers` AS `b` WHERE b.`id` = ? AND b.`deleted_at` IS NULL"
 	row := r.db.QueryRow(query, id)
 	// scan the row and return the buyer
-	err = row.Scan(&b.ID, &b.CardNumberID, &b.FirstName, &b.LastName)
+	err = row.Scan(&b.ID, &b.CardNumberID, &b.FirstName, &b.LastName, &b.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -101,11 +116,11 @@ func (r *BuyerMySQL) Save(b *internal.Buyer) (err error) {
 	return
 }
 
-// Update receives a buyer and updates it. Returns an error if the buyer is not found.
+// Update receives a buyer and updates it if its version matches the stored one.
 func (r *BuyerMySQL) Update(b *internal.Buyer) (err error) {
 	// execute the query
-	query := "UPDATE `buyers` SET `card_number_id` = ?, `first_name` = ?, `last_name` = ? WHERE `id` = ?"
-	_, err = r.db.Exec(query, b.CardNumberID, b.FirstName, b.LastName, b.ID)
+	query := "UPDATE `buyers` SET `card_number_id` = ?, `first_name` = ?, `last_name` = ?, `version` = `version` + 1 WHERE `id` = ? AND `version` = ? AND `deleted_at` IS NULL"
+	result, err := r.db.Exec(query, b.CardNumberID, b.FirstName, b.LastName, b.ID, b.Version)
 
 	if err != nil {
 		var mysqlErr *mysql.MySQLError
@@ -121,39 +136,153 @@ func (r *BuyerMySQL) Update(b *internal.Buyer) (err error) {
 		return
 	}
 
+	// the version is always bumped, so no affected rows means the buyer changed (or vanished) since it was read
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		err = internal.ErrBuyerRepository
+		return
+	}
+	if rowsAffected == 0 {
+		err = internal.ErrBuyerRepositoryVersionConflict
+		return
+	}
+
+	b.Version++
+
 	return
 }
 
-// Delete receives a buyer ID and deletes it. Returns an error if the buyer is not found.
-func (r *BuyerMySQL) Delete(id int) (err error) {
-	// execute the query
-	query := "DELETE FROM `buyers` WHERE `id` = ?"
-	result, err := r.db.Exec(query, id)
-	if err != nil {
-		var mysqlErr *mysql.MySQLError
-		if errors.As(err, &mysqlErr) {
-			switch mysqlErr.Number {
-			case 1451:
-				err = internal.ErrBuyerRepositoryFK
-			default:
-				err = internal.ErrBuyerRepository
-			}
+// Delete marks the buyer with the given ID as deleted. If version is not zero the buyer must still be at that version.
+// Returns an error if the buyer is not found or was modified since.
+func (r *BuyerMySQL) Delete(id int, version int) (err error) {
+	err = softDelete(r.db, "buyers", id, version)
+	switch err {
+	case nil:
+	case errRowNotFound:
+		err = internal.ErrBuyerRepositoryNotFound
+	case errRowVersion:
+		err = internal.ErrBuyerRepositoryVersionConflict
+	default:
+		err = internal.ErrBuyerRepository
+	}
+
+	return
+}
+
+// Restore receives the ID of a deleted buyer and unmarks it. Returns an error if there is no such buyer.
+func (r *BuyerMySQL) Restore(id int) (err error) {
+	ok, err := restoreDeleted(r.db, "buyers", id)
+	switch {
+	case err != nil:
+		err = internal.ErrBuyerRepository
+	case !ok:
+		err = internal.ErrBuyerRepositoryNotFound
+	}
+
+	return
+}
+
+// Purge deletes the buyer with the given ID permanently, deleted or not. If version is not zero the buyer must still be
+// at that version. Returns an error if the buyer is not found, was modified since, rows block the delete or rows depend on it and cascade is false.
+func (r *BuyerMySQL) Purge(id int, version int, cascade bool) (err error) {
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// lock the buyer and check its version
+		if err = lockRow(tx, "buyers", id, version); err != nil {
+			return
+		}
+
+		// check what depends on it, in the same transaction as the delete
+		if err = checkDependents(tx, internal.ResourceBuyers, id, cascade); err != nil {
+			return
 		}
 
+		// delete it
+		_, err = tx.Exec("DELETE FROM `buyers` WHERE `id` = ?", id)
 		return
-	}
+	})
 
-	// check if the buyer was deleted
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
+	var mysqlErr *mysql.MySQLError
+	switch {
+	case err == nil:
+	case errors.Is(err, errRowNotFound):
+		err = internal.ErrBuyerRepositoryNotFound
+	case errors.Is(err, errRowVersion):
+		err = internal.ErrBuyerRepositoryVersionConflict
+	case errors.Is(err, internal.ErrDependentsRepositoryUnconfirmed):
+	case errors.Is(err, errDependentsBlocking), errors.As(err, &mysqlErr) && mysqlErr.Number == 1451:
+		err = internal.ErrBuyerRepositoryFK
+	default:
 		err = internal.ErrBuyerRepository
-		return
 	}
 
-	// return an error if the buyer was not deleted
-	if rowsAffected == 0 {
-		err = internal.ErrBuyerRepositoryNotFound
+	return
+}
+
+// GetAnalytics returns the purchase activity of the buyer between the given days (unbounded when zero), with its top most
+// ordered products. The cancelled orders are left out and each order is worth the sale price of its product record.
+func (r *BuyerMySQL) GetAnalytics(id int, from time.Time, to time.Time, top int) (a internal.BuyerAnalytics, err error) {
+	a.BuyerID = id
+	a.From = from
+	a.To = to
+
+	// filter of the orders
+	where := "po.`buyer_id` = ? AND po.`order_status_id` <> ?"
+	args := []any{id, internal.OrderStatusCancelled}
+	if !from.IsZero() {
+		where += " AND po.`order_date` >= ?"
+		args = append(args, from)
+	}
+	if !to.IsZero() {
+		where += " AND po.`order_date` <= ?"
+		args = append(args, to)
+	}
+
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// the buyer must exist
+		var exists bool
+		if err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM `buyers` WHERE `id` = ? AND `deleted_at` IS NULL)", id).Scan(&exists); err != nil {
+			return
+		}
+		if !exists {
+			err = internal.ErrBuyerRepositoryNotFound
+			return
+		}
+
+		// totals
+		var lastOrderDate sql.NullTime
+		query := "SELECT COUNT(*), COALESCE(SUM(pr.`sale_price`), 0), MAX(po.`order_date`) " +
+			"FROM `purchase_orders` AS `po` INNER JOIN `product_records` AS `pr` ON pr.`id` = po.`product_record_id` " +
+			"WHERE " + where
+		if err = tx.QueryRow(query, args...).Scan(&a.Orders, &a.Spend, &lastOrderDate); err != nil {
+			return
+		}
+		a.LastOrderDate = lastOrderDate.Time
+
+		// top products
+		query = "SELECT p.`id`, p.`product_code`, p.`description`, COUNT(*), COALESCE(SUM(pr.`sale_price`), 0) " +
+			"FROM `purchase_orders` AS `po` INNER JOIN `product_records` AS `pr` ON pr.`id` = po.`product_record_id` " +
+			"INNER JOIN `products` AS `p` ON p.`id` = pr.`product_id` " +
+			"WHERE " + where + " " +
+			"GROUP BY p.`id`, p.`product_code`, p.`description` ORDER BY COUNT(*) DESC, SUM(pr.`sale_price`) DESC, p.`id` LIMIT ?"
+		rows, err := tx.Query(query, append(args, top)...)
+		if err != nil {
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var p internal.BuyerProduct
+			if err = rows.Scan(&p.ProductID, &p.ProductCode, &p.Description, &p.Orders, &p.Spend); err != nil {
+				return
+			}
+			a.TopProducts = append(a.TopProducts, p)
+		}
+		err = rows.Err()
+
 		return
+	})
+	if err != nil && !errors.Is(err, internal.ErrBuyerRepositoryNotFound) {
+		err = internal.ErrBuyerRepository
 	}
 
 	return
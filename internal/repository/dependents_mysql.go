@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// subqueries of the ids of the rows that belong to a resource, every ? is the id of the resource
+const (
+	sectionsOfWarehouse = "SELECT `id` FROM `sections` WHERE `warehouse_id` = ?"
+	batchesOfWarehouse  = "SELECT `id` FROM `product_batches` WHERE `section_id` IN (" + sectionsOfWarehouse + ")"
+	batchesOfSection    = "SELECT `id` FROM `product_batches` WHERE `section_id` = ?"
+	batchesOfProduct    = "SELECT `id` FROM `product_batches` WHERE `product_id` = ?"
+	recordsOfProduct    = "SELECT `id` FROM `product_records` WHERE `product_id` = ?"
+	ordersOfProduct     = "SELECT `id` FROM `purchase_orders` WHERE `product_record_id` IN (" + recordsOfProduct + ")"
+	ordersOfBuyer       = "SELECT `id` FROM `purchase_orders` WHERE `buyer_id` = ?"
+)
+
+// dependent is a kind of rows that depend on a resource: the ones of the table that match the condition,
+// where every ? is the id of the resource
+type dependent struct {
+	table  string
+	effect string
+	where  string
+}
+
+// dependents are the rows that depend on each resource following the foreign keys of the schema (docs/api_db.sql):
+// the ones deleted by cascade (also through other deleted rows), the ones whose reference is set to null and the
+// ones whose reference restricts the delete. The ledger of inventory movements has no foreign key to batches nor sections.
+var dependents = map[string][]dependent{
+	internal.ResourceWarehouses: {
+		{table: "sections", effect: internal.DependentDeleted, where: "`warehouse_id` = ?"},
+		{table: "product_batches", effect: internal.DependentDeleted, where: "`id` IN (" + batchesOfWarehouse + ")"},
+		{table: "section_temperature_readings", effect: internal.DependentDeleted, where: "`section_id` IN (" + sectionsOfWarehouse + ")"},
+		{table: "inbound_orders", effect: internal.DependentDeleted, where: "`warehouse_id` = ? OR `product_batch_id` IN (" + batchesOfWarehouse + ")"},
+		{table: "employees", effect: internal.DependentNulled, where: "`warehouse_id` = ?"},
+		{table: "employee_assignments", effect: internal.DependentBlocking, where: "`warehouse_id` = ?"},
+	},
+	internal.ResourceSections: {
+		{table: "product_batches", effect: internal.DependentDeleted, where: "`section_id` = ?"},
+		{table: "section_temperature_readings", effect: internal.DependentDeleted, where: "`section_id` = ?"},
+		{table: "inbound_orders", effect: internal.DependentDeleted, where: "`product_batch_id` IN (" + batchesOfSection + ")"},
+	},
+	internal.ResourceProductBatches: {
+		{table: "inbound_orders", effect: internal.DependentDeleted, where: "`product_batch_id` = ?"},
+	},
+	internal.ResourceProducts: {
+		{table: "product_batches", effect: internal.DependentDeleted, where: "`product_id` = ?"},
+		{table: "inbound_orders", effect: internal.DependentDeleted, where: "`product_batch_id` IN (" + batchesOfProduct + ")"},
+		{table: "product_records", effect: internal.DependentDeleted, where: "`product_id` = ?"},
+		{table: "purchase_orders", effect: internal.DependentDeleted, where: "`product_record_id` IN (" + recordsOfProduct + ")"},
+		{table: "purchase_order_status_history", effect: internal.DependentDeleted, where: "`purchase_order_id` IN (" + ordersOfProduct + ")"},
+		{table: "product_stock_thresholds", effect: internal.DependentDeleted, where: "`product_id` = ?"},
+	},
+	internal.ResourceProductTypes: {
+		{table: "sections", effect: internal.DependentBlocking, where: "`product_type_id` = ?"},
+		{table: "products", effect: internal.DependentBlocking, where: "`product_type_id` = ?"},
+	},
+	internal.ResourceSellers: {
+		{table: "products", effect: internal.DependentNulled, where: "`seller_id` = ?"},
+	},
+	internal.ResourceBuyers: {
+		{table: "purchase_orders", effect: internal.DependentDeleted, where: "`buyer_id` = ?"},
+		{table: "purchase_order_status_history", effect: internal.DependentDeleted, where: "`purchase_order_id` IN (" + ordersOfBuyer + ")"},
+	},
+	internal.ResourceEmployees: {
+		{table: "employee_assignments", effect: internal.DependentDeleted, where: "`employee_id` = ?"},
+		{table: "inbound_orders", effect: internal.DependentDeleted, where: "`employee_id` = ?"},
+		{table: "inventory_movements", effect: internal.DependentNulled, where: "`employee_id` = ?"},
+	},
+}
+
+// errDependentsBlocking is returned when rows that prevent the resource from being deleted depend on it
+var errDependentsBlocking = errors.New("repository: resource has blocking dependents")
+
+// NewDependentsMySQL creates a new instance of the dependents repository for MySQL
+func NewDependentsMySQL(db *sql.DB) *DependentsMySQL {
+	return &DependentsMySQL{
+		db: db,
+	}
+}
+
+// DependentsMySQL is the default implementation of the dependents repository for MySQL
+type DependentsMySQL struct {
+	db *sql.DB
+}
+
+// Get returns the rows that depend on the resource with the given ID (deleted or not). The rows are counted in one
+// transaction, so the counts are consistent with each other.
+func (r *DependentsMySQL) Get(resource string, id int) (d internal.Dependents, err error) {
+	kinds, ok := dependents[resource]
+	if !ok {
+		err = internal.ErrDependentsRepositoryResource
+		return
+	}
+	d.Resource = resource
+	d.ID = id
+
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// the resource must exist (the resources are named after their tables)
+		var exists bool
+		if err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM `"+resource+"` WHERE `id` = ?)", id).Scan(&exists); err != nil {
+			return
+		}
+		if !exists {
+			err = internal.ErrDependentsRepositoryNotFound
+			return
+		}
+
+		// count the rows of every kind
+		d.Items, err = countDependents(tx, kinds, id)
+		return
+	})
+	if err != nil && err != internal.ErrDependentsRepositoryNotFound {
+		err = internal.ErrDependentsRepository
+	}
+
+	return
+}
+
+// countDependents counts in the transaction the rows of every kind that depend on the resource with the given ID,
+// only the kinds with rows are returned
+func countDependents(tx *sql.Tx, kinds []dependent, id int) (items []internal.Dependent, err error) {
+	for _, kind := range kinds {
+		args := make([]any, strings.Count(kind.where, "?"))
+		for i := range args {
+			args[i] = id
+		}
+
+		var count int
+		if err = tx.QueryRow("SELECT COUNT(*) FROM `"+kind.table+"` WHERE "+kind.where, args...).Scan(&count); err != nil {
+			return
+		}
+		if count > 0 {
+			items = append(items, internal.Dependent{Kind: kind.table, Effect: kind.effect, Count: count})
+		}
+	}
+
+	return
+}
+
+// checkDependents checks in the transaction, before the resource with the given ID is deleted permanently, that no rows
+// block the delete (errDependentsBlocking) and that the cascade was confirmed if rows depend on it
+// (internal.ErrDependentsRepositoryUnconfirmed)
+func checkDependents(tx *sql.Tx, resource string, id int, cascade bool) (err error) {
+	items, err := countDependents(tx, dependents[resource], id)
+	if err != nil {
+		return
+	}
+
+	d := internal.Dependents{Resource: resource, ID: id, Items: items}
+	switch {
+	case d.Blocked():
+		err = errDependentsBlocking
+	case d.Any() && !cascade:
+		err = internal.ErrDependentsRepositoryUnconfirmed
+	}
+	return
+}
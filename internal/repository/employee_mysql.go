@@ -3,6 +3,7 @@ package repository
 import (
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/manuelfirman/go-API/internal"
@@ -20,25 +21,39 @@ type EmployeeMySQL struct {
 	db *sql.DB
 }
 
-// GetAll returns all employees. Returns an error if the operation fails.
-func (r *EmployeeMySQL) GetAll() (employees []internal.Employee, err error) {
+// GetAll returns all the employees, the deleted ones only if includeDeleted
+func (r *EmployeeMySQL) GetAll(includeDeleted bool) (employees []internal.Employee, err error) {
+	err = r.ForEach(includeDeleted, func(employee internal.Employee) error {
+		employees = append(employees, employee)
+		return nil
+	})
+	return
+}
+
+// ForEach calls fn with every employee as the rows are scanned, so the employees are never held in memory.
+// The deleted employees are only included if includeDeleted. It stops at the first error returned by fn and returns it as is.
+func (r *EmployeeMySQL) ForEach(includeDeleted bool, fn func(employee internal.Employee) error) (err error) {
 	// execute the query
-	query := "SELECT e.`id`, e.`card_number_id`, e.`first_name`, e.`last_name`, e.`warehouse_id` FROM `employees` AS `e`"
+	query := "SELECT e.`id`, e.`card_number_id`, e.`first_name`, e.`last_name`, e.`warehouse_id`, e.`version`, e.`deleted_at` FROM `employees` AS `e`" + notDeleted("e", includeDeleted) + " ORDER BY e.`id`"
 	rows, err := r.db.Query(query)
 	if err != nil {
 		return
 	}
 	defer rows.Close()
 
-	// iterate over the rows
+	// iterate over the rows and yield the employees
 	for rows.Next() {
 		var employee internal.Employee
-		err = rows.Scan(&employee.ID, &employee.CardNumberID, &employee.FirstName, &employee.LastName, &employee.WarehouseID)
+		var deletedAt sql.NullTime
+		err = rows.Scan(&employee.ID, &employee.CardNumberID, &employee.FirstName, &employee.LastName, &employee.WarehouseID, &employee.Version, &deletedAt)
 		if err != nil {
 			return
 		}
+		employee.DeletedAt = deletedAt.Time
 
-		employees = append(employees, employee)
+		if err = fn(employee); err != nil {
+			return
+		}
 	}
 
 	err = rows.Err()
@@ -52,10 +67,10 @@ func (r *EmployeeMySQL) GetAll() (employees []internal.Employee, err error) {
 // Get returns an employee by ID. Returns an error if the employee is not found.
 func (r *EmployeeMySQL) Get(id int) (e internal.Employee, err error) {
 	// execute the query
-	query := "SELECT e.`id`, e.`card_number_id`, e.`first_name`, e.`last_name`, e.`warehouse_id` FROM `employees` AS `e` WHERE e.`id` = ?"
+	query := "SELECT e.`id`, e.`card_number_id`, e.`first_name`, e.`last_name`, e.`warehouse_id`, e.`version` FROM `employees` AS `e` WHERE e.`id` = ? AND e.`deleted_at` IS NULL"
 	row := r.db.QueryRow(query, id)
 	// scan the row and return the employee
-	err = row.Scan(&e.ID, &e.CardNumberID, &e.FirstName, &e.LastName, &e.WarehouseID)
+	err = row.Scan(&e.ID, &e.CardNumberID, &e.FirstName, &e.LastName, &e.WarehouseID, &e.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -70,87 +85,360 @@ func (r *EmployeeMySQL) Get(id int) (e internal.Employee, err error) {
 	return
 }
 
-// Save receives an employee and saves it.
+// Save receives an employee and saves it, opening its assignment to its warehouse from today.
 func (r *EmployeeMySQL) Save(e *internal.Employee) (err error) {
-	// execute the query
-	query := "INSERT INTO `employees` (`card_number_id`, `first_name`, `last_name`, `warehouse_id`) VALUES (?, ?, ?, ?)"
-	result, err := r.db.Exec(query, e.CardNumberID, e.FirstName, e.LastName, e.WarehouseID)
-	if err != nil {
-		var mysqlErr *mysql.MySQLError
-		if errors.As(err, &mysqlErr) {
-			switch mysqlErr.Number {
-			case 1062:
-				err = internal.ErrEmployeeRepositoryDuplicated
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// execute the query
+		query := "INSERT INTO `employees` (`card_number_id`, `first_name`, `last_name`, `warehouse_id`) VALUES (?, ?, ?, ?)"
+		result, err := tx.Exec(query, e.CardNumberID, e.FirstName, e.LastName, e.WarehouseID)
+		if err != nil {
+			var mysqlErr *mysql.MySQLError
+			if errors.As(err, &mysqlErr) {
+				switch mysqlErr.Number {
+				case 1062:
+					err = internal.ErrEmployeeRepositoryDuplicated
+				case 1452:
+					err = internal.ErrEmployeeRepositoryWarehouseNotFound
+				default:
+					err = internal.ErrEmployeeRepository
+				}
+			}
+
+			return
+		}
+
+		// get the last inserted ID
+		id, err := result.LastInsertId()
+		if err != nil {
+			return
+		}
+
+		// the history starts at the warehouse the employee is created in
+		err = assignEmployee(tx, &internal.EmployeeAssignment{EmployeeID: int(id), WarehouseID: e.WarehouseID, StartDate: currentDate()})
+		if err != nil {
+			return
+		}
+
+		e.ID = int(id)
+		return
+	})
+
+	return
+}
+
+// Update receives an employee and updates it if its version matches the stored one. Moving the employee to another
+// warehouse is recorded in its assignments as from today. Returns an error if the operation fails.
+func (r *EmployeeMySQL) Update(e *internal.Employee) (err error) {
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// lock the employee and get the warehouse it works at
+		var warehouseID sql.NullInt64
+		row := tx.QueryRow("SELECT `warehouse_id` FROM `employees` WHERE `id` = ? AND `deleted_at` IS NULL FOR UPDATE", e.ID)
+		if err = row.Scan(&warehouseID); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				// the employee vanished since it was read
+				err = internal.ErrEmployeeRepositoryVersionConflict
 			default:
 				err = internal.ErrEmployeeRepository
 			}
+			return
+		}
+
+		// record the transfer
+		if int(warehouseID.Int64) != e.WarehouseID {
+			err = assignEmployee(tx, &internal.EmployeeAssignment{EmployeeID: e.ID, WarehouseID: e.WarehouseID, StartDate: currentDate()})
+			if err != nil {
+				return
+			}
+		}
+
+		// execute the query
+		query := "UPDATE `employees` SET `card_number_id` = ?, `first_name` = ?, `last_name` = ?, `warehouse_id` = ?, `version` = `version` + 1 WHERE `id` = ? AND `version` = ?"
+		result, err := tx.Exec(query, e.CardNumberID, e.FirstName, e.LastName, e.WarehouseID, e.ID, e.Version)
+		if err != nil {
+			var mysqlErr *mysql.MySQLError
+			if errors.As(err, &mysqlErr) {
+				switch mysqlErr.Number {
+				case 1452:
+					err = internal.ErrEmployeeRepositoryForeignKey
+				case 1062:
+					err = internal.ErrEmployeeRepositoryDuplicated
+				default:
+					err = internal.ErrEmployeeRepository
+				}
+			}
+
+			return
+		}
+
+		// the version is always bumped, so no affected rows means the employee changed since it was read
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return
+		}
+		if rows == 0 {
+			err = internal.ErrEmployeeRepositoryVersionConflict
+			return
 		}
 
+		return
+	})
+	if err != nil {
 		return
 	}
 
-	// get the last inserted ID
-	id, err := result.LastInsertId()
-	if err != nil {
+	e.Version++
+
+	return
+}
+
+// Delete marks the employee with the given ID as deleted. If version is not zero the employee must still be at that version.
+// Returns an error if the employee is not found or was modified since.
+func (r *EmployeeMySQL) Delete(id int, version int) (err error) {
+	err = softDelete(r.db, "employees", id, version)
+	switch err {
+	case nil:
+	case errRowNotFound:
+		err = internal.ErrEmployeeRepositoryNotFound
+	case errRowVersion:
+		err = internal.ErrEmployeeRepositoryVersionConflict
+	default:
+		err = internal.ErrEmployeeRepository
+	}
+
+	return
+}
+
+// Restore receives the ID of a deleted employee and unmarks it. Returns an error if the operation fails.
+func (r *EmployeeMySQL) Restore(id int) (err error) {
+	ok, err := restoreDeleted(r.db, "employees", id)
+	switch {
+	case err != nil:
+		err = internal.ErrEmployeeRepository
+	case !ok:
+		err = internal.ErrEmployeeRepositoryNotFound
+	}
+
+	return
+}
+
+// Purge deletes the employee with the given ID permanently, deleted or not. If version is not zero the employee must still be
+// at that version. Returns an error if the employee is not found, was modified since, rows block the delete or rows depend on it and cascade is false.
+func (r *EmployeeMySQL) Purge(id int, version int, cascade bool) (err error) {
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// lock the employee and check its version
+		if err = lockRow(tx, "employees", id, version); err != nil {
+			return
+		}
+
+		// check what depends on it, in the same transaction as the delete
+		if err = checkDependents(tx, internal.ResourceEmployees, id, cascade); err != nil {
+			return
+		}
+
+		// delete it
+		_, err = tx.Exec("DELETE FROM `employees` WHERE `id` = ?", id)
 		return
+	})
+
+	var mysqlErr *mysql.MySQLError
+	switch {
+	case err == nil:
+	case errors.Is(err, errRowNotFound):
+		err = internal.ErrEmployeeRepositoryNotFound
+	case errors.Is(err, errRowVersion):
+		err = internal.ErrEmployeeRepositoryVersionConflict
+	case errors.Is(err, internal.ErrDependentsRepositoryUnconfirmed):
+	case errors.Is(err, errDependentsBlocking), errors.As(err, &mysqlErr) && mysqlErr.Number == 1451:
+		err = internal.ErrEmployeeRepositoryForeignKey
+	default:
+		err = internal.ErrEmployeeRepository
 	}
 
-	e.ID = int(id)
 	return
 }
 
-// Update receives an employee and updates it. Returns an error if the operation fails.
-func (r *EmployeeMySQL) Update(e *internal.Employee) (err error) {
-	// execute the query
-	query := "UPDATE `employees` SET `card_number_id` = ?, `first_name` = ?, `last_name` = ?, `warehouse_id` = ? WHERE `id` = ?"
-	_, err = r.db.Exec(query, e.CardNumberID, e.FirstName, e.LastName, e.WarehouseID, e.ID)
-	if err != nil {
-		var mysqlErr *mysql.MySQLError
-		if errors.As(err, &mysqlErr) {
-			switch mysqlErr.Number {
-			case 1452:
-				err = internal.ErrEmployeeRepositoryForeignKey
-			case 1062:
-				err = internal.ErrEmployeeRepositoryDuplicated
+// Assign moves the employee to the warehouse of the assignment from its start date: the current assignment ends that day.
+// An assignment starting the same day as the current one replaces its warehouse.
+func (r *EmployeeMySQL) Assign(a *internal.EmployeeAssignment) (err error) {
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// lock the employee
+		var id int
+		row := tx.QueryRow("SELECT `id` FROM `employees` WHERE `id` = ? AND `deleted_at` IS NULL FOR UPDATE", a.EmployeeID)
+		if err = row.Scan(&id); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				err = internal.ErrEmployeeRepositoryNotFound
 			default:
 				err = internal.ErrEmployeeRepository
 			}
+			return
+		}
+
+		// record the transfer
+		if err = assignEmployee(tx, a); err != nil {
+			return
+		}
+
+		// the employee works at the new warehouse
+		_, err = tx.Exec("UPDATE `employees` SET `warehouse_id` = ?, `version` = `version` + 1 WHERE `id` = ?", a.WarehouseID, a.EmployeeID)
+		if err != nil {
+			err = internal.ErrEmployeeRepository
+			return
 		}
 
+		return
+	})
+
+	return
+}
+
+// GetAssignments returns the assignments of the employee, the oldest first
+func (r *EmployeeMySQL) GetAssignments(employeeID int) (assignments []internal.EmployeeAssignment, err error) {
+	// check that the employee exists
+	var id int
+	if err = r.db.QueryRow("SELECT `id` FROM `employees` WHERE `id` = ? AND `deleted_at` IS NULL", employeeID).Scan(&id); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err = internal.ErrEmployeeRepositoryNotFound
+		default:
+			err = internal.ErrEmployeeRepository
+		}
+		return
+	}
+
+	// execute the query
+	query := "SELECT `id`, `employee_id`, `warehouse_id`, `start_date`, `end_date` FROM `employee_assignments` WHERE `employee_id` = ? ORDER BY `start_date`, `id`"
+	rows, err := r.db.Query(query, employeeID)
+	if err != nil {
+		err = internal.ErrEmployeeRepository
+		return
+	}
+	defer rows.Close()
+
+	// iterate over the rows
+	for rows.Next() {
+		var a internal.EmployeeAssignment
+		var endDate sql.NullTime
+		if err = rows.Scan(&a.ID, &a.EmployeeID, &a.WarehouseID, &a.StartDate, &endDate); err != nil {
+			err = internal.ErrEmployeeRepository
+			return
+		}
+		a.EndDate = endDate.Time
+
+		assignments = append(assignments, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = internal.ErrEmployeeRepository
 		return
 	}
 
 	return
 }
 
-// Delete receives an employee ID and deletes it. Returns an error if the operation fails.
-func (r *EmployeeMySQL) Delete(id int) (err error) {
+// GetByWarehouseAt returns the employees that worked at the warehouse on the given date
+func (r *EmployeeMySQL) GetByWarehouseAt(warehouseID int, at time.Time) (employees []internal.Employee, err error) {
+	// check that the warehouse exists
+	var id int
+	if err = r.db.QueryRow("SELECT `id` FROM `warehouses` WHERE `id` = ? AND `deleted_at` IS NULL", warehouseID).Scan(&id); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err = internal.ErrEmployeeRepositoryWarehouseNotFound
+		default:
+			err = internal.ErrEmployeeRepository
+		}
+		return
+	}
+
 	// execute the query
-	query := "DELETE FROM `employees` WHERE `id` = ?"
-	result, err := r.db.Exec(query, id)
+	query := "SELECT e.`id`, e.`card_number_id`, e.`first_name`, e.`last_name`, a.`warehouse_id`, e.`version` " +
+		"FROM `employee_assignments` AS `a` INNER JOIN `employees` AS `e` ON e.`id` = a.`employee_id` " +
+		"WHERE a.`warehouse_id` = ? AND a.`start_date` <= ? AND (a.`end_date` IS NULL OR a.`end_date` > ?) AND e.`deleted_at` IS NULL ORDER BY e.`id`"
+	rows, err := r.db.Query(query, warehouseID, at, at)
 	if err != nil {
-		var mysqlErr *mysql.MySQLError
-		if errors.As(err, &mysqlErr) {
-			switch mysqlErr.Number {
-			case 1451:
-				err = internal.ErrEmployeeRepositoryForeignKey
-			default:
-				err = internal.ErrEmployeeRepository
-			}
+		err = internal.ErrEmployeeRepository
+		return
+	}
+	defer rows.Close()
+
+	// iterate over the rows
+	for rows.Next() {
+		var employee internal.Employee
+		if err = rows.Scan(&employee.ID, &employee.CardNumberID, &employee.FirstName, &employee.LastName, &employee.WarehouseID, &employee.Version); err != nil {
+			err = internal.ErrEmployeeRepository
+			return
 		}
 
+		employees = append(employees, employee)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = internal.ErrEmployeeRepository
 		return
 	}
 
-	// get the number of rows affected
-	rows, err := result.RowsAffected()
+	return
+}
+
+// assignEmployee ends the current assignment of the employee the day the given one starts and opens the given one.
+// The employee must be locked by the caller.
+func assignEmployee(tx *sql.Tx, a *internal.EmployeeAssignment) (err error) {
+	// get the current assignment
+	var current internal.EmployeeAssignment
+	row := tx.QueryRow("SELECT `id`, `warehouse_id`, `start_date` FROM `employee_assignments` WHERE `employee_id` = ? AND `end_date` IS NULL FOR UPDATE", a.EmployeeID)
+	err = row.Scan(&current.ID, &current.WarehouseID, &current.StartDate)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// first assignment
+		err = nil
+	case err != nil:
+		err = internal.ErrEmployeeRepository
+		return
+	case current.WarehouseID == a.WarehouseID:
+		err = internal.ErrEmployeeRepositoryAlreadyAssigned
+		return
+	case a.StartDate.Before(current.StartDate):
+		// the history can't be rewritten
+		err = internal.ErrEmployeeRepositoryAssignmentDate
+		return
+	case a.StartDate.Equal(current.StartDate):
+		// same day: the current assignment was a mistake, it's replaced
+		if _, err = tx.Exec("DELETE FROM `employee_assignments` WHERE `id` = ?", current.ID); err != nil {
+			err = internal.ErrEmployeeRepository
+			return
+		}
+	default:
+		if _, err = tx.Exec("UPDATE `employee_assignments` SET `end_date` = ? WHERE `id` = ?", a.StartDate, current.ID); err != nil {
+			err = internal.ErrEmployeeRepository
+			return
+		}
+	}
+
+	// open the new assignment
+	query := "INSERT INTO `employee_assignments` (`employee_id`, `warehouse_id`, `start_date`) VALUES (?, ?, ?)"
+	result, err := tx.Exec(query, a.EmployeeID, a.WarehouseID, a.StartDate)
 	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		switch {
+		case errors.As(err, &mysqlErr) && mysqlErr.Number == 1452:
+			err = internal.ErrEmployeeRepositoryWarehouseNotFound
+		default:
+			err = internal.ErrEmployeeRepository
+		}
 		return
 	}
 
-	if rows == 0 {
-		err = internal.ErrEmployeeRepositoryNotFound
+	id, err := result.LastInsertId()
+	if err != nil {
+		return
 	}
+	a.ID = int(id)
 
 	return
 }
+
+// currentDate returns the current day in UTC
+func currentDate() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/manuelfirman/go-API/platform/web/idempotency"
+)
+
+// NewIdempotencyMySQL creates a new instance of the idempotency store for MySQL
+func NewIdempotencyMySQL(db *sql.DB) *IdempotencyMySQL {
+	return &IdempotencyMySQL{
+		db: db,
+	}
+}
+
+// IdempotencyMySQL is the MySQL implementation of the idempotency store
+type IdempotencyMySQL struct {
+	db *sql.DB
+}
+
+// Get returns the unexpired record of the given key
+func (r *IdempotencyMySQL) Get(key string) (rec idempotency.Record, err error) {
+	// execute the query
+	query := "SELECT i.`idempotency_key`, i.`fingerprint`, i.`status_code`, i.`content_type`, i.`etag`, i.`body`, i.`expires_at` FROM `idempotency_keys` AS `i` WHERE i.`idempotency_key` = ? AND i.`expires_at` > ?"
+	row := r.db.QueryRow(query, key, time.Now().UTC())
+
+	// scan the row and return the record
+	err = row.Scan(&rec.Key, &rec.Fingerprint, &rec.StatusCode, &rec.ContentType, &rec.ETag, &rec.Body, &rec.ExpiresAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err = idempotency.ErrStoreNotFound
+		default:
+			err = idempotency.ErrStoreInternal
+		}
+
+		return
+	}
+
+	return
+}
+
+// Reserve saves the given pending record unless the key has an unexpired record. The primary key makes the
+// reservation atomic across the instances of the API: only one of them inserts the row.
+func (r *IdempotencyMySQL) Reserve(rec idempotency.Record) (err error) {
+	// an expired record of the key no longer holds it
+	_, err = r.db.Exec("DELETE FROM `idempotency_keys` WHERE `idempotency_key` = ? AND `expires_at` <= ?", rec.Key, time.Now().UTC())
+	if err != nil {
+		err = idempotency.ErrStoreInternal
+		return
+	}
+
+	// execute the query
+	query := "INSERT INTO `idempotency_keys` (`idempotency_key`, `fingerprint`, `status_code`, `content_type`, `etag`, `body`, `expires_at`) VALUES (?, ?, 0, '', '', '', ?)"
+	_, err = r.db.Exec(query, rec.Key, rec.Fingerprint, rec.ExpiresAt.UTC())
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		switch {
+		case errors.As(err, &mysqlErr) && mysqlErr.Number == 1062:
+			err = idempotency.ErrStoreKeyExists
+		default:
+			err = idempotency.ErrStoreInternal
+		}
+		return
+	}
+
+	return
+}
+
+// Save saves the given record, replacing the reservation or any expired record with the same key
+func (r *IdempotencyMySQL) Save(rec idempotency.Record) (err error) {
+	// empty bodies (e.g. 204) are stored as empty, not NULL
+	if rec.Body == nil {
+		rec.Body = []byte{}
+	}
+
+	// execute the query
+	query := "INSERT INTO `idempotency_keys` (`idempotency_key`, `fingerprint`, `status_code`, `content_type`, `etag`, `body`, `expires_at`) VALUES (?, ?, ?, ?, ?, ?, ?) " +
+		"ON DUPLICATE KEY UPDATE `fingerprint` = VALUES(`fingerprint`), `status_code` = VALUES(`status_code`), `content_type` = VALUES(`content_type`), `etag` = VALUES(`etag`), `body` = VALUES(`body`), `expires_at` = VALUES(`expires_at`)"
+	_, err = r.db.Exec(query, rec.Key, rec.Fingerprint, rec.StatusCode, rec.ContentType, rec.ETag, rec.Body, rec.ExpiresAt.UTC())
+	if err != nil {
+		err = idempotency.ErrStoreInternal
+		return
+	}
+
+	// purge a few expired records so the table does not grow unbounded
+	_, _ = r.db.Exec("DELETE FROM `idempotency_keys` WHERE `expires_at` <= ? LIMIT 100", time.Now().UTC())
+
+	return
+}
+
+// Release deletes the pending record of the given key
+func (r *IdempotencyMySQL) Release(key string) (err error) {
+	_, err = r.db.Exec("DELETE FROM `idempotency_keys` WHERE `idempotency_key` = ? AND `status_code` = 0", key)
+	if err != nil {
+		err = idempotency.ErrStoreInternal
+		return
+	}
+
+	return
+}
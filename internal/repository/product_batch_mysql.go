@@ -0,0 +1,786 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// productBatchColumns are the columns of a product batch, in the order they are scanned
+const productBatchColumns = "pb.`id`, pb.`batch_number`, pb.`due_date`, pb.`minimum_temperature`, pb.`current_temperature`, pb.`initial_quantity`, pb.`current_quantity`, pb.`manufacturing_date`, pb.`manufacturing_hour`, pb.`section_id`, pb.`product_id`, pb.`deleted_at`"
+
+// reasons of the movements recorded when a product batch is deleted and restored
+const (
+	batchDeletedReason  = "batch deleted"
+	batchRestoredReason = "batch restored"
+)
+
+// NewProductBatchMySQL creates a new instance of the product batch repository for MySQL
+func NewProductBatchMySQL(db *sql.DB) *ProductBatchMySQL {
+	return &ProductBatchMySQL{
+		db: db,
+	}
+}
+
+// ProductBatchMySQL is the default implementation of the product batch repository for MySQL
+type ProductBatchMySQL struct {
+	db *sql.DB
+}
+
+// GetAll returns all the product batches, the deleted ones only if includeDeleted
+func (r *ProductBatchMySQL) GetAll(includeDeleted bool) (batches []internal.ProductBatch, err error) {
+	err = r.ForEach(includeDeleted, func(pb internal.ProductBatch) error {
+		batches = append(batches, pb)
+		return nil
+	})
+	return
+}
+
+// ForEach calls fn with every product batch as the rows are scanned, so the batches are never held in memory.
+// The deleted batches are only included if includeDeleted. It stops at the first error returned by fn and returns it as is.
+func (r *ProductBatchMySQL) ForEach(includeDeleted bool, fn func(pb internal.ProductBatch) error) (err error) {
+	query := "SELECT " + productBatchColumns + " FROM `product_batches` AS `pb`" + notDeleted("pb", includeDeleted) + " ORDER BY pb.`id`"
+	err = r.forEach(query, nil, fn)
+	return
+}
+
+// AddMovement applies the movement to the quantity of its batch and the capacity of its section and records it,
+// in one transaction. The quantity of the batch must match its ledger and stay between 0 and its initial quantity.
+func (r *ProductBatchMySQL) AddMovement(m *internal.InventoryMovement) (err error) {
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// lock the batch and get its quantities
+		var sectionID, quantity, initialQuantity int
+		row := tx.QueryRow("SELECT `section_id`, `current_quantity`, `initial_quantity` FROM `product_batches` WHERE `id` = ? AND `deleted_at` IS NULL FOR UPDATE", m.ProductBatchID)
+		if err = row.Scan(&sectionID, &quantity, &initialQuantity); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = internal.ErrProductBatchRepositoryNotFound
+			}
+			return
+		}
+
+		// the new quantity must be in range
+		newQuantity := quantity + m.Quantity
+		if newQuantity < 0 || newQuantity > initialQuantity {
+			err = internal.ErrProductBatchRepositoryQuantityOutOfRange
+			return
+		}
+
+		// take or release the capacity of the section
+		if err = updateSectionCapacity(tx, map[int]int{sectionID: m.Quantity}); err != nil {
+			return
+		}
+
+		// update the quantity of the batch
+		if _, err = tx.Exec("UPDATE `product_batches` SET `current_quantity` = ? WHERE `id` = ?", newQuantity, m.ProductBatchID); err != nil {
+			return
+		}
+
+		// record the movement
+		m.SectionID = sectionID
+		movements := []internal.InventoryMovement{*m}
+		if err = recordMovements(tx, m.ProductBatchID, quantity, movements, m.Reason); err != nil {
+			return
+		}
+		*m = movements[0]
+
+		return
+	})
+	err = productBatchError(err)
+
+	return
+}
+
+// GetMovements returns the movements of the product batch, oldest first. The movements of a deleted batch are kept.
+func (r *ProductBatchMySQL) GetMovements(batchID int) (movements []internal.InventoryMovement, err error) {
+	// execute the query
+	query := "SELECT `id`, `product_batch_id`, `section_id`, `movement_type`, `quantity`, `employee_id`, `reason`, `created_at` FROM `inventory_movements` WHERE `product_batch_id` = ? ORDER BY `id`"
+	rows, err := r.db.Query(query, batchID)
+	if err != nil {
+		err = internal.ErrProductBatchRepository
+		return
+	}
+	defer rows.Close()
+
+	// iterate over the rows
+	for rows.Next() {
+		var m internal.InventoryMovement
+		var employeeID sql.NullInt64
+		if err = rows.Scan(&m.ID, &m.ProductBatchID, &m.SectionID, &m.Type, &m.Quantity, &employeeID, &m.Reason, &m.CreatedAt); err != nil {
+			err = internal.ErrProductBatchRepository
+			return
+		}
+		m.EmployeeID = int(employeeID.Int64)
+		movements = append(movements, m)
+	}
+
+	// check if there was an error during the iteration
+	if err = rows.Err(); err != nil {
+		err = internal.ErrProductBatchRepository
+		return
+	}
+
+	// a batch without movements must exist
+	if len(movements) == 0 {
+		_, err = r.Get(batchID)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// Transfer moves units of the batch to the target section in one transaction: the capacity of both sections is updated,
+// the target section must store the product type and keep its temperature, and the movement is recorded in the ledger with the employees.
+// Moving part of the units splits the batch: the units moved make up a new batch (a copy of the source one) in the target section.
+func (r *ProductBatchMySQL) Transfer(t *internal.BatchTransfer) (err error) {
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// lock the source batch
+		row := tx.QueryRow("SELECT "+productBatchColumns+" FROM `product_batches` AS `pb` WHERE pb.`id` = ? AND pb.`deleted_at` IS NULL FOR UPDATE", t.ProductBatchID)
+		pb, err := scanProductBatch(row)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = internal.ErrProductBatchRepositoryNotFound
+			}
+			return
+		}
+		switch {
+		case pb.SectionID == t.ToSectionID:
+			err = internal.ErrProductBatchRepositorySameSection
+			return
+		case t.Quantity > pb.CurrentQuantity:
+			err = internal.ErrProductBatchRepositoryQuantityOutOfRange
+			return
+		}
+		quantity := pb.CurrentQuantity
+		t.FromSectionID = pb.SectionID
+
+		// move the capacity and check the product type and temperature at the destination
+		if err = updateSectionCapacity(tx, map[int]int{t.FromSectionID: -t.Quantity, t.ToSectionID: t.Quantity}); err != nil {
+			return
+		}
+		if err = checkBatchPlacement(tx, t.ToSectionID, pb.ProductID); err != nil {
+			return
+		}
+
+		// move the whole batch or split it
+		t.Split = t.Quantity < pb.CurrentQuantity
+		if t.Split {
+			if _, err = tx.Exec("UPDATE `product_batches` SET `current_quantity` = `current_quantity` - ? WHERE `id` = ?", t.Quantity, pb.ID); err != nil {
+				return
+			}
+			target := pb
+			target.InitialQuantity = t.Quantity
+			target.CurrentQuantity = t.Quantity
+			target.SectionID = t.ToSectionID
+			if err = insertProductBatch(tx, &target); err != nil {
+				return
+			}
+			t.TargetBatchID = target.ID
+		} else {
+			if _, err = tx.Exec("UPDATE `product_batches` SET `section_id` = ? WHERE `id` = ?", t.ToSectionID, pb.ID); err != nil {
+				return
+			}
+			t.TargetBatchID = pb.ID
+		}
+
+		// record the units sent and received
+		sent := []internal.InventoryMovement{{SectionID: t.FromSectionID, Type: internal.MovementTransfer, Quantity: -t.Quantity, EmployeeID: t.SourceEmployeeID, Reason: t.Reason}}
+		received := []internal.InventoryMovement{{SectionID: t.ToSectionID, Type: internal.MovementTransfer, Quantity: t.Quantity, EmployeeID: t.TargetEmployeeID, Reason: t.Reason}}
+		if !t.Split {
+			err = recordMovements(tx, pb.ID, quantity, append(sent, received...), "transfer")
+			return
+		}
+		if err = recordMovements(tx, pb.ID, quantity, sent, "transfer"); err != nil {
+			return
+		}
+		err = recordMovements(tx, t.TargetBatchID, 0, received, "transfer")
+		return
+	})
+	err = productBatchError(err)
+
+	return
+}
+
+// GetExpiring returns the batches with units left that expire on or before until, the ones that expire first first.
+// Deleted batches, and the ones of deleted products or stored in deleted sections or warehouses, are left out.
+// If warehouseID is not 0, only the batches stored in the sections of that warehouse are returned.
+func (r *ProductBatchMySQL) GetExpiring(until time.Time, warehouseID int) (batches []internal.ProductBatch, err error) {
+	query := "SELECT " + productBatchColumns + " FROM `product_batches` AS `pb` INNER JOIN `sections` AS `s` ON s.`id` = pb.`section_id` " +
+		"INNER JOIN `warehouses` AS `w` ON w.`id` = s.`warehouse_id` INNER JOIN `products` AS `p` ON p.`id` = pb.`product_id` " +
+		"WHERE pb.`current_quantity` > 0 AND pb.`due_date` <= ? AND (? = 0 OR s.`warehouse_id` = ?) " +
+		"AND pb.`deleted_at` IS NULL AND s.`deleted_at` IS NULL AND w.`deleted_at` IS NULL AND p.`deleted_at` IS NULL ORDER BY pb.`due_date`, pb.`id`"
+	err = r.forEach(query, []any{until, warehouseID, warehouseID}, func(pb internal.ProductBatch) error {
+		batches = append(batches, pb)
+		return nil
+	})
+	return
+}
+
+// ForEachPickable calls fn with every batch of the product with units left that doesn't expire before from,
+// the ones that expire first first, leaving out the deleted batches and the ones stored in deleted sections or warehouses. It stops at the first error returned by fn and returns it as is.
+func (r *ProductBatchMySQL) ForEachPickable(productID int, from time.Time, fn func(pb internal.ProductBatch) error) (err error) {
+	// check that the product exists
+	var exists bool
+	err = r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM `products` WHERE `id` = ? AND `deleted_at` IS NULL)", productID).Scan(&exists)
+	if err != nil {
+		err = internal.ErrProductBatchRepository
+		return
+	}
+	if !exists {
+		err = internal.ErrProductBatchRepositoryProductNotFound
+		return
+	}
+
+	query := "SELECT " + productBatchColumns + " FROM `product_batches` AS `pb` INNER JOIN `sections` AS `s` ON s.`id` = pb.`section_id` " +
+		"INNER JOIN `warehouses` AS `w` ON w.`id` = s.`warehouse_id` " +
+		"WHERE pb.`product_id` = ? AND pb.`current_quantity` > 0 AND pb.`due_date` >= ? " +
+		"AND pb.`deleted_at` IS NULL AND s.`deleted_at` IS NULL AND w.`deleted_at` IS NULL ORDER BY pb.`due_date`, pb.`id`"
+	err = r.forEach(query, []any{productID, from}, fn)
+	return
+}
+
+// forEach runs the query of product batches and calls fn with every batch as the rows are scanned.
+// It stops at the first error returned by fn and returns it as is.
+func (r *ProductBatchMySQL) forEach(query string, args []any, fn func(pb internal.ProductBatch) error) (err error) {
+	// execute the query
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		err = internal.ErrProductBatchRepository
+		return
+	}
+	defer rows.Close()
+
+	// iterate over the rows
+	for rows.Next() {
+		var pb internal.ProductBatch
+		pb, err = scanProductBatch(rows)
+		if err != nil {
+			err = internal.ErrProductBatchRepository
+			return
+		}
+		if err = fn(pb); err != nil {
+			return
+		}
+	}
+
+	// check if there was an error during the iteration
+	if err = rows.Err(); err != nil {
+		err = internal.ErrProductBatchRepository
+		return
+	}
+
+	return
+}
+
+// Get returns a product batch by ID
+func (r *ProductBatchMySQL) Get(id int) (pb internal.ProductBatch, err error) {
+	// execute the query
+	query := "SELECT " + productBatchColumns + " FROM `product_batches` AS `pb` WHERE pb.`id` = ? AND pb.`deleted_at` IS NULL"
+	row := r.db.QueryRow(query, id)
+
+	// scan the row and return the product batch
+	pb, err = scanProductBatch(row)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err = internal.ErrProductBatchRepositoryNotFound
+		default:
+			err = internal.ErrProductBatchRepository
+		}
+
+		return
+	}
+
+	return
+}
+
+// Save places the product batch in its section: the section capacity is taken, its product type and temperature checked,
+// the batch inserted and its units received in the ledger in one transaction
+func (r *ProductBatchMySQL) Save(pb *internal.ProductBatch) (err error) {
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		err = placeProductBatch(tx, pb, 0, "batch received")
+		return
+	})
+	err = productBatchError(err)
+
+	return
+}
+
+// Receive places the batch brought in by the inbound order in its section and inserts the order in one transaction.
+// The employee must work at the warehouse of the order and the section must be in it; the units are received
+// in the ledger by the employee.
+func (r *ProductBatchMySQL) Receive(o *internal.InboundOrder, pb *internal.ProductBatch) (err error) {
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// the employee must work at the warehouse (locked, so it can't be moved to another one meanwhile)
+		var employeeWarehouseID sql.NullInt64
+		row := tx.QueryRow("SELECT `warehouse_id` FROM `employees` WHERE `id` = ? LOCK IN SHARE MODE", o.EmployeeID)
+		if err = row.Scan(&employeeWarehouseID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = internal.ErrProductBatchRepositoryEmployeeNotFound
+			}
+			return
+		}
+		if int(employeeWarehouseID.Int64) != o.WarehouseID {
+			err = internal.ErrProductBatchRepositoryEmployeeWarehouse
+			return
+		}
+
+		// the section must be in the warehouse
+		var sectionWarehouseID int
+		row = tx.QueryRow("SELECT `warehouse_id` FROM `sections` WHERE `id` = ?", pb.SectionID)
+		if err = row.Scan(&sectionWarehouseID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = internal.ErrProductBatchRepositorySectionNotFound
+			}
+			return
+		}
+		if sectionWarehouseID != o.WarehouseID {
+			err = internal.ErrProductBatchRepositorySectionWarehouse
+			return
+		}
+
+		// place the batch
+		if err = placeProductBatch(tx, pb, o.EmployeeID, fmt.Sprintf("inbound order %d", o.OrderNumber)); err != nil {
+			return
+		}
+
+		// insert the order
+		o.ProductBatchID = pb.ID
+		query := "INSERT INTO `inbound_orders` (`order_number`, `order_date`, `warehouse_id`, `employee_id`, `product_batch_id`) VALUES (?, ?, ?, ?, ?)"
+		result, err := tx.Exec(query, o.OrderNumber, o.OrderDate, o.WarehouseID, o.EmployeeID, o.ProductBatchID)
+		if err != nil {
+			var mysqlErr *mysql.MySQLError
+			if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+				err = internal.ErrProductBatchRepositoryOrderDuplicated
+			}
+			return
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return
+		}
+		o.ID = int(id)
+
+		return
+	})
+	err = productBatchError(err)
+
+	return
+}
+
+// Update updates the product batch: the capacity is released from the section it was in and taken from its (new) section
+// in the same transaction, so moving or consuming units keeps both sections consistent
+func (r *ProductBatchMySQL) Update(pb *internal.ProductBatch) (err error) {
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// lock the batch and get where it is now
+		sectionID, productID, quantity, err := lockProductBatch(tx, pb.ID)
+		if err != nil {
+			return
+		}
+
+		// move the capacity
+		deltas := map[int]int{sectionID: -quantity}
+		deltas[pb.SectionID] += pb.CurrentQuantity
+		if err = updateSectionCapacity(tx, deltas); err != nil {
+			return
+		}
+
+		// a batch moved to another section (or of another product) must be of the type stored there and keep its temperature
+		if sectionID != pb.SectionID || productID != pb.ProductID {
+			if err = checkBatchPlacement(tx, pb.SectionID, pb.ProductID); err != nil {
+				return
+			}
+		}
+
+		// update the batch
+		query := "UPDATE `product_batches` SET `batch_number` = ?, `due_date` = ?, `minimum_temperature` = ?, `current_temperature` = ?, `initial_quantity` = ?, `current_quantity` = ?, `manufacturing_date` = ?, `manufacturing_hour` = ?, `section_id` = ?, `product_id` = ? WHERE `id` = ?"
+		_, err = tx.Exec(query, pb.BatchNumber, pb.DueDate, pb.MinimumTemperature, pb.CurrentTemperature, pb.InitialQuantity, pb.CurrentQuantity, pb.ManufacturingDate, pb.ManufacturingHour, pb.SectionID, pb.ProductID, pb.ID)
+		if err != nil {
+			return
+		}
+
+		// record the changes of quantity in the ledger: the units moved out of the section and into the new one,
+		// and the difference with the new current quantity as an adjustment
+		var movements []internal.InventoryMovement
+		if sectionID != pb.SectionID && quantity > 0 {
+			movements = append(movements,
+				internal.InventoryMovement{SectionID: sectionID, Type: internal.MovementTransfer, Quantity: -quantity},
+				internal.InventoryMovement{SectionID: pb.SectionID, Type: internal.MovementTransfer, Quantity: quantity},
+			)
+		}
+		if pb.CurrentQuantity != quantity {
+			movements = append(movements, internal.InventoryMovement{SectionID: pb.SectionID, Type: internal.MovementAdjustment, Quantity: pb.CurrentQuantity - quantity})
+		}
+		err = recordMovements(tx, pb.ID, quantity, movements, "batch updated")
+		return
+	})
+	err = productBatchError(err)
+
+	return
+}
+
+// Delete marks the product batch as deleted: the units left are written off and their capacity released from its section
+func (r *ProductBatchMySQL) Delete(id int) (err error) {
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// lock the batch and get where it is
+		sectionID, _, quantity, err := lockProductBatch(tx, id)
+		if err != nil {
+			return
+		}
+
+		// release the capacity
+		if err = updateSectionCapacity(tx, map[int]int{sectionID: -quantity}); err != nil {
+			return
+		}
+
+		// mark the batch, without units left
+		if _, err = tx.Exec("UPDATE `product_batches` SET `current_quantity` = 0, `deleted_at` = ? WHERE `id` = ?", time.Now().UTC(), id); err != nil {
+			return
+		}
+
+		// write off the units left
+		var movements []internal.InventoryMovement
+		if quantity > 0 {
+			movements = append(movements, internal.InventoryMovement{SectionID: sectionID, Type: internal.MovementWriteOff, Quantity: -quantity})
+		}
+		err = recordMovements(tx, id, quantity, movements, batchDeletedReason)
+		return
+	})
+	err = productBatchError(err)
+
+	return
+}
+
+// Restore unmarks the deleted product batch: the units written off when it was deleted are put back in its section,
+// which must still store the product type, keep its temperature and have room for them
+func (r *ProductBatchMySQL) Restore(id int) (err error) {
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// lock the deleted batch and get where it was
+		var sectionID, productID int
+		row := tx.QueryRow("SELECT `section_id`, `product_id` FROM `product_batches` WHERE `id` = ? AND `deleted_at` IS NOT NULL FOR UPDATE", id)
+		if err = row.Scan(&sectionID, &productID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = internal.ErrProductBatchRepositoryNotFound
+			}
+			return
+		}
+
+		// a deleted batch gets no movements, so the last one (if any) wrote off the units it had
+		var quantity int
+		var movementType, reason string
+		row = tx.QueryRow("SELECT `quantity`, `movement_type`, `reason` FROM `inventory_movements` WHERE `product_batch_id` = ? ORDER BY `id` DESC LIMIT 1", id)
+		err = row.Scan(&quantity, &movementType, &reason)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err = nil
+		case err != nil:
+			return
+		}
+		restored := 0
+		if movementType == internal.MovementWriteOff && reason == batchDeletedReason {
+			restored = -quantity
+		}
+
+		// the batch goes back in its section
+		if err = checkBatchPlacement(tx, sectionID, productID); err != nil {
+			return
+		}
+		if err = updateSectionCapacity(tx, map[int]int{sectionID: restored}); err != nil {
+			return
+		}
+
+		// unmark the batch with its units
+		if _, err = tx.Exec("UPDATE `product_batches` SET `current_quantity` = ?, `deleted_at` = NULL WHERE `id` = ?", restored, id); err != nil {
+			return
+		}
+
+		// record the units put back
+		var movements []internal.InventoryMovement
+		if restored > 0 {
+			movements = append(movements, internal.InventoryMovement{SectionID: sectionID, Type: internal.MovementAdjustment, Quantity: restored})
+		}
+		err = recordMovements(tx, id, 0, movements, batchRestoredReason)
+		return
+	})
+	err = productBatchError(err)
+
+	return
+}
+
+// Purge deletes the product batch permanently, deleted or not, and releases its capacity from its section.
+// Returns an error if the batch is not found, rows block the delete or rows depend on it and cascade is false.
+func (r *ProductBatchMySQL) Purge(id int, cascade bool) (err error) {
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// lock the batch and get where it is (a deleted batch has no units left)
+		var sectionID, quantity int
+		row := tx.QueryRow("SELECT `section_id`, `current_quantity` FROM `product_batches` WHERE `id` = ? FOR UPDATE", id)
+		if err = row.Scan(&sectionID, &quantity); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = internal.ErrProductBatchRepositoryNotFound
+			}
+			return
+		}
+
+		// check what depends on it, in the same transaction as the delete
+		if err = checkDependents(tx, internal.ResourceProductBatches, id, cascade); err != nil {
+			return
+		}
+
+		// release the capacity
+		if err = updateSectionCapacity(tx, map[int]int{sectionID: -quantity}); err != nil {
+			return
+		}
+
+		// delete the batch
+		if _, err = tx.Exec("DELETE FROM `product_batches` WHERE `id` = ?", id); err != nil {
+			return
+		}
+
+		// write off the units left (the ledger is kept after the batch is deleted)
+		var movements []internal.InventoryMovement
+		if quantity > 0 {
+			movements = append(movements, internal.InventoryMovement{SectionID: sectionID, Type: internal.MovementWriteOff, Quantity: -quantity})
+		}
+		err = recordMovements(tx, id, quantity, movements, batchDeletedReason)
+		return
+	})
+	err = productBatchError(err)
+
+	return
+}
+
+// GetOutOfTemperature returns the temperatures of the stored batches (with units left, leaving out the deleted batches,
+// products, sections and warehouses) that are out of range: the batch or its section is warmer than the recommended
+// temperature of the product or colder than the minimum temperature of the batch, or the section or warehouse can't
+// get as cold as the recommended temperature.
+func (r *ProductBatchMySQL) GetOutOfTemperature() (batches []internal.BatchTemperature, err error) {
+	// execute the query
+	query := "SELECT pb.`id`, pb.`batch_number`, pb.`product_id`, pb.`section_id`, s.`warehouse_id`, p.`recom_freez_temp`, pb.`minimum_temperature`, pb.`current_temperature`, s.`current_temperature`, s.`minimum_temperature`, w.`minimum_temperature` " +
+		"FROM `product_batches` AS `pb` " +
+		"INNER JOIN `products` AS `p` ON p.`id` = pb.`product_id` " +
+		"INNER JOIN `sections` AS `s` ON s.`id` = pb.`section_id` " +
+		"INNER JOIN `warehouses` AS `w` ON w.`id` = s.`warehouse_id` " +
+		"WHERE pb.`current_quantity` > 0 AND pb.`deleted_at` IS NULL AND s.`deleted_at` IS NULL AND w.`deleted_at` IS NULL AND p.`deleted_at` IS NULL AND (" +
+		"pb.`current_temperature` > p.`recom_freez_temp` OR pb.`current_temperature` < pb.`minimum_temperature` OR " +
+		"s.`current_temperature` > p.`recom_freez_temp` OR s.`current_temperature` < pb.`minimum_temperature` OR " +
+		"s.`minimum_temperature` > p.`recom_freez_temp` OR w.`minimum_temperature` > p.`recom_freez_temp`) " +
+		"ORDER BY pb.`id`"
+	rows, err := r.db.Query(query)
+	if err != nil {
+		err = internal.ErrProductBatchRepository
+		return
+	}
+	defer rows.Close()
+
+	// iterate over the rows
+	for rows.Next() {
+		var bt internal.BatchTemperature
+		err = rows.Scan(&bt.BatchID, &bt.BatchNumber, &bt.ProductID, &bt.SectionID, &bt.WarehouseID, &bt.RecommendedTemperature, &bt.BatchMinimumTemperature, &bt.BatchCurrentTemperature, &bt.SectionCurrentTemperature, &bt.SectionMinimumTemperature, &bt.WarehouseMinimumTemperature)
+		if err != nil {
+			err = internal.ErrProductBatchRepository
+			return
+		}
+		batches = append(batches, bt)
+	}
+
+	// check if there was an error during the iteration
+	if err = rows.Err(); err != nil {
+		err = internal.ErrProductBatchRepository
+		return
+	}
+
+	return
+}
+
+// checkBatchPlacement checks in the transaction that the section stores the type of the product, that the
+// recommended temperature of the product is in the temperature range of its type and that the section and its
+// warehouse can get as cold as the recommended temperature of the product
+func checkBatchPlacement(tx *sql.Tx, sectionID int, productID int) (err error) {
+	// the type of product the section stores, its temperature range and the coldest the section and its warehouse can get
+	var sectionType int
+	var typeMinimum, typeMaximum float64
+	var sectionMinimum, warehouseMinimum float64
+	row := tx.QueryRow("SELECT s.`product_type_id`, pt.`minimum_temperature`, pt.`maximum_temperature`, s.`minimum_temperature`, w.`minimum_temperature` FROM `sections` AS `s` INNER JOIN `warehouses` AS `w` ON w.`id` = s.`warehouse_id` INNER JOIN `product_types` AS `pt` ON pt.`id` = s.`product_type_id` WHERE s.`id` = ? AND s.`deleted_at` IS NULL AND w.`deleted_at` IS NULL", sectionID)
+	if err = row.Scan(&sectionType, &typeMinimum, &typeMaximum, &sectionMinimum, &warehouseMinimum); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = internal.ErrProductBatchRepositorySectionNotFound
+		}
+		return
+	}
+
+	// the type of the product and the temperature it must be kept at
+	var productType sql.NullInt64
+	var recommended float64
+	row = tx.QueryRow("SELECT `product_type_id`, `recom_freez_temp` FROM `products` WHERE `id` = ? AND `deleted_at` IS NULL", productID)
+	if err = row.Scan(&productType, &recommended); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = internal.ErrProductBatchRepositoryProductNotFound
+		}
+		return
+	}
+
+	// a product without type can't go in any section, as every section stores a type,
+	// nor a product that must be kept out of the temperature range of its type
+	if !productType.Valid || int(productType.Int64) != sectionType || recommended < typeMinimum || recommended > typeMaximum {
+		err = internal.ErrProductBatchRepositoryProductType
+		return
+	}
+	if sectionMinimum > recommended || warehouseMinimum > recommended {
+		err = internal.ErrProductBatchRepositoryTemperature
+		return
+	}
+
+	return
+}
+
+// placeProductBatch places the product batch in its section in the transaction: the section capacity is taken,
+// the section must store the product type and keep its temperature, and the batch is inserted with its units received in the ledger
+func placeProductBatch(tx *sql.Tx, pb *internal.ProductBatch, employeeID int, reason string) (err error) {
+	// take the capacity of the section and check it can store the product
+	if err = updateSectionCapacity(tx, map[int]int{pb.SectionID: pb.CurrentQuantity}); err != nil {
+		return
+	}
+	if err = checkBatchPlacement(tx, pb.SectionID, pb.ProductID); err != nil {
+		return
+	}
+
+	// insert the batch
+	if err = insertProductBatch(tx, pb); err != nil {
+		return
+	}
+
+	// open the ledger of the batch with the units received
+	if pb.CurrentQuantity > 0 {
+		err = insertMovement(tx, &internal.InventoryMovement{ProductBatchID: pb.ID, SectionID: pb.SectionID, Type: internal.MovementReceipt, Quantity: pb.CurrentQuantity, EmployeeID: employeeID, Reason: reason})
+	}
+
+	return
+}
+
+// insertProductBatch inserts the product batch and sets its ID
+func insertProductBatch(tx *sql.Tx, pb *internal.ProductBatch) (err error) {
+	query := "INSERT INTO `product_batches` (`batch_number`, `due_date`, `minimum_temperature`, `current_temperature`, `initial_quantity`, `current_quantity`, `manufacturing_date`, `manufacturing_hour`, `section_id`, `product_id`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	result, err := tx.Exec(query, pb.BatchNumber, pb.DueDate, pb.MinimumTemperature, pb.CurrentTemperature, pb.InitialQuantity, pb.CurrentQuantity, pb.ManufacturingDate, pb.ManufacturingHour, pb.SectionID, pb.ProductID)
+	if err != nil {
+		return
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return
+	}
+	pb.ID = int(id)
+
+	return
+}
+
+// recordMovements checks that the ledger of the batch adds up to the quantity it had before the change
+// and appends the movements to it. Movements without a reason get the given one.
+func recordMovements(tx *sql.Tx, batchID int, quantity int, movements []internal.InventoryMovement, reason string) (err error) {
+	var balance int
+	row := tx.QueryRow("SELECT COALESCE(SUM(`quantity`), 0) FROM `inventory_movements` WHERE `product_batch_id` = ?", batchID)
+	if err = row.Scan(&balance); err != nil {
+		return
+	}
+	if balance != quantity {
+		err = internal.ErrProductBatchRepositoryLedgerMismatch
+		return
+	}
+
+	for i := range movements {
+		movements[i].ProductBatchID = batchID
+		if movements[i].Reason == "" {
+			movements[i].Reason = reason
+		}
+		if err = insertMovement(tx, &movements[i]); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// insertMovement appends the movement to the ledger
+func insertMovement(tx *sql.Tx, m *internal.InventoryMovement) (err error) {
+	m.CreatedAt = time.Now().UTC()
+	employeeID := sql.NullInt64{Int64: int64(m.EmployeeID), Valid: m.EmployeeID != 0}
+
+	query := "INSERT INTO `inventory_movements` (`product_batch_id`, `section_id`, `movement_type`, `quantity`, `employee_id`, `reason`, `created_at`) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	result, err := tx.Exec(query, m.ProductBatchID, m.SectionID, m.Type, m.Quantity, employeeID, m.Reason, m.CreatedAt)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1452 {
+			// the batch and section are not constrained (the ledger outlives them), so a missing reference is the employee
+			err = internal.ErrProductBatchRepositoryEmployeeNotFound
+		}
+		return
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return
+	}
+	m.ID = int(id)
+
+	return
+}
+
+// lockProductBatch locks the product batch (not deleted) in the transaction and returns its section, product and current quantity
+func lockProductBatch(tx *sql.Tx, id int) (sectionID int, productID int, quantity int, err error) {
+	row := tx.QueryRow("SELECT `section_id`, `product_id`, `current_quantity` FROM `product_batches` WHERE `id` = ? AND `deleted_at` IS NULL FOR UPDATE", id)
+	err = row.Scan(&sectionID, &productID, &quantity)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = internal.ErrProductBatchRepositoryNotFound
+	}
+	return
+}
+
+// scanProductBatch scans a product batch row
+func scanProductBatch(row interface{ Scan(dest ...any) error }) (pb internal.ProductBatch, err error) {
+	var deletedAt sql.NullTime
+	err = row.Scan(&pb.ID, &pb.BatchNumber, &pb.DueDate, &pb.MinimumTemperature, &pb.CurrentTemperature, &pb.InitialQuantity, &pb.CurrentQuantity, &pb.ManufacturingDate, &pb.ManufacturingHour, &pb.SectionID, &pb.ProductID, &deletedAt)
+	pb.DeletedAt = deletedAt.Time
+	return
+}
+
+// productBatchError maps an error of a product batch transaction to a repository error
+func productBatchError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, internal.ErrProductBatchRepositoryNotFound),
+		errors.Is(err, internal.ErrProductBatchRepositorySectionNotFound),
+		errors.Is(err, internal.ErrProductBatchRepositoryProductNotFound),
+		errors.Is(err, internal.ErrProductBatchRepositoryCapacityExceeded),
+		errors.Is(err, internal.ErrProductBatchRepositoryTemperature),
+		errors.Is(err, internal.ErrProductBatchRepositoryProductType),
+		errors.Is(err, internal.ErrProductBatchRepositoryEmployeeNotFound),
+		errors.Is(err, internal.ErrProductBatchRepositoryQuantityOutOfRange),
+		errors.Is(err, internal.ErrProductBatchRepositoryLedgerMismatch),
+		errors.Is(err, internal.ErrProductBatchRepositorySameSection),
+		errors.Is(err, internal.ErrProductBatchRepositoryEmployeeWarehouse),
+		errors.Is(err, internal.ErrProductBatchRepositorySectionWarehouse),
+		errors.Is(err, internal.ErrProductBatchRepositoryOrderDuplicated),
+		errors.Is(err, internal.ErrDependentsRepositoryUnconfirmed):
+		return err
+	case errors.Is(err, errDependentsBlocking):
+		return internal.ErrProductBatchRepositoryFK
+	}
+
+	var mysqlErr *mysql.MySQLError
+	switch {
+	case errors.As(err, &mysqlErr) && mysqlErr.Number == 1452:
+		// the section is locked before writing the batch, so a missing reference is the product
+		return internal.ErrProductBatchRepositoryProductNotFound
+	case errors.As(err, &mysqlErr) && mysqlErr.Number == 1451:
+		return internal.ErrProductBatchRepositoryFK
+	}
+
+	return internal.ErrProductBatchRepository
+}
@@ -0,0 +1,105 @@
+package repository_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/manuelfirman/go-API/internal"
+	"github.com/manuelfirman/go-API/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests for ProductBatchMySQL.Purge method
+func TestProductBatchMySQL_Purge(t *testing.T) {
+	lockBatch := regexp.QuoteMeta("SELECT `section_id`, `current_quantity` FROM `product_batches` WHERE `id` = ? FOR UPDATE")
+	countOrders := regexp.QuoteMeta("SELECT COUNT(*) FROM `inbound_orders` WHERE `product_batch_id` = ?")
+
+	t.Run("blocked when rows depend on it and the cascade is not confirmed", func(t *testing.T) {
+		// arrange
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockBatch).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"section_id", "current_quantity"}).AddRow(2, 5))
+		mock.ExpectQuery(countOrders).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectRollback()
+		rp := repository.NewProductBatchMySQL(db)
+
+		// act
+		err = rp.Purge(1, false)
+
+		// assert
+		require.ErrorIs(t, err, internal.ErrDependentsRepositoryUnconfirmed)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("deleted with what depends on it when the cascade is confirmed", func(t *testing.T) {
+		// arrange
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockBatch).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"section_id", "current_quantity"}).AddRow(2, 0))
+		mock.ExpectQuery(countOrders).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta("FROM `sections` WHERE `id` IN (?) ORDER BY `id` FOR UPDATE")).WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "current_capacity", "maximum_capacity"}).AddRow(2, 0, 10))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `product_batches` WHERE `id` = ?")).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(SUM(`quantity`), 0) FROM `inventory_movements`")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(0))
+		mock.ExpectCommit()
+		rp := repository.NewProductBatchMySQL(db)
+
+		// act
+		err = rp.Purge(1, true)
+
+		// assert
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		// arrange
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockBatch).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"section_id", "current_quantity"}))
+		mock.ExpectRollback()
+		rp := repository.NewProductBatchMySQL(db)
+
+		// act
+		err = rp.Purge(1, true)
+
+		// assert
+		require.ErrorIs(t, err, internal.ErrProductBatchRepositoryNotFound)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// Tests for ProductBatchMySQL.GetOutOfTemperature method
+func TestProductBatchMySQL_GetOutOfTemperature(t *testing.T) {
+	columns := []string{"id", "batch_number", "product_id", "section_id", "warehouse_id", "recom_freez_temp", "minimum_temperature", "current_temperature", "current_temperature", "minimum_temperature", "minimum_temperature"}
+
+	t.Run("leaves out the batches of a deleted section", func(t *testing.T) {
+		// arrange
+		// - the batch 2 is in a deleted section, so only the batch 1 meets the conditions of the query
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		mock.ExpectQuery(regexp.QuoteMeta("WHERE pb.`current_quantity` > 0 AND pb.`deleted_at` IS NULL AND s.`deleted_at` IS NULL AND w.`deleted_at` IS NULL AND p.`deleted_at` IS NULL AND (")).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 10, 1, 1, 1, -18.0, -25.0, -10.0, -10.0, -30.0, -30.0))
+		rp := repository.NewProductBatchMySQL(db)
+
+		// act
+		batches, err := rp.GetOutOfTemperature()
+
+		// assert
+		expectedBatches := []internal.BatchTemperature{
+			{BatchID: 1, BatchNumber: 10, ProductID: 1, SectionID: 1, WarehouseID: 1, RecommendedTemperature: -18, BatchMinimumTemperature: -25, BatchCurrentTemperature: -10, SectionCurrentTemperature: -10, SectionMinimumTemperature: -30, WarehouseMinimumTemperature: -30},
+		}
+		require.NoError(t, err)
+		require.Equal(t, expectedBatches, batches)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/manuelfirman/go-API/internal"
@@ -20,10 +22,10 @@ func NewProductMySQL(db *sql.DB) internal.ProductRepository {
 	}
 }
 
-// GetAll returns all products. Returns an error if the operation fails.
-func (r *repository) GetAll() (products []internal.Product, err error) {
+// GetAll returns all products, the deleted ones only if includeDeleted. Returns an error if the operation fails.
+func (r *repository) GetAll(includeDeleted bool) (products []internal.Product, err error) {
 	// set and execute the query
-	query := "SELECT * FROM products;"
+	query := "SELECT `id`, `product_code`, `description`, `height`, `length`, `width`, `weight`, `expiration_rate`, `freezing_rate`, `recom_freez_temp`, COALESCE(`product_type_id`, 0), `seller_id`, `version`, `deleted_at` FROM `products`" + notDeleted("", includeDeleted)
 	rows, err := r.db.Query(query)
 	if err != nil {
 		return
@@ -32,7 +34,9 @@ func (r *repository) GetAll() (products []internal.Product, err error) {
 	// iterate over the rows and append the products
 	for rows.Next() {
 		p := internal.Product{}
-		_ = rows.Scan(&p.ID, &p.ProductCode, &p.Description, &p.Height, &p.Length, &p.Width, &p.Weight, &p.ExpirationRate, &p.FreezingRate, &p.RecomFreezTemp, &p.ProductTypeID, &p.SellerID)
+		var deletedAt sql.NullTime
+		_ = rows.Scan(&p.ID, &p.ProductCode, &p.Description, &p.Height, &p.Length, &p.Width, &p.Weight, &p.ExpirationRate, &p.FreezingRate, &p.RecomFreezTemp, &p.ProductTypeID, &p.SellerID, &p.Version, &deletedAt)
+		p.DeletedAt = deletedAt.Time
 		products = append(products, p)
 	}
 
@@ -45,14 +49,50 @@ func (r *repository) GetAll() (products []internal.Product, err error) {
 	return
 }
 
+// ForEach calls fn with every product as the rows are scanned, so the products are never held in memory.
+// The deleted products are only included if includeDeleted. It stops at the first error returned by fn and returns it as is.
+func (r *repository) ForEach(includeDeleted bool, fn func(p internal.Product) error) (err error) {
+	// set and execute the query
+	query := "SELECT `id`, `product_code`, `description`, `height`, `length`, `width`, `weight`, `expiration_rate`, `freezing_rate`, `recom_freez_temp`, COALESCE(`product_type_id`, 0), `seller_id`, `version`, `deleted_at` FROM `products`" + notDeleted("", includeDeleted) + " ORDER BY `id`"
+	rows, err := r.db.Query(query)
+	if err != nil {
+		err = internal.ErrProductRepositoryConn
+		return
+	}
+	defer rows.Close()
+
+	// iterate over the rows and yield the products
+	for rows.Next() {
+		p := internal.Product{}
+		var deletedAt sql.NullTime
+		err = rows.Scan(&p.ID, &p.ProductCode, &p.Description, &p.Height, &p.Length, &p.Width, &p.Weight, &p.ExpirationRate, &p.FreezingRate, &p.RecomFreezTemp, &p.ProductTypeID, &p.SellerID, &p.Version, &deletedAt)
+		if err != nil {
+			err = internal.ErrProductRepositoryUnknown
+			return
+		}
+		p.DeletedAt = deletedAt.Time
+		if err = fn(p); err != nil {
+			return
+		}
+	}
+
+	// check for errors
+	if err = rows.Err(); err != nil {
+		err = internal.ErrProductRepositoryUnknown
+		return
+	}
+
+	return
+}
+
 // Get returns a product by ID. Returns an error if the product is not found.
 func (r *repository) Get(id int) (p internal.Product, err error) {
 	// set and execute the query
-	query := "SELECT * FROM products WHERE id=?;"
+	query := "SELECT `id`, `product_code`, `description`, `height`, `length`, `width`, `weight`, `expiration_rate`, `freezing_rate`, `recom_freez_temp`, COALESCE(`product_type_id`, 0), `seller_id`, `version` FROM `products` WHERE `id` = ? AND `deleted_at` IS NULL"
 	row := r.db.QueryRow(query, id)
 
 	// scan the row and return the product
-	err = row.Scan(&p.ID, &p.ProductCode, &p.Description, &p.Height, &p.Length, &p.Width, &p.Weight, &p.ExpirationRate, &p.FreezingRate, &p.RecomFreezTemp, &p.ProductTypeID, &p.SellerID)
+	err = row.Scan(&p.ID, &p.ProductCode, &p.Description, &p.Height, &p.Length, &p.Width, &p.Weight, &p.ExpirationRate, &p.FreezingRate, &p.RecomFreezTemp, &p.ProductTypeID, &p.SellerID, &p.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -70,7 +110,7 @@ func (r *repository) Get(id int) (p internal.Product, err error) {
 func (r *repository) Save(p *internal.Product) (id int, err error) {
 	// set and prepare the query
 	query := "INSERT INTO `products` (`product_code`, `description`, `height`, `length`, `width`, `weight`, `expiration_rate`, `freezing_rate`, `recom_freez_temp`, `product_type_id`, `seller_id`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
-	result, err := r.db.Exec(query, (*p).ProductCode, (*p).Description, (*p).Height, (*p).Length, (*p).Width, (*p).Weight, (*p).ExpirationRate, (*p).FreezingRate, (*p).RecomFreezTemp, (*p).ProductTypeID, (*p).SellerID)
+	result, err := r.db.Exec(query, (*p).ProductCode, (*p).Description, (*p).Height, (*p).Length, (*p).Width, (*p).Weight, (*p).ExpirationRate, (*p).FreezingRate, (*p).RecomFreezTemp, nullProductType(p.ProductTypeID), (*p).SellerID)
 
 	if err != nil {
 		var mysqlErr *mysql.MySQLError
@@ -80,7 +120,7 @@ func (r *repository) Save(p *internal.Product) (id int, err error) {
 				fmt.Println(err)
 				err = internal.ErrProductRepositoryDuplicated
 			case 1452:
-				err = internal.ErrSellerRepositoryNotFound
+				err = productFKError(mysqlErr)
 			default:
 				err = internal.ErrProductRepositoryUnknown
 			}
@@ -99,11 +139,41 @@ func (r *repository) Save(p *internal.Product) (id int, err error) {
 	return
 }
 
-// Update receives a product and updates it. Returns an error if the product is not found.
+// SaveBulk receives products and saves them in batches. If atomic, either all of them are saved or none.
+func (r *repository) SaveBulk(products []internal.Product, atomic bool) (results []internal.BulkResult, err error) {
+	// set the columns and the rows to insert
+	columns := []string{"product_code", "description", "height", "length", "width", "weight", "expiration_rate", "freezing_rate", "recom_freez_temp", "product_type_id", "seller_id"}
+	rows := make([][]any, len(products))
+	for i, p := range products {
+		rows[i] = []any{p.ProductCode, p.Description, p.Height, p.Length, p.Width, p.Weight, p.ExpirationRate, p.FreezingRate, p.RecomFreezTemp, nullProductType(p.ProductTypeID), p.SellerID}
+	}
+
+	// insert the rows
+	results, err = bulkInsert(r.db, "products", columns, rows, atomic, func(err error) error {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) {
+			switch mysqlErr.Number {
+			case 1062:
+				return internal.ErrProductRepositoryDuplicated
+			case 1452:
+				return productFKError(mysqlErr)
+			}
+		}
+		return internal.ErrProductRepositoryUnknown
+	})
+	if err != nil {
+		err = internal.ErrProductRepositoryTransaction
+		return
+	}
+
+	return
+}
+
+// Update receives a product and updates it if its version matches the stored one.
 func (r *repository) Update(p *internal.Product) (err error) {
 	// execute the query
-	query := "UPDATE `products` SET `product_code` = ?, `description` = ?, `height` = ?, `length` = ?, `width` = ?, `weight` = ?, `expiration_rate` = ?, `freezing_rate` = ?, `recom_freez_temp` = ?, `product_type_id` = ?, `seller_id` = ? WHERE `id` = ?"
-	result, err := r.db.Exec(query, (*p).ProductCode, (*p).Description, (*p).Height, (*p).Length, (*p).Width, (*p).Weight, (*p).ExpirationRate, (*p).FreezingRate, (*p).RecomFreezTemp, (*p).ProductTypeID, (*p).SellerID, (*p).ID)
+	query := "UPDATE `products` SET `product_code` = ?, `description` = ?, `height` = ?, `length` = ?, `width` = ?, `weight` = ?, `expiration_rate` = ?, `freezing_rate` = ?, `recom_freez_temp` = ?, `product_type_id` = ?, `seller_id` = ?, `version` = `version` + 1 WHERE `id` = ? AND `version` = ? AND `deleted_at` IS NULL"
+	result, err := r.db.Exec(query, (*p).ProductCode, (*p).Description, (*p).Height, (*p).Length, (*p).Width, (*p).Weight, (*p).ExpirationRate, (*p).FreezingRate, (*p).RecomFreezTemp, nullProductType(p.ProductTypeID), (*p).SellerID, (*p).ID, (*p).Version)
 
 	if err != nil {
 		var mysqlErr *mysql.MySQLError
@@ -113,7 +183,7 @@ func (r *repository) Update(p *internal.Product) (err error) {
 			case 1062:
 				err = internal.ErrProductRepositoryDuplicated
 			case 1452:
-				err = internal.ErrSellerRepositoryNotFound
+				err = productFKError(mysqlErr)
 			default:
 				err = internal.ErrProductRepositoryUnknown
 			}
@@ -126,38 +196,90 @@ func (r *repository) Update(p *internal.Product) (err error) {
 		return
 	}
 
+	// the version is always bumped, so no affected rows means the product changed (or vanished) since it was read
 	if rows == 0 {
-		err = internal.ErrProductRepositoryNothingToUpdate
+		err = internal.ErrProductRepositoryVersionConflict
 		return
 	}
 
+	(*p).Version++
+
 	return
 }
 
-// Delete receives a product ID and deletes it. Returns an error if the product is not found.
-func (r *repository) Delete(id int) (err error) {
-	// execute the query
-	result, err := r.db.Exec("DELETE FROM `products` WHERE `id` = ?", id)
-	if err != nil {
-		var mysqlErr *mysql.MySQLError
-		if errors.As(err, &mysqlErr) {
-			switch mysqlErr.Number {
-			case 1451:
-				err = internal.ErrProductRepositoryForeignKey
-			}
-		}
+// HasBatchesInOtherType returns whether the product has batches (with units left) stored in sections of another product type.
+func (r *repository) HasBatchesInOtherType(id int, productTypeID int) (has bool, err error) {
+	query := "SELECT EXISTS(SELECT 1 FROM `product_batches` AS `pb` INNER JOIN `sections` AS `s` ON s.`id` = pb.`section_id` " +
+		"WHERE pb.`product_id` = ? AND pb.`current_quantity` > 0 AND pb.`deleted_at` IS NULL AND s.`product_type_id` <> ?)"
+	if err = r.db.QueryRow(query, id, productTypeID).Scan(&has); err != nil {
+		err = internal.ErrProductRepositoryUnknown
 		return
 	}
 
-	rows, err := result.RowsAffected()
+	return
+}
 
-	if err != nil {
-		return
+// Delete marks the product with the given ID as deleted. If version is not zero the product must still be at that version.
+// Returns an error if the product is not found or was modified since.
+func (r *repository) Delete(id int, version int) (err error) {
+	err = softDelete(r.db, "products", id, version)
+	switch err {
+	case nil:
+	case errRowNotFound:
+		err = internal.ErrProductRepositoryNotFound
+	case errRowVersion:
+		err = internal.ErrProductRepositoryVersionConflict
+	default:
+		err = internal.ErrProductRepositoryUnknown
 	}
 
-	if rows == 0 {
+	return
+}
+
+// Restore receives the ID of a deleted product and unmarks it. Returns an error if there is no such product.
+func (r *repository) Restore(id int) (err error) {
+	ok, err := restoreDeleted(r.db, "products", id)
+	switch {
+	case err != nil:
+		err = internal.ErrProductRepositoryUnknown
+	case !ok:
 		err = internal.ErrProductRepositoryNotFound
+	}
+
+	return
+}
+
+// Purge deletes the product with the given ID permanently, deleted or not. If version is not zero the product must still be
+// at that version. Returns an error if the product is not found, was modified since, rows block the delete or rows depend on it and cascade is false.
+func (r *repository) Purge(id int, version int, cascade bool) (err error) {
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// lock the product and check its version
+		if err = lockRow(tx, "products", id, version); err != nil {
+			return
+		}
+
+		// check what depends on it, in the same transaction as the delete
+		if err = checkDependents(tx, internal.ResourceProducts, id, cascade); err != nil {
+			return
+		}
+
+		// delete it
+		_, err = tx.Exec("DELETE FROM `products` WHERE `id` = ?", id)
 		return
+	})
+
+	var mysqlErr *mysql.MySQLError
+	switch {
+	case err == nil:
+	case errors.Is(err, errRowNotFound):
+		err = internal.ErrProductRepositoryNotFound
+	case errors.Is(err, errRowVersion):
+		err = internal.ErrProductRepositoryVersionConflict
+	case errors.Is(err, internal.ErrDependentsRepositoryUnconfirmed):
+	case errors.Is(err, errDependentsBlocking), errors.As(err, &mysqlErr) && mysqlErr.Number == 1451:
+		err = internal.ErrProductRepositoryForeignKey
+	default:
+		err = internal.ErrProductRepositoryUnknown
 	}
 
 	return
@@ -167,3 +289,112 @@ func (r *repository) Delete(id int) (err error) {
 func (r *repository) GetRecordsByProductReport(id int) (products []internal.Product, err error) {
 	return
 }
+
+// GetRecords returns the records (prices over time) of the product with the given id, the oldest first.
+func (r *repository) GetRecords(id int) (records []internal.ProductRecord, err error) {
+	// check that the product exists
+	var productID int
+	if err = r.db.QueryRow("SELECT `id` FROM `products` WHERE `id` = ? AND `deleted_at` IS NULL", id).Scan(&productID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err = internal.ErrProductRepositoryNotFound
+		default:
+			err = internal.ErrProductRepositoryUnknown
+		}
+		return
+	}
+
+	// set and execute the query
+	query := "SELECT `id`, `last_update_date`, `purchase_price`, `sale_price`, `product_id` FROM `product_records` WHERE `product_id` = ? ORDER BY `last_update_date`, `id`"
+	rows, err := r.db.Query(query, id)
+	if err != nil {
+		err = internal.ErrProductRepositoryUnknown
+		return
+	}
+	defer rows.Close()
+
+	// iterate over the rows and append the records
+	for rows.Next() {
+		var pr internal.ProductRecord
+		var salePrice sql.NullFloat64
+		if err = rows.Scan(&pr.ID, &pr.LastUpdateDate, &pr.PurchasePrice, &salePrice, &pr.ProductID); err != nil {
+			err = internal.ErrProductRepositoryUnknown
+			return
+		}
+		pr.SalePrice = salePrice.Float64
+		records = append(records, pr)
+	}
+
+	// check for errors
+	if err = rows.Err(); err != nil {
+		err = internal.ErrProductRepositoryUnknown
+		return
+	}
+
+	return
+}
+
+// GetMargins returns the margins of the products for sale at the given moment, of the given seller (all when zero),
+// ordered by seller and product. Only the prices of the record in effect at that moment are taken.
+func (r *repository) GetMargins(sellerID int, at time.Time) (margins []internal.ProductMargin, err error) {
+	// check that the seller exists
+	if sellerID != 0 {
+		var id int
+		if err = r.db.QueryRow("SELECT `id` FROM `sellers` WHERE `id` = ? AND `deleted_at` IS NULL", sellerID).Scan(&id); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				err = internal.ErrSellerRepositoryNotFound
+			default:
+				err = internal.ErrProductRepositoryUnknown
+			}
+			return
+		}
+	}
+
+	// set and execute the query
+	query := "SELECT p.`id`, p.`product_code`, p.`description`, p.`seller_id`, pr.`id`, pr.`last_update_date`, pr.`purchase_price`, pr.`sale_price` " +
+		"FROM `products` AS `p` INNER JOIN `product_records` AS `pr` ON pr.`product_id` = p.`id` " +
+		"WHERE pr.`id` = (SELECT r.`id` FROM `product_records` AS `r` WHERE r.`product_id` = p.`id` AND r.`last_update_date` <= ? ORDER BY r.`last_update_date` DESC, r.`id` DESC LIMIT 1) " +
+		"AND pr.`sale_price` IS NOT NULL AND p.`deleted_at` IS NULL AND (? = 0 OR p.`seller_id` = ?) " +
+		"ORDER BY p.`seller_id`, p.`id`"
+	rows, err := r.db.Query(query, at, sellerID, sellerID)
+	if err != nil {
+		err = internal.ErrProductRepositoryUnknown
+		return
+	}
+	defer rows.Close()
+
+	// iterate over the rows and append the margins
+	for rows.Next() {
+		var m internal.ProductMargin
+		var productSellerID sql.NullInt64
+		if err = rows.Scan(&m.ProductID, &m.ProductCode, &m.Description, &productSellerID, &m.Record.ID, &m.Record.LastUpdateDate, &m.Record.PurchasePrice, &m.Record.SalePrice); err != nil {
+			err = internal.ErrProductRepositoryUnknown
+			return
+		}
+		m.SellerID = int(productSellerID.Int64)
+		m.Record.ProductID = m.ProductID
+		margins = append(margins, m)
+	}
+
+	// check for errors
+	if err = rows.Err(); err != nil {
+		err = internal.ErrProductRepositoryUnknown
+		return
+	}
+
+	return
+}
+
+// nullProductType returns the product type of a product to store, NULL when it has none (zero)
+func nullProductType(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
+
+// productFKError returns the error of the missing reference of a product: its seller or its product type
+func productFKError(mysqlErr *mysql.MySQLError) error {
+	if strings.Contains(mysqlErr.Message, "fk_products_product_type_id") {
+		return internal.ErrProductTypeRepositoryNotFound
+	}
+	return internal.ErrSellerRepositoryNotFound
+}
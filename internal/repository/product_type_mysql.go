@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// productTypeColumns are the columns of a product type, in the order they are scanned
+const productTypeColumns = "`id`, `name`, `storage_class`, `minimum_temperature`, `maximum_temperature`, `version`, `deleted_at`"
+
+// NewProductTypeMySQL creates a new instance of the product type repository for MySQL
+func NewProductTypeMySQL(db *sql.DB) *ProductTypeMySQL {
+	return &ProductTypeMySQL{
+		db: db,
+	}
+}
+
+// ProductTypeMySQL is the MySQL implementation of the product type repository
+type ProductTypeMySQL struct {
+	db *sql.DB
+}
+
+// GetAll returns all product types, the deleted ones only if includeDeleted. Returns an error if the operation fails.
+func (r *ProductTypeMySQL) GetAll(includeDeleted bool) (types []internal.ProductType, err error) {
+	rows, err := r.db.Query("SELECT " + productTypeColumns + " FROM `product_types`" + notDeleted("", includeDeleted) + " ORDER BY `id`")
+	if err != nil {
+		err = internal.ErrProductTypeRepository
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pt internal.ProductType
+		if err = scanProductType(rows, &pt); err != nil {
+			err = internal.ErrProductTypeRepository
+			return
+		}
+		types = append(types, pt)
+	}
+	if err = rows.Err(); err != nil {
+		err = internal.ErrProductTypeRepository
+		return
+	}
+
+	return
+}
+
+// Get returns a product type by ID. Returns an error if the product type is not found.
+func (r *ProductTypeMySQL) Get(id int) (pt internal.ProductType, err error) {
+	row := r.db.QueryRow("SELECT "+productTypeColumns+" FROM `product_types` WHERE `id` = ? AND `deleted_at` IS NULL", id)
+	if err = scanProductType(row, &pt); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err = internal.ErrProductTypeRepositoryNotFound
+		default:
+			err = internal.ErrProductTypeRepository
+		}
+		return
+	}
+
+	return
+}
+
+// Save saves the given product type. Returns an error if a product type with the same name already exists.
+func (r *ProductTypeMySQL) Save(pt *internal.ProductType) (err error) {
+	query := "INSERT INTO `product_types` (`name`, `storage_class`, `minimum_temperature`, `maximum_temperature`) VALUES (?, ?, ?, ?)"
+	result, err := r.db.Exec(query, pt.Name, pt.StorageClass, pt.MinimumTemperature, pt.MaximumTemperature)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		switch {
+		case errors.As(err, &mysqlErr) && mysqlErr.Number == 1062:
+			err = internal.ErrProductTypeRepositoryDuplicated
+		default:
+			err = internal.ErrProductTypeRepository
+		}
+		return
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		err = internal.ErrProductTypeRepository
+		return
+	}
+	pt.ID = int(id)
+	pt.Version = 1
+
+	return
+}
+
+// Update updates the given product type if its version matches the stored one
+func (r *ProductTypeMySQL) Update(pt *internal.ProductType) (err error) {
+	query := "UPDATE `product_types` SET `name` = ?, `storage_class` = ?, `minimum_temperature` = ?, `maximum_temperature` = ?, `version` = `version` + 1 WHERE `id` = ? AND `version` = ? AND `deleted_at` IS NULL"
+	result, err := r.db.Exec(query, pt.Name, pt.StorageClass, pt.MinimumTemperature, pt.MaximumTemperature, pt.ID, pt.Version)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		switch {
+		case errors.As(err, &mysqlErr) && mysqlErr.Number == 1062:
+			err = internal.ErrProductTypeRepositoryDuplicated
+		default:
+			err = internal.ErrProductTypeRepository
+		}
+		return
+	}
+
+	// the version is always bumped, so no affected rows means the product type changed (or vanished) since it was read
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		err = internal.ErrProductTypeRepository
+		return
+	}
+	if rowsAffected == 0 {
+		err = internal.ErrProductTypeRepositoryVersionConflict
+		return
+	}
+	pt.Version++
+
+	return
+}
+
+// Delete marks the product type with the given ID as deleted. If version is not zero the product type must still be at that version.
+// Returns an error if the product type is not found or was modified since.
+func (r *ProductTypeMySQL) Delete(id int, version int) (err error) {
+	err = softDelete(r.db, "product_types", id, version)
+	switch err {
+	case nil:
+	case errRowNotFound:
+		err = internal.ErrProductTypeRepositoryNotFound
+	case errRowVersion:
+		err = internal.ErrProductTypeRepositoryVersionConflict
+	default:
+		err = internal.ErrProductTypeRepository
+	}
+
+	return
+}
+
+// Restore unmarks the deleted product type with the given ID. Returns an error if there is no such product type.
+func (r *ProductTypeMySQL) Restore(id int) (err error) {
+	ok, err := restoreDeleted(r.db, "product_types", id)
+	switch {
+	case err != nil:
+		err = internal.ErrProductTypeRepository
+	case !ok:
+		err = internal.ErrProductTypeRepositoryNotFound
+	}
+
+	return
+}
+
+// Purge deletes the product type with the given ID permanently, deleted or not. If version is not zero the product type must still be
+// at that version. Returns an error if the product type is not found, was modified since, rows block the delete or rows depend on it and cascade is false.
+func (r *ProductTypeMySQL) Purge(id int, version int, cascade bool) (err error) {
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// lock the product type and check its version
+		if err = lockRow(tx, "product_types", id, version); err != nil {
+			return
+		}
+
+		// check what depends on it, in the same transaction as the delete
+		if err = checkDependents(tx, internal.ResourceProductTypes, id, cascade); err != nil {
+			return
+		}
+
+		// delete it
+		_, err = tx.Exec("DELETE FROM `product_types` WHERE `id` = ?", id)
+		return
+	})
+
+	var mysqlErr *mysql.MySQLError
+	switch {
+	case err == nil:
+	case errors.Is(err, errRowNotFound):
+		err = internal.ErrProductTypeRepositoryNotFound
+	case errors.Is(err, errRowVersion):
+		err = internal.ErrProductTypeRepositoryVersionConflict
+	case errors.Is(err, internal.ErrDependentsRepositoryUnconfirmed):
+	case errors.Is(err, errDependentsBlocking), errors.As(err, &mysqlErr) && mysqlErr.Number == 1451:
+		err = internal.ErrProductTypeRepositoryFK
+	default:
+		err = internal.ErrProductTypeRepository
+	}
+
+	return
+}
+
+// scanProductType scans a product type row
+func scanProductType(row interface{ Scan(dest ...any) error }, pt *internal.ProductType) (err error) {
+	var deletedAt sql.NullTime
+	err = row.Scan(&pt.ID, &pt.Name, &pt.StorageClass, &pt.MinimumTemperature, &pt.MaximumTemperature, &pt.Version, &deletedAt)
+	pt.DeletedAt = deletedAt.Time
+	return
+}
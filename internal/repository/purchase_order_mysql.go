@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// purchaseOrderColumns are the columns of a purchase order, in the order they are scanned
+const purchaseOrderColumns = "`id`, `order_number`, `order_date`, `tracking_code`, `buyer_id`, `product_record_id`, `order_status_id`, `carrier_id`"
+
+// NewPurchaseOrderMySQL creates a new instance of the purchase order repository for MySQL
+func NewPurchaseOrderMySQL(db *sql.DB) *PurchaseOrderMySQL {
+	return &PurchaseOrderMySQL{
+		db: db,
+	}
+}
+
+// PurchaseOrderMySQL is the default implementation of the purchase order repository for MySQL
+type PurchaseOrderMySQL struct {
+	db *sql.DB
+}
+
+// GetAll returns all the purchase orders
+func (r *PurchaseOrderMySQL) GetAll() (orders []internal.PurchaseOrder, err error) {
+	// execute the query
+	rows, err := r.db.Query("SELECT " + purchaseOrderColumns + " FROM `purchase_orders` ORDER BY `id`")
+	if err != nil {
+		err = internal.ErrPurchaseOrderRepository
+		return
+	}
+	defer rows.Close()
+
+	// iterate over the rows
+	for rows.Next() {
+		var po internal.PurchaseOrder
+		if po, err = scanPurchaseOrder(rows); err != nil {
+			err = internal.ErrPurchaseOrderRepository
+			return
+		}
+		orders = append(orders, po)
+	}
+
+	// check if there was an error during the iteration
+	if err = rows.Err(); err != nil {
+		err = internal.ErrPurchaseOrderRepository
+		return
+	}
+
+	return
+}
+
+// Get returns a purchase order by ID
+func (r *PurchaseOrderMySQL) Get(id int) (po internal.PurchaseOrder, err error) {
+	row := r.db.QueryRow("SELECT "+purchaseOrderColumns+" FROM `purchase_orders` WHERE `id` = ?", id)
+	po, err = scanPurchaseOrder(row)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err = internal.ErrPurchaseOrderRepositoryNotFound
+		default:
+			err = internal.ErrPurchaseOrderRepository
+		}
+		return
+	}
+
+	return
+}
+
+// Save saves the purchase order and the first entry of its status history in one transaction
+func (r *PurchaseOrderMySQL) Save(po *internal.PurchaseOrder) (err error) {
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// insert the order
+		query := "INSERT INTO `purchase_orders` (`order_number`, `order_date`, `tracking_code`, `buyer_id`, `product_record_id`, `order_status_id`) VALUES (?, ?, ?, ?, ?, ?)"
+		result, err := tx.Exec(query, po.OrderNumber, po.OrderDate, po.TrackingCode, po.BuyerID, po.ProductRecordID, po.Status)
+		if err != nil {
+			return
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return
+		}
+		po.ID = int(id)
+
+		// open its history
+		err = insertOrderStatusChange(tx, &internal.OrderStatusChange{PurchaseOrderID: po.ID, To: po.Status})
+		return
+	})
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		switch {
+		case errors.As(err, &mysqlErr) && mysqlErr.Number == 1062:
+			err = purchaseOrderDuplicatedError(mysqlErr)
+		case errors.As(err, &mysqlErr) && mysqlErr.Number == 1452:
+			err = internal.ErrPurchaseOrderRepositoryFK
+		default:
+			err = internal.ErrPurchaseOrderRepository
+		}
+		return
+	}
+
+	return
+}
+
+// UpdateStatus changes the status of the purchase order if it is still change.From and records the change, in one transaction
+func (r *PurchaseOrderMySQL) UpdateStatus(change *internal.OrderStatusChange) (err error) {
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// change the status only if nobody changed it since it was read
+		result, err := tx.Exec("UPDATE `purchase_orders` SET `order_status_id` = ? WHERE `id` = ? AND `order_status_id` = ?", change.To, change.PurchaseOrderID, change.From)
+		if err != nil {
+			return
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return
+		}
+		if rowsAffected == 0 {
+			var exists bool
+			if err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM `purchase_orders` WHERE `id` = ?)", change.PurchaseOrderID).Scan(&exists); err != nil {
+				return
+			}
+			err = internal.ErrPurchaseOrderRepositoryStatusConflict
+			if !exists {
+				err = internal.ErrPurchaseOrderRepositoryNotFound
+			}
+			return
+		}
+
+		// record the change
+		err = insertOrderStatusChange(tx, change)
+		return
+	})
+	if err != nil && !errors.Is(err, internal.ErrPurchaseOrderRepositoryNotFound) && !errors.Is(err, internal.ErrPurchaseOrderRepositoryStatusConflict) {
+		err = internal.ErrPurchaseOrderRepository
+	}
+
+	return
+}
+
+// GetHistory returns the status changes of the purchase order, oldest first
+func (r *PurchaseOrderMySQL) GetHistory(id int) (changes []internal.OrderStatusChange, err error) {
+	// the order must exist
+	if _, err = r.Get(id); err != nil {
+		return
+	}
+
+	// execute the query
+	query := "SELECT `id`, `purchase_order_id`, `from_status_id`, `to_status_id`, `changed_at` FROM `purchase_order_status_history` WHERE `purchase_order_id` = ? ORDER BY `id`"
+	rows, err := r.db.Query(query, id)
+	if err != nil {
+		err = internal.ErrPurchaseOrderRepository
+		return
+	}
+	defer rows.Close()
+
+	// iterate over the rows
+	for rows.Next() {
+		var change internal.OrderStatusChange
+		var from sql.NullInt64
+		if err = rows.Scan(&change.ID, &change.PurchaseOrderID, &from, &change.To, &change.ChangedAt); err != nil {
+			err = internal.ErrPurchaseOrderRepository
+			return
+		}
+		change.From = internal.OrderStatus(from.Int64)
+		changes = append(changes, change)
+	}
+
+	// check if there was an error during the iteration
+	if err = rows.Err(); err != nil {
+		err = internal.ErrPurchaseOrderRepository
+		return
+	}
+
+	return
+}
+
+// GetByTrackingCode returns the purchase order with the given tracking code
+func (r *PurchaseOrderMySQL) GetByTrackingCode(code string) (po internal.PurchaseOrder, err error) {
+	row := r.db.QueryRow("SELECT "+purchaseOrderColumns+" FROM `purchase_orders` WHERE `tracking_code` = ?", code)
+	po, err = scanPurchaseOrder(row)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err = internal.ErrPurchaseOrderRepositoryNotFound
+		default:
+			err = internal.ErrPurchaseOrderRepository
+		}
+		return
+	}
+
+	return
+}
+
+// GetCarrier returns the carrier with the given ID
+func (r *PurchaseOrderMySQL) GetCarrier(id int) (c internal.Carrier, err error) {
+	row := r.db.QueryRow("SELECT `id`, `cid`, `company_name`, `address`, `telephone`, `locality_id` FROM `carries` WHERE `id` = ?", id)
+	err = row.Scan(&c.ID, &c.CID, &c.CompanyName, &c.Address, &c.Telephone, &c.LocalityID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err = internal.ErrPurchaseOrderRepositoryCarrierNotFound
+		default:
+			err = internal.ErrPurchaseOrderRepository
+		}
+		return
+	}
+
+	return
+}
+
+// SuggestCarrier returns the carrier with the fewest orders in transit (pending to shipped), preferring the ones
+// in the locality of the warehouse. With warehouseID 0 (or a warehouse without locality) any carrier is preferred alike.
+func (r *PurchaseOrderMySQL) SuggestCarrier(warehouseID int) (c internal.Carrier, err error) {
+	query := "SELECT c.`id`, c.`cid`, c.`company_name`, c.`address`, c.`telephone`, c.`locality_id` FROM `carries` AS `c` " +
+		"LEFT JOIN `purchase_orders` AS `po` ON po.`carrier_id` = c.`id` AND po.`order_status_id` IN (?, ?, ?, ?) " +
+		"GROUP BY c.`id` " +
+		"ORDER BY COALESCE(c.`locality_id` = (SELECT w.`locality_id` FROM `warehouses` AS `w` WHERE w.`id` = ?), 0) DESC, COUNT(po.`id`), c.`id` " +
+		"LIMIT 1"
+	row := r.db.QueryRow(query, internal.OrderStatusPending, internal.OrderStatusConfirmed, internal.OrderStatusPicking, internal.OrderStatusShipped, warehouseID)
+	err = row.Scan(&c.ID, &c.CID, &c.CompanyName, &c.Address, &c.Telephone, &c.LocalityID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err = internal.ErrPurchaseOrderRepositoryCarrierNotFound
+		default:
+			err = internal.ErrPurchaseOrderRepository
+		}
+		return
+	}
+
+	return
+}
+
+// GetShippingWarehouse returns the warehouse holding the most units of the product of the record (0 if none holds it)
+func (r *PurchaseOrderMySQL) GetShippingWarehouse(productRecordID int) (warehouseID int, err error) {
+	query := "SELECT s.`warehouse_id` FROM `product_records` AS `pr` " +
+		"INNER JOIN `product_batches` AS `pb` ON pb.`product_id` = pr.`product_id` " +
+		"INNER JOIN `sections` AS `s` ON s.`id` = pb.`section_id` " +
+		"WHERE pr.`id` = ? AND pb.`current_quantity` > 0 " +
+		"GROUP BY s.`warehouse_id` ORDER BY SUM(pb.`current_quantity`) DESC, s.`warehouse_id` LIMIT 1"
+	err = r.db.QueryRow(query, productRecordID).Scan(&warehouseID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err = nil
+		default:
+			err = internal.ErrPurchaseOrderRepository
+		}
+		return
+	}
+
+	return
+}
+
+// AssignShipment sets the carrier and tracking code of the purchase order if its status is still the given one
+func (r *PurchaseOrderMySQL) AssignShipment(id int, status internal.OrderStatus, carrierID int, trackingCode string) (err error) {
+	result, err := r.db.Exec("UPDATE `purchase_orders` SET `carrier_id` = ?, `tracking_code` = ? WHERE `id` = ? AND `order_status_id` = ?", carrierID, trackingCode, id, status)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		switch {
+		case errors.As(err, &mysqlErr) && mysqlErr.Number == 1062:
+			err = internal.ErrPurchaseOrderRepositoryTrackingCodeDuplicated
+		case errors.As(err, &mysqlErr) && mysqlErr.Number == 1452:
+			err = internal.ErrPurchaseOrderRepositoryCarrierNotFound
+		default:
+			err = internal.ErrPurchaseOrderRepository
+		}
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		err = internal.ErrPurchaseOrderRepository
+		return
+	}
+	if rowsAffected == 0 {
+		// the order is gone or its status changed
+		if _, err = r.Get(id); err == nil {
+			err = internal.ErrPurchaseOrderRepositoryStatusConflict
+		}
+		return
+	}
+
+	return
+}
+
+// insertOrderStatusChange appends the change to the status history of its purchase order
+func insertOrderStatusChange(tx *sql.Tx, change *internal.OrderStatusChange) (err error) {
+	change.ChangedAt = time.Now().UTC()
+	from := sql.NullInt64{Int64: int64(change.From), Valid: change.From != 0}
+
+	query := "INSERT INTO `purchase_order_status_history` (`purchase_order_id`, `from_status_id`, `to_status_id`, `changed_at`) VALUES (?, ?, ?, ?)"
+	result, err := tx.Exec(query, change.PurchaseOrderID, from, change.To, change.ChangedAt)
+	if err != nil {
+		return
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return
+	}
+	change.ID = int(id)
+
+	return
+}
+
+// scanPurchaseOrder scans a purchase order row
+func scanPurchaseOrder(row interface{ Scan(dest ...any) error }) (po internal.PurchaseOrder, err error) {
+	var carrierID sql.NullInt64
+	err = row.Scan(&po.ID, &po.OrderNumber, &po.OrderDate, &po.TrackingCode, &po.BuyerID, &po.ProductRecordID, &po.Status, &carrierID)
+	po.CarrierID = int(carrierID.Int64)
+	return
+}
+
+// purchaseOrderDuplicatedError returns the error of a duplicate key of a purchase order: its order number or its tracking code
+func purchaseOrderDuplicatedError(mysqlErr *mysql.MySQLError) error {
+	if strings.Contains(mysqlErr.Message, "idx_purchase_orders_tracking_code") {
+		return internal.ErrPurchaseOrderRepositoryTrackingCodeDuplicated
+	}
+	return internal.ErrPurchaseOrderRepositoryDuplicated
+}
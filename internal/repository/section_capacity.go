@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// updateSectionCapacity adds to the current capacity of each section its delta (units placed if positive, released if negative).
+// The sections are locked in id order (so concurrent transactions don't deadlock) and the whole change fails
+// if a section would exceed its maximum capacity. It must run inside the transaction that places or removes the units.
+func updateSectionCapacity(tx *sql.Tx, deltas map[int]int) (err error) {
+	if len(deltas) == 0 {
+		return
+	}
+
+	// lock the sections
+	ids := make([]int, 0, len(deltas))
+	args := make([]any, 0, len(deltas))
+	for id := range deltas {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	query := "SELECT `id`, `current_capacity`, `maximum_capacity` FROM `sections` WHERE `id` IN (?" + strings.Repeat(", ?", len(ids)-1) + ") ORDER BY `id` FOR UPDATE"
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	// check the capacity of every section
+	found := 0
+	for rows.Next() {
+		var id, current, maximum int
+		if err = rows.Scan(&id, &current, &maximum); err != nil {
+			return
+		}
+		found++
+
+		// releasing units is always allowed, placing them must fit in the section
+		if delta := deltas[id]; delta > 0 && current+delta > maximum {
+			err = internal.ErrProductBatchRepositoryCapacityExceeded
+			return
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+	rows.Close()
+	if found != len(ids) {
+		err = internal.ErrProductBatchRepositorySectionNotFound
+		return
+	}
+
+	// update the capacity (bumping the version, so stale copies of the section can't overwrite it)
+	for _, id := range ids {
+		if deltas[id] == 0 {
+			continue
+		}
+		_, err = tx.Exec("UPDATE `sections` SET `current_capacity` = GREATEST(`current_capacity` + ?, 0), `version` = `version` + 1 WHERE `id` = ?", deltas[id], id)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"database/sql"
 	"errors"
+	"strings"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/manuelfirman/go-API/internal"
@@ -20,24 +21,38 @@ type SectionMySQL struct {
 	db *sql.DB
 }
 
-// GetAll returns all the sections
-func (r *SectionMySQL) GetAll() (sections []internal.Section, err error) {
+// GetAll returns all the sections, the deleted ones only if includeDeleted
+func (r *SectionMySQL) GetAll(includeDeleted bool) (sections []internal.Section, err error) {
+	err = r.ForEach(includeDeleted, func(section internal.Section) error {
+		sections = append(sections, section)
+		return nil
+	})
+	return
+}
+
+// ForEach calls fn with every section as the rows are scanned, so the sections are never held in memory.
+// The deleted sections are only included if includeDeleted. It stops at the first error returned by fn and returns it as is.
+func (r *SectionMySQL) ForEach(includeDeleted bool, fn func(section internal.Section) error) (err error) {
 	// execute the query
-	query := "SELECT `s.id`, `s.section_number`, `s.current_temperature`, `s.minimum_temperature`, `s.current_capacity`, `s.minimum_capacity`, `s.maximum_capacity`, `s.warehouse_id`, `s.product_type_id` FROM `sections` AS `s`"
+	query := "SELECT s.`id`, s.`section_number`, s.`current_temperature`, s.`minimum_temperature`, s.`current_capacity`, s.`minimum_capacity`, s.`maximum_capacity`, s.`warehouse_id`, s.`product_type_id`, s.`version`, s.`deleted_at` FROM `sections` AS `s`" + notDeleted("s", includeDeleted) + " ORDER BY s.`id`"
 	rows, err := r.db.Query(query)
 	if err != nil {
 		return
 	}
 	defer rows.Close()
-	// iterate over the rows
+	// iterate over the rows and yield the sections
 	for rows.Next() {
 		var section internal.Section
-		err = rows.Scan(&section.ID, &section.SectionNumber, &section.CurrentTemperature, &section.MinimumTemperature, &section.CurrentCapacity, &section.MinimumCapacity, &section.MaximumCapacity, &section.WarehouseID, &section.ProductTypeID)
+		var deletedAt sql.NullTime
+		err = rows.Scan(&section.ID, &section.SectionNumber, &section.CurrentTemperature, &section.MinimumTemperature, &section.CurrentCapacity, &section.MinimumCapacity, &section.MaximumCapacity, &section.WarehouseID, &section.ProductTypeID, &section.Version, &deletedAt)
 		if err != nil {
 			return
 		}
+		section.DeletedAt = deletedAt.Time
 
-		sections = append(sections, section)
+		if err = fn(section); err != nil {
+			return
+		}
 	}
 	// check if there was an error during the iteration
 	err = rows.Err()
@@ -51,11 +66,11 @@ func (r *SectionMySQL) GetAll() (sections []internal.Section, err error) {
 // Get returns a section by ID
 func (r *SectionMySQL) Get(id int) (section internal.Section, err error) {
 	// execute the query
-	query := "SELECT `s.id`, `s.section_number`, `s.current_temperature`, `s.minimum_temperature`, `s.current_capacity`, `s.minimum_capacity`, `s.maximum_capacity`, `s.warehouse_id`, `s.product_type_id` FROM `sections` AS `s` WHERE `s.id` = ?"
+	query := "SELECT s.`id`, s.`section_number`, s.`current_temperature`, s.`minimum_temperature`, s.`current_capacity`, s.`minimum_capacity`, s.`maximum_capacity`, s.`warehouse_id`, s.`product_type_id`, s.`version` FROM `sections` AS `s` WHERE s.`id` = ? AND s.`deleted_at` IS NULL"
 	row := r.db.QueryRow(query, id)
 
 	// scan the row and return the section
-	err = row.Scan(&section.ID, &section.SectionNumber, &section.CurrentTemperature, &section.MinimumTemperature, &section.CurrentCapacity, &section.MinimumCapacity, &section.MaximumCapacity, &section.WarehouseID, &section.ProductTypeID)
+	err = row.Scan(&section.ID, &section.SectionNumber, &section.CurrentTemperature, &section.MinimumTemperature, &section.CurrentCapacity, &section.MinimumCapacity, &section.MaximumCapacity, &section.WarehouseID, &section.ProductTypeID, &section.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -70,16 +85,19 @@ func (r *SectionMySQL) Get(id int) (section internal.Section, err error) {
 	return
 }
 
+// Save receives a section and saves it empty (its current capacity only changes with the batches stored in it)
 func (r *SectionMySQL) Save(section *internal.Section) (err error) {
 	// execute the query
-	query := "INSERT INTO `sections` (`section_number`, `current_temperature`, `minimum_temperature`, `current_capacity`, `minimum_capacity`, `maximum_capacity`, `warehouse_id`, `product_type_id`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
-	result, err := r.db.Exec(query, (*section).SectionNumber, (*section).CurrentTemperature, (*section).MinimumTemperature, (*section).CurrentCapacity, (*section).MinimumCapacity, (*section).MaximumCapacity, (*section).WarehouseID, (*section).ProductTypeID)
+	query := "INSERT INTO `sections` (`section_number`, `current_temperature`, `minimum_temperature`, `current_capacity`, `minimum_capacity`, `maximum_capacity`, `warehouse_id`, `product_type_id`) VALUES (?, ?, ?, 0, ?, ?, ?, ?)"
+	result, err := r.db.Exec(query, (*section).SectionNumber, (*section).CurrentTemperature, (*section).MinimumTemperature, (*section).MinimumCapacity, (*section).MaximumCapacity, (*section).WarehouseID, (*section).ProductTypeID)
 	if err != nil {
 		var mysqlErr *mysql.MySQLError
 		if errors.As(err, &mysqlErr) {
 			switch mysqlErr.Number {
 			case 1062:
 				err = internal.ErrSectionRepositoryDuplicated
+			case 1452:
+				err = sectionFKError(mysqlErr)
 			default:
 				err = internal.ErrSectionRepository
 			}
@@ -93,22 +111,26 @@ func (r *SectionMySQL) Save(section *internal.Section) (err error) {
 	if err != nil {
 		return
 	}
-	// set the ID of the section
+	// set the ID and the capacity of the section
 	section.ID = int(id)
+	section.CurrentCapacity = 0
 
 	return
 }
 
-// Update receives a section and updates it
+// Update receives a section and updates it if its version matches the stored one, except for its current capacity
+// (only changed by the batches stored in it)
 func (r *SectionMySQL) Update(section *internal.Section) (err error) {
-	query := "UPDATE `sections` SET `section_number` = ?, `current_temperature` = ?, `minimum_temperature` = ?, `current_capacity` = ?, `minimum_capacity` = ?, `maximum_capacity` = ?, `warehouse_id` = ?, `product_type_id` = ? WHERE `id` = ?"
-	_, err = r.db.Exec(query, (*section).SectionNumber, (*section).CurrentTemperature, (*section).MinimumTemperature, (*section).CurrentCapacity, (*section).MinimumCapacity, (*section).MaximumCapacity, (*section).WarehouseID, (*section).ProductTypeID, (*section).ID)
+	query := "UPDATE `sections` SET `section_number` = ?, `current_temperature` = ?, `minimum_temperature` = ?, `minimum_capacity` = ?, `maximum_capacity` = ?, `warehouse_id` = ?, `product_type_id` = ?, `version` = `version` + 1 WHERE `id` = ? AND `version` = ? AND `deleted_at` IS NULL"
+	result, err := r.db.Exec(query, (*section).SectionNumber, (*section).CurrentTemperature, (*section).MinimumTemperature, (*section).MinimumCapacity, (*section).MaximumCapacity, (*section).WarehouseID, (*section).ProductTypeID, (*section).ID, (*section).Version)
 	if err != nil {
 		var mysqlErr *mysql.MySQLError
 		if errors.As(err, &mysqlErr) {
 			switch mysqlErr.Number {
 			case 1062:
 				err = internal.ErrSectionRepositoryDuplicated
+			case 1452:
+				err = sectionFKError(mysqlErr)
 			default:
 				err = internal.ErrSectionRepository
 			}
@@ -117,37 +139,103 @@ func (r *SectionMySQL) Update(section *internal.Section) (err error) {
 		return
 	}
 
+	// the version is always bumped, so no affected rows means the section changed (or vanished) since it was read
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+	if rowsAffected == 0 {
+		err = internal.ErrSectionRepositoryVersionConflict
+		return
+	}
+
+	section.Version++
+
 	return
 }
 
-// Delete receives an ID and deletes the section
-func (r *SectionMySQL) Delete(id int) (err error) {
-	// execute the query
-	query := "DELETE FROM `sections` WHERE `id` = ?"
-	result, err := r.db.Exec(query, id)
-	if err != nil {
-		var mysqlErr *mysql.MySQLError
-		if errors.As(err, &mysqlErr) {
-			switch mysqlErr.Number {
-			case 1451:
-				err = internal.ErrSectionRepositoryFK
-			default:
-				err = internal.ErrSectionRepository
-			}
-		}
-
+// HasBatchesOfOtherType returns whether the section stores batches (with units left) of products not of the given type
+func (r *SectionMySQL) HasBatchesOfOtherType(id int, productTypeID int) (has bool, err error) {
+	query := "SELECT EXISTS(SELECT 1 FROM `product_batches` AS `pb` INNER JOIN `products` AS `p` ON p.`id` = pb.`product_id` " +
+		"WHERE pb.`section_id` = ? AND pb.`current_quantity` > 0 AND pb.`deleted_at` IS NULL AND (p.`product_type_id` IS NULL OR p.`product_type_id` <> ?))"
+	if err = r.db.QueryRow(query, id, productTypeID).Scan(&has); err != nil {
+		err = internal.ErrSectionRepository
 		return
 	}
-	// check if the section was deleted
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return
+
+	return
+}
+
+// Delete marks the section with the given ID as deleted. If version is not zero the section must still be at that version.
+// Returns an error if the section is not found or was modified since.
+func (r *SectionMySQL) Delete(id int, version int) (err error) {
+	err = softDelete(r.db, "sections", id, version)
+	switch err {
+	case nil:
+	case errRowNotFound:
+		err = internal.ErrSectionRepositoryNotFound
+	case errRowVersion:
+		err = internal.ErrSectionRepositoryVersionConflict
+	default:
+		err = internal.ErrSectionRepository
 	}
-	// return an error if the section was not found
-	if rowsAffected == 0 {
+
+	return
+}
+
+// Restore receives an ID and unmarks the deleted section
+func (r *SectionMySQL) Restore(id int) (err error) {
+	ok, err := restoreDeleted(r.db, "sections", id)
+	switch {
+	case err != nil:
+		err = internal.ErrSectionRepository
+	case !ok:
 		err = internal.ErrSectionRepositoryNotFound
+	}
+
+	return
+}
+
+// Purge deletes the section with the given ID permanently, deleted or not. If version is not zero the section must still be
+// at that version. Returns an error if the section is not found, was modified since, rows block the delete or rows depend on it and cascade is false.
+func (r *SectionMySQL) Purge(id int, version int, cascade bool) (err error) {
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// lock the section and check its version
+		if err = lockRow(tx, "sections", id, version); err != nil {
+			return
+		}
+
+		// check what depends on it, in the same transaction as the delete
+		if err = checkDependents(tx, internal.ResourceSections, id, cascade); err != nil {
+			return
+		}
+
+		// delete it
+		_, err = tx.Exec("DELETE FROM `sections` WHERE `id` = ?", id)
 		return
+	})
+
+	var mysqlErr *mysql.MySQLError
+	switch {
+	case err == nil:
+	case errors.Is(err, errRowNotFound):
+		err = internal.ErrSectionRepositoryNotFound
+	case errors.Is(err, errRowVersion):
+		err = internal.ErrSectionRepositoryVersionConflict
+	case errors.Is(err, internal.ErrDependentsRepositoryUnconfirmed):
+	case errors.Is(err, errDependentsBlocking), errors.As(err, &mysqlErr) && mysqlErr.Number == 1451:
+		err = internal.ErrSectionRepositoryFK
+	default:
+		err = internal.ErrSectionRepository
 	}
 
 	return
 }
+
+// sectionFKError returns the error of the missing reference of a section: its product type or its warehouse
+func sectionFKError(mysqlErr *mysql.MySQLError) error {
+	if strings.Contains(mysqlErr.Message, "fk_sections_product_type_id") {
+		return internal.ErrProductTypeRepositoryNotFound
+	}
+	return internal.ErrSectionRepositoryFK
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// NewSectionReadingMySQL creates a new instance of the section reading repository for MySQL
+func NewSectionReadingMySQL(db *sql.DB) *SectionReadingMySQL {
+	return &SectionReadingMySQL{
+		db: db,
+	}
+}
+
+// SectionReadingMySQL is the default implementation of the section reading repository for MySQL
+type SectionReadingMySQL struct {
+	db *sql.DB
+}
+
+// Save saves the readings of the section and sets its current temperature to the latest one read, in one transaction.
+// Readings older than the latest stored one are kept in the history without changing the current temperature.
+func (r *SectionReadingMySQL) Save(sectionID int, readings []internal.SectionReading) (err error) {
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// lock the section
+		var id int
+		err = tx.QueryRow("SELECT `id` FROM `sections` WHERE `id` = ? AND `deleted_at` IS NULL FOR UPDATE", sectionID).Scan(&id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = internal.ErrSectionReadingRepositorySectionNotFound
+			}
+			return
+		}
+
+		// insert the readings
+		columns := []string{"section_id", "temperature", "recorded_at"}
+		for start := 0; start < len(readings); start += bulkBatchSize {
+			end := min(start+bulkBatchSize, len(readings))
+			rows := make([][]any, 0, end-start)
+			for _, reading := range readings[start:end] {
+				rows = append(rows, []any{sectionID, reading.Temperature, reading.RecordedAt})
+			}
+
+			var firstID int
+			firstID, err = insertRows(tx, "section_temperature_readings", columns, rows)
+			if err != nil {
+				return
+			}
+			for i := start; i < end; i++ {
+				readings[i].ID = firstID + (i - start)
+				readings[i].SectionID = sectionID
+			}
+		}
+
+		// the current temperature is the latest one read
+		query := "UPDATE `sections` SET `current_temperature` = (" +
+			"SELECT `temperature` FROM `section_temperature_readings` WHERE `section_id` = ? ORDER BY `recorded_at` DESC, `id` DESC LIMIT 1" +
+			"), `version` = `version` + 1 WHERE `id` = ?"
+		_, err = tx.Exec(query, sectionID, sectionID)
+		return
+	})
+	if err != nil && !errors.Is(err, internal.ErrSectionReadingRepositorySectionNotFound) {
+		err = internal.ErrSectionReadingRepository
+	}
+
+	return
+}
+
+// ForEach calls fn with every reading of the section recorded in [from, to) as the rows are scanned, oldest first.
+// It stops at the first error returned by fn and returns it as is.
+func (r *SectionReadingMySQL) ForEach(sectionID int, from time.Time, to time.Time, fn func(r internal.SectionReading) error) (err error) {
+	// execute the query
+	query := "SELECT `id`, `section_id`, `temperature`, `recorded_at` FROM `section_temperature_readings` WHERE `section_id` = ? AND `recorded_at` >= ? AND `recorded_at` < ? ORDER BY `recorded_at`, `id`"
+	rows, err := r.db.Query(query, sectionID, from, to)
+	if err != nil {
+		err = internal.ErrSectionReadingRepository
+		return
+	}
+	defer rows.Close()
+
+	// iterate over the rows
+	for rows.Next() {
+		var reading internal.SectionReading
+		if err = rows.Scan(&reading.ID, &reading.SectionID, &reading.Temperature, &reading.RecordedAt); err != nil {
+			err = internal.ErrSectionReadingRepository
+			return
+		}
+		if err = fn(reading); err != nil {
+			return
+		}
+	}
+
+	// check if there was an error during the iteration
+	if err = rows.Err(); err != nil {
+		err = internal.ErrSectionReadingRepository
+		return
+	}
+
+	return
+}
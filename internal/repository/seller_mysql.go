@@ -3,6 +3,7 @@ package repository
 import (
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/manuelfirman/go-API/internal"
@@ -21,9 +22,19 @@ func NewSellerMySQL(db *sql.DB) *SellerMySQL {
 	}
 }
 
-// GetAll returns all sellers
-func (r *SellerMySQL) GetAll() (sellers []internal.Seller, err error) {
-	rows, err := r.db.Query("SELECT * FROM sellers")
+// GetAll returns all the sellers, the deleted ones only if includeDeleted
+func (r *SellerMySQL) GetAll(includeDeleted bool) (sellers []internal.Seller, err error) {
+	err = r.ForEach(includeDeleted, func(seller internal.Seller) error {
+		sellers = append(sellers, seller)
+		return nil
+	})
+	return
+}
+
+// ForEach calls fn with every seller as the rows are scanned, so the sellers are never held in memory.
+// The deleted sellers are only included if includeDeleted. It stops at the first error returned by fn and returns it as is.
+func (r *SellerMySQL) ForEach(includeDeleted bool, fn func(seller internal.Seller) error) (err error) {
+	rows, err := r.db.Query("SELECT id, cid, company_name, address, telephone, locality_id, version, deleted_at FROM sellers" + notDeleted("", includeDeleted) + " ORDER BY id")
 	if err != nil {
 		return
 	}
@@ -31,7 +42,8 @@ func (r *SellerMySQL) GetAll() (sellers []internal.Seller, err error) {
 
 	for rows.Next() {
 		var s internal.Seller
-		err = rows.Scan(&s.ID, &s.CID, &s.CompanyName, &s.Address, &s.Telephone, &s.LocalityID)
+		var deletedAt sql.NullTime
+		err = rows.Scan(&s.ID, &s.CID, &s.CompanyName, &s.Address, &s.Telephone, &s.LocalityID, &s.Version, &deletedAt)
 		if err != nil {
 			switch err {
 			case sql.ErrNoRows:
@@ -41,8 +53,11 @@ func (r *SellerMySQL) GetAll() (sellers []internal.Seller, err error) {
 			}
 			return
 		}
+		s.DeletedAt = deletedAt.Time
 
-		sellers = append(sellers, s)
+		if err = fn(s); err != nil {
+			return
+		}
 	}
 
 	// check for errors
@@ -56,11 +71,11 @@ func (r *SellerMySQL) GetAll() (sellers []internal.Seller, err error) {
 
 // Get returns a seller by ID
 func (r *SellerMySQL) Get(id int) (s internal.Seller, err error) {
-	query := "SELECT * FROM sellers WHERE id = ?"
+	query := "SELECT id, cid, company_name, address, telephone, locality_id, version FROM sellers WHERE id = ? AND deleted_at IS NULL"
 	row := r.db.QueryRow(query, id)
 
 	// scan the row and return the product
-	err = row.Scan(&s.ID, &s.CID, &s.CompanyName, &s.Address, &s.Telephone, &s.LocalityID)
+	err = row.Scan(&s.ID, &s.CID, &s.CompanyName, &s.Address, &s.Telephone, &s.LocalityID, &s.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -104,10 +119,38 @@ func (r *SellerMySQL) Save(s *internal.Seller) (id int, err error) {
 	return
 }
 
-// Update updates a seller
+// SaveBulk saves sellers in batches. If atomic, either all of them are saved or none
+func (r *SellerMySQL) SaveBulk(sellers []internal.Seller, atomic bool) (results []internal.BulkResult, err error) {
+	columns := []string{"cid", "company_name", "address", "telephone", "locality_id"}
+	rows := make([][]any, len(sellers))
+	for i, s := range sellers {
+		rows[i] = []any{s.CID, s.CompanyName, s.Address, s.Telephone, s.LocalityID}
+	}
+
+	results, err = bulkInsert(r.db, "sellers", columns, rows, atomic, func(err error) error {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) {
+			switch mysqlErr.Number {
+			case 1062:
+				return internal.ErrSellerRepositoryDuplicated
+			case 1452:
+				return internal.ErrSellerRepositoryForeignKey
+			}
+		}
+		return internal.ErrSellerRepositoryUnknown
+	})
+	if err != nil {
+		err = internal.ErrSellerRepositoryTransaction
+		return
+	}
+
+	return
+}
+
+// Update updates a seller if its version matches the stored one
 func (r *SellerMySQL) Update(s *internal.Seller) (err error) {
-	query := "UPDATE sellers SET cid = ?, company_name = ?, address = ?, telephone = ?, locality_id = ? WHERE id = ?"
-	result, err := r.db.Exec(query, s.CID, s.CompanyName, s.Address, s.Telephone, s.LocalityID, s.ID)
+	query := "UPDATE sellers SET cid = ?, company_name = ?, address = ?, telephone = ?, locality_id = ?, version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL"
+	result, err := r.db.Exec(query, s.CID, s.CompanyName, s.Address, s.Telephone, s.LocalityID, s.ID, s.Version)
 	if err != nil {
 		var mysqlErr *mysql.MySQLError
 		if errors.As(err, &mysqlErr) {
@@ -124,45 +167,170 @@ func (r *SellerMySQL) Update(s *internal.Seller) (err error) {
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		err = internal.ErrSellerRepositoryUnknown
+		return
 	}
 
+	// the version is always bumped, so no affected rows means the seller changed (or vanished) since it was read
 	if rowsAffected == 0 {
-		err = internal.ErrSellerRepositoryNothingToUpdate
+		err = internal.ErrSellerRepositoryVersionConflict
+		return
 	}
 
+	s.Version++
+
 	return
 }
 
-// Delete deletes a seller by ID
-func (r *SellerMySQL) Delete(id int) (err error) {
-	query := "DELETE FROM sellers WHERE id = ?"
-	result, err := r.db.Exec(query, id)
-	if err != nil {
-		var mysqlErr *mysql.MySQLError
-		if errors.As(err, &mysqlErr) {
-			switch mysqlErr.Number {
-			case 1451:
-				err = internal.ErrSellerRepositoryForeignKey
-			default:
-				err = internal.ErrSellerRepositoryUnknown
-			}
+// Delete marks the seller with the given ID as deleted. If version is not zero the seller must still be at that version.
+// Returns an error if the seller is not found or was modified since.
+func (r *SellerMySQL) Delete(id int, version int) (err error) {
+	err = softDelete(r.db, "sellers", id, version)
+	switch err {
+	case nil:
+	case errRowNotFound:
+		err = internal.ErrSellerRepositoryNotFound
+	case errRowVersion:
+		err = internal.ErrSellerRepositoryVersionConflict
+	default:
+		err = internal.ErrSellerRepositoryUnknown
+	}
+
+	return
+}
+
+// Restore unmarks a deleted seller by ID
+func (r *SellerMySQL) Restore(id int) (err error) {
+	ok, err := restoreDeleted(r.db, "sellers", id)
+	switch {
+	case err != nil:
+		err = internal.ErrSellerRepositoryUnknown
+	case !ok:
+		err = internal.ErrSellerRepositoryNotFound
+	}
+
+	return
+}
+
+// Purge deletes the seller with the given ID permanently, deleted or not. If version is not zero the seller must still be
+// at that version. Returns an error if the seller is not found, was modified since, rows block the delete or rows depend on it and cascade is false.
+func (r *SellerMySQL) Purge(id int, version int, cascade bool) (err error) {
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// lock the seller and check its version
+		if err = lockRow(tx, "sellers", id, version); err != nil {
 			return
 		}
-	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			err = internal.ErrSellerRepositoryNotFound
-		default:
-			err = internal.ErrSellerRepositoryUnknown
+		// check what depends on it, in the same transaction as the delete
+		if err = checkDependents(tx, internal.ResourceSellers, id, cascade); err != nil {
+			return
 		}
+
+		// delete it
+		_, err = tx.Exec("DELETE FROM `sellers` WHERE `id` = ?", id)
 		return
-	}
+	})
 
-	if rowsAffected == 0 {
+	var mysqlErr *mysql.MySQLError
+	switch {
+	case err == nil:
+	case errors.Is(err, errRowNotFound):
 		err = internal.ErrSellerRepositoryNotFound
+	case errors.Is(err, errRowVersion):
+		err = internal.ErrSellerRepositoryVersionConflict
+	case errors.Is(err, internal.ErrDependentsRepositoryUnconfirmed):
+	case errors.Is(err, errDependentsBlocking), errors.As(err, &mysqlErr) && mysqlErr.Number == 1451:
+		err = internal.ErrSellerRepositoryForeignKey
+	default:
+		err = internal.ErrSellerRepositoryUnknown
+	}
+
+	return
+}
+
+// GetPerformance returns the activity of the products of the seller: their stock, the units sold between the given days
+// (unbounded when zero, cancelled orders excluded) and the products without movements of their batches nor orders since idleSince.
+// Each purchase order is a unit sold at the sale price of its product record.
+func (r *SellerMySQL) GetPerformance(id int, from time.Time, to time.Time, idleSince time.Time) (p internal.SellerPerformance, err error) {
+	p.SellerID = id
+	p.From = from
+	p.To = to
+	p.IdleSince = idleSince
+
+	// filter of the orders
+	where := "pr.`product_id` IN (SELECT `id` FROM `products` WHERE `seller_id` = ?) AND po.`order_status_id` <> ?"
+	args := []any{id, internal.OrderStatusCancelled}
+	if !from.IsZero() {
+		where += " AND po.`order_date` >= ?"
+		args = append(args, from)
+	}
+	if !to.IsZero() {
+		where += " AND po.`order_date` <= ?"
+		args = append(args, to)
+	}
+
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// the seller must exist
+		var exists bool
+		if err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM `sellers` WHERE `id` = ? AND `deleted_at` IS NULL)", id).Scan(&exists); err != nil {
+			return
+		}
+		if !exists {
+			err = internal.ErrSellerRepositoryNotFound
+			return
+		}
+
+		// products
+		if err = tx.QueryRow("SELECT COUNT(*) FROM `products` WHERE `seller_id` = ? AND `deleted_at` IS NULL", id).Scan(&p.Products); err != nil {
+			return
+		}
+
+		// stock
+		query := "SELECT COALESCE(SUM(pb.`current_quantity`), 0) " +
+			"FROM `product_batches` AS `pb` INNER JOIN `products` AS `p` ON p.`id` = pb.`product_id` " +
+			"INNER JOIN `sections` AS `s` ON s.`id` = pb.`section_id` INNER JOIN `warehouses` AS `w` ON w.`id` = s.`warehouse_id` " +
+			"WHERE p.`seller_id` = ? AND p.`deleted_at` IS NULL AND pb.`deleted_at` IS NULL AND s.`deleted_at` IS NULL AND w.`deleted_at` IS NULL"
+		if err = tx.QueryRow(query, id).Scan(&p.UnitsInStock); err != nil {
+			return
+		}
+
+		// sales
+		query = "SELECT COUNT(*), COALESCE(SUM(pr.`sale_price`), 0) " +
+			"FROM `purchase_orders` AS `po` INNER JOIN `product_records` AS `pr` ON pr.`id` = po.`product_record_id` " +
+			"WHERE " + where
+		if err = tx.QueryRow(query, args...).Scan(&p.UnitsSold, &p.Revenue); err != nil {
+			return
+		}
+
+		// idle products
+		query = "SELECT p.`id`, p.`product_code`, p.`description`, " +
+			"(SELECT MAX(im.`created_at`) FROM `inventory_movements` AS `im` INNER JOIN `product_batches` AS `pb` ON pb.`id` = im.`product_batch_id` WHERE pb.`product_id` = p.`id`) AS `last_movement`, " +
+			"(SELECT MAX(po.`order_date`) FROM `purchase_orders` AS `po` INNER JOIN `product_records` AS `pr` ON pr.`id` = po.`product_record_id` WHERE pr.`product_id` = p.`id`) AS `last_order` " +
+			"FROM `products` AS `p` WHERE p.`seller_id` = ? AND p.`deleted_at` IS NULL " +
+			"HAVING (`last_movement` IS NULL OR `last_movement` < ?) AND (`last_order` IS NULL OR `last_order` < ?) ORDER BY p.`id`"
+		rows, err := tx.Query(query, id, idleSince, idleSince)
+		if err != nil {
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var ip internal.IdleProduct
+			var lastMovement, lastOrder sql.NullTime
+			if err = rows.Scan(&ip.ProductID, &ip.ProductCode, &ip.Description, &lastMovement, &lastOrder); err != nil {
+				return
+			}
+			ip.LastActivity = lastMovement.Time
+			if lastOrder.Time.After(ip.LastActivity) {
+				ip.LastActivity = lastOrder.Time
+			}
+			p.IdleProducts = append(p.IdleProducts, ip)
+		}
+		err = rows.Err()
+
+		return
+	})
+	if err != nil && !errors.Is(err, internal.ErrSellerRepositoryNotFound) {
+		err = internal.ErrSellerRepositoryUnknown
 	}
 
 	return
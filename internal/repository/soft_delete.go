@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	// errRowNotFound is returned when there is no row with the given id
+	errRowNotFound = errors.New("row not found")
+	// errRowVersion is returned when the row is not at the expected version
+	errRowVersion = errors.New("row version conflict")
+)
+
+// queryExecer is a database or a transaction, able to run statements and queries
+type queryExecer interface {
+	execer
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// softDelete marks the row of the table with the given id as deleted, bumping its version. If version is not zero
+// the row must still be at that version. It returns errRowNotFound if there is no such row or it was already deleted,
+// and errRowVersion if it was modified since.
+func softDelete(db queryExecer, table string, id int, version int) (err error) {
+	query := "UPDATE `" + table + "` SET `deleted_at` = ?, `version` = `version` + 1 WHERE `id` = ? AND `deleted_at` IS NULL AND (? = 0 OR `version` = ?)"
+	result, err := db.Exec(query, time.Now().UTC(), id, version, version)
+	if err != nil {
+		return
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil || rows > 0 {
+		return
+	}
+
+	// nothing was marked: the row is missing or at another version
+	var exists bool
+	if err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM `"+table+"` WHERE `id` = ? AND `deleted_at` IS NULL)", id).Scan(&exists); err != nil {
+		return
+	}
+	err = errRowNotFound
+	if exists {
+		err = errRowVersion
+	}
+	return
+}
+
+// lockRow locks the row of the table with the given id (deleted or not) until the end of the transaction. If version
+// is not zero the row must be at that version. It returns errRowNotFound if there is no such row and errRowVersion
+// if it is at another version.
+func lockRow(tx *sql.Tx, table string, id int, version int) (err error) {
+	var current int
+	err = tx.QueryRow("SELECT `version` FROM `"+table+"` WHERE `id` = ? FOR UPDATE", id).Scan(&current)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		err = errRowNotFound
+	case err == nil && version != 0 && current != version:
+		err = errRowVersion
+	}
+	return
+}
+
+// restoreDeleted unmarks the deleted row of the table with the given id, bumping its version.
+// It returns false if there is no such row or it wasn't deleted.
+func restoreDeleted(db execer, table string, id int) (ok bool, err error) {
+	query := "UPDATE `" + table + "` SET `deleted_at` = NULL, `version` = `version` + 1 WHERE `id` = ? AND `deleted_at` IS NOT NULL"
+	result, err := db.Exec(query, id)
+	if err != nil {
+		return
+	}
+
+	rows, err := result.RowsAffected()
+	ok = rows > 0
+	return
+}
+
+// notDeleted returns the condition that leaves out the deleted rows of the table alias (none if empty), empty if they are included
+func notDeleted(alias string, includeDeleted bool) string {
+	if includeDeleted {
+		return ""
+	}
+	if alias == "" {
+		return " WHERE `deleted_at` IS NULL"
+	}
+	return " WHERE " + alias + ".`deleted_at` IS NULL"
+}
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// NewStockMySQL creates a new instance of the stock repository for MySQL
+func NewStockMySQL(db *sql.DB) *StockMySQL {
+	return &StockMySQL{
+		db: db,
+	}
+}
+
+// StockMySQL is the default implementation of the stock repository for MySQL
+type StockMySQL struct {
+	db *sql.DB
+}
+
+// GetByProduct returns the units of the product held in each section, ordered by warehouse and section
+func (r *StockMySQL) GetByProduct(productID int) (levels []internal.StockLevel, err error) {
+	// check that the product exists
+	if err = r.exists("products", productID, internal.ErrStockRepositoryProductNotFound); err != nil {
+		return
+	}
+
+	query := "SELECT pb.`product_id`, s.`warehouse_id`, pb.`section_id`, SUM(pb.`current_quantity`), COUNT(*) " +
+		"FROM `product_batches` AS `pb` INNER JOIN `sections` AS `s` ON s.`id` = pb.`section_id` INNER JOIN `warehouses` AS `w` ON w.`id` = s.`warehouse_id` " +
+		"WHERE pb.`product_id` = ? AND pb.`current_quantity` > 0 " +
+		"AND pb.`deleted_at` IS NULL AND s.`deleted_at` IS NULL AND w.`deleted_at` IS NULL " +
+		"GROUP BY pb.`product_id`, s.`warehouse_id`, pb.`section_id` ORDER BY s.`warehouse_id`, pb.`section_id`"
+	levels, err = r.getLevels(query, productID)
+	return
+}
+
+// GetByWarehouse returns the units of each product held in each section of the warehouse, ordered by product and section
+func (r *StockMySQL) GetByWarehouse(warehouseID int) (levels []internal.StockLevel, err error) {
+	// check that the warehouse exists
+	if err = r.exists("warehouses", warehouseID, internal.ErrStockRepositoryWarehouseNotFound); err != nil {
+		return
+	}
+
+	query := "SELECT pb.`product_id`, s.`warehouse_id`, pb.`section_id`, SUM(pb.`current_quantity`), COUNT(*) " +
+		"FROM `product_batches` AS `pb` INNER JOIN `sections` AS `s` ON s.`id` = pb.`section_id` INNER JOIN `products` AS `p` ON p.`id` = pb.`product_id` " +
+		"WHERE s.`warehouse_id` = ? AND pb.`current_quantity` > 0 " +
+		"AND pb.`deleted_at` IS NULL AND s.`deleted_at` IS NULL AND p.`deleted_at` IS NULL " +
+		"GROUP BY pb.`product_id`, s.`warehouse_id`, pb.`section_id` ORDER BY pb.`product_id`, pb.`section_id`"
+	levels, err = r.getLevels(query, warehouseID)
+	return
+}
+
+// GetThreshold returns the low-stock threshold of the product
+func (r *StockMySQL) GetThreshold(productID int) (threshold int, err error) {
+	row := r.db.QueryRow("SELECT `threshold` FROM `product_stock_thresholds` WHERE `product_id` = ?", productID)
+	if err = row.Scan(&threshold); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err = internal.ErrStockRepositoryThresholdNotFound
+		default:
+			err = internal.ErrStockRepository
+		}
+		return
+	}
+
+	return
+}
+
+// SetThreshold sets (inserts or replaces) the low-stock threshold of the product
+func (r *StockMySQL) SetThreshold(productID int, threshold int) (err error) {
+	query := "INSERT INTO `product_stock_thresholds` (`product_id`, `threshold`) VALUES (?, ?) ON DUPLICATE KEY UPDATE `threshold` = VALUES(`threshold`)"
+	_, err = r.db.Exec(query, productID, threshold)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		switch {
+		case errors.As(err, &mysqlErr) && mysqlErr.Number == 1452:
+			err = internal.ErrStockRepositoryProductNotFound
+		default:
+			err = internal.ErrStockRepository
+		}
+		return
+	}
+
+	return
+}
+
+// DeleteThreshold removes the low-stock threshold of the product
+func (r *StockMySQL) DeleteThreshold(productID int) (err error) {
+	result, err := r.db.Exec("DELETE FROM `product_stock_thresholds` WHERE `product_id` = ?", productID)
+	if err != nil {
+		err = internal.ErrStockRepository
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		err = internal.ErrStockRepository
+		return
+	}
+	if rowsAffected == 0 {
+		err = internal.ErrStockRepositoryThresholdNotFound
+		return
+	}
+
+	return
+}
+
+// GetBelowThreshold returns the stock of the products whose quantity is below their threshold, ordered by product
+func (r *StockMySQL) GetBelowThreshold() (stocks []internal.ProductStock, err error) {
+	// execute the query
+	query := "SELECT t.`product_id`, COALESCE(SUM(pb.`current_quantity`), 0) AS `quantity`, t.`threshold` " +
+		"FROM `product_stock_thresholds` AS `t` INNER JOIN `products` AS `p` ON p.`id` = t.`product_id` AND p.`deleted_at` IS NULL " +
+		"LEFT JOIN (`product_batches` AS `pb` " +
+		"INNER JOIN `sections` AS `s` ON s.`id` = pb.`section_id` AND s.`deleted_at` IS NULL " +
+		"INNER JOIN `warehouses` AS `w` ON w.`id` = s.`warehouse_id` AND w.`deleted_at` IS NULL" +
+		") ON pb.`product_id` = t.`product_id` AND pb.`deleted_at` IS NULL " +
+		"GROUP BY t.`product_id`, t.`threshold` HAVING `quantity` < t.`threshold` ORDER BY t.`product_id`"
+	rows, err := r.db.Query(query)
+	if err != nil {
+		err = internal.ErrStockRepository
+		return
+	}
+	defer rows.Close()
+
+	// iterate over the rows
+	for rows.Next() {
+		var ps internal.ProductStock
+		if err = rows.Scan(&ps.ProductID, &ps.Quantity, &ps.LowStockThreshold); err != nil {
+			err = internal.ErrStockRepository
+			return
+		}
+		stocks = append(stocks, ps)
+	}
+
+	// check if there was an error during the iteration
+	if err = rows.Err(); err != nil {
+		err = internal.ErrStockRepository
+		return
+	}
+
+	return
+}
+
+// exists returns notFound if there is no row with the id in the table, or it is deleted
+func (r *StockMySQL) exists(table string, id int, notFound error) (err error) {
+	var exists bool
+	err = r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM `"+table+"` WHERE `id` = ? AND `deleted_at` IS NULL)", id).Scan(&exists)
+	if err != nil {
+		err = internal.ErrStockRepository
+		return
+	}
+	if !exists {
+		err = notFound
+		return
+	}
+
+	return
+}
+
+// getLevels runs the query of stock levels and scans its rows
+func (r *StockMySQL) getLevels(query string, args ...any) (levels []internal.StockLevel, err error) {
+	// execute the query
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		err = internal.ErrStockRepository
+		return
+	}
+	defer rows.Close()
+
+	// iterate over the rows
+	for rows.Next() {
+		var level internal.StockLevel
+		if err = rows.Scan(&level.ProductID, &level.WarehouseID, &level.SectionID, &level.Quantity, &level.Batches); err != nil {
+			err = internal.ErrStockRepository
+			return
+		}
+		levels = append(levels, level)
+	}
+
+	// check if there was an error during the iteration
+	if err = rows.Err(); err != nil {
+		err = internal.ErrStockRepository
+		return
+	}
+
+	return
+}
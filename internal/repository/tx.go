@@ -0,0 +1,25 @@
+package repository
+
+import "database/sql"
+
+// withTx runs fn in a transaction: it is committed if fn succeeds and rolled back otherwise
+func withTx(db *sql.DB, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return
+	}
+
+	err = tx.Commit()
+	return
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/manuelfirman/go-API/internal"
@@ -21,9 +22,19 @@ func NewWarehouseMySQL(db *sql.DB) *WarehouseMySQL {
 	}
 }
 
-// GetAll returns all Warehouses
-func (w *WarehouseMySQL) GetAll() (warehouses []internal.Warehouse, err error) {
-	query := "SELECT `id`, `warehouse_code`, `address`, `telephone`, `minimum_capacity`, `minimum_temperature`, `locality_id` FROM warehouses"
+// GetAll returns all the warehouses, the deleted ones only if includeDeleted
+func (w *WarehouseMySQL) GetAll(includeDeleted bool) (warehouses []internal.Warehouse, err error) {
+	err = w.ForEach(includeDeleted, func(wh internal.Warehouse) error {
+		warehouses = append(warehouses, wh)
+		return nil
+	})
+	return
+}
+
+// ForEach calls fn with every warehouse as the rows are scanned, so the warehouses are never held in memory.
+// The deleted warehouses are only included if includeDeleted. It stops at the first error returned by fn and returns it as is.
+func (w *WarehouseMySQL) ForEach(includeDeleted bool, fn func(wh internal.Warehouse) error) (err error) {
+	query := "SELECT `id`, `warehouse_code`, `address`, `telephone`, `minimum_capacity`, `minimum_temperature`, `locality_id`, `version`, `deleted_at` FROM warehouses" + notDeleted("", includeDeleted) + " ORDER BY `id`"
 	rows, err := w.db.Query(query)
 	if err != nil {
 		return
@@ -32,7 +43,8 @@ func (w *WarehouseMySQL) GetAll() (warehouses []internal.Warehouse, err error) {
 
 	for rows.Next() {
 		var w internal.Warehouse
-		err = rows.Scan(&w.ID, &w.WarehouseCode, &w.Address, &w.Telephone, &w.MinimumCapacity, &w.MinimumTemperature, &w.LocalityId)
+		var deletedAt sql.NullTime
+		err = rows.Scan(&w.ID, &w.WarehouseCode, &w.Address, &w.Telephone, &w.MinimumCapacity, &w.MinimumTemperature, &w.LocalityId, &w.Version, &deletedAt)
 		if err != nil {
 			switch err {
 			case sql.ErrNoRows:
@@ -42,8 +54,11 @@ func (w *WarehouseMySQL) GetAll() (warehouses []internal.Warehouse, err error) {
 			}
 			return
 		}
+		w.DeletedAt = deletedAt.Time
 
-		warehouses = append(warehouses, w)
+		if err = fn(w); err != nil {
+			return
+		}
 	}
 
 	return
@@ -51,11 +66,11 @@ func (w *WarehouseMySQL) GetAll() (warehouses []internal.Warehouse, err error) {
 
 // Get returns a Warehouse by ID
 func (w *WarehouseMySQL) Get(id int) (wh internal.Warehouse, err error) {
-	query := "SELECT `id`, `warehouse_code`, `address`, `telephone`, `minimum_capacity`, `minimum_temperature`, `locality_id` FROM warehouses WHERE id = ?"
+	query := "SELECT `id`, `warehouse_code`, `address`, `telephone`, `minimum_capacity`, `minimum_temperature`, `locality_id`, `version` FROM warehouses WHERE id = ? AND deleted_at IS NULL"
 	row := w.db.QueryRow(query, id)
 
 	// scan the row and return the product
-	err = row.Scan(&wh.ID, &wh.WarehouseCode, &wh.Address, &wh.Telephone, &wh.MinimumCapacity, &wh.MinimumTemperature, &wh.LocalityId)
+	err = row.Scan(&wh.ID, &wh.WarehouseCode, &wh.Address, &wh.Telephone, &wh.MinimumCapacity, &wh.MinimumTemperature, &wh.LocalityId, &wh.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -99,10 +114,10 @@ func (w *WarehouseMySQL) Save(wh *internal.Warehouse) (id int, err error) {
 	return
 }
 
-// Update updates a Warehouse
+// Update updates a Warehouse if its version matches the stored one
 func (r *WarehouseMySQL) Update(s *internal.Warehouse) (err error) {
-	query := "UPDATE Warehouses SET warehouse_code = ?, address = ?, telephone = ?, minimum_capacity = ?, minimum_temperature = ?, locality_id = ? WHERE id = ?"
-	result, err := r.db.Exec(query, s.WarehouseCode, s.Address, s.Telephone, s.MinimumCapacity, s.MinimumTemperature, s.LocalityId, s.ID)
+	query := "UPDATE Warehouses SET warehouse_code = ?, address = ?, telephone = ?, minimum_capacity = ?, minimum_temperature = ?, locality_id = ?, version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL"
+	result, err := r.db.Exec(query, s.WarehouseCode, s.Address, s.Telephone, s.MinimumCapacity, s.MinimumTemperature, s.LocalityId, s.ID, s.Version)
 	if err != nil {
 		var mysqlErr *mysql.MySQLError
 		if errors.As(err, &mysqlErr) {
@@ -119,46 +134,136 @@ func (r *WarehouseMySQL) Update(s *internal.Warehouse) (err error) {
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		err = internal.ErrWarehouseRepositoryUnknown
+		return
 	}
 
+	// the version is always bumped, so no affected rows means the warehouse changed (or vanished) since it was read
 	if rowsAffected == 0 {
-		err = internal.ErrWarehouseRepositoryNothingToUpdate
+		err = internal.ErrWarehouseRepositoryVersionConflict
+		return
 	}
 
+	s.Version++
+
 	return
 }
 
-// Delete deletes a Warehouse by ID
-func (r *WarehouseMySQL) Delete(id int) (err error) {
-	query := "DELETE FROM Warehouses WHERE id = ?"
-	result, err := r.db.Exec(query, id)
-	if err != nil {
-		var mysqlErr *mysql.MySQLError
-		if errors.As(err, &mysqlErr) {
-			switch mysqlErr.Number {
-			case 1451:
-				err = internal.ErrWarehouseRepositoryForeignKey
-			default:
-				err = internal.ErrWarehouseRepositoryUnknown
-			}
+// Delete marks the warehouse with the given ID as deleted. If version is not zero the warehouse must still be at that version.
+// Returns an error if the warehouse is not found or was modified since.
+func (r *WarehouseMySQL) Delete(id int, version int) (err error) {
+	err = softDelete(r.db, "warehouses", id, version)
+	switch err {
+	case nil:
+	case errRowNotFound:
+		err = internal.ErrWarehouseRepositoryNotFound
+	case errRowVersion:
+		err = internal.ErrWarehouseRepositoryVersionConflict
+	default:
+		err = internal.ErrWarehouseRepositoryUnknown
+	}
+
+	return
+}
+
+// Restore unmarks a deleted Warehouse by ID
+func (r *WarehouseMySQL) Restore(id int) (err error) {
+	ok, err := restoreDeleted(r.db, "warehouses", id)
+	switch {
+	case err != nil:
+		err = internal.ErrWarehouseRepositoryUnknown
+	case !ok:
+		err = internal.ErrWarehouseRepositoryNotFound
+	}
+
+	return
+}
+
+// Purge deletes the warehouse with the given ID permanently, deleted or not. If version is not zero the warehouse must still be
+// at that version. Returns an error if the warehouse is not found, was modified since, rows block the delete or rows depend on it and cascade is false.
+func (r *WarehouseMySQL) Purge(id int, version int, cascade bool) (err error) {
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// lock the warehouse and check its version
+		if err = lockRow(tx, "warehouses", id, version); err != nil {
 			return
 		}
+
+		// check what depends on it, in the same transaction as the delete
+		if err = checkDependents(tx, internal.ResourceWarehouses, id, cascade); err != nil {
+			return
+		}
+
+		// delete it
+		_, err = tx.Exec("DELETE FROM `warehouses` WHERE `id` = ?", id)
+		return
+	})
+
+	var mysqlErr *mysql.MySQLError
+	switch {
+	case err == nil:
+	case errors.Is(err, errRowNotFound):
+		err = internal.ErrWarehouseRepositoryNotFound
+	case errors.Is(err, errRowVersion):
+		err = internal.ErrWarehouseRepositoryVersionConflict
+	case errors.Is(err, internal.ErrDependentsRepositoryUnconfirmed):
+	case errors.Is(err, errDependentsBlocking), errors.As(err, &mysqlErr) && mysqlErr.Number == 1451:
+		err = internal.ErrWarehouseRepositoryForeignKey
+	default:
+		err = internal.ErrWarehouseRepositoryUnknown
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
+	return
+}
+
+// GetSummary returns the utilisation of the warehouse. The figures are read in one transaction, so they are consistent
+// with each other. Returns an error if the warehouse is not found.
+func (r *WarehouseMySQL) GetSummary(id int, expiringUntil time.Time, excursionsSince time.Time) (s internal.WarehouseSummary, err error) {
+	s.WarehouseID = id
+	s.ExpiringUntil = expiringUntil
+	s.ExcursionsSince = excursionsSince
+
+	err = withTx(r.db, func(tx *sql.Tx) (err error) {
+		// the warehouse must exist
+		var exists bool
+		if err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM `warehouses` WHERE `id` = ? AND `deleted_at` IS NULL)", id).Scan(&exists); err != nil {
+			return
+		}
+		if !exists {
 			err = internal.ErrWarehouseRepositoryNotFound
-		default:
+			return
+		}
+
+		// sections and their capacity
+		query := "SELECT COUNT(*), COALESCE(SUM(`maximum_capacity`), 0), COALESCE(SUM(`current_capacity`), 0), COALESCE(SUM(GREATEST(`maximum_capacity` - `current_capacity`, 0)), 0) " +
+			"FROM `sections` WHERE `warehouse_id` = ? AND `deleted_at` IS NULL"
+		if err = tx.QueryRow(query, id).Scan(&s.Sections, &s.TotalCapacity, &s.UsedCapacity, &s.FreeCapacity); err != nil {
+			return
+		}
+
+		// employees
+		if err = tx.QueryRow("SELECT COUNT(*) FROM `employees` WHERE `warehouse_id` = ? AND `deleted_at` IS NULL", id).Scan(&s.Employees); err != nil {
+			return
+		}
+
+		// batches with units left and the ones nearing expiry
+		query = "SELECT COUNT(*), COUNT(CASE WHEN pb.`due_date` <= ? THEN 1 END), COUNT(DISTINCT CASE WHEN pb.`due_date` <= ? THEN pb.`product_id` END) " +
+			"FROM `product_batches` AS `pb` INNER JOIN `sections` AS `s` ON s.`id` = pb.`section_id` INNER JOIN `products` AS `p` ON p.`id` = pb.`product_id` " +
+			"WHERE s.`warehouse_id` = ? AND pb.`current_quantity` > 0 AND pb.`deleted_at` IS NULL AND s.`deleted_at` IS NULL AND p.`deleted_at` IS NULL"
+		if err = tx.QueryRow(query, expiringUntil, expiringUntil, id).Scan(&s.Batches, &s.ExpiringBatches, &s.ExpiringProducts); err != nil {
+			return
+		}
+
+		// readings below the minimum temperature of their section
+		query = "SELECT COUNT(*) FROM `section_temperature_readings` AS `r` INNER JOIN `sections` AS `s` ON s.`id` = r.`section_id` " +
+			"WHERE s.`warehouse_id` = ? AND s.`deleted_at` IS NULL AND r.`recorded_at` >= ? AND r.`temperature` < s.`minimum_temperature`"
+		err = tx.QueryRow(query, id, excursionsSince).Scan(&s.TemperatureExcursions)
+		return
+	})
+	if err != nil {
+		if !errors.Is(err, internal.ErrWarehouseRepositoryNotFound) {
 			err = internal.ErrWarehouseRepositoryUnknown
 		}
 		return
 	}
 
-	if rowsAffected == 0 {
-		err = internal.ErrWarehouseRepositoryNotFound
-	}
-
 	return
 }
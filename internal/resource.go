@@ -0,0 +1,25 @@
+package internal
+
+// Resources of the API, named after the tables they are stored in
+const (
+	// ResourceBuyers are the buyers
+	ResourceBuyers = "buyers"
+	// ResourceEmployees are the employees
+	ResourceEmployees = "employees"
+	// ResourceProductBatches are the product batches
+	ResourceProductBatches = "product_batches"
+	// ResourceProductTypes are the product types
+	ResourceProductTypes = "product_types"
+	// ResourceProducts are the products
+	ResourceProducts = "products"
+	// ResourcePurchaseOrders are the purchase orders
+	ResourcePurchaseOrders = "purchase_orders"
+	// ResourceSections are the sections
+	ResourceSections = "sections"
+	// ResourceSellers are the sellers
+	ResourceSellers = "sellers"
+	// ResourceStockThresholds are the low-stock thresholds of the products (identified by the product)
+	ResourceStockThresholds = "product_stock_thresholds"
+	// ResourceWarehouses are the warehouses
+	ResourceWarehouses = "warehouses"
+)
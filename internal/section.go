@@ -1,5 +1,7 @@
 package internal
 
+import "time"
+
 // Section is a struct that contains the section's information
 type Section struct {
 	// ID is the unique identifier of the section
@@ -20,4 +22,8 @@ type Section struct {
 	WarehouseID int
 	// ProductTypeID is the unique identifier of the type of product stored in the section
 	ProductTypeID int
+	// Version is the version of the section, incremented on every update (optimistic concurrency)
+	Version int
+	// DeletedAt is the moment the section was deleted, zero unless it is
+	DeletedAt time.Time
 }
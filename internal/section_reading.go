@@ -0,0 +1,35 @@
+package internal
+
+import "time"
+
+// SectionReading is a struct that contains a temperature reading of a section sent by its sensors
+type SectionReading struct {
+	// ID is the unique identifier of the reading
+	ID int
+	// SectionID is the unique identifier of the section where the temperature was read
+	SectionID int
+	// Temperature is the temperature read
+	Temperature float64
+	// RecordedAt is the moment at which the temperature was read
+	RecordedAt time.Time
+	// Excursion is set when the temperature is below the minimum temperature of the section (computed, not stored)
+	Excursion bool
+}
+
+// SectionReadingBucket is a struct that summarizes the readings of a section in an interval of time
+type SectionReadingBucket struct {
+	// Start is the beginning of the interval (inclusive)
+	Start time.Time
+	// End is the end of the interval (exclusive)
+	End time.Time
+	// Count is the number of readings in the interval
+	Count int
+	// Minimum is the lowest temperature read in the interval
+	Minimum float64
+	// Maximum is the highest temperature read in the interval
+	Maximum float64
+	// Average is the average temperature read in the interval
+	Average float64
+	// Excursions is the number of readings below the minimum temperature of the section in the interval
+	Excursions int
+}
@@ -0,0 +1,22 @@
+package internal
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrSectionReadingRepositorySectionNotFound is returned when the section of the readings is not found
+	ErrSectionReadingRepositorySectionNotFound = errors.New("repository: section of the readings not found")
+	// ErrSectionReadingRepository is the generic error of the repository
+	ErrSectionReadingRepository = errors.New("repository: internal error")
+)
+
+// SectionReadingRepository is an interface that contains the methods that the section reading repository should support
+type SectionReadingRepository interface {
+	// Save saves the readings of the section and sets its current temperature to the latest one read
+	Save(sectionID int, readings []SectionReading) error
+	// ForEach calls fn with every reading of the section recorded in [from, to), oldest first.
+	// It stops at the first error returned by fn.
+	ForEach(sectionID int, from time.Time, to time.Time, fn func(r SectionReading) error) error
+}
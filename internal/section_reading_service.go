@@ -0,0 +1,29 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrSectionReadingServiceSectionNotFound is returned when the section of the readings is not found
+	ErrSectionReadingServiceSectionNotFound = errors.New("service: section of the readings not found")
+	// ErrSectionReadingServiceInvalidField is returned when a field of a reading or of the query is invalid
+	ErrSectionReadingServiceInvalidField = errors.New("service: invalid field")
+	// ErrSectionReadingService is the generic error of the service
+	ErrSectionReadingService = errors.New("service: internal error")
+	// ErrSectionReadingServiceUnknown is returned when the repository returns an unknown error
+	ErrSectionReadingServiceUnknown = errors.New("service: unknown error")
+)
+
+// SectionReadingService is an interface that contains the methods that the section reading service should support
+type SectionReadingService interface {
+	// Save saves the readings of the section, updating its current temperature, and flags the excursions
+	Save(ctx context.Context, sectionID int, readings []SectionReading) error
+	// ForEach calls fn with every reading of the section recorded in [from, to), oldest first, with the excursions flagged.
+	// It stops at the first error returned by fn.
+	ForEach(sectionID int, from time.Time, to time.Time, fn func(r SectionReading) error) error
+	// Downsample summarizes the readings of the section recorded in [from, to) in buckets of the given interval
+	Downsample(sectionID int, from time.Time, to time.Time, interval time.Duration) ([]SectionReadingBucket, error)
+}
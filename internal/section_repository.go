@@ -13,20 +13,32 @@ var (
 	ErrSectionRepositoryFK = errors.New("repository: section has purchase orders")
 	// ErrSectionRepositoryNoData is returned when the Section has no data
 	ErrSectionRepositoryNoData = errors.New("repository: section table has no data")
+	// ErrSectionRepositoryVersionConflict is returned when the Section was modified since it was read
+	ErrSectionRepositoryVersionConflict = errors.New("repository: section version conflict")
 )
 
 // SectionRepository is an interface that contains the methods that the section repository should support
 type SectionRepository interface {
-	// FindAll returns all the sections
-	GetAll() ([]Section, error)
+	// FindAll returns all the sections, the deleted ones only if includeDeleted
+	GetAll(includeDeleted bool) ([]Section, error)
+	// ForEach calls fn with every section (the deleted ones only if includeDeleted) as it is read, it stops at the first error returned by fn
+	ForEach(includeDeleted bool, fn func(section Section) error) error
 	// FindByID returns the section with the given ID
 	Get(id int) (Section, error)
-	// Save saves the given section
+	// Save saves the given section, empty (current capacity 0)
 	Save(section *Section) error
-	// Update updates the given section
+	// Update updates the given section if its version matches the stored one, except for its current capacity
+	// (only changed by the batches stored in it)
 	Update(section *Section) error
-	// Delete deletes the section with the given ID
-	Delete(id int) error
+	// HasBatchesOfOtherType returns whether the section stores batches (with units left) of products not of the given type
+	HasBatchesOfOtherType(id int, productTypeID int) (bool, error)
+	// Delete marks the section with the given ID as deleted, at the given version (any if zero)
+	Delete(id int, version int) error
+	// Restore unmarks the deleted section with the given ID
+	Restore(id int) error
+	// Purge deletes the section with the given ID permanently, deleted or not, at the given version (any if zero),
+	// if nothing blocks it and, when rows depend on it, cascade confirms they are deleted or nulled with it
+	Purge(id int, version int, cascade bool) error
 	// GetAllProducts
 	// GetAllProducts(id int) ([]map[string]interface{}, error)
 }
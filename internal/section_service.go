@@ -1,6 +1,9 @@
 package internal
 
-import "errors"
+import (
+	"context"
+	"errors"
+)
 
 var (
 	//ErrSectionFieldRequired is returned when the Section field is required
@@ -17,20 +20,33 @@ var (
 	ErrSectionServiceUnkown = errors.New("service: unknown error")
 	// ErrSectionServiceInvalidField is returned when the field is invalid
 	ErrSectionServiceInvalidField = errors.New("service: invalid field")
+	// ErrSectionServiceProductType is returned when the product type of the Section changes while it stores batches of another type
+	ErrSectionServiceProductType = errors.New("service: section stores batches of another product type")
+	// ErrSectionServiceVersionConflict is returned when the Section was modified since it was read
+	ErrSectionServiceVersionConflict = errors.New("service: section version conflict")
 )
 
 // SectionService is an interface that contains the methods that the section service should support
 type SectionService interface {
-	// FindAll returns all the sections
-	GetAll() ([]Section, error)
+	// FindAll returns all the sections, the deleted ones only if includeDeleted
+	GetAll(includeDeleted bool) ([]Section, error)
+	// ForEach calls fn with every section (the deleted ones only if includeDeleted) without loading all of them in memory, it stops at the first error returned by fn
+	ForEach(includeDeleted bool, fn func(section Section) error) error
 	// FindByID returns the section with the given ID
 	Get(id int) (Section, error)
 	// Save saves the given section
-	Save(section *Section) error
+	Save(ctx context.Context, section *Section) error
+	// Validate checks the given section as Save does, without saving it
+	Validate(section *Section) error
 	// Update updates the given section
-	Update(section *Section) error
-	// Delete deletes the section with the given ID
-	Delete(id int) error
+	Update(ctx context.Context, section *Section) error
+	// Delete marks the section with the given ID as deleted, at the given version (any if zero)
+	Delete(ctx context.Context, id int, version int) error
+	// Restore unmarks the deleted section with the given ID
+	Restore(ctx context.Context, id int) error
+	// Purge deletes the section with the given ID permanently, deleted or not, at the given version (any if zero).
+	// The rows that depend on it are deleted or nulled with it only if the context confirms the cascade (WithCascadeConfirmed)
+	Purge(ctx context.Context, id int, version int) error
 	// GetAllProducts returns all the products
 	// GetAllProducts(id int) ([]map[string]interface{}, error)
 }
@@ -1,5 +1,7 @@
 package internal
 
+import "time"
+
 // Seller is a struct that contains the seller's information
 type Seller struct {
 	// ID is the unique identifier of the seller
@@ -14,4 +16,42 @@ type Seller struct {
 	Telephone string
 	// LocalityID is the seller's locality id
 	LocalityID string
+	// Version is the version of the seller, incremented on every update (optimistic concurrency)
+	Version int
+	// DeletedAt is the moment the seller was deleted, zero unless it is
+	DeletedAt time.Time
+}
+
+// SellerPerformance is a struct that contains the activity of the products of a seller
+type SellerPerformance struct {
+	// SellerID is the unique identifier of the seller
+	SellerID int
+	// From is the first day of the period of the sales, zero when unbounded
+	From time.Time
+	// To is the last day of the period of the sales, zero when unbounded
+	To time.Time
+	// Products is the number of products of the seller
+	Products int
+	// UnitsInStock is the number of units left in the batches of the products
+	UnitsInStock int
+	// UnitsSold is the number of units ordered through purchase orders in the period (cancelled orders excluded)
+	UnitsSold int
+	// Revenue is the sum of the sale prices of the units sold
+	Revenue float64
+	// IdleSince is the moment from which a product without movements nor orders is idle
+	IdleSince time.Time
+	// IdleProducts is the products without movements of their batches nor orders since IdleSince
+	IdleProducts []IdleProduct
+}
+
+// IdleProduct is a struct that contains a product without activity
+type IdleProduct struct {
+	// ProductID is the unique identifier of the product
+	ProductID int
+	// ProductCode is the unique code of the product
+	ProductCode string
+	// Description is the description of the product
+	Description string
+	// LastActivity is the moment of the latest movement of its batches or order of the product, zero when it never had any
+	LastActivity time.Time
 }
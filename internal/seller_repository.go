@@ -1,6 +1,9 @@
 package internal
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	// ErrSellerRepositoryNotFound is returned when the seller is not found
@@ -19,18 +22,32 @@ var (
 	ErrSellerRepositoryUnknown = errors.New("sellers repository: unknown error")
 	// ErrSellerRepositoryNothingToUpdate is returned when there is nothing to update
 	ErrSellerRepositoryNothingToUpdate = errors.New("sellers repository: nothing to update")
+	// ErrSellerRepositoryVersionConflict is returned when the seller was modified since it was read
+	ErrSellerRepositoryVersionConflict = errors.New("sellers repository: version conflict")
 )
 
 // SellerRepository is an interface that contains the methods that the seller repository should support
 type SellerRepository interface {
-	// GetAll returns all the sellers
-	GetAll() ([]Seller, error)
+	// GetAll returns all the sellers, the deleted ones only if includeDeleted
+	GetAll(includeDeleted bool) ([]Seller, error)
+	// ForEach calls fn with every seller (the deleted ones only if includeDeleted) as it is read, it stops at the first error returned by fn
+	ForEach(includeDeleted bool, fn func(seller Seller) error) error
 	// Get returns the seller with the given ID
 	Get(id int) (Seller, error)
 	// Save saves the given seller
 	Save(seller *Seller) (int, error)
-	// Update updates the given seller
+	// SaveBulk saves the given sellers in batches. If atomic, either all of them are saved or none
+	SaveBulk(sellers []Seller, atomic bool) ([]BulkResult, error)
+	// Update updates the given seller if its version matches the stored one
 	Update(seller *Seller) error
-	// Delete deletes the seller with the given ID
-	Delete(id int) error
+	// Delete marks the seller with the given ID as deleted, at the given version (any if zero)
+	Delete(id int, version int) error
+	// Restore unmarks the deleted seller with the given ID
+	Restore(id int) error
+	// Purge deletes the seller with the given ID permanently, deleted or not, at the given version (any if zero),
+	// if nothing blocks it and, when rows depend on it, cascade confirms they are deleted or nulled with it
+	Purge(id int, version int, cascade bool) error
+	// GetPerformance returns the activity of the products of the seller with the given ID: the sales between the given
+	// days (unbounded when zero) and the products idle since the given moment
+	GetPerformance(id int, from time.Time, to time.Time, idleSince time.Time) (SellerPerformance, error)
 }
@@ -1,6 +1,10 @@
 package internal
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"time"
+)
 
 var (
 	// ErrSellerServiceNotFound is returned when the seller is not found
@@ -18,18 +22,36 @@ var (
 	ErrSellerServiceUnknown = errors.New("sellers service: unknown error")
 	// ErrSellerServiceNothingToUpdate is returned when there is nothing to update
 	ErrSellerServiceNothingToUpdate = errors.New("sellers service: nothing to update")
+	// ErrSellerServiceVersionConflict is returned when the seller was modified since it was read
+	ErrSellerServiceVersionConflict = errors.New("sellers service: version conflict")
+	// ErrSellerServiceInvalidField is returned when a field is invalid
+	ErrSellerServiceInvalidField = errors.New("sellers service: invalid field")
 )
 
 // SellerService is an interface that contains the methods that the seller service should support
 type SellerService interface {
-	// GetAll returns all the sellers
-	GetAll() ([]Seller, error)
+	// GetAll returns all the sellers, the deleted ones only if includeDeleted
+	GetAll(includeDeleted bool) ([]Seller, error)
+	// ForEach calls fn with every seller (the deleted ones only if includeDeleted) without loading all of them in memory, it stops at the first error returned by fn
+	ForEach(includeDeleted bool, fn func(seller Seller) error) error
 	// Get returns the seller with the given ID
 	Get(id int) (Seller, error)
 	// Save saves the given seller
-	Save(seller *Seller) (Seller, error)
+	Save(ctx context.Context, seller *Seller) (Seller, error)
+	// Validate checks the given seller as Save does, without saving it
+	Validate(seller *Seller) error
+	// SaveBulk saves the given sellers. If atomic, either all of them are saved or none
+	SaveBulk(ctx context.Context, sellers []Seller, atomic bool) ([]BulkResult, error)
 	// Update updates the given seller
-	Update(seller *Seller) error
-	// Delete deletes the seller with the given ID
-	Delete(id int) error
+	Update(ctx context.Context, seller *Seller) error
+	// Delete marks the seller with the given ID as deleted, at the given version (any if zero)
+	Delete(ctx context.Context, id int, version int) error
+	// Restore unmarks the deleted seller with the given ID
+	Restore(ctx context.Context, id int) error
+	// Purge deletes the seller with the given ID permanently, deleted or not, at the given version (any if zero).
+	// The rows that depend on it are deleted or nulled with it only if the context confirms the cascade (WithCascadeConfirmed)
+	Purge(ctx context.Context, id int, version int) error
+	// GetPerformance returns the activity of the products of the seller with the given ID: the sales between the given
+	// days (unbounded when zero) and the products without activity in the last given days
+	GetPerformance(id int, from time.Time, to time.Time, idleDays int) (SellerPerformance, error)
 }
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// NewAuditDefault creates a new instance of the audit service
+func NewAuditDefault(rp internal.AuditRepository) *AuditDefault {
+	return &AuditDefault{
+		rp: rp,
+	}
+}
+
+// AuditDefault is the default implementation of the audit service
+type AuditDefault struct {
+	rp internal.AuditRepository
+}
+
+// Record saves the change made to the resource with the given ID in the audit log, by the source carried by the context.
+// The resources are recorded field by field (snake_case), and on updates only the fields that changed.
+func (s *AuditDefault) Record(ctx context.Context, resource string, id int, action string, before any, after any) (err error) {
+	src := internal.AuditSourceFrom(ctx)
+	e := internal.AuditEntry{
+		Resource:   resource,
+		ResourceID: id,
+		Action:     action,
+		Before:     auditFields(before),
+		After:      auditFields(after),
+		Actor:      src.Actor,
+		RequestID:  src.RequestID,
+		CreatedAt:  time.Now(),
+	}
+	if e.Before != nil && e.After != nil {
+		e.Before, e.After = changedFields(e.Before, e.After)
+	}
+
+	err = s.rp.Save(&e)
+	if err != nil {
+		switch err {
+		case internal.ErrAuditRepository:
+			err = fmt.Errorf("%w: %v", internal.ErrAuditService, err)
+		default:
+			err = fmt.Errorf("%w: %v", internal.ErrAuditServiceUnknown, err)
+		}
+		return
+	}
+
+	return
+}
+
+// GetAll returns the entries of the audit log that meet the filter, the oldest first
+func (s *AuditDefault) GetAll(filter internal.AuditFilter) (entries []internal.AuditEntry, err error) {
+	entries, err = s.rp.GetAll(filter)
+	if err != nil {
+		switch err {
+		case internal.ErrAuditRepository:
+			err = fmt.Errorf("%w: %v", internal.ErrAuditService, err)
+		default:
+			err = fmt.Errorf("%w: %v", internal.ErrAuditServiceUnknown, err)
+		}
+		return
+	}
+
+	return
+}
+
+// auditor records the changes made to the resources of a kind in the audit log. The changes are already committed
+// when they are recorded, so a failure to record one is logged and the request that made it still succeeds:
+// failing it would report a committed change as failed, and a client retrying it would make the change twice.
+type auditor struct {
+	au       internal.AuditService
+	resource string
+}
+
+// record records the change made to the resource with the given ID
+func (a auditor) record(ctx context.Context, id int, action string, before any, after any) {
+	if err := a.au.Record(ctx, a.resource, id, action, before, after); err != nil {
+		log.Printf("audit: %s %s %d: %v", action, a.resource, id, err)
+	}
+}
+
+// auditFields returns the exported fields of the resource (a struct or a pointer to one) by their snake_case names,
+// nil if there is no resource. The moments are RFC 3339, nil when zero, as they read in the API.
+func auditFields(resource any) map[string]any {
+	v := reflect.Indirect(reflect.ValueOf(resource))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := make(map[string]any, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		value := v.Field(i).Interface()
+		if t, ok := value.(time.Time); ok {
+			value = nil
+			if !t.IsZero() {
+				value = t.Format(time.RFC3339)
+			}
+		}
+		fields[snakeCase(f.Name)] = value
+	}
+	return fields
+}
+
+// changedFields returns the fields whose values differ between before and after, with their value on each side
+func changedFields(before map[string]any, after map[string]any) (changedBefore map[string]any, changedAfter map[string]any) {
+	changedBefore = make(map[string]any)
+	changedAfter = make(map[string]any)
+	for name, value := range after {
+		if !reflect.DeepEqual(before[name], value) {
+			changedBefore[name] = before[name]
+			changedAfter[name] = value
+		}
+	}
+	return
+}
+
+// snakeCase returns the snake_case form of the name of a field, e.g. ProductBatchID is product_batch_id
+func snakeCase(name string) string {
+	var sb strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// a word starts after a lowercase letter or a digit, or at the last capital of an acronym
+			if i > 0 && (!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
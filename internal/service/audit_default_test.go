@@ -0,0 +1,91 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Tests for snakeCase function
+func TestSnakeCase(t *testing.T) {
+	cases := []struct {
+		name     string
+		field    string
+		expected string
+	}{
+		{name: "single word", field: "Temperature", expected: "temperature"},
+		{name: "acronym alone", field: "ID", expected: "id"},
+		{name: "words", field: "CurrentTemperature", expected: "current_temperature"},
+		{name: "acronym at the end", field: "WarehouseID", expected: "warehouse_id"},
+		{name: "several words and an acronym", field: "ProductBatchID", expected: "product_batch_id"},
+		{name: "acronym at the start", field: "IDCardNumber", expected: "id_card_number"},
+		{name: "digit", field: "Address2Line", expected: "address2_line"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// arrange
+			// ...
+
+			// act
+			result := snakeCase(c.field)
+
+			// assert
+			require.Equal(t, c.expected, result)
+		})
+	}
+}
+
+// Tests for changedFields function
+func TestChangedFields(t *testing.T) {
+	cases := []struct {
+		name           string
+		before         map[string]any
+		after          map[string]any
+		expectedBefore map[string]any
+		expectedAfter  map[string]any
+	}{
+		{
+			name:           "unchanged field is left out",
+			before:         map[string]any{"id": 1, "current_temperature": 4.5},
+			after:          map[string]any{"id": 1, "current_temperature": 4.5},
+			expectedBefore: map[string]any{},
+			expectedAfter:  map[string]any{},
+		},
+		{
+			name:           "changed field is on both sides",
+			before:         map[string]any{"id": 1, "current_temperature": 4.5},
+			after:          map[string]any{"id": 1, "current_temperature": 2.0},
+			expectedBefore: map[string]any{"current_temperature": 4.5},
+			expectedAfter:  map[string]any{"current_temperature": 2.0},
+		},
+		{
+			name:           "field set from nil",
+			before:         map[string]any{"id": 1, "product_type_id": nil},
+			after:          map[string]any{"id": 1, "product_type_id": 3},
+			expectedBefore: map[string]any{"product_type_id": nil},
+			expectedAfter:  map[string]any{"product_type_id": 3},
+		},
+		{
+			name:           "nested values are compared deeply",
+			before:         map[string]any{"tags": []string{"cold"}, "quantity": 10},
+			after:          map[string]any{"tags": []string{"cold"}, "quantity": 8},
+			expectedBefore: map[string]any{"quantity": 10},
+			expectedAfter:  map[string]any{"quantity": 8},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// arrange
+			// ...
+
+			// act
+			changedBefore, changedAfter := changedFields(c.before, c.after)
+
+			// assert
+			require.Equal(t, c.expectedBefore, changedBefore)
+			require.Equal(t, c.expectedAfter, changedAfter)
+		})
+	}
+}
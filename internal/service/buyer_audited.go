@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// NewBuyerAudited creates a new instance of the buyer service that records the changes made through sv in the audit log
+func NewBuyerAudited(sv internal.BuyerService, au internal.AuditService) *BuyerAudited {
+	return &BuyerAudited{
+		BuyerService: sv,
+		audit:        auditor{au: au, resource: internal.ResourceBuyers},
+	}
+}
+
+// BuyerAudited is the implementation of the buyer service that records the changes in the audit log.
+// The rest of the methods are the ones of the wrapped service.
+type BuyerAudited struct {
+	internal.BuyerService
+	audit auditor
+}
+
+// Save saves the buyer and records its creation
+func (s *BuyerAudited) Save(ctx context.Context, buyer *internal.Buyer) (err error) {
+	if err = s.BuyerService.Save(ctx, buyer); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, buyer.ID, internal.AuditCreate, nil, buyer)
+	return
+}
+
+// Update updates the buyer and records the fields that changed
+func (s *BuyerAudited) Update(ctx context.Context, buyer *internal.Buyer) (err error) {
+	before := s.snapshot(buyer.ID)
+	if err = s.BuyerService.Update(ctx, buyer); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, buyer.ID, internal.AuditUpdate, before, s.snapshot(buyer.ID))
+	return
+}
+
+// Delete deletes the buyer and records it
+func (s *BuyerAudited) Delete(ctx context.Context, id int, version int) (err error) {
+	before := s.snapshot(id)
+	if err = s.BuyerService.Delete(ctx, id, version); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditDelete, before, nil)
+	return
+}
+
+// Restore restores the deleted buyer and records it
+func (s *BuyerAudited) Restore(ctx context.Context, id int) (err error) {
+	if err = s.BuyerService.Restore(ctx, id); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditRestore, nil, s.snapshot(id))
+	return
+}
+
+// Purge deletes the buyer permanently and records it
+func (s *BuyerAudited) Purge(ctx context.Context, id int, version int) (err error) {
+	before := s.snapshot(id)
+	if err = s.BuyerService.Purge(ctx, id, version); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditPurge, before, nil)
+	return
+}
+
+// snapshot returns the buyer with the given ID as it is, nil if it can't be read (e.g. it is deleted)
+func (s *BuyerAudited) snapshot(id int) any {
+	buyer, err := s.BuyerService.Get(id)
+	if err != nil {
+		return nil
+	}
+	return buyer
+}
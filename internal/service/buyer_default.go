@@ -1,11 +1,20 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/manuelfirman/go-API/internal"
 )
 
+const (
+	// analyticsTopProducts is the number of top products of the buyer analytics by default
+	analyticsTopProducts = 5
+	// analyticsMaxTopProducts is the maximum number of top products of the buyer analytics
+	analyticsMaxTopProducts = 50
+)
+
 // NewBuyerDefault creates a new instance of the buyer service
 func NewBuyerDefault(rp internal.BuyerRepository) *BuyerDefault {
 	return &BuyerDefault{
@@ -20,8 +29,8 @@ type BuyerDefault struct {
 }
 
 // GetAll returns all buyers. Returns an error if the operation fails.
-func (s *BuyerDefault) GetAll() (buyers []internal.Buyer, err error) {
-	buyers, err = s.rp.GetAll()
+func (s *BuyerDefault) GetAll(includeDeleted bool) (buyers []internal.Buyer, err error) {
+	buyers, err = s.rp.GetAll(includeDeleted)
 	if err != nil {
 		switch err {
 		case internal.ErrBuyerRepository:
@@ -36,6 +45,27 @@ func (s *BuyerDefault) GetAll() (buyers []internal.Buyer, err error) {
 	return
 }
 
+// ForEach calls fn with every buyer. Errors returned by fn are returned as is.
+func (s *BuyerDefault) ForEach(includeDeleted bool, fn func(buyer internal.Buyer) error) (err error) {
+	var fnErr error
+	err = s.rp.ForEach(includeDeleted, func(buyer internal.Buyer) error {
+		fnErr = fn(buyer)
+		return fnErr
+	})
+	if err != nil && err != fnErr {
+		switch err {
+		case internal.ErrBuyerRepository:
+			err = fmt.Errorf("%w: %v", internal.ErrBuyerService, err)
+		default:
+			err = fmt.Errorf("%w: %v", internal.ErrBuyerServiceUnkown, err)
+		}
+
+		return
+	}
+
+	return
+}
+
 // Get returns a buyer by ID. Returns an error if the buyer is not found.
 func (s *BuyerDefault) Get(id int) (buyer internal.Buyer, err error) {
 	buyer, err = s.rp.Get(id)
@@ -56,7 +86,7 @@ func (s *BuyerDefault) Get(id int) (buyer internal.Buyer, err error) {
 }
 
 // Save saves the given buyer. Returns an error if the operation fails.
-func (s *BuyerDefault) Save(buyer *internal.Buyer) (err error) {
+func (s *BuyerDefault) Save(ctx context.Context, buyer *internal.Buyer) (err error) {
 	// validate buyer
 	if err = ValidateBuyer(buyer); err != nil {
 		return
@@ -80,8 +110,14 @@ func (s *BuyerDefault) Save(buyer *internal.Buyer) (err error) {
 	return
 }
 
+// Validate validates the given buyer as Save does, without saving it.
+func (s *BuyerDefault) Validate(buyer *internal.Buyer) (err error) {
+	err = ValidateBuyer(buyer)
+	return
+}
+
 // Update updates the given buyer. Returns an error if the operation fails.
-func (s *BuyerDefault) Update(buyer *internal.Buyer) (err error) {
+func (s *BuyerDefault) Update(ctx context.Context, buyer *internal.Buyer) (err error) {
 	// validate buyer
 	if err = ValidateBuyer(buyer); err != nil {
 		return
@@ -91,6 +127,8 @@ func (s *BuyerDefault) Update(buyer *internal.Buyer) (err error) {
 	err = s.rp.Update(buyer)
 	if err != nil {
 		switch err {
+		case internal.ErrBuyerRepositoryVersionConflict:
+			err = fmt.Errorf("%w: %v", internal.ErrBuyerServiceVersionConflict, err)
 		case internal.ErrBuyerRepositoryDuplicated:
 			err = fmt.Errorf("%w: %v", internal.ErrBuyerServiceDuplicated, err)
 		case internal.ErrBuyerRepository:
@@ -104,13 +142,57 @@ func (s *BuyerDefault) Update(buyer *internal.Buyer) (err error) {
 	return
 }
 
-// Delete deletes the buyer with the given ID. Returns an error if the operation fails.
-func (s *BuyerDefault) Delete(id int) (err error) {
-	err = s.rp.Delete(id)
+// Delete marks the buyer with the given ID as deleted, at the given version (any if zero). Returns an error if the buyer is not found or was modified since.
+func (s *BuyerDefault) Delete(ctx context.Context, id int, version int) (err error) {
+	err = s.rp.Delete(id, version)
 	if err != nil {
 		switch err {
 		case internal.ErrBuyerRepositoryNotFound:
 			err = fmt.Errorf("%w: %v", internal.ErrBuyerServiceNotFound, err)
+		case internal.ErrBuyerRepositoryVersionConflict:
+			err = fmt.Errorf("%w: %v", internal.ErrBuyerServiceVersionConflict, err)
+		case internal.ErrBuyerRepository:
+			err = fmt.Errorf("%w: %v", internal.ErrBuyerService, err)
+		default:
+			err = fmt.Errorf("%w: %v", internal.ErrBuyerServiceUnkown, err)
+		}
+
+		return
+	}
+
+	return
+}
+
+// Restore unmarks the deleted buyer with the given ID. Returns an error if there is no such buyer.
+func (s *BuyerDefault) Restore(ctx context.Context, id int) (err error) {
+	err = s.rp.Restore(id)
+	if err != nil {
+		switch err {
+		case internal.ErrBuyerRepositoryNotFound:
+			err = fmt.Errorf("%w: %v", internal.ErrBuyerServiceNotFound, err)
+		case internal.ErrBuyerRepository:
+			err = fmt.Errorf("%w: %v", internal.ErrBuyerService, err)
+		default:
+			err = fmt.Errorf("%w: %v", internal.ErrBuyerServiceUnkown, err)
+		}
+
+		return
+	}
+
+	return
+}
+
+// Purge deletes the buyer with the given ID permanently, deleted or not, at the given version (any if zero). Returns an error if the buyer is not found or was modified since.
+func (s *BuyerDefault) Purge(ctx context.Context, id int, version int) (err error) {
+	err = s.rp.Purge(id, version, internal.CascadeConfirmed(ctx))
+	if err != nil {
+		switch err {
+		case internal.ErrBuyerRepositoryNotFound:
+			err = fmt.Errorf("%w: %v", internal.ErrBuyerServiceNotFound, err)
+		case internal.ErrBuyerRepositoryVersionConflict:
+			err = fmt.Errorf("%w: %v", internal.ErrBuyerServiceVersionConflict, err)
+		case internal.ErrDependentsRepositoryUnconfirmed:
+			err = fmt.Errorf("%w: %v", internal.ErrDependentsServiceUnconfirmed, err)
 		case internal.ErrBuyerRepositoryFK:
 			err = fmt.Errorf("%w: %v", internal.ErrBuyerServiceFK, err)
 		case internal.ErrBuyerRepository:
@@ -125,6 +207,43 @@ func (s *BuyerDefault) Delete(id int) (err error) {
 	return
 }
 
+// GetAnalytics returns the purchase activity of the buyer between the given days (unbounded when zero), with its top most
+// ordered products (analyticsTopProducts when zero). Returns an error if the buyer is not found.
+func (s *BuyerDefault) GetAnalytics(id int, from time.Time, to time.Time, top int) (a internal.BuyerAnalytics, err error) {
+	// validate the filter
+	if top == 0 {
+		top = analyticsTopProducts
+	}
+	switch {
+	case !from.IsZero() && !to.IsZero() && to.Before(from):
+		err = fmt.Errorf("%w: %v", internal.ErrBuyerServiceInvalidFilter, "to before from")
+		return
+	case top < 0 || top > analyticsMaxTopProducts:
+		err = fmt.Errorf("%w: top must be between 1 and %d", internal.ErrBuyerServiceInvalidFilter, analyticsMaxTopProducts)
+		return
+	}
+
+	a, err = s.rp.GetAnalytics(id, from, to, top)
+	if err != nil {
+		switch err {
+		case internal.ErrBuyerRepositoryNotFound:
+			err = fmt.Errorf("%w: %v", internal.ErrBuyerServiceNotFound, err)
+		case internal.ErrBuyerRepository:
+			err = fmt.Errorf("%w: %v", internal.ErrBuyerService, err)
+		default:
+			err = fmt.Errorf("%w: %v", internal.ErrBuyerServiceUnkown, err)
+		}
+
+		return
+	}
+
+	if a.Orders > 0 {
+		a.AverageBasket = a.Spend / float64(a.Orders)
+	}
+
+	return
+}
+
 // ValidateBuyer validates a buyer
 func ValidateBuyer(buyer *internal.Buyer) (err error) {
 	// - validate required fields
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// NewDependentsDefault creates a new instance of the dependents service
+func NewDependentsDefault(rp internal.DependentsRepository) *DependentsDefault {
+	return &DependentsDefault{
+		rp: rp,
+	}
+}
+
+// DependentsDefault is the default implementation of the dependents service
+type DependentsDefault struct {
+	rp internal.DependentsRepository
+}
+
+// Get returns the rows that depend on the resource with the given ID, what deleting it permanently would remove or null.
+// Returns an error if the resource is not found.
+func (s *DependentsDefault) Get(resource string, id int) (d internal.Dependents, err error) {
+	d, err = s.rp.Get(resource, id)
+	if err != nil {
+		switch err {
+		case internal.ErrDependentsRepositoryNotFound:
+			err = fmt.Errorf("%w: %v", internal.ErrDependentsServiceNotFound, err)
+		case internal.ErrDependentsRepository, internal.ErrDependentsRepositoryResource:
+			err = fmt.Errorf("%w: %v", internal.ErrDependentsService, err)
+		default:
+			err = fmt.Errorf("%w: %v", internal.ErrDependentsServiceUnknown, err)
+		}
+		return
+	}
+
+	return
+}
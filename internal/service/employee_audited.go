@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// NewEmployeeAudited creates a new instance of the employee service that records the changes made through sv in the audit log
+func NewEmployeeAudited(sv internal.EmployeeService, au internal.AuditService) *EmployeeAudited {
+	return &EmployeeAudited{
+		EmployeeService: sv,
+		audit:           auditor{au: au, resource: internal.ResourceEmployees},
+	}
+}
+
+// EmployeeAudited is the implementation of the employee service that records the changes in the audit log.
+// The rest of the methods are the ones of the wrapped service.
+type EmployeeAudited struct {
+	internal.EmployeeService
+	audit auditor
+}
+
+// Save saves the employee and records its creation
+func (s *EmployeeAudited) Save(ctx context.Context, employee *internal.Employee) (err error) {
+	if err = s.EmployeeService.Save(ctx, employee); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, employee.ID, internal.AuditCreate, nil, employee)
+	return
+}
+
+// Update updates the employee and records the fields that changed
+func (s *EmployeeAudited) Update(ctx context.Context, employee *internal.Employee) (err error) {
+	before := s.snapshot(employee.ID)
+	if err = s.EmployeeService.Update(ctx, employee); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, employee.ID, internal.AuditUpdate, before, s.snapshot(employee.ID))
+	return
+}
+
+// Delete deletes the employee and records it
+func (s *EmployeeAudited) Delete(ctx context.Context, id int, version int) (err error) {
+	before := s.snapshot(id)
+	if err = s.EmployeeService.Delete(ctx, id, version); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditDelete, before, nil)
+	return
+}
+
+// Restore restores the deleted employee and records it
+func (s *EmployeeAudited) Restore(ctx context.Context, id int) (err error) {
+	if err = s.EmployeeService.Restore(ctx, id); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditRestore, nil, s.snapshot(id))
+	return
+}
+
+// Purge deletes the employee permanently and records it
+func (s *EmployeeAudited) Purge(ctx context.Context, id int, version int) (err error) {
+	before := s.snapshot(id)
+	if err = s.EmployeeService.Purge(ctx, id, version); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditPurge, before, nil)
+	return
+}
+
+// Assign moves the employee to the warehouse of the assignment and records the fields that changed
+func (s *EmployeeAudited) Assign(ctx context.Context, assignment *internal.EmployeeAssignment) (err error) {
+	before := s.snapshot(assignment.EmployeeID)
+	if err = s.EmployeeService.Assign(ctx, assignment); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, assignment.EmployeeID, internal.AuditUpdate, before, s.snapshot(assignment.EmployeeID))
+	return
+}
+
+// snapshot returns the employee with the given ID as it is, nil if it can't be read (e.g. it is deleted)
+func (s *EmployeeAudited) snapshot(id int) any {
+	employee, err := s.EmployeeService.Get(id)
+	if err != nil {
+		return nil
+	}
+	return employee
+}
@@ -1,7 +1,9 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/manuelfirman/go-API/internal"
 )
@@ -19,8 +21,8 @@ type EmployeeDefault struct {
 }
 
 // GetAll returns all employees. Returns an error if the operation fails.
-func (s *EmployeeDefault) GetAll() (employees []internal.Employee, err error) {
-	employees, err = s.rp.GetAll()
+func (s *EmployeeDefault) GetAll(includeDeleted bool) (employees []internal.Employee, err error) {
+	employees, err = s.rp.GetAll(includeDeleted)
 	if err != nil {
 		switch err {
 		case internal.ErrEmployeeRepository:
@@ -35,6 +37,27 @@ func (s *EmployeeDefault) GetAll() (employees []internal.Employee, err error) {
 	return
 }
 
+// ForEach calls fn with every employee. Errors returned by fn are returned as is.
+func (s *EmployeeDefault) ForEach(includeDeleted bool, fn func(employee internal.Employee) error) (err error) {
+	var fnErr error
+	err = s.rp.ForEach(includeDeleted, func(employee internal.Employee) error {
+		fnErr = fn(employee)
+		return fnErr
+	})
+	if err != nil && err != fnErr {
+		switch err {
+		case internal.ErrEmployeeRepository:
+			err = fmt.Errorf("%w: %v", internal.ErrEmployeeServiceInternalError, err)
+		default:
+			err = fmt.Errorf("%w: %v", internal.ErrEmployeeServiceUnknown, err)
+		}
+
+		return
+	}
+
+	return
+}
+
 // Get returns an employee by ID. Returns an error if the employee is not found.
 func (s *EmployeeDefault) Get(id int) (employee internal.Employee, err error) {
 	employee, err = s.rp.Get(id)
@@ -55,7 +78,7 @@ func (s *EmployeeDefault) Get(id int) (employee internal.Employee, err error) {
 }
 
 // Save saves the given employee. Returns an error if the operation fails.
-func (s *EmployeeDefault) Save(employee *internal.Employee) (err error) {
+func (s *EmployeeDefault) Save(ctx context.Context, employee *internal.Employee) (err error) {
 	// validate employee
 	if err = validateEmployee(employee); err != nil {
 		return
@@ -66,6 +89,8 @@ func (s *EmployeeDefault) Save(employee *internal.Employee) (err error) {
 		switch err {
 		case internal.ErrEmployeeRepositoryDuplicated:
 			err = fmt.Errorf("%w: %v", internal.ErrEmployeeServiceDuplicated, "card number id")
+		case internal.ErrEmployeeRepositoryWarehouseNotFound:
+			err = fmt.Errorf("%w: %v", internal.ErrEmployeeServiceWarehouseNotFound, err)
 		case internal.ErrEmployeeRepository:
 			err = fmt.Errorf("%w: %v", internal.ErrEmployeeServiceInternalError, err)
 		default:
@@ -78,14 +103,66 @@ func (s *EmployeeDefault) Save(employee *internal.Employee) (err error) {
 	return
 }
 
+// Validate validates the given employee as Save does, without saving it.
+func (s *EmployeeDefault) Validate(employee *internal.Employee) (err error) {
+	err = validateEmployee(employee)
+	return
+}
+
 // Update updates the given employee. Returns an error if the operation fails.
-func (s *EmployeeDefault) Update(employee *internal.Employee) (err error) {
+func (s *EmployeeDefault) Update(ctx context.Context, employee *internal.Employee) (err error) {
 	// validate employee
 	if err = validateEmployee(employee); err != nil {
 		return
 	}
 
 	err = s.rp.Update(employee)
+	if err != nil {
+		switch err {
+		case internal.ErrEmployeeRepositoryVersionConflict:
+			err = fmt.Errorf("%w: %v", internal.ErrEmployeeServiceVersionConflict, err)
+		case internal.ErrEmployeeRepositoryNotFound:
+			err = fmt.Errorf("%w: %v", internal.ErrEmployeeServiceNotFound, err)
+		case internal.ErrEmployeeRepositoryDuplicated:
+			err = fmt.Errorf("%w: %v", internal.ErrEmployeeServiceDuplicated, "card number id")
+		case internal.ErrEmployeeRepositoryWarehouseNotFound:
+			err = fmt.Errorf("%w: %v", internal.ErrEmployeeServiceWarehouseNotFound, err)
+		case internal.ErrEmployeeRepository:
+			err = fmt.Errorf("%w: %v", internal.ErrEmployeeServiceInternalError, err)
+		default:
+			err = fmt.Errorf("%w: %v", internal.ErrEmployeeServiceUnknown, err)
+		}
+
+		return
+	}
+
+	return
+}
+
+// Delete marks the employee with the given ID as deleted, at the given version (any if zero). Returns an error if the employee is not found or was modified since.
+func (s *EmployeeDefault) Delete(ctx context.Context, id int, version int) (err error) {
+	err = s.rp.Delete(id, version)
+	if err != nil {
+		switch err {
+		case internal.ErrEmployeeRepositoryNotFound:
+			err = fmt.Errorf("%w: %v", internal.ErrEmployeeServiceNotFound, err)
+		case internal.ErrEmployeeRepositoryVersionConflict:
+			err = fmt.Errorf("%w: %v", internal.ErrEmployeeServiceVersionConflict, err)
+		case internal.ErrEmployeeRepository:
+			err = fmt.Errorf("%w: %v", internal.ErrEmployeeServiceInternalError, err)
+		default:
+			err = fmt.Errorf("%w: %v", internal.ErrEmployeeServiceUnknown, err)
+		}
+
+		return
+	}
+
+	return
+}
+
+// Restore unmarks the deleted employee with the given ID. Returns an error if there is no such employee.
+func (s *EmployeeDefault) Restore(ctx context.Context, id int) (err error) {
+	err = s.rp.Restore(id)
 	if err != nil {
 		switch err {
 		case internal.ErrEmployeeRepositoryNotFound:
@@ -102,13 +179,17 @@ func (s *EmployeeDefault) Update(employee *internal.Employee) (err error) {
 	return
 }
 
-// Delete deletes the employee with the given ID. Returns an error if the operation fails.
-func (s *EmployeeDefault) Delete(id int) (err error) {
-	err = s.rp.Delete(id)
+// Purge deletes the employee with the given ID permanently, deleted or not, at the given version (any if zero). Returns an error if the employee is not found or was modified since.
+func (s *EmployeeDefault) Purge(ctx context.Context, id int, version int) (err error) {
+	err = s.rp.Purge(id, version, internal.CascadeConfirmed(ctx))
 	if err != nil {
 		switch err {
 		case internal.ErrEmployeeRepositoryNotFound:
 			err = fmt.Errorf("%w: %v", internal.ErrEmployeeServiceNotFound, err)
+		case internal.ErrEmployeeRepositoryVersionConflict:
+			err = fmt.Errorf("%w: %v", internal.ErrEmployeeServiceVersionConflict, err)
+		case internal.ErrDependentsRepositoryUnconfirmed:
+			err = fmt.Errorf("%w: %v", internal.ErrDependentsServiceUnconfirmed, err)
 		case internal.ErrEmployeeRepository:
 			err = fmt.Errorf("%w: %v", internal.ErrEmployeeServiceInternalError, err)
 		default:
@@ -121,6 +202,77 @@ func (s *EmployeeDefault) Delete(id int) (err error) {
 	return
 }
 
+// Assign moves the employee to the warehouse of the assignment from its start date (today when zero).
+// Returns an error if the employee or the warehouse is not found or the assignment starts before the current one.
+func (s *EmployeeDefault) Assign(ctx context.Context, assignment *internal.EmployeeAssignment) (err error) {
+	// validate the assignment
+	if assignment.WarehouseID <= 0 {
+		err = fmt.Errorf("%w: %v", internal.ErrEmployeeServiceFieldRequired, "warehouse id")
+		return
+	}
+	if assignment.StartDate.IsZero() {
+		assignment.StartDate = today()
+	}
+	if assignment.StartDate.After(today()) {
+		err = fmt.Errorf("%w: %v", internal.ErrEmployeeServiceInvalidDate, "effective date")
+		return
+	}
+	assignment.EndDate = time.Time{}
+
+	err = s.rp.Assign(assignment)
+	if err != nil {
+		err = employeeAssignmentError(err)
+		return
+	}
+
+	return
+}
+
+// GetAssignments returns the assignments of the employee, the oldest first. Returns an error if the employee is not found.
+func (s *EmployeeDefault) GetAssignments(employeeID int) (assignments []internal.EmployeeAssignment, err error) {
+	assignments, err = s.rp.GetAssignments(employeeID)
+	if err != nil {
+		err = employeeAssignmentError(err)
+		return
+	}
+
+	return
+}
+
+// GetByWarehouseAt returns the employees that worked at the warehouse on the given date (today when zero).
+// Returns an error if the warehouse is not found.
+func (s *EmployeeDefault) GetByWarehouseAt(warehouseID int, at time.Time) (employees []internal.Employee, err error) {
+	if at.IsZero() {
+		at = today()
+	}
+
+	employees, err = s.rp.GetByWarehouseAt(warehouseID, at)
+	if err != nil {
+		err = employeeAssignmentError(err)
+		return
+	}
+
+	return
+}
+
+// employeeAssignmentError maps an error of the repository assignments to the service ones
+func employeeAssignmentError(err error) error {
+	switch err {
+	case internal.ErrEmployeeRepositoryNotFound:
+		return fmt.Errorf("%w: %v", internal.ErrEmployeeServiceNotFound, err)
+	case internal.ErrEmployeeRepositoryWarehouseNotFound:
+		return fmt.Errorf("%w: %v", internal.ErrEmployeeServiceWarehouseNotFound, err)
+	case internal.ErrEmployeeRepositoryAlreadyAssigned:
+		return fmt.Errorf("%w: %v", internal.ErrEmployeeServiceAlreadyAssigned, err)
+	case internal.ErrEmployeeRepositoryAssignmentDate:
+		return fmt.Errorf("%w: %v", internal.ErrEmployeeServiceAssignmentDate, err)
+	case internal.ErrEmployeeRepository:
+		return fmt.Errorf("%w: %v", internal.ErrEmployeeServiceInternalError, err)
+	default:
+		return fmt.Errorf("%w: %v", internal.ErrEmployeeServiceUnknown, err)
+	}
+}
+
 // validateEmployee validates the employee fields
 func validateEmployee(employee *internal.Employee) (err error) {
 	// validate employee
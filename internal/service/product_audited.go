@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// NewProductAudited creates a new instance of the product service that records the changes made through sv in the audit log
+func NewProductAudited(sv internal.ProductService, au internal.AuditService) *ProductAudited {
+	return &ProductAudited{
+		ProductService: sv,
+		audit:          auditor{au: au, resource: internal.ResourceProducts},
+	}
+}
+
+// ProductAudited is the implementation of the product service that records the changes in the audit log.
+// The rest of the methods are the ones of the wrapped service.
+type ProductAudited struct {
+	internal.ProductService
+	audit auditor
+}
+
+// Save saves the product and records its creation
+func (s *ProductAudited) Save(ctx context.Context, p *internal.Product) (saved internal.Product, err error) {
+	if saved, err = s.ProductService.Save(ctx, p); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, saved.ID, internal.AuditCreate, nil, saved)
+	return
+}
+
+// SaveBulk saves the products and records the creation of the ones that were saved
+func (s *ProductAudited) SaveBulk(ctx context.Context, products []internal.Product, atomic bool) (results []internal.BulkResult, err error) {
+	if results, err = s.ProductService.SaveBulk(ctx, products, atomic); err != nil {
+		return
+	}
+
+	for _, result := range results {
+		if result.Err != nil {
+			continue
+		}
+		saved := products[result.Index]
+		saved.ID = result.ID
+		s.audit.record(ctx, saved.ID, internal.AuditCreate, nil, saved)
+	}
+	return
+}
+
+// Update updates the product and records the fields that changed
+func (s *ProductAudited) Update(ctx context.Context, p *internal.Product) (err error) {
+	before := s.snapshot(p.ID)
+	if err = s.ProductService.Update(ctx, p); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, p.ID, internal.AuditUpdate, before, s.snapshot(p.ID))
+	return
+}
+
+// Delete deletes the product and records it
+func (s *ProductAudited) Delete(ctx context.Context, id int, version int) (err error) {
+	before := s.snapshot(id)
+	if err = s.ProductService.Delete(ctx, id, version); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditDelete, before, nil)
+	return
+}
+
+// Restore restores the deleted product and records it
+func (s *ProductAudited) Restore(ctx context.Context, id int) (err error) {
+	if err = s.ProductService.Restore(ctx, id); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditRestore, nil, s.snapshot(id))
+	return
+}
+
+// Purge deletes the product permanently and records it
+func (s *ProductAudited) Purge(ctx context.Context, id int, version int) (err error) {
+	before := s.snapshot(id)
+	if err = s.ProductService.Purge(ctx, id, version); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditPurge, before, nil)
+	return
+}
+
+// snapshot returns the product with the given ID as it is, nil if it can't be read (e.g. it is deleted)
+func (s *ProductAudited) snapshot(id int) any {
+	p, err := s.ProductService.Get(id)
+	if err != nil {
+		return nil
+	}
+	return p
+}
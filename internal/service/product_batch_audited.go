@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// NewProductBatchAudited creates a new instance of the product batch service that records the changes made through sv in the audit log
+func NewProductBatchAudited(sv internal.ProductBatchService, au internal.AuditService) *ProductBatchAudited {
+	return &ProductBatchAudited{
+		ProductBatchService: sv,
+		audit:               auditor{au: au, resource: internal.ResourceProductBatches},
+	}
+}
+
+// ProductBatchAudited is the implementation of the product batch service that records the changes in the audit log.
+// The rest of the methods are the ones of the wrapped service.
+type ProductBatchAudited struct {
+	internal.ProductBatchService
+	audit auditor
+}
+
+// Save saves the product batch and records its creation
+func (s *ProductBatchAudited) Save(ctx context.Context, pb *internal.ProductBatch) (err error) {
+	if err = s.ProductBatchService.Save(ctx, pb); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, pb.ID, internal.AuditCreate, nil, pb)
+	return
+}
+
+// Update updates the product batch and records the fields that changed
+func (s *ProductBatchAudited) Update(ctx context.Context, pb *internal.ProductBatch) (err error) {
+	before := s.snapshot(pb.ID)
+	if err = s.ProductBatchService.Update(ctx, pb); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, pb.ID, internal.AuditUpdate, before, s.snapshot(pb.ID))
+	return
+}
+
+// Delete deletes the product batch and records it
+func (s *ProductBatchAudited) Delete(ctx context.Context, id int) (err error) {
+	before := s.snapshot(id)
+	if err = s.ProductBatchService.Delete(ctx, id); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditDelete, before, nil)
+	return
+}
+
+// Restore restores the deleted product batch and records it
+func (s *ProductBatchAudited) Restore(ctx context.Context, id int) (err error) {
+	if err = s.ProductBatchService.Restore(ctx, id); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditRestore, nil, s.snapshot(id))
+	return
+}
+
+// Purge deletes the product batch permanently and records it
+func (s *ProductBatchAudited) Purge(ctx context.Context, id int) (err error) {
+	before := s.snapshot(id)
+	if err = s.ProductBatchService.Purge(ctx, id); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditPurge, before, nil)
+	return
+}
+
+// AddMovement records the movement of units of the batch and the fields of the batch that changed
+func (s *ProductBatchAudited) AddMovement(ctx context.Context, m *internal.InventoryMovement) (err error) {
+	before := s.snapshot(m.ProductBatchID)
+	if err = s.ProductBatchService.AddMovement(ctx, m); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, m.ProductBatchID, internal.AuditUpdate, before, s.snapshot(m.ProductBatchID))
+	return
+}
+
+// Transfer moves the units of the batch to another section and records the fields of the batch that changed,
+// and the creation of the new batch when it was split
+func (s *ProductBatchAudited) Transfer(ctx context.Context, t *internal.BatchTransfer) (err error) {
+	before := s.snapshot(t.ProductBatchID)
+	if err = s.ProductBatchService.Transfer(ctx, t); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, t.ProductBatchID, internal.AuditUpdate, before, s.snapshot(t.ProductBatchID))
+	if t.Split {
+		s.audit.record(ctx, t.TargetBatchID, internal.AuditCreate, nil, s.snapshot(t.TargetBatchID))
+	}
+	return
+}
+
+// Receive places the batch brought in by the inbound order and records its creation
+func (s *ProductBatchAudited) Receive(ctx context.Context, o *internal.InboundOrder, pb *internal.ProductBatch) (err error) {
+	if err = s.ProductBatchService.Receive(ctx, o, pb); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, pb.ID, internal.AuditCreate, nil, s.snapshot(pb.ID))
+	return
+}
+
+// snapshot returns the product batch with the given ID as it is, nil if it can't be read (e.g. it is deleted)
+func (s *ProductBatchAudited) snapshot(id int) any {
+	pb, err := s.ProductBatchService.Get(id)
+	if err != nil {
+		return nil
+	}
+	return pb
+}
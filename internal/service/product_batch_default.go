@@ -0,0 +1,400 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// NewProductBatchDefault creates a new instance of the product batch service
+func NewProductBatchDefault(rp internal.ProductBatchRepository) *ProductBatchDefault {
+	return &ProductBatchDefault{
+		rp: rp,
+	}
+}
+
+// ProductBatchDefault is the default implementation of the product batch service
+type ProductBatchDefault struct {
+	rp internal.ProductBatchRepository
+}
+
+// GetAll returns all product batches. Returns an error if the operation fails.
+func (s *ProductBatchDefault) GetAll(includeDeleted bool) (batches []internal.ProductBatch, err error) {
+	batches, err = s.rp.GetAll(includeDeleted)
+	if err != nil {
+		err = productBatchServiceError(err)
+		return
+	}
+
+	return
+}
+
+// ForEach calls fn with every product batch. Errors returned by fn are returned as is.
+func (s *ProductBatchDefault) ForEach(includeDeleted bool, fn func(pb internal.ProductBatch) error) (err error) {
+	var fnErr error
+	err = s.rp.ForEach(includeDeleted, func(pb internal.ProductBatch) error {
+		fnErr = fn(pb)
+		return fnErr
+	})
+	if err != nil && err != fnErr {
+		err = productBatchServiceError(err)
+		return
+	}
+
+	return
+}
+
+// Get returns a product batch by ID. Returns an error if the product batch is not found.
+func (s *ProductBatchDefault) Get(id int) (pb internal.ProductBatch, err error) {
+	pb, err = s.rp.Get(id)
+	if err != nil {
+		err = productBatchServiceError(err)
+		return
+	}
+
+	return
+}
+
+// Save places the given product batch in its section. Returns an error if the section can't hold it
+// (not enough capacity, it stores another product type or it can't keep the recommended temperature of the product).
+func (s *ProductBatchDefault) Save(ctx context.Context, pb *internal.ProductBatch) (err error) {
+	if err = validateProductBatch(pb); err != nil {
+		return
+	}
+
+	err = s.rp.Save(pb)
+	if err != nil {
+		err = productBatchServiceError(err)
+		return
+	}
+
+	return
+}
+
+// Update updates the given product batch. Moving it to another section or changing its current quantity
+// updates the capacity of the sections. Returns an error if the (new) section can't hold it.
+func (s *ProductBatchDefault) Update(ctx context.Context, pb *internal.ProductBatch) (err error) {
+	if err = validateProductBatch(pb); err != nil {
+		return
+	}
+
+	err = s.rp.Update(pb)
+	if err != nil {
+		err = productBatchServiceError(err)
+		return
+	}
+
+	return
+}
+
+// Delete marks the product batch with the given ID as deleted. Returns an error if the product batch is not found.
+func (s *ProductBatchDefault) Delete(ctx context.Context, id int) (err error) {
+	err = s.rp.Delete(id)
+	if err != nil {
+		err = productBatchServiceError(err)
+		return
+	}
+
+	return
+}
+
+// Restore unmarks the deleted product batch with the given ID. Returns an error if there is no such product batch.
+func (s *ProductBatchDefault) Restore(ctx context.Context, id int) (err error) {
+	err = s.rp.Restore(id)
+	if err != nil {
+		err = productBatchServiceError(err)
+		return
+	}
+
+	return
+}
+
+// Purge deletes the product batch with the given ID permanently, deleted or not. Returns an error if the product batch is not found.
+func (s *ProductBatchDefault) Purge(ctx context.Context, id int) (err error) {
+	err = s.rp.Purge(id, internal.CascadeConfirmed(ctx))
+	if err != nil {
+		err = productBatchServiceError(err)
+		return
+	}
+
+	return
+}
+
+// AddMovement applies the movement to the quantity of its batch and records it in the ledger. Receipts add units,
+// picks and write-offs remove them and adjustments do either; transfers are only recorded when a batch is moved.
+// Returns an error if the movement is invalid or leaves the batch out of range.
+func (s *ProductBatchDefault) AddMovement(ctx context.Context, m *internal.InventoryMovement) (err error) {
+	if err = validateMovement(m); err != nil {
+		return
+	}
+
+	err = s.rp.AddMovement(m)
+	if err != nil {
+		err = productBatchServiceError(err)
+		return
+	}
+
+	return
+}
+
+// GetMovements returns the movements of the product batch, oldest first. Returns an error if the batch is not found.
+func (s *ProductBatchDefault) GetMovements(batchID int) (movements []internal.InventoryMovement, err error) {
+	movements, err = s.rp.GetMovements(batchID)
+	if err != nil {
+		err = productBatchServiceError(err)
+		return
+	}
+
+	return
+}
+
+// Transfer moves units of a batch to the target section, splitting the batch if only part of its units are moved.
+// Returns an error if the transfer is invalid or the target section can't hold the units (capacity or temperature).
+func (s *ProductBatchDefault) Transfer(ctx context.Context, t *internal.BatchTransfer) (err error) {
+	switch {
+	case t.ProductBatchID <= 0:
+		err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "product_batch_id")
+	case t.ToSectionID <= 0:
+		err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "section_id")
+	case t.Quantity <= 0:
+		err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "quantity")
+	case t.SourceEmployeeID <= 0:
+		err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "source_employee_id")
+	case t.TargetEmployeeID <= 0:
+		err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "target_employee_id")
+	}
+	if err != nil {
+		return
+	}
+
+	err = s.rp.Transfer(t)
+	if err != nil {
+		err = productBatchServiceError(err)
+		return
+	}
+
+	return
+}
+
+// Receive places the batch brought in by the inbound order in its section and saves the order, all or nothing.
+// Returns an error if the employee doesn't work at the warehouse of the order, the section is not in it or can't hold the batch.
+func (s *ProductBatchDefault) Receive(ctx context.Context, o *internal.InboundOrder, pb *internal.ProductBatch) (err error) {
+	switch {
+	case o.OrderNumber <= 0:
+		err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "order_number")
+	case o.OrderDate.IsZero():
+		err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "order_date")
+	case o.WarehouseID <= 0:
+		err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "warehouse_id")
+	case o.EmployeeID <= 0:
+		err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "employee_id")
+	}
+	if err != nil {
+		return
+	}
+	if err = validateProductBatch(pb); err != nil {
+		return
+	}
+
+	err = s.rp.Receive(o, pb)
+	if err != nil {
+		err = productBatchServiceError(err)
+		return
+	}
+
+	return
+}
+
+// GetExpiring returns the batches with units left that expire within the given number of days from today
+// (the expired ones included), optionally only the ones of a warehouse. Returns an error if the operation fails.
+func (s *ProductBatchDefault) GetExpiring(days int, warehouseID int) (batches []internal.ProductBatch, err error) {
+	if days < 0 {
+		err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "days")
+		return
+	}
+
+	until := today().AddDate(0, 0, days)
+	batches, err = s.rp.GetExpiring(until, warehouseID)
+	if err != nil {
+		err = productBatchServiceError(err)
+		return
+	}
+
+	return
+}
+
+// errPickDone stops the iteration of the batches once the quantity to pick is covered
+var errPickDone = errors.New("pick done")
+
+// Pick returns the batches to pick the given quantity of the product from, first expired first out: the batches
+// that expire first are emptied first and expired batches are skipped. If there are not enough units,
+// the picks cover the units there are. Returns an error if the product is not found.
+func (s *ProductBatchDefault) Pick(productID int, quantity int) (picks []internal.BatchPick, err error) {
+	if quantity <= 0 {
+		err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "quantity")
+		return
+	}
+
+	picks = []internal.BatchPick{}
+	left := quantity
+	err = s.rp.ForEachPickable(productID, today(), func(pb internal.ProductBatch) error {
+		units := min(left, pb.CurrentQuantity)
+		picks = append(picks, internal.BatchPick{
+			BatchID:     pb.ID,
+			BatchNumber: pb.BatchNumber,
+			SectionID:   pb.SectionID,
+			DueDate:     pb.DueDate,
+			Quantity:    units,
+		})
+
+		left -= units
+		if left == 0 {
+			return errPickDone
+		}
+		return nil
+	})
+	if err != nil && err != errPickDone {
+		picks = nil
+		err = productBatchServiceError(err)
+		return
+	}
+	err = nil
+
+	return
+}
+
+// today returns the current date (UTC), as the dates of the batches are stored
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+// GetTemperatureCompliance returns the stored batches that are out of the temperature range of their product,
+// with the reasons why each of them is out of range. Returns an error if the operation fails.
+func (s *ProductBatchDefault) GetTemperatureCompliance() (batches []internal.BatchTemperature, err error) {
+	batches, err = s.rp.GetOutOfTemperature()
+	if err != nil {
+		err = productBatchServiceError(err)
+		return
+	}
+
+	for i := range batches {
+		batches[i].Reasons = temperatureReasons(batches[i])
+	}
+
+	return
+}
+
+// temperatureReasons returns the reasons why the batch is out of the temperature range of its product
+func temperatureReasons(bt internal.BatchTemperature) (reasons []string) {
+	reasons = []string{}
+	if bt.BatchCurrentTemperature > bt.RecommendedTemperature {
+		reasons = append(reasons, internal.TemperatureBatchAboveRecommended)
+	}
+	if bt.BatchCurrentTemperature < bt.BatchMinimumTemperature {
+		reasons = append(reasons, internal.TemperatureBatchBelowMinimum)
+	}
+	if bt.SectionCurrentTemperature > bt.RecommendedTemperature {
+		reasons = append(reasons, internal.TemperatureSectionAboveRecommended)
+	}
+	if bt.SectionCurrentTemperature < bt.BatchMinimumTemperature {
+		reasons = append(reasons, internal.TemperatureSectionBelowMinimum)
+	}
+	if bt.SectionMinimumTemperature > bt.RecommendedTemperature {
+		reasons = append(reasons, internal.TemperatureSectionUnreachable)
+	}
+	if bt.WarehouseMinimumTemperature > bt.RecommendedTemperature {
+		reasons = append(reasons, internal.TemperatureWarehouseUnreachable)
+	}
+
+	return
+}
+
+// productBatchServiceError maps a product batch repository error to a service error
+func productBatchServiceError(err error) error {
+	switch err {
+	case internal.ErrProductBatchRepositoryNotFound:
+		return fmt.Errorf("%w: %v", internal.ErrProductBatchServiceNotFound, err)
+	case internal.ErrProductBatchRepositorySectionNotFound:
+		return fmt.Errorf("%w: %v", internal.ErrProductBatchServiceSectionNotFound, err)
+	case internal.ErrProductBatchRepositoryProductNotFound:
+		return fmt.Errorf("%w: %v", internal.ErrProductBatchServiceProductNotFound, err)
+	case internal.ErrProductBatchRepositoryCapacityExceeded:
+		return fmt.Errorf("%w: %v", internal.ErrProductBatchServiceCapacityExceeded, err)
+	case internal.ErrProductBatchRepositoryTemperature:
+		return fmt.Errorf("%w: %v", internal.ErrProductBatchServiceTemperature, err)
+	case internal.ErrProductBatchRepositoryProductType:
+		return fmt.Errorf("%w: %v", internal.ErrProductBatchServiceProductType, err)
+	case internal.ErrProductBatchRepositoryEmployeeNotFound:
+		return fmt.Errorf("%w: %v", internal.ErrProductBatchServiceEmployeeNotFound, err)
+	case internal.ErrProductBatchRepositoryQuantityOutOfRange:
+		return fmt.Errorf("%w: %v", internal.ErrProductBatchServiceQuantityOutOfRange, err)
+	case internal.ErrProductBatchRepositoryLedgerMismatch:
+		return fmt.Errorf("%w: %v", internal.ErrProductBatchServiceLedgerMismatch, err)
+	case internal.ErrProductBatchRepositorySameSection:
+		return fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "section_id: the batch is already in the section")
+	case internal.ErrProductBatchRepositoryEmployeeWarehouse:
+		return fmt.Errorf("%w: %v", internal.ErrProductBatchServiceEmployeeWarehouse, err)
+	case internal.ErrProductBatchRepositorySectionWarehouse:
+		return fmt.Errorf("%w: %v", internal.ErrProductBatchServiceSectionWarehouse, err)
+	case internal.ErrProductBatchRepositoryOrderDuplicated:
+		return fmt.Errorf("%w: %v", internal.ErrProductBatchServiceOrderDuplicated, err)
+	case internal.ErrProductBatchRepositoryFK:
+		return fmt.Errorf("%w: %v", internal.ErrProductBatchServiceFK, err)
+	case internal.ErrDependentsRepositoryUnconfirmed:
+		return fmt.Errorf("%w: %v", internal.ErrDependentsServiceUnconfirmed, err)
+	case internal.ErrProductBatchRepository:
+		return fmt.Errorf("%w: %v", internal.ErrProductBatchService, err)
+	default:
+		return fmt.Errorf("%w: %v", internal.ErrProductBatchServiceUnknown, err)
+	}
+}
+
+// validateProductBatch validates the product batch fields
+func validateProductBatch(pb *internal.ProductBatch) (err error) {
+	switch {
+	case pb.BatchNumber <= 0:
+		err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "batch_number")
+	case pb.InitialQuantity < 0:
+		err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "initial_quantity")
+	case pb.CurrentQuantity < 0 || pb.CurrentQuantity > pb.InitialQuantity:
+		err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "current_quantity")
+	case pb.ManufacturingHour < 0 || pb.ManufacturingHour > 23:
+		err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "manufacturing_hour")
+	case pb.DueDate.Before(pb.ManufacturingDate):
+		err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "due_date")
+	case pb.SectionID <= 0:
+		err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "section_id")
+	case pb.ProductID <= 0:
+		err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "product_id")
+	}
+
+	return
+}
+
+// validateMovement validates the movement fields: the sign of the quantity must match the type of the movement
+func validateMovement(m *internal.InventoryMovement) (err error) {
+	switch m.Type {
+	case internal.MovementReceipt:
+		if m.Quantity <= 0 {
+			err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "quantity")
+		}
+	case internal.MovementPick, internal.MovementWriteOff:
+		if m.Quantity >= 0 {
+			err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "quantity")
+		}
+	case internal.MovementAdjustment:
+		if m.Quantity == 0 {
+			err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "quantity")
+		}
+	default:
+		err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "type")
+	}
+	if err == nil && m.EmployeeID < 0 {
+		err = fmt.Errorf("%w: %v", internal.ErrProductBatchServiceInvalidField, "employee_id")
+	}
+
+	return
+}
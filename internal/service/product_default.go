@@ -1,6 +1,11 @@
 package service
 
-import "github.com/manuelfirman/go-API/internal"
+import (
+	"context"
+	"time"
+
+	"github.com/manuelfirman/go-API/internal"
+)
 
 // NewProductDefault creates a new instance of the product service
 func NewProductDefault(rp internal.ProductRepository) *ProductDefault {
@@ -16,8 +21,8 @@ type ProductDefault struct {
 }
 
 // GetAll returns all products. Returns an error if the operation fails.
-func (s *ProductDefault) GetAll() (products []internal.Product, err error) {
-	products, err = s.rp.GetAll()
+func (s *ProductDefault) GetAll(includeDeleted bool) (products []internal.Product, err error) {
+	products, err = s.rp.GetAll(includeDeleted)
 	if err != nil {
 		switch err {
 		case internal.ErrProductRepositoryNotFound:
@@ -32,6 +37,26 @@ func (s *ProductDefault) GetAll() (products []internal.Product, err error) {
 
 }
 
+// ForEach calls fn with every product. Errors returned by fn are returned as is.
+func (s *ProductDefault) ForEach(includeDeleted bool, fn func(p internal.Product) error) (err error) {
+	var fnErr error
+	err = s.rp.ForEach(includeDeleted, func(p internal.Product) error {
+		fnErr = fn(p)
+		return fnErr
+	})
+	if err != nil && err != fnErr {
+		switch err {
+		case internal.ErrProductRepositoryConn:
+			err = internal.ErrProductServiceDBError
+		default:
+			err = internal.ErrProductServiceUnkown
+		}
+		return
+	}
+
+	return
+}
+
 // Get returns a product by ID. Returns an error if the product is not found.
 func (s *ProductDefault) Get(id int) (p internal.Product, err error) {
 	p, err = s.rp.Get(id)
@@ -49,7 +74,7 @@ func (s *ProductDefault) Get(id int) (p internal.Product, err error) {
 }
 
 // Save receives a product and saves it. It returns the ID of the product saved.
-func (s *ProductDefault) Save(p *internal.Product) (prod internal.Product, err error) {
+func (s *ProductDefault) Save(ctx context.Context, p *internal.Product) (prod internal.Product, err error) {
 	id, err := s.rp.Save(p)
 	if err != nil {
 		switch err {
@@ -57,6 +82,8 @@ func (s *ProductDefault) Save(p *internal.Product) (prod internal.Product, err e
 			err = internal.ErrProductServiceDuplicated
 		case internal.ErrSellerRepositoryNotFound:
 			err = internal.ErrSellerServiceNotFound
+		case internal.ErrProductTypeRepositoryNotFound:
+			err = internal.ErrProductTypeServiceNotFound
 		default:
 			err = internal.ErrProductServiceUnkown
 		}
@@ -69,8 +96,50 @@ func (s *ProductDefault) Save(p *internal.Product) (prod internal.Product, err e
 	return
 }
 
+// SaveBulk receives products and saves them. If atomic, either all of them are saved or none.
+// The results hold the outcome of each product, the error is only set when the operation itself fails.
+func (s *ProductDefault) SaveBulk(ctx context.Context, products []internal.Product, atomic bool) (results []internal.BulkResult, err error) {
+	results, err = s.rp.SaveBulk(products, atomic)
+	if err != nil {
+		switch err {
+		case internal.ErrProductRepositoryTransaction, internal.ErrProductRepositoryConn:
+			err = internal.ErrProductServiceDBError
+		default:
+			err = internal.ErrProductServiceUnkown
+		}
+		return
+	}
+
+	for i := range results {
+		switch results[i].Err {
+		case nil, internal.ErrBulkNotSaved:
+		case internal.ErrProductRepositoryDuplicated:
+			results[i].Err = internal.ErrProductServiceDuplicated
+		case internal.ErrSellerRepositoryNotFound:
+			results[i].Err = internal.ErrSellerServiceNotFound
+		case internal.ErrProductTypeRepositoryNotFound:
+			results[i].Err = internal.ErrProductTypeServiceNotFound
+		default:
+			results[i].Err = internal.ErrProductServiceUnkown
+		}
+	}
+
+	return
+}
+
+// Validate receives a product and checks it as Save does, without saving it.
+// Save has no rules of its own (fields are validated by the handler), so the product is always valid.
+func (s *ProductDefault) Validate(p *internal.Product) (err error) {
+	return
+}
+
 // Update receives a product and updates it. Returns an error if the product is not found.
-func (s *ProductDefault) Update(p *internal.Product) (err error) {
+func (s *ProductDefault) Update(ctx context.Context, p *internal.Product) (err error) {
+	// the product type can't change while the product has batches stored in sections of another type
+	if err = s.checkProductType(p); err != nil {
+		return
+	}
+
 	err = s.rp.Update(p)
 	if err != nil {
 		switch err {
@@ -80,25 +149,100 @@ func (s *ProductDefault) Update(p *internal.Product) (err error) {
 			err = internal.ErrProductServiceDuplicated
 		case internal.ErrProductRepositoryNothingToUpdate:
 			err = internal.ErrProductServiceNothingToUpdate
+		case internal.ErrProductRepositoryVersionConflict:
+			err = internal.ErrProductServiceVersionConflict
+		case internal.ErrSellerRepositoryNotFound:
+			err = internal.ErrSellerServiceNotFound
+		case internal.ErrProductTypeRepositoryNotFound:
+			err = internal.ErrProductTypeServiceNotFound
+		default:
+			err = internal.ErrProductServiceUnkown
+		}
+
+		return
+	}
+
+	return
+}
+
+// checkProductType checks the product has no batches stored in sections of another type if its product type changes
+func (s *ProductDefault) checkProductType(p *internal.Product) (err error) {
+	stored, err := s.rp.Get(p.ID)
+	if err != nil {
+		switch err {
+		case internal.ErrProductRepositoryNotFound:
+			err = internal.ErrProductServiceNotFound
 		default:
 			err = internal.ErrProductServiceUnkown
 		}
+		return
+	}
+	if stored.ProductTypeID == p.ProductTypeID {
+		return
+	}
 
+	has, err := s.rp.HasBatchesInOtherType(p.ID, p.ProductTypeID)
+	if err != nil {
+		err = internal.ErrProductServiceUnkown
+		return
+	}
+	if has {
+		err = internal.ErrProductServiceProductType
 		return
 	}
 
 	return
 }
 
-// Delete receives a product ID and deletes it. Returns an error if the product is not found.
-func (s *ProductDefault) Delete(id int) (err error) {
-	err = s.rp.Delete(id)
+// Delete marks the product with the given ID as deleted, at the given version (any if zero). Returns an error if the product is not found or was modified since.
+func (s *ProductDefault) Delete(ctx context.Context, id int, version int) (err error) {
+	err = s.rp.Delete(id, version)
 	if err != nil {
 		switch err {
 		case internal.ErrProductRepositoryNotFound:
 			err = internal.ErrProductServiceNotFound
+		case internal.ErrProductRepositoryVersionConflict:
+			err = internal.ErrProductServiceVersionConflict
+		default:
+			err = internal.ErrProductServiceUnkown
+		}
+
+		return
+	}
+
+	return
+}
+
+// Restore unmarks the deleted product with the given ID. Returns an error if there is no such product.
+func (s *ProductDefault) Restore(ctx context.Context, id int) (err error) {
+	err = s.rp.Restore(id)
+	if err != nil {
+		switch err {
+		case internal.ErrProductRepositoryNotFound:
+			err = internal.ErrProductServiceNotFound
+		default:
+			err = internal.ErrProductServiceUnkown
+		}
+
+		return
+	}
+
+	return
+}
+
+// Purge deletes the product with the given ID permanently, deleted or not, at the given version (any if zero). Returns an error if the product is not found or was modified since.
+func (s *ProductDefault) Purge(ctx context.Context, id int, version int) (err error) {
+	err = s.rp.Purge(id, version, internal.CascadeConfirmed(ctx))
+	if err != nil {
+		switch err {
+		case internal.ErrProductRepositoryNotFound:
+			err = internal.ErrProductServiceNotFound
+		case internal.ErrProductRepositoryVersionConflict:
+			err = internal.ErrProductServiceVersionConflict
 		case internal.ErrProductRepositoryForeignKey:
 			err = internal.ErrProductServiceForeignKey
+		case internal.ErrDependentsRepositoryUnconfirmed:
+			err = internal.ErrDependentsServiceUnconfirmed
 		default:
 			err = internal.ErrProductServiceUnkown
 		}
@@ -113,3 +257,86 @@ func (s *ProductDefault) Delete(id int) (err error) {
 func (s *ProductDefault) GetRecordsByProductReport(id int) (products []internal.Product, err error) {
 	return
 }
+
+// GetPrices returns the price timeline of a product and its prices at the given moment (now when zero).
+func (s *ProductDefault) GetPrices(id int, at time.Time) (prices internal.ProductPrices, err error) {
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+
+	records, err := s.rp.GetRecords(id)
+	if err != nil {
+		switch err {
+		case internal.ErrProductRepositoryNotFound:
+			err = internal.ErrProductServiceNotFound
+		default:
+			err = internal.ErrProductServiceUnkown
+		}
+		return
+	}
+
+	prices = internal.ProductPrices{
+		ProductID: id,
+		At:        at,
+		Timeline:  records,
+	}
+	// - the current prices are those of the latest record with a sale price that took effect (the records come oldest first)
+	for _, pr := range records {
+		if pr.LastUpdateDate.After(at) {
+			break
+		}
+		if pr.SalePrice == 0 {
+			// not for sale (NULL sale price)
+			continue
+		}
+		prices.Current = pr
+	}
+
+	return
+}
+
+// GetMargins returns the margins of the products for sale at the given moment (now when zero), of the given seller (all when zero),
+// and their average per seller.
+func (s *ProductDefault) GetMargins(sellerID int, at time.Time) (report internal.MarginReport, err error) {
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+
+	margins, err := s.rp.GetMargins(sellerID, at)
+	if err != nil {
+		switch err {
+		case internal.ErrSellerRepositoryNotFound:
+			err = internal.ErrSellerServiceNotFound
+		default:
+			err = internal.ErrProductServiceUnkown
+		}
+		return
+	}
+
+	report = internal.MarginReport{
+		At:       at,
+		Products: margins,
+	}
+	// - the margins come ordered by seller, so the products of a seller are together
+	for i := range report.Products {
+		m := &report.Products[i]
+		m.Margin = m.Record.SalePrice - m.Record.PurchasePrice
+		if m.Record.SalePrice != 0 {
+			m.MarginRate = m.Margin / m.Record.SalePrice
+		}
+
+		if len(report.Sellers) == 0 || report.Sellers[len(report.Sellers)-1].SellerID != m.SellerID {
+			report.Sellers = append(report.Sellers, internal.SellerMargin{SellerID: m.SellerID})
+		}
+		sm := &report.Sellers[len(report.Sellers)-1]
+		sm.Products++
+		sm.AverageMargin += m.Margin
+		sm.AverageMarginRate += m.MarginRate
+	}
+	for i := range report.Sellers {
+		report.Sellers[i].AverageMargin /= float64(report.Sellers[i].Products)
+		report.Sellers[i].AverageMarginRate /= float64(report.Sellers[i].Products)
+	}
+
+	return
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// NewProductTypeAudited creates a new instance of the product type service that records the changes made through sv in the audit log
+func NewProductTypeAudited(sv internal.ProductTypeService, au internal.AuditService) *ProductTypeAudited {
+	return &ProductTypeAudited{
+		ProductTypeService: sv,
+		audit:              auditor{au: au, resource: internal.ResourceProductTypes},
+	}
+}
+
+// ProductTypeAudited is the implementation of the product type service that records the changes in the audit log.
+// The rest of the methods are the ones of the wrapped service.
+type ProductTypeAudited struct {
+	internal.ProductTypeService
+	audit auditor
+}
+
+// Save saves the product type and records its creation
+func (s *ProductTypeAudited) Save(ctx context.Context, pt *internal.ProductType) (err error) {
+	if err = s.ProductTypeService.Save(ctx, pt); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, pt.ID, internal.AuditCreate, nil, pt)
+	return
+}
+
+// Update updates the product type and records the fields that changed
+func (s *ProductTypeAudited) Update(ctx context.Context, pt *internal.ProductType) (err error) {
+	before := s.snapshot(pt.ID)
+	if err = s.ProductTypeService.Update(ctx, pt); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, pt.ID, internal.AuditUpdate, before, s.snapshot(pt.ID))
+	return
+}
+
+// Delete deletes the product type and records it
+func (s *ProductTypeAudited) Delete(ctx context.Context, id int, version int) (err error) {
+	before := s.snapshot(id)
+	if err = s.ProductTypeService.Delete(ctx, id, version); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditDelete, before, nil)
+	return
+}
+
+// Restore restores the deleted product type and records it
+func (s *ProductTypeAudited) Restore(ctx context.Context, id int) (err error) {
+	if err = s.ProductTypeService.Restore(ctx, id); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditRestore, nil, s.snapshot(id))
+	return
+}
+
+// Purge deletes the product type permanently and records it
+func (s *ProductTypeAudited) Purge(ctx context.Context, id int, version int) (err error) {
+	before := s.snapshot(id)
+	if err = s.ProductTypeService.Purge(ctx, id, version); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditPurge, before, nil)
+	return
+}
+
+// snapshot returns the product type with the given ID as it is, nil if it can't be read (e.g. it is deleted)
+func (s *ProductTypeAudited) snapshot(id int) any {
+	pt, err := s.ProductTypeService.Get(id)
+	if err != nil {
+		return nil
+	}
+	return pt
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// NewProductTypeDefault creates a new instance of the product type service
+func NewProductTypeDefault(rp internal.ProductTypeRepository) *ProductTypeDefault {
+	return &ProductTypeDefault{
+		rp: rp,
+	}
+}
+
+// ProductTypeDefault is the default implementation of the product type service
+type ProductTypeDefault struct {
+	rp internal.ProductTypeRepository
+}
+
+// GetAll returns all product types. Returns an error if the operation fails.
+func (s *ProductTypeDefault) GetAll(includeDeleted bool) (types []internal.ProductType, err error) {
+	types, err = s.rp.GetAll(includeDeleted)
+	if err != nil {
+		err = productTypeServiceError(err)
+		return
+	}
+
+	return
+}
+
+// Get returns a product type by ID. Returns an error if the product type is not found.
+func (s *ProductTypeDefault) Get(id int) (pt internal.ProductType, err error) {
+	pt, err = s.rp.Get(id)
+	if err != nil {
+		err = productTypeServiceError(err)
+		return
+	}
+
+	return
+}
+
+// Save saves the given product type. Returns an error if it is invalid or its name is already in use.
+func (s *ProductTypeDefault) Save(ctx context.Context, pt *internal.ProductType) (err error) {
+	if err = validateProductType(pt); err != nil {
+		return
+	}
+
+	err = s.rp.Save(pt)
+	if err != nil {
+		err = productTypeServiceError(err)
+		return
+	}
+
+	return
+}
+
+// Update updates the given product type. Returns an error if it is invalid or was modified since it was read.
+func (s *ProductTypeDefault) Update(ctx context.Context, pt *internal.ProductType) (err error) {
+	if err = validateProductType(pt); err != nil {
+		return
+	}
+
+	err = s.rp.Update(pt)
+	if err != nil {
+		err = productTypeServiceError(err)
+		return
+	}
+
+	return
+}
+
+// Delete marks the product type with the given ID as deleted, at the given version (any if zero). Returns an error if the product type is not found or was modified since.
+func (s *ProductTypeDefault) Delete(ctx context.Context, id int, version int) (err error) {
+	err = s.rp.Delete(id, version)
+	if err != nil {
+		err = productTypeServiceError(err)
+		return
+	}
+
+	return
+}
+
+// Restore unmarks the deleted product type with the given ID. Returns an error if there is no such product type.
+func (s *ProductTypeDefault) Restore(ctx context.Context, id int) (err error) {
+	err = s.rp.Restore(id)
+	if err != nil {
+		err = productTypeServiceError(err)
+		return
+	}
+
+	return
+}
+
+// Purge deletes the product type with the given ID permanently, deleted or not, at the given version (any if zero). Returns an error if the product type is not found or was modified since.
+func (s *ProductTypeDefault) Purge(ctx context.Context, id int, version int) (err error) {
+	err = s.rp.Purge(id, version, internal.CascadeConfirmed(ctx))
+	if err != nil {
+		err = productTypeServiceError(err)
+		return
+	}
+
+	return
+}
+
+// validateProductType validates the product type fields
+func validateProductType(pt *internal.ProductType) (err error) {
+	switch {
+	case pt.Name == "" || len(pt.Name) > 50:
+		err = fmt.Errorf("%w: %v", internal.ErrProductTypeServiceInvalidField, "name")
+	case pt.StorageClass != internal.StorageClassAmbient && pt.StorageClass != internal.StorageClassRefrigerated && pt.StorageClass != internal.StorageClassFrozen:
+		err = fmt.Errorf("%w: %v", internal.ErrProductTypeServiceInvalidField, "storage_class")
+	case pt.MinimumTemperature > pt.MaximumTemperature:
+		err = fmt.Errorf("%w: %v", internal.ErrProductTypeServiceInvalidField, "minimum_temperature is above maximum_temperature")
+	}
+	return
+}
+
+// productTypeServiceError maps a product type repository error to a service error
+func productTypeServiceError(err error) error {
+	switch err {
+	case internal.ErrProductTypeRepositoryNotFound:
+		return fmt.Errorf("%w: %v", internal.ErrProductTypeServiceNotFound, err)
+	case internal.ErrProductTypeRepositoryDuplicated:
+		return fmt.Errorf("%w: %v", internal.ErrProductTypeServiceDuplicated, err)
+	case internal.ErrProductTypeRepositoryFK:
+		return fmt.Errorf("%w: %v", internal.ErrProductTypeServiceFK, err)
+	case internal.ErrDependentsRepositoryUnconfirmed:
+		return fmt.Errorf("%w: %v", internal.ErrDependentsServiceUnconfirmed, err)
+	case internal.ErrProductTypeRepositoryVersionConflict:
+		return fmt.Errorf("%w: %v", internal.ErrProductTypeServiceVersionConflict, err)
+	case internal.ErrProductTypeRepository:
+		return fmt.Errorf("%w: %v", internal.ErrProductTypeService, err)
+	default:
+		return fmt.Errorf("%w: %v", internal.ErrProductTypeServiceUnknown, err)
+	}
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// NewPurchaseOrderAudited creates a new instance of the purchase order service that records the changes made through sv in the audit log
+func NewPurchaseOrderAudited(sv internal.PurchaseOrderService, au internal.AuditService) *PurchaseOrderAudited {
+	return &PurchaseOrderAudited{
+		PurchaseOrderService: sv,
+		audit:                auditor{au: au, resource: internal.ResourcePurchaseOrders},
+	}
+}
+
+// PurchaseOrderAudited is the implementation of the purchase order service that records the changes in the audit log.
+// The rest of the methods are the ones of the wrapped service.
+type PurchaseOrderAudited struct {
+	internal.PurchaseOrderService
+	audit auditor
+}
+
+// Save saves the purchase order and records its creation
+func (s *PurchaseOrderAudited) Save(ctx context.Context, po *internal.PurchaseOrder) (err error) {
+	if err = s.PurchaseOrderService.Save(ctx, po); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, po.ID, internal.AuditCreate, nil, po)
+	return
+}
+
+// Transition moves the purchase order to the given status and records the fields that changed
+func (s *PurchaseOrderAudited) Transition(ctx context.Context, id int, to internal.OrderStatus) (po internal.PurchaseOrder, err error) {
+	before := s.snapshot(id)
+	if po, err = s.PurchaseOrderService.Transition(ctx, id, to); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditUpdate, before, po)
+	return
+}
+
+// AssignShipment assigns the carrier that ships the purchase order and records the fields that changed
+func (s *PurchaseOrderAudited) AssignShipment(ctx context.Context, id int, carrierID int, warehouseID int) (po internal.PurchaseOrder, err error) {
+	before := s.snapshot(id)
+	if po, err = s.PurchaseOrderService.AssignShipment(ctx, id, carrierID, warehouseID); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditUpdate, before, po)
+	return
+}
+
+// snapshot returns the purchase order with the given ID as it is, nil if it can't be read
+func (s *PurchaseOrderAudited) snapshot(id int) any {
+	po, err := s.PurchaseOrderService.Get(id)
+	if err != nil {
+		return nil
+	}
+	return po
+}
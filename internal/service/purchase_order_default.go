@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// orderStatusTransitions are the statuses a purchase order can go to from each status.
+// Delivered and cancelled orders are final.
+var orderStatusTransitions = map[internal.OrderStatus][]internal.OrderStatus{
+	internal.OrderStatusPending:   {internal.OrderStatusConfirmed, internal.OrderStatusCancelled},
+	internal.OrderStatusConfirmed: {internal.OrderStatusPicking, internal.OrderStatusCancelled},
+	internal.OrderStatusPicking:   {internal.OrderStatusShipped, internal.OrderStatusCancelled},
+	internal.OrderStatusShipped:   {internal.OrderStatusDelivered},
+}
+
+// trackingCodeAttempts is the number of tracking codes generated before giving up on finding one not in use
+const trackingCodeAttempts = 5
+
+// NewPurchaseOrderDefault creates a new instance of the purchase order service
+func NewPurchaseOrderDefault(rp internal.PurchaseOrderRepository, codes internal.TrackingCodeGenerator) *PurchaseOrderDefault {
+	return &PurchaseOrderDefault{
+		rp:    rp,
+		codes: codes,
+	}
+}
+
+// PurchaseOrderDefault is the default implementation of the purchase order service
+type PurchaseOrderDefault struct {
+	rp internal.PurchaseOrderRepository
+	// codes generates the tracking codes of the shipments
+	codes internal.TrackingCodeGenerator
+}
+
+// GetAll returns all purchase orders. Returns an error if the operation fails.
+func (s *PurchaseOrderDefault) GetAll() (orders []internal.PurchaseOrder, err error) {
+	orders, err = s.rp.GetAll()
+	if err != nil {
+		err = purchaseOrderServiceError(err)
+		return
+	}
+
+	return
+}
+
+// Get returns a purchase order by ID. Returns an error if the purchase order is not found.
+func (s *PurchaseOrderDefault) Get(id int) (po internal.PurchaseOrder, err error) {
+	po, err = s.rp.Get(id)
+	if err != nil {
+		err = purchaseOrderServiceError(err)
+		return
+	}
+
+	return
+}
+
+// Save saves the given purchase order, which starts pending. Its tracking code is generated if it has none.
+// Returns an error if the operation fails.
+func (s *PurchaseOrderDefault) Save(ctx context.Context, po *internal.PurchaseOrder) (err error) {
+	switch {
+	case po.OrderNumber <= 0:
+		err = fmt.Errorf("%w: %v", internal.ErrPurchaseOrderServiceInvalidField, "order_number")
+	case po.BuyerID <= 0:
+		err = fmt.Errorf("%w: %v", internal.ErrPurchaseOrderServiceInvalidField, "buyer_id")
+	case po.ProductRecordID <= 0:
+		err = fmt.Errorf("%w: %v", internal.ErrPurchaseOrderServiceInvalidField, "product_record_id")
+	}
+	if err != nil {
+		return
+	}
+
+	po.Status = internal.OrderStatusPending
+	if po.TrackingCode == "" {
+		// - the carrier isn't known yet
+		values := map[string]string{"carrier": "", "order": strconv.Itoa(po.OrderNumber)}
+		_, err = s.withTrackingCode(values, func(code string) error {
+			po.TrackingCode = code
+			return s.rp.Save(po)
+		})
+		return
+	}
+
+	err = s.rp.Save(po)
+	if err != nil {
+		err = purchaseOrderServiceError(err)
+		return
+	}
+
+	return
+}
+
+// Transition moves the purchase order to the given status and records the change in its history.
+// Returns an error if the status workflow doesn't allow going from the current status to the given one.
+func (s *PurchaseOrderDefault) Transition(ctx context.Context, id int, to internal.OrderStatus) (po internal.PurchaseOrder, err error) {
+	po, err = s.Get(id)
+	if err != nil {
+		return
+	}
+
+	if !canTransition(po.Status, to) {
+		err = fmt.Errorf("%w: from %s to %s", internal.ErrPurchaseOrderServiceInvalidTransition, po.Status, to)
+		return
+	}
+
+	err = s.rp.UpdateStatus(&internal.OrderStatusChange{PurchaseOrderID: id, From: po.Status, To: to})
+	if err != nil {
+		err = purchaseOrderServiceError(err)
+		return
+	}
+	po.Status = to
+
+	return
+}
+
+// GetHistory returns the status changes of the purchase order, oldest first. Returns an error if the purchase order is not found.
+func (s *PurchaseOrderDefault) GetHistory(id int) (changes []internal.OrderStatusChange, err error) {
+	changes, err = s.rp.GetHistory(id)
+	if err != nil {
+		err = purchaseOrderServiceError(err)
+		return
+	}
+
+	return
+}
+
+// AssignShipment assigns a carrier to the purchase order and gives it a new tracking code.
+// If carrierID is 0 the carrier with the fewest orders in transit is chosen, preferring the ones in the locality
+// of the warehouse (if warehouseID is 0, the warehouse holding the most units of the ordered product).
+// Returns an error if the order was already shipped, delivered or cancelled.
+func (s *PurchaseOrderDefault) AssignShipment(ctx context.Context, id int, carrierID int, warehouseID int) (po internal.PurchaseOrder, err error) {
+	switch {
+	case carrierID < 0:
+		err = fmt.Errorf("%w: %v", internal.ErrPurchaseOrderServiceInvalidField, "carrier_id")
+	case warehouseID < 0:
+		err = fmt.Errorf("%w: %v", internal.ErrPurchaseOrderServiceInvalidField, "warehouse_id")
+	}
+	if err != nil {
+		return
+	}
+
+	po, err = s.Get(id)
+	if err != nil {
+		return
+	}
+	switch po.Status {
+	case internal.OrderStatusPending, internal.OrderStatusConfirmed, internal.OrderStatusPicking:
+	default:
+		err = fmt.Errorf("%w: %s", internal.ErrPurchaseOrderServiceNotShippable, po.Status)
+		return
+	}
+
+	// carrier
+	var carrier internal.Carrier
+	if carrierID != 0 {
+		carrier, err = s.rp.GetCarrier(carrierID)
+	} else {
+		if warehouseID == 0 {
+			warehouseID, err = s.rp.GetShippingWarehouse(po.ProductRecordID)
+			if err != nil {
+				err = purchaseOrderServiceError(err)
+				return
+			}
+		}
+		carrier, err = s.rp.SuggestCarrier(warehouseID)
+	}
+	if err != nil {
+		err = purchaseOrderServiceError(err)
+		return
+	}
+
+	// tracking code
+	values := map[string]string{"carrier": strconv.Itoa(carrier.CID), "order": strconv.Itoa(po.OrderNumber)}
+	code, err := s.withTrackingCode(values, func(code string) error {
+		return s.rp.AssignShipment(id, po.Status, carrier.ID, code)
+	})
+	if err != nil {
+		return
+	}
+	po.CarrierID = carrier.ID
+	po.TrackingCode = code
+
+	return
+}
+
+// Track returns the purchase order with the given tracking code, its carrier and its status timeline.
+// Returns an error if no order has the tracking code or it has no carrier assigned.
+func (s *PurchaseOrderDefault) Track(code string) (t internal.Tracking, err error) {
+	t.Order, err = s.rp.GetByTrackingCode(code)
+	if err != nil {
+		err = purchaseOrderServiceError(err)
+		return
+	}
+	if t.Order.CarrierID == 0 {
+		// the order isn't shipping yet, so there is nothing to track
+		err = fmt.Errorf("%w: no carrier assigned", internal.ErrPurchaseOrderServiceNotFound)
+		return
+	}
+
+	t.Carrier, err = s.rp.GetCarrier(t.Order.CarrierID)
+	if err != nil {
+		err = purchaseOrderServiceError(err)
+		return
+	}
+
+	t.History, err = s.rp.GetHistory(t.Order.ID)
+	if err != nil {
+		err = purchaseOrderServiceError(err)
+		return
+	}
+
+	return
+}
+
+// withTrackingCode generates a tracking code with the given values and saves it with fn, generating another one
+// while fn fails because a purchase order already has it (up to trackingCodeAttempts times)
+func (s *PurchaseOrderDefault) withTrackingCode(values map[string]string, fn func(code string) error) (code string, err error) {
+	for i := 0; i < trackingCodeAttempts; i++ {
+		code, err = s.codes.Generate(values)
+		if err != nil {
+			err = fmt.Errorf("%w: %v", internal.ErrPurchaseOrderService, err)
+			return
+		}
+
+		err = fn(code)
+		if err == nil {
+			return
+		}
+		if err != internal.ErrPurchaseOrderRepositoryTrackingCodeDuplicated {
+			err = purchaseOrderServiceError(err)
+			return
+		}
+	}
+
+	err = fmt.Errorf("%w: no unused tracking code after %d attempts", internal.ErrPurchaseOrderService, trackingCodeAttempts)
+	return
+}
+
+// canTransition returns whether a purchase order can go from a status to another one
+func canTransition(from internal.OrderStatus, to internal.OrderStatus) bool {
+	for _, allowed := range orderStatusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// purchaseOrderServiceError maps a purchase order repository error to a service error
+func purchaseOrderServiceError(err error) error {
+	switch err {
+	case internal.ErrPurchaseOrderRepositoryNotFound:
+		return fmt.Errorf("%w: %v", internal.ErrPurchaseOrderServiceNotFound, err)
+	case internal.ErrPurchaseOrderRepositoryDuplicated:
+		return fmt.Errorf("%w: %v", internal.ErrPurchaseOrderServiceDuplicated, err)
+	case internal.ErrPurchaseOrderRepositoryTrackingCodeDuplicated:
+		return fmt.Errorf("%w: %v", internal.ErrPurchaseOrderServiceTrackingCodeDuplicated, err)
+	case internal.ErrPurchaseOrderRepositoryFK:
+		return fmt.Errorf("%w: %v", internal.ErrPurchaseOrderServiceFK, err)
+	case internal.ErrPurchaseOrderRepositoryCarrierNotFound:
+		return fmt.Errorf("%w: %v", internal.ErrPurchaseOrderServiceCarrierNotFound, err)
+	case internal.ErrPurchaseOrderRepositoryStatusConflict:
+		return fmt.Errorf("%w: %v", internal.ErrPurchaseOrderServiceStatusConflict, err)
+	case internal.ErrPurchaseOrderRepository:
+		return fmt.Errorf("%w: %v", internal.ErrPurchaseOrderService, err)
+	default:
+		return fmt.Errorf("%w: %v", internal.ErrPurchaseOrderServiceUnknown, err)
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// NewSectionAudited creates a new instance of the section service that records the changes made through sv in the audit log
+func NewSectionAudited(sv internal.SectionService, au internal.AuditService) *SectionAudited {
+	return &SectionAudited{
+		SectionService: sv,
+		audit:          auditor{au: au, resource: internal.ResourceSections},
+	}
+}
+
+// SectionAudited is the implementation of the section service that records the changes in the audit log.
+// The rest of the methods are the ones of the wrapped service.
+type SectionAudited struct {
+	internal.SectionService
+	audit auditor
+}
+
+// Save saves the section and records its creation
+func (s *SectionAudited) Save(ctx context.Context, section *internal.Section) (err error) {
+	if err = s.SectionService.Save(ctx, section); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, section.ID, internal.AuditCreate, nil, section)
+	return
+}
+
+// Update updates the section and records the fields that changed
+func (s *SectionAudited) Update(ctx context.Context, section *internal.Section) (err error) {
+	before := s.snapshot(section.ID)
+	if err = s.SectionService.Update(ctx, section); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, section.ID, internal.AuditUpdate, before, s.snapshot(section.ID))
+	return
+}
+
+// Delete deletes the section and records it
+func (s *SectionAudited) Delete(ctx context.Context, id int, version int) (err error) {
+	before := s.snapshot(id)
+	if err = s.SectionService.Delete(ctx, id, version); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditDelete, before, nil)
+	return
+}
+
+// Restore restores the deleted section and records it
+func (s *SectionAudited) Restore(ctx context.Context, id int) (err error) {
+	if err = s.SectionService.Restore(ctx, id); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditRestore, nil, s.snapshot(id))
+	return
+}
+
+// Purge deletes the section permanently and records it
+func (s *SectionAudited) Purge(ctx context.Context, id int, version int) (err error) {
+	before := s.snapshot(id)
+	if err = s.SectionService.Purge(ctx, id, version); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditPurge, before, nil)
+	return
+}
+
+// snapshot returns the section with the given ID as it is, nil if it can't be read (e.g. it is deleted)
+func (s *SectionAudited) snapshot(id int) any {
+	section, err := s.SectionService.Get(id)
+	if err != nil {
+		return nil
+	}
+	return section
+}
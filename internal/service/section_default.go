@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/manuelfirman/go-API/internal"
@@ -19,8 +20,8 @@ type SectionDefault struct {
 }
 
 // GetAll returns all sections. Returns an error if the operation fails.
-func (s *SectionDefault) GetAll() (sections []internal.Section, err error) {
-	sections, err = s.rp.GetAll()
+func (s *SectionDefault) GetAll(includeDeleted bool) (sections []internal.Section, err error) {
+	sections, err = s.rp.GetAll(includeDeleted)
 	if err != nil {
 		switch err {
 		case internal.ErrSectionRepository:
@@ -35,6 +36,27 @@ func (s *SectionDefault) GetAll() (sections []internal.Section, err error) {
 	return
 }
 
+// ForEach calls fn with every section. Errors returned by fn are returned as is.
+func (s *SectionDefault) ForEach(includeDeleted bool, fn func(section internal.Section) error) (err error) {
+	var fnErr error
+	err = s.rp.ForEach(includeDeleted, func(section internal.Section) error {
+		fnErr = fn(section)
+		return fnErr
+	})
+	if err != nil && err != fnErr {
+		switch err {
+		case internal.ErrSectionRepository:
+			err = fmt.Errorf("%w: %v", internal.ErrSectionService, err)
+		default:
+			err = fmt.Errorf("%w: %v", internal.ErrSectionServiceUnkown, err)
+		}
+
+		return
+	}
+
+	return
+}
+
 // Get returns a section by ID. Returns an error if the section is not found.
 func (s *SectionDefault) Get(id int) (section internal.Section, err error) {
 	section, err = s.rp.Get(id)
@@ -55,7 +77,9 @@ func (s *SectionDefault) Get(id int) (section internal.Section, err error) {
 }
 
 // Save saves the given section. Returns an error if the operation fails.
-func (s *SectionDefault) Save(section *internal.Section) (err error) {
+func (s *SectionDefault) Save(ctx context.Context, section *internal.Section) (err error) {
+	// a new section is empty, its capacity only changes with the batches stored in it
+	section.CurrentCapacity = 0
 	if err = validateSection(section); err != nil {
 		return
 	}
@@ -67,6 +91,8 @@ func (s *SectionDefault) Save(section *internal.Section) (err error) {
 			err = fmt.Errorf("%w: %v", internal.ErrSectionServiceDuplicated, err)
 		case internal.ErrSectionRepositoryFK:
 			err = fmt.Errorf("%w: %v", internal.ErrSectionServiceFK, err)
+		case internal.ErrProductTypeRepositoryNotFound:
+			err = fmt.Errorf("%w: %v", internal.ErrProductTypeServiceNotFound, err)
 		case internal.ErrSectionRepository:
 			err = fmt.Errorf("%w: %v", internal.ErrSectionService, err)
 		default:
@@ -79,17 +105,33 @@ func (s *SectionDefault) Save(section *internal.Section) (err error) {
 	return
 }
 
+// Validate validates the given section as Save does, without saving it.
+func (s *SectionDefault) Validate(section *internal.Section) (err error) {
+	section.CurrentCapacity = 0
+	err = validateSection(section)
+	return
+}
+
 // Update updates the given section. Returns an error if the operation fails.
-func (s *SectionDefault) Update(section *internal.Section) (err error) {
+func (s *SectionDefault) Update(ctx context.Context, section *internal.Section) (err error) {
 	if err = validateSection(section); err != nil {
 		return
 	}
 
+	// the product type can't change while the section stores batches of products of another type
+	if err = s.checkProductType(section); err != nil {
+		return
+	}
+
 	err = s.rp.Update(section)
 	if err != nil {
 		switch err {
+		case internal.ErrSectionRepositoryVersionConflict:
+			err = fmt.Errorf("%w: %v", internal.ErrSectionServiceVersionConflict, err)
 		case internal.ErrSectionRepositoryFK:
 			err = fmt.Errorf("%w: %v", internal.ErrSectionServiceFK, err)
+		case internal.ErrProductTypeRepositoryNotFound:
+			err = fmt.Errorf("%w: %v", internal.ErrProductTypeServiceNotFound, err)
 		case internal.ErrSectionRepository:
 			err = fmt.Errorf("%w: %v", internal.ErrSectionService, err)
 		default:
@@ -102,13 +144,57 @@ func (s *SectionDefault) Update(section *internal.Section) (err error) {
 	return
 }
 
-// Delete deletes a section by ID. Returns an error if the operation fails.
-func (s *SectionDefault) Delete(id int) (err error) {
-	err = s.rp.Delete(id)
+// Delete marks the section with the given ID as deleted, at the given version (any if zero). Returns an error if the section is not found or was modified since.
+func (s *SectionDefault) Delete(ctx context.Context, id int, version int) (err error) {
+	err = s.rp.Delete(id, version)
 	if err != nil {
 		switch err {
 		case internal.ErrSectionRepositoryNotFound:
 			err = fmt.Errorf("%w: %v", internal.ErrSectionServiceNotFound, err)
+		case internal.ErrSectionRepositoryVersionConflict:
+			err = fmt.Errorf("%w: %v", internal.ErrSectionServiceVersionConflict, err)
+		case internal.ErrSectionRepository:
+			err = fmt.Errorf("%w: %v", internal.ErrSectionService, err)
+		default:
+			err = fmt.Errorf("%w: %v", internal.ErrSectionServiceUnkown, err)
+		}
+
+		return
+	}
+
+	return
+}
+
+// Restore unmarks the deleted section with the given ID. Returns an error if there is no such section.
+func (s *SectionDefault) Restore(ctx context.Context, id int) (err error) {
+	err = s.rp.Restore(id)
+	if err != nil {
+		switch err {
+		case internal.ErrSectionRepositoryNotFound:
+			err = fmt.Errorf("%w: %v", internal.ErrSectionServiceNotFound, err)
+		case internal.ErrSectionRepository:
+			err = fmt.Errorf("%w: %v", internal.ErrSectionService, err)
+		default:
+			err = fmt.Errorf("%w: %v", internal.ErrSectionServiceUnkown, err)
+		}
+
+		return
+	}
+
+	return
+}
+
+// Purge deletes the section with the given ID permanently, deleted or not, at the given version (any if zero). Returns an error if the section is not found or was modified since.
+func (s *SectionDefault) Purge(ctx context.Context, id int, version int) (err error) {
+	err = s.rp.Purge(id, version, internal.CascadeConfirmed(ctx))
+	if err != nil {
+		switch err {
+		case internal.ErrSectionRepositoryNotFound:
+			err = fmt.Errorf("%w: %v", internal.ErrSectionServiceNotFound, err)
+		case internal.ErrSectionRepositoryVersionConflict:
+			err = fmt.Errorf("%w: %v", internal.ErrSectionServiceVersionConflict, err)
+		case internal.ErrDependentsRepositoryUnconfirmed:
+			err = fmt.Errorf("%w: %v", internal.ErrDependentsServiceUnconfirmed, err)
 		case internal.ErrSectionRepository:
 			err = fmt.Errorf("%w: %v", internal.ErrSectionService, err)
 		case internal.ErrSectionRepositoryFK:
@@ -123,6 +209,38 @@ func (s *SectionDefault) Delete(id int) (err error) {
 	return
 }
 
+// checkProductType checks the section doesn't store batches of another type if its product type changes
+func (s *SectionDefault) checkProductType(section *internal.Section) (err error) {
+	stored, err := s.rp.Get(section.ID)
+	if err != nil {
+		switch err {
+		case internal.ErrSectionRepositoryNotFound:
+			err = fmt.Errorf("%w: %v", internal.ErrSectionServiceNotFound, err)
+		case internal.ErrSectionRepository:
+			err = fmt.Errorf("%w: %v", internal.ErrSectionService, err)
+		default:
+			err = fmt.Errorf("%w: %v", internal.ErrSectionServiceUnkown, err)
+		}
+
+		return
+	}
+	if stored.ProductTypeID == section.ProductTypeID {
+		return
+	}
+
+	has, err := s.rp.HasBatchesOfOtherType(section.ID, section.ProductTypeID)
+	if err != nil {
+		err = fmt.Errorf("%w: %v", internal.ErrSectionService, err)
+		return
+	}
+	if has {
+		err = fmt.Errorf("%w: product_type_id", internal.ErrSectionServiceProductType)
+		return
+	}
+
+	return
+}
+
 // validateSection validates the section fields
 func validateSection(section *internal.Section) (err error) {
 	if section.SectionNumber == 0 {
@@ -157,5 +275,9 @@ func validateSection(section *internal.Section) (err error) {
 		err = fmt.Errorf("%w: %v", internal.ErrSectionServiceInvalidField, "product_type_id")
 	}
 
+	if section.CurrentCapacity > section.MaximumCapacity {
+		err = fmt.Errorf("%w: %v", internal.ErrSectionServiceInvalidField, "current_capacity exceeds maximum_capacity")
+	}
+
 	return
 }
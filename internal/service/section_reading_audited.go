@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// NewSectionReadingAudited creates a new instance of the section reading service that records in the audit log
+// the changes that the readings saved through sv make to the sections, read through svSection
+func NewSectionReadingAudited(sv internal.SectionReadingService, svSection internal.SectionService, au internal.AuditService) *SectionReadingAudited {
+	return &SectionReadingAudited{
+		SectionReadingService: sv,
+		svSection:             svSection,
+		audit:                 auditor{au: au, resource: internal.ResourceSections},
+	}
+}
+
+// SectionReadingAudited is the implementation of the section reading service that records in the audit log
+// the changes made to the sections. The rest of the methods are the ones of the wrapped service.
+type SectionReadingAudited struct {
+	internal.SectionReadingService
+	svSection internal.SectionService
+	audit     auditor
+}
+
+// Save saves the readings of the section and records the fields of the section that changed (its current temperature)
+func (s *SectionReadingAudited) Save(ctx context.Context, sectionID int, readings []internal.SectionReading) (err error) {
+	before := s.snapshot(sectionID)
+	if err = s.SectionReadingService.Save(ctx, sectionID, readings); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, sectionID, internal.AuditUpdate, before, s.snapshot(sectionID))
+	return
+}
+
+// snapshot returns the section with the given ID as it is, nil if it can't be read (e.g. it is deleted)
+func (s *SectionReadingAudited) snapshot(id int) any {
+	section, err := s.svSection.Get(id)
+	if err != nil {
+		return nil
+	}
+	return section
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// readingClockSkew is how far in the future a reading can be recorded, to tolerate the clocks of the sensors
+const readingClockSkew = 5 * time.Minute
+
+// NewSectionReadingDefault creates a new instance of the section reading service
+func NewSectionReadingDefault(rp internal.SectionReadingRepository, rpSection internal.SectionRepository) *SectionReadingDefault {
+	return &SectionReadingDefault{
+		rp:        rp,
+		rpSection: rpSection,
+	}
+}
+
+// SectionReadingDefault is the default implementation of the section reading service
+type SectionReadingDefault struct {
+	rp        internal.SectionReadingRepository
+	rpSection internal.SectionRepository
+}
+
+// Save saves the readings of the section, updating its current temperature to the latest one read.
+// Readings without a recorded time are recorded now. The readings below the minimum temperature of the
+// section are flagged as excursions. Returns an error if a reading is invalid or the section is not found.
+func (s *SectionReadingDefault) Save(ctx context.Context, sectionID int, readings []internal.SectionReading) (err error) {
+	now := time.Now().UTC()
+	for i := range readings {
+		if readings[i].RecordedAt.IsZero() {
+			readings[i].RecordedAt = now
+		}
+		readings[i].RecordedAt = readings[i].RecordedAt.UTC()
+		if readings[i].RecordedAt.After(now.Add(readingClockSkew)) {
+			err = fmt.Errorf("%w: %v", internal.ErrSectionReadingServiceInvalidField, "recorded_at")
+			return
+		}
+	}
+
+	section, err := s.getSection(sectionID)
+	if err != nil {
+		return
+	}
+
+	err = s.rp.Save(sectionID, readings)
+	if err != nil {
+		err = sectionReadingServiceError(err)
+		return
+	}
+
+	for i := range readings {
+		readings[i].Excursion = readings[i].Temperature < section.MinimumTemperature
+	}
+
+	return
+}
+
+// ForEach calls fn with every reading of the section recorded in [from, to), oldest first, with the excursions flagged.
+// Errors returned by fn are returned as is.
+func (s *SectionReadingDefault) ForEach(sectionID int, from time.Time, to time.Time, fn func(r internal.SectionReading) error) (err error) {
+	if !from.Before(to) {
+		err = fmt.Errorf("%w: %v", internal.ErrSectionReadingServiceInvalidField, "from")
+		return
+	}
+
+	section, err := s.getSection(sectionID)
+	if err != nil {
+		return
+	}
+
+	var fnErr error
+	err = s.rp.ForEach(sectionID, from, to, func(r internal.SectionReading) error {
+		r.Excursion = r.Temperature < section.MinimumTemperature
+		fnErr = fn(r)
+		return fnErr
+	})
+	if err != nil && err != fnErr {
+		err = sectionReadingServiceError(err)
+		return
+	}
+
+	return
+}
+
+// Downsample summarizes the readings of the section recorded in [from, to) in buckets of the given interval,
+// aligned to multiples of the interval. Intervals without readings are left out.
+func (s *SectionReadingDefault) Downsample(sectionID int, from time.Time, to time.Time, interval time.Duration) (buckets []internal.SectionReadingBucket, err error) {
+	if interval <= 0 {
+		err = fmt.Errorf("%w: %v", internal.ErrSectionReadingServiceInvalidField, "interval")
+		return
+	}
+
+	// the readings come oldest first, so each one either falls in the last bucket or opens a new one
+	var sum float64
+	err = s.ForEach(sectionID, from, to, func(r internal.SectionReading) error {
+		start := r.RecordedAt.Truncate(interval)
+		last := len(buckets) - 1
+		if last < 0 || !buckets[last].Start.Equal(start) {
+			if last >= 0 {
+				buckets[last].Average = sum / float64(buckets[last].Count)
+			}
+			buckets = append(buckets, internal.SectionReadingBucket{
+				Start:   start,
+				End:     start.Add(interval),
+				Minimum: r.Temperature,
+				Maximum: r.Temperature,
+			})
+			last++
+			sum = 0
+		}
+
+		b := &buckets[last]
+		b.Count++
+		b.Minimum = min(b.Minimum, r.Temperature)
+		b.Maximum = max(b.Maximum, r.Temperature)
+		if r.Excursion {
+			b.Excursions++
+		}
+		sum += r.Temperature
+		return nil
+	})
+	if err != nil {
+		buckets = nil
+		return
+	}
+	if last := len(buckets) - 1; last >= 0 {
+		buckets[last].Average = sum / float64(buckets[last].Count)
+	}
+
+	return
+}
+
+// getSection returns the section of the readings
+func (s *SectionReadingDefault) getSection(id int) (section internal.Section, err error) {
+	section, err = s.rpSection.Get(id)
+	if err != nil {
+		switch err {
+		case internal.ErrSectionRepositoryNotFound:
+			err = fmt.Errorf("%w: %v", internal.ErrSectionReadingServiceSectionNotFound, err)
+		default:
+			err = fmt.Errorf("%w: %v", internal.ErrSectionReadingService, err)
+		}
+
+		return
+	}
+
+	return
+}
+
+// sectionReadingServiceError maps a section reading repository error to a service error
+func sectionReadingServiceError(err error) error {
+	switch err {
+	case internal.ErrSectionReadingRepositorySectionNotFound:
+		return fmt.Errorf("%w: %v", internal.ErrSectionReadingServiceSectionNotFound, err)
+	case internal.ErrSectionReadingRepository:
+		return fmt.Errorf("%w: %v", internal.ErrSectionReadingService, err)
+	default:
+		return fmt.Errorf("%w: %v", internal.ErrSectionReadingServiceUnknown, err)
+	}
+}
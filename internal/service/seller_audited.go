@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// NewSellerAudited creates a new instance of the seller service that records the changes made through sv in the audit log
+func NewSellerAudited(sv internal.SellerService, au internal.AuditService) *SellerAudited {
+	return &SellerAudited{
+		SellerService: sv,
+		audit:         auditor{au: au, resource: internal.ResourceSellers},
+	}
+}
+
+// SellerAudited is the implementation of the seller service that records the changes in the audit log.
+// The rest of the methods are the ones of the wrapped service.
+type SellerAudited struct {
+	internal.SellerService
+	audit auditor
+}
+
+// Save saves the seller and records its creation
+func (s *SellerAudited) Save(ctx context.Context, seller *internal.Seller) (saved internal.Seller, err error) {
+	if saved, err = s.SellerService.Save(ctx, seller); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, saved.ID, internal.AuditCreate, nil, saved)
+	return
+}
+
+// SaveBulk saves the sellers and records the creation of the ones that were saved
+func (s *SellerAudited) SaveBulk(ctx context.Context, sellers []internal.Seller, atomic bool) (results []internal.BulkResult, err error) {
+	if results, err = s.SellerService.SaveBulk(ctx, sellers, atomic); err != nil {
+		return
+	}
+
+	for _, result := range results {
+		if result.Err != nil {
+			continue
+		}
+		saved := sellers[result.Index]
+		saved.ID = result.ID
+		s.audit.record(ctx, saved.ID, internal.AuditCreate, nil, saved)
+	}
+	return
+}
+
+// Update updates the seller and records the fields that changed
+func (s *SellerAudited) Update(ctx context.Context, seller *internal.Seller) (err error) {
+	before := s.snapshot(seller.ID)
+	if err = s.SellerService.Update(ctx, seller); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, seller.ID, internal.AuditUpdate, before, s.snapshot(seller.ID))
+	return
+}
+
+// Delete deletes the seller and records it
+func (s *SellerAudited) Delete(ctx context.Context, id int, version int) (err error) {
+	before := s.snapshot(id)
+	if err = s.SellerService.Delete(ctx, id, version); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditDelete, before, nil)
+	return
+}
+
+// Restore restores the deleted seller and records it
+func (s *SellerAudited) Restore(ctx context.Context, id int) (err error) {
+	if err = s.SellerService.Restore(ctx, id); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditRestore, nil, s.snapshot(id))
+	return
+}
+
+// Purge deletes the seller permanently and records it
+func (s *SellerAudited) Purge(ctx context.Context, id int, version int) (err error) {
+	before := s.snapshot(id)
+	if err = s.SellerService.Purge(ctx, id, version); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditPurge, before, nil)
+	return
+}
+
+// snapshot returns the seller with the given ID as it is, nil if it can't be read (e.g. it is deleted)
+func (s *SellerAudited) snapshot(id int) any {
+	seller, err := s.SellerService.Get(id)
+	if err != nil {
+		return nil
+	}
+	return seller
+}
@@ -1,6 +1,12 @@
 package service
 
-import "github.com/manuelfirman/go-API/internal"
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/manuelfirman/go-API/internal"
+)
 
 // NewProductDefault creates a new instance of the product service
 func NewSellerDefault(rp internal.SellerRepository) *SellerDefault {
@@ -16,8 +22,8 @@ type SellerDefault struct {
 }
 
 // GetAll returns all products. Returns an error if the operation fails.
-func (s *SellerDefault) GetAll() (products []internal.Seller, err error) {
-	products, err = s.rp.GetAll()
+func (s *SellerDefault) GetAll(includeDeleted bool) (products []internal.Seller, err error) {
+	products, err = s.rp.GetAll(includeDeleted)
 	if err != nil {
 		switch err {
 		case internal.ErrSellerRepositoryNotFound:
@@ -31,6 +37,26 @@ func (s *SellerDefault) GetAll() (products []internal.Seller, err error) {
 	return
 }
 
+// ForEach calls fn with every seller. Errors returned by fn are returned as is.
+func (s *SellerDefault) ForEach(includeDeleted bool, fn func(seller internal.Seller) error) (err error) {
+	var fnErr error
+	err = s.rp.ForEach(includeDeleted, func(seller internal.Seller) error {
+		fnErr = fn(seller)
+		return fnErr
+	})
+	if err != nil && err != fnErr {
+		switch err {
+		case internal.ErrSellerRepositoryNotFound:
+			err = internal.ErrSellerServiceNotFound
+		default:
+			err = internal.ErrSellerServiceUnknown
+		}
+		return
+	}
+
+	return
+}
+
 // Get returns a product by ID. Returns an error if the product is not found.
 func (s *SellerDefault) Get(id int) (p internal.Seller, err error) {
 	p, err = s.rp.Get(id)
@@ -48,7 +74,7 @@ func (s *SellerDefault) Get(id int) (p internal.Seller, err error) {
 }
 
 // Save receives a product and saves it. It returns the ID of the product saved.
-func (s *SellerDefault) Save(sell *internal.Seller) (seller internal.Seller, err error) {
+func (s *SellerDefault) Save(ctx context.Context, sell *internal.Seller) (seller internal.Seller, err error) {
 	id, err := s.rp.Save(sell)
 	if err != nil {
 		switch err {
@@ -67,8 +93,43 @@ func (s *SellerDefault) Save(sell *internal.Seller) (seller internal.Seller, err
 	return
 }
 
+// SaveBulk receives sellers and saves them. If atomic, either all of them are saved or none.
+// The results hold the outcome of each seller, the error is only set when the operation itself fails.
+func (s *SellerDefault) SaveBulk(ctx context.Context, sellers []internal.Seller, atomic bool) (results []internal.BulkResult, err error) {
+	results, err = s.rp.SaveBulk(sellers, atomic)
+	if err != nil {
+		switch err {
+		case internal.ErrSellerRepositoryTransaction, internal.ErrSellerRepositoryConn:
+			err = internal.ErrSellerServiceDB
+		default:
+			err = internal.ErrSellerServiceUnknown
+		}
+		return
+	}
+
+	for i := range results {
+		switch results[i].Err {
+		case nil, internal.ErrBulkNotSaved:
+		case internal.ErrSellerRepositoryDuplicated:
+			results[i].Err = internal.ErrSellerServiceDuplicated
+		case internal.ErrSellerRepositoryForeignKey:
+			results[i].Err = internal.ErrSellerServiceForeignKey
+		default:
+			results[i].Err = internal.ErrSellerServiceUnknown
+		}
+	}
+
+	return
+}
+
+// Validate receives a seller and checks it as Save does, without saving it.
+// Save has no rules of its own (fields are validated by the handler), so the seller is always valid.
+func (s *SellerDefault) Validate(sell *internal.Seller) (err error) {
+	return
+}
+
 // Update receives a product and updates it. Returns an error if the product is not found.
-func (s *SellerDefault) Update(p *internal.Seller) (err error) {
+func (s *SellerDefault) Update(ctx context.Context, p *internal.Seller) (err error) {
 	err = s.rp.Update(p)
 	if err != nil {
 		switch err {
@@ -76,6 +137,8 @@ func (s *SellerDefault) Update(p *internal.Seller) (err error) {
 			err = internal.ErrSellerServiceNotFound
 		case internal.ErrSellerRepositoryNothingToUpdate:
 			err = internal.ErrSellerServiceNothingToUpdate
+		case internal.ErrSellerRepositoryVersionConflict:
+			err = internal.ErrSellerServiceVersionConflict
 		default:
 			err = internal.ErrSellerServiceUnknown
 		}
@@ -85,9 +148,74 @@ func (s *SellerDefault) Update(p *internal.Seller) (err error) {
 	return
 }
 
-// Delete receives a product ID and deletes it. Returns an error if the product is not found.
-func (s *SellerDefault) Delete(id int) (err error) {
-	err = s.rp.Delete(id)
+// Delete marks the seller with the given ID as deleted, at the given version (any if zero). Returns an error if the seller is not found or was modified since.
+func (s *SellerDefault) Delete(ctx context.Context, id int, version int) (err error) {
+	err = s.rp.Delete(id, version)
+	if err != nil {
+		switch err {
+		case internal.ErrSellerRepositoryNotFound:
+			err = internal.ErrSellerServiceNotFound
+		case internal.ErrSellerRepositoryVersionConflict:
+			err = internal.ErrSellerServiceVersionConflict
+		default:
+			err = internal.ErrSellerServiceUnknown
+		}
+		return
+	}
+
+	return
+}
+
+// Restore unmarks the deleted seller with the given ID. Returns an error if there is no such seller.
+func (s *SellerDefault) Restore(ctx context.Context, id int) (err error) {
+	err = s.rp.Restore(id)
+	if err != nil {
+		switch err {
+		case internal.ErrSellerRepositoryNotFound:
+			err = internal.ErrSellerServiceNotFound
+		default:
+			err = internal.ErrSellerServiceUnknown
+		}
+		return
+	}
+
+	return
+}
+
+// Purge deletes the seller with the given ID permanently, deleted or not, at the given version (any if zero). Returns an error if the seller is not found or was modified since.
+func (s *SellerDefault) Purge(ctx context.Context, id int, version int) (err error) {
+	err = s.rp.Purge(id, version, internal.CascadeConfirmed(ctx))
+	if err != nil {
+		switch err {
+		case internal.ErrSellerRepositoryNotFound:
+			err = internal.ErrSellerServiceNotFound
+		case internal.ErrSellerRepositoryVersionConflict:
+			err = internal.ErrSellerServiceVersionConflict
+		case internal.ErrDependentsRepositoryUnconfirmed:
+			err = internal.ErrDependentsServiceUnconfirmed
+		default:
+			err = internal.ErrSellerServiceUnknown
+		}
+		return
+	}
+
+	return
+}
+
+// GetPerformance returns the activity of the products of the seller: the sales between the given days (unbounded when zero)
+// and the products without activity in the last given days. Returns an error if the seller is not found.
+func (s *SellerDefault) GetPerformance(id int, from time.Time, to time.Time, idleDays int) (p internal.SellerPerformance, err error) {
+	// validate the filter
+	switch {
+	case !from.IsZero() && !to.IsZero() && to.Before(from):
+		err = fmt.Errorf("%w: %v", internal.ErrSellerServiceInvalidField, "to before from")
+		return
+	case idleDays < 0:
+		err = fmt.Errorf("%w: %v", internal.ErrSellerServiceInvalidField, "days")
+		return
+	}
+
+	p, err = s.rp.GetPerformance(id, from, to, today().AddDate(0, 0, -idleDays))
 	if err != nil {
 		switch err {
 		case internal.ErrSellerRepositoryNotFound:
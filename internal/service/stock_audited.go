@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// NewStockAudited creates a new instance of the stock service that records the changes made through sv in the audit log
+func NewStockAudited(sv internal.StockService, au internal.AuditService) *StockAudited {
+	return &StockAudited{
+		StockService: sv,
+		audit:        auditor{au: au, resource: internal.ResourceStockThresholds},
+	}
+}
+
+// StockAudited is the implementation of the stock service that records the changes of the low-stock thresholds
+// in the audit log, by product. The rest of the methods are the ones of the wrapped service.
+type StockAudited struct {
+	internal.StockService
+	audit auditor
+}
+
+// stockThreshold is the low-stock threshold of a product as it is recorded in the audit log
+type stockThreshold struct {
+	// ProductID is the unique identifier of the product
+	ProductID int
+	// LowStockThreshold is the quantity below which the product has to be reordered
+	LowStockThreshold int
+}
+
+// SetThreshold sets the low-stock threshold of the product and records its creation or change
+func (s *StockAudited) SetThreshold(ctx context.Context, productID int, threshold int) (err error) {
+	before := s.snapshot(productID)
+	if err = s.StockService.SetThreshold(ctx, productID, threshold); err != nil {
+		return
+	}
+
+	action := internal.AuditUpdate
+	if before == nil {
+		action = internal.AuditCreate
+	}
+	s.audit.record(ctx, productID, action, before, stockThreshold{ProductID: productID, LowStockThreshold: threshold})
+	return
+}
+
+// DeleteThreshold removes the low-stock threshold of the product and records it
+func (s *StockAudited) DeleteThreshold(ctx context.Context, productID int) (err error) {
+	before := s.snapshot(productID)
+	if err = s.StockService.DeleteThreshold(ctx, productID); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, productID, internal.AuditPurge, before, nil)
+	return
+}
+
+// snapshot returns the low-stock threshold of the product, nil if it has none or it can't be read
+func (s *StockAudited) snapshot(productID int) any {
+	ps, err := s.StockService.GetProductStock(productID)
+	if err != nil || ps.LowStockThreshold == 0 {
+		return nil
+	}
+	return stockThreshold{ProductID: ps.ProductID, LowStockThreshold: ps.LowStockThreshold}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// NewStockDefault creates a new instance of the stock service
+func NewStockDefault(rp internal.StockRepository) *StockDefault {
+	return &StockDefault{
+		rp: rp,
+	}
+}
+
+// StockDefault is the default implementation of the stock service
+type StockDefault struct {
+	rp internal.StockRepository
+}
+
+// GetProductStock returns the units of the product held in each section and warehouse, with its low-stock threshold.
+// Returns an error if the product is not found.
+func (s *StockDefault) GetProductStock(productID int) (stock internal.ProductStock, err error) {
+	levels, err := s.rp.GetByProduct(productID)
+	if err != nil {
+		err = stockServiceError(err)
+		return
+	}
+
+	threshold, err := s.rp.GetThreshold(productID)
+	if err != nil && err != internal.ErrStockRepositoryThresholdNotFound {
+		err = stockServiceError(err)
+		return
+	}
+	err = nil
+
+	stock = internal.ProductStock{
+		ProductID:         productID,
+		LowStockThreshold: threshold,
+		Levels:            levels,
+	}
+	for _, level := range levels {
+		stock.Quantity += level.Quantity
+	}
+
+	return
+}
+
+// GetWarehouseStock returns the units of each product held in each section of the warehouse.
+// Returns an error if the warehouse is not found.
+func (s *StockDefault) GetWarehouseStock(warehouseID int) (stock internal.WarehouseStock, err error) {
+	levels, err := s.rp.GetByWarehouse(warehouseID)
+	if err != nil {
+		err = stockServiceError(err)
+		return
+	}
+
+	stock = internal.WarehouseStock{
+		WarehouseID: warehouseID,
+		Levels:      levels,
+	}
+	for _, level := range levels {
+		stock.Quantity += level.Quantity
+	}
+
+	return
+}
+
+// SetThreshold sets the low-stock threshold of the product. Returns an error if the threshold is not positive
+// or the product is not found.
+func (s *StockDefault) SetThreshold(ctx context.Context, productID int, threshold int) (err error) {
+	if threshold <= 0 {
+		err = fmt.Errorf("%w: %v", internal.ErrStockServiceInvalidField, "low_stock_threshold")
+		return
+	}
+
+	err = s.rp.SetThreshold(productID, threshold)
+	if err != nil {
+		err = stockServiceError(err)
+		return
+	}
+
+	return
+}
+
+// DeleteThreshold removes the low-stock threshold of the product. Returns an error if it has none.
+func (s *StockDefault) DeleteThreshold(ctx context.Context, productID int) (err error) {
+	err = s.rp.DeleteThreshold(productID)
+	if err != nil {
+		err = stockServiceError(err)
+		return
+	}
+
+	return
+}
+
+// GetReorder returns the products whose stock is below their low-stock threshold. Returns an error if the operation fails.
+func (s *StockDefault) GetReorder() (stocks []internal.ProductStock, err error) {
+	stocks, err = s.rp.GetBelowThreshold()
+	if err != nil {
+		err = stockServiceError(err)
+		return
+	}
+
+	return
+}
+
+// stockServiceError maps a stock repository error to a service error
+func stockServiceError(err error) error {
+	switch err {
+	case internal.ErrStockRepositoryProductNotFound:
+		return fmt.Errorf("%w: %v", internal.ErrStockServiceProductNotFound, err)
+	case internal.ErrStockRepositoryWarehouseNotFound:
+		return fmt.Errorf("%w: %v", internal.ErrStockServiceWarehouseNotFound, err)
+	case internal.ErrStockRepositoryThresholdNotFound:
+		return fmt.Errorf("%w: %v", internal.ErrStockServiceThresholdNotFound, err)
+	case internal.ErrStockRepository:
+		return fmt.Errorf("%w: %v", internal.ErrStockService, err)
+	default:
+		return fmt.Errorf("%w: %v", internal.ErrStockServiceUnknown, err)
+	}
+}
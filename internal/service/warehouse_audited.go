@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// NewWarehouseAudited creates a new instance of the warehouse service that records the changes made through sv in the audit log
+func NewWarehouseAudited(sv internal.WarehouseService, au internal.AuditService) *WarehouseAudited {
+	return &WarehouseAudited{
+		WarehouseService: sv,
+		audit:            auditor{au: au, resource: internal.ResourceWarehouses},
+	}
+}
+
+// WarehouseAudited is the implementation of the warehouse service that records the changes in the audit log.
+// The rest of the methods are the ones of the wrapped service.
+type WarehouseAudited struct {
+	internal.WarehouseService
+	audit auditor
+}
+
+// Save saves the warehouse and records its creation
+func (s *WarehouseAudited) Save(ctx context.Context, warehouse *internal.Warehouse) (saved internal.Warehouse, err error) {
+	if saved, err = s.WarehouseService.Save(ctx, warehouse); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, saved.ID, internal.AuditCreate, nil, saved)
+	return
+}
+
+// Update updates the warehouse and records the fields that changed
+func (s *WarehouseAudited) Update(ctx context.Context, warehouse *internal.Warehouse) (err error) {
+	before := s.snapshot(warehouse.ID)
+	if err = s.WarehouseService.Update(ctx, warehouse); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, warehouse.ID, internal.AuditUpdate, before, s.snapshot(warehouse.ID))
+	return
+}
+
+// Delete deletes the warehouse and records it
+func (s *WarehouseAudited) Delete(ctx context.Context, id int, version int) (err error) {
+	before := s.snapshot(id)
+	if err = s.WarehouseService.Delete(ctx, id, version); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditDelete, before, nil)
+	return
+}
+
+// Restore restores the deleted warehouse and records it
+func (s *WarehouseAudited) Restore(ctx context.Context, id int) (err error) {
+	if err = s.WarehouseService.Restore(ctx, id); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditRestore, nil, s.snapshot(id))
+	return
+}
+
+// Purge deletes the warehouse permanently and records it
+func (s *WarehouseAudited) Purge(ctx context.Context, id int, version int) (err error) {
+	before := s.snapshot(id)
+	if err = s.WarehouseService.Purge(ctx, id, version); err != nil {
+		return
+	}
+
+	s.audit.record(ctx, id, internal.AuditPurge, before, nil)
+	return
+}
+
+// snapshot returns the warehouse with the given ID as it is, nil if it can't be read (e.g. it is deleted)
+func (s *WarehouseAudited) snapshot(id int) any {
+	warehouse, err := s.WarehouseService.Get(id)
+	if err != nil {
+		return nil
+	}
+	return warehouse
+}
@@ -1,6 +1,15 @@
 package service
 
-import "github.com/manuelfirman/go-API/internal"
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/manuelfirman/go-API/internal"
+)
+
+// excursionsWindow is how far back the temperature excursions of the warehouse summary are counted
+const excursionsWindow = 24 * time.Hour
 
 // NewWarehouseDefault creates a new instance of the warehouse service
 type WarehouseDefault struct {
@@ -16,8 +25,8 @@ func NewWarehouseDefault(rp internal.WarehouseRepository) *WarehouseDefault {
 }
 
 // GetAll returns all products. Returns an error if the operation fails.
-func (w *WarehouseDefault) GetAll() (warehouses []internal.Warehouse, err error) {
-	warehouses, err = w.rp.GetAll()
+func (w *WarehouseDefault) GetAll(includeDeleted bool) (warehouses []internal.Warehouse, err error) {
+	warehouses, err = w.rp.GetAll(includeDeleted)
 	if err != nil {
 		switch err {
 		case internal.ErrWarehouseRepositoryNotFound:
@@ -31,6 +40,26 @@ func (w *WarehouseDefault) GetAll() (warehouses []internal.Warehouse, err error)
 	return
 }
 
+// ForEach calls fn with every warehouse. Errors returned by fn are returned as is.
+func (w *WarehouseDefault) ForEach(includeDeleted bool, fn func(wh internal.Warehouse) error) (err error) {
+	var fnErr error
+	err = w.rp.ForEach(includeDeleted, func(wh internal.Warehouse) error {
+		fnErr = fn(wh)
+		return fnErr
+	})
+	if err != nil && err != fnErr {
+		switch err {
+		case internal.ErrWarehouseRepositoryNotFound:
+			err = internal.ErrWarehouseServiceNotFound
+		default:
+			err = internal.ErrWarehouseServiceUnknown
+		}
+		return
+	}
+
+	return
+}
+
 // Get returns a product by ID. Returns an error if the product is not found.
 func (w *WarehouseDefault) Get(id int) (p internal.Warehouse, err error) {
 	p, err = w.rp.Get(id)
@@ -48,7 +77,7 @@ func (w *WarehouseDefault) Get(id int) (p internal.Warehouse, err error) {
 }
 
 // Save receives a product and saves it. It returns the ID of the product saved.
-func (w *WarehouseDefault) Save(wh *internal.Warehouse) (warehouse internal.Warehouse, err error) {
+func (w *WarehouseDefault) Save(ctx context.Context, wh *internal.Warehouse) (warehouse internal.Warehouse, err error) {
 	id, err := w.rp.Save(wh)
 	if err != nil {
 		switch err {
@@ -68,8 +97,14 @@ func (w *WarehouseDefault) Save(wh *internal.Warehouse) (warehouse internal.Ware
 	return
 }
 
+// Validate receives a warehouse and checks it as Save does, without saving it.
+// Save has no rules of its own (fields are validated by the handler), so the warehouse is always valid.
+func (w *WarehouseDefault) Validate(wh *internal.Warehouse) (err error) {
+	return
+}
+
 // Update receives a product and updates it. Returns an error if the product is not found.
-func (w *WarehouseDefault) Update(p *internal.Warehouse) (err error) {
+func (w *WarehouseDefault) Update(ctx context.Context, p *internal.Warehouse) (err error) {
 	err = w.rp.Update(p)
 	if err != nil {
 		switch err {
@@ -77,6 +112,8 @@ func (w *WarehouseDefault) Update(p *internal.Warehouse) (err error) {
 			err = internal.ErrWarehouseServiceNotFound
 		case internal.ErrWarehouseRepositoryNothingToUpdate:
 			err = internal.ErrWarehouseServiceNothingToUpdate
+		case internal.ErrWarehouseRepositoryVersionConflict:
+			err = internal.ErrWarehouseServiceVersionConflict
 		default:
 			err = internal.ErrWarehouseServiceUnknown
 		}
@@ -85,13 +122,15 @@ func (w *WarehouseDefault) Update(p *internal.Warehouse) (err error) {
 	return
 }
 
-// Delete deletes a product by ID. Returns an error if the product is not found.
-func (w *WarehouseDefault) Delete(id int) (err error) {
-	err = w.rp.Delete(id)
+// Delete marks the warehouse with the given ID as deleted, at the given version (any if zero). Returns an error if the warehouse is not found or was modified since.
+func (w *WarehouseDefault) Delete(ctx context.Context, id int, version int) (err error) {
+	err = w.rp.Delete(id, version)
 	if err != nil {
 		switch err {
 		case internal.ErrWarehouseRepositoryNotFound:
 			err = internal.ErrWarehouseServiceNotFound
+		case internal.ErrWarehouseRepositoryVersionConflict:
+			err = internal.ErrWarehouseServiceVersionConflict
 		default:
 			err = internal.ErrWarehouseServiceUnknown
 		}
@@ -99,3 +138,62 @@ func (w *WarehouseDefault) Delete(id int) (err error) {
 
 	return
 }
+
+// Restore unmarks the deleted warehouse with the given ID. Returns an error if there is no such warehouse.
+func (w *WarehouseDefault) Restore(ctx context.Context, id int) (err error) {
+	err = w.rp.Restore(id)
+	if err != nil {
+		switch err {
+		case internal.ErrWarehouseRepositoryNotFound:
+			err = internal.ErrWarehouseServiceNotFound
+		default:
+			err = internal.ErrWarehouseServiceUnknown
+		}
+	}
+
+	return
+}
+
+// Purge deletes the warehouse with the given ID permanently, deleted or not, at the given version (any if zero). Returns an error if the warehouse is not found or was modified since.
+func (w *WarehouseDefault) Purge(ctx context.Context, id int, version int) (err error) {
+	err = w.rp.Purge(id, version, internal.CascadeConfirmed(ctx))
+	if err != nil {
+		switch err {
+		case internal.ErrWarehouseRepositoryNotFound:
+			err = internal.ErrWarehouseServiceNotFound
+		case internal.ErrWarehouseRepositoryVersionConflict:
+			err = internal.ErrWarehouseServiceVersionConflict
+		case internal.ErrDependentsRepositoryUnconfirmed:
+			err = internal.ErrDependentsServiceUnconfirmed
+		case internal.ErrWarehouseRepositoryForeignKey:
+			err = internal.ErrWarehouseServiceForeignKey
+		default:
+			err = internal.ErrWarehouseServiceUnknown
+		}
+	}
+
+	return
+}
+
+// GetSummary returns the utilisation of the warehouse: the batches that expire within the given number of days from today
+// (the expired ones included) and the temperature excursions of the last 24 hours are counted.
+// Returns an error if the warehouse is not found.
+func (w *WarehouseDefault) GetSummary(id int, days int) (s internal.WarehouseSummary, err error) {
+	if days < 0 {
+		err = fmt.Errorf("%w: %v", internal.ErrWarehouseServiceInvalidField, "days")
+		return
+	}
+
+	s, err = w.rp.GetSummary(id, today().AddDate(0, 0, days), time.Now().UTC().Add(-excursionsWindow))
+	if err != nil {
+		switch err {
+		case internal.ErrWarehouseRepositoryNotFound:
+			err = internal.ErrWarehouseServiceNotFound
+		default:
+			err = internal.ErrWarehouseServiceUnknown
+		}
+		return
+	}
+
+	return
+}
@@ -0,0 +1,42 @@
+package internal
+
+// StockLevel is a struct that contains the units of a product held in a section
+type StockLevel struct {
+	// ProductID is the unique identifier of the product
+	ProductID int
+	// WarehouseID is the unique identifier of the warehouse of the section
+	WarehouseID int
+	// SectionID is the unique identifier of the section
+	SectionID int
+	// Quantity is the sum of the current quantity of the batches of the product in the section
+	Quantity int
+	// Batches is the number of batches of the product with units left in the section
+	Batches int
+}
+
+// ProductStock is a struct that contains the units of a product held across the warehouses
+type ProductStock struct {
+	// ProductID is the unique identifier of the product
+	ProductID int
+	// Quantity is the total number of units of the product
+	Quantity int
+	// LowStockThreshold is the quantity below which the product has to be reordered (0 if it has no threshold)
+	LowStockThreshold int
+	// Levels are the units of the product held in each section
+	Levels []StockLevel
+}
+
+// LowStock returns whether the product has a threshold and its quantity is below it
+func (ps ProductStock) LowStock() bool {
+	return ps.LowStockThreshold > 0 && ps.Quantity < ps.LowStockThreshold
+}
+
+// WarehouseStock is a struct that contains the units of every product held in a warehouse
+type WarehouseStock struct {
+	// WarehouseID is the unique identifier of the warehouse
+	WarehouseID int
+	// Quantity is the total number of units held in the warehouse
+	Quantity int
+	// Levels are the units of each product held in each section of the warehouse
+	Levels []StockLevel
+}
@@ -0,0 +1,31 @@
+package internal
+
+import "errors"
+
+var (
+	// ErrStockRepositoryProductNotFound is returned when the product is not found
+	ErrStockRepositoryProductNotFound = errors.New("repository: product not found")
+	// ErrStockRepositoryWarehouseNotFound is returned when the warehouse is not found
+	ErrStockRepositoryWarehouseNotFound = errors.New("repository: warehouse not found")
+	// ErrStockRepositoryThresholdNotFound is returned when the product has no low-stock threshold
+	ErrStockRepositoryThresholdNotFound = errors.New("repository: low-stock threshold not found")
+	// ErrStockRepository is the generic error of the repository
+	ErrStockRepository = errors.New("repository: internal error")
+)
+
+// StockRepository is an interface that contains the methods that the stock repository should support.
+// The stock is the current quantity of the product batches, aggregated by product, section and warehouse.
+type StockRepository interface {
+	// GetByProduct returns the units of the product held in each section, ordered by warehouse and section
+	GetByProduct(productID int) ([]StockLevel, error)
+	// GetByWarehouse returns the units of each product held in each section of the warehouse, ordered by product and section
+	GetByWarehouse(warehouseID int) ([]StockLevel, error)
+	// GetThreshold returns the low-stock threshold of the product
+	GetThreshold(productID int) (int, error)
+	// SetThreshold sets the low-stock threshold of the product
+	SetThreshold(productID int, threshold int) error
+	// DeleteThreshold removes the low-stock threshold of the product
+	DeleteThreshold(productID int) error
+	// GetBelowThreshold returns the stock of the products whose quantity is below their threshold (without levels)
+	GetBelowThreshold() ([]ProductStock, error)
+}
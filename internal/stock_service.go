@@ -0,0 +1,35 @@
+package internal
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrStockServiceProductNotFound is returned when the product is not found
+	ErrStockServiceProductNotFound = errors.New("service: product not found")
+	// ErrStockServiceWarehouseNotFound is returned when the warehouse is not found
+	ErrStockServiceWarehouseNotFound = errors.New("service: warehouse not found")
+	// ErrStockServiceThresholdNotFound is returned when the product has no low-stock threshold
+	ErrStockServiceThresholdNotFound = errors.New("service: low-stock threshold not found")
+	// ErrStockServiceInvalidField is returned when a field is invalid
+	ErrStockServiceInvalidField = errors.New("service: invalid field")
+	// ErrStockService is the generic error of the service
+	ErrStockService = errors.New("service: internal error")
+	// ErrStockServiceUnknown is returned when the repository returns an unknown error
+	ErrStockServiceUnknown = errors.New("service: unknown error")
+)
+
+// StockService is an interface that contains the methods that the stock service should support
+type StockService interface {
+	// GetProductStock returns the units of the product held in each section and warehouse, with its low-stock threshold
+	GetProductStock(productID int) (ProductStock, error)
+	// GetWarehouseStock returns the units of each product held in each section of the warehouse
+	GetWarehouseStock(warehouseID int) (WarehouseStock, error)
+	// SetThreshold sets the low-stock threshold of the product
+	SetThreshold(ctx context.Context, productID int, threshold int) error
+	// DeleteThreshold removes the low-stock threshold of the product
+	DeleteThreshold(ctx context.Context, productID int) error
+	// GetReorder returns the products whose stock is below their low-stock threshold
+	GetReorder() ([]ProductStock, error)
+}
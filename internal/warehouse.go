@@ -1,5 +1,7 @@
 package internal
 
+import "time"
+
 // Warehouse is a struct that contains the warehouse's information
 type Warehouse struct {
 	// ID is the unique identifier of the warehouse
@@ -16,4 +18,36 @@ type Warehouse struct {
 	MinimumTemperature float64
 	// LocalityId is the id of the locality where the warehouse is located
 	LocalityId string
+	// Version is the version of the warehouse, incremented on every update (optimistic concurrency)
+	Version int
+	// DeletedAt is the moment the warehouse was deleted, zero unless it is
+	DeletedAt time.Time
+}
+
+// WarehouseSummary is a struct that contains the utilisation of a warehouse
+type WarehouseSummary struct {
+	// WarehouseID is the unique identifier of the warehouse
+	WarehouseID int
+	// Sections is the number of sections of the warehouse
+	Sections int
+	// TotalCapacity is the sum of the maximum capacity of the sections
+	TotalCapacity int
+	// UsedCapacity is the sum of the current capacity of the sections
+	UsedCapacity int
+	// FreeCapacity is the capacity left in the sections
+	FreeCapacity int
+	// Employees is the number of employees working at the warehouse
+	Employees int
+	// Batches is the number of product batches with units left stored in the warehouse
+	Batches int
+	// ExpiringBatches is the number of those batches that expire on or before ExpiringUntil (the expired ones included)
+	ExpiringBatches int
+	// ExpiringProducts is the number of different products of the expiring batches
+	ExpiringProducts int
+	// ExpiringUntil is the date up to which the batches are counted as expiring
+	ExpiringUntil time.Time
+	// TemperatureExcursions is the number of temperature readings of the sections below their minimum temperature since ExcursionsSince
+	TemperatureExcursions int
+	// ExcursionsSince is the moment from which the temperature excursions are counted
+	ExcursionsSince time.Time
 }
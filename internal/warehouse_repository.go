@@ -1,6 +1,9 @@
 package internal
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	// ErrWarehouseRepositoryNotFound is returned when a warehouse is not found.
@@ -13,18 +16,30 @@ var (
 	ErrWarehouseRepositoryForeignKey = errors.New("warehouse repository: warehouse foreign key constraint")
 	// ErrWarehouseRepositoryNothingToUpdate is returned when there is nothing to update.
 	ErrWarehouseRepositoryNothingToUpdate = errors.New("warehouse repository: nothing to update")
+	// ErrWarehouseRepositoryVersionConflict is returned when the warehouse was modified since it was read.
+	ErrWarehouseRepositoryVersionConflict = errors.New("warehouse repository: version conflict")
 )
 
 // WarehouseRepository is an interface that contains the methods that the warehouse repository should support
 type WarehouseRepository interface {
-	// GetAll returns all the warehouses
-	GetAll() ([]Warehouse, error)
+	// GetAll returns all the warehouses, the deleted ones only if includeDeleted
+	GetAll(includeDeleted bool) ([]Warehouse, error)
+	// ForEach calls fn with every warehouse (the deleted ones only if includeDeleted) as it is read, it stops at the first error returned by fn
+	ForEach(includeDeleted bool, fn func(wh Warehouse) error) error
 	// Get returns the warehouse with the given ID
 	Get(id int) (Warehouse, error)
 	// Save saves the given warehouse
 	Save(warehouse *Warehouse) (int, error)
-	// Update updates the given warehouse
+	// Update updates the given warehouse if its version matches the stored one
 	Update(warehouse *Warehouse) error
-	// Delete deletes the warehouse with the given ID
-	Delete(id int) error
+	// Delete marks the warehouse with the given ID as deleted, at the given version (any if zero)
+	Delete(id int, version int) error
+	// Restore unmarks the deleted warehouse with the given ID
+	Restore(id int) error
+	// Purge deletes the warehouse with the given ID permanently, deleted or not, at the given version (any if zero),
+	// if nothing blocks it and, when rows depend on it, cascade confirms they are deleted or nulled with it
+	Purge(id int, version int, cascade bool) error
+	// GetSummary returns the utilisation of the warehouse with the given ID: the batches expiring on or before
+	// expiringUntil and the temperature excursions since excursionsSince are counted
+	GetSummary(id int, expiringUntil time.Time, excursionsSince time.Time) (WarehouseSummary, error)
 }
@@ -1,6 +1,9 @@
 package internal
 
-import "errors"
+import (
+	"context"
+	"errors"
+)
 
 var (
 	// ErrWarehouseServiceNotFound is returned when a warehouse is not found.
@@ -13,18 +16,34 @@ var (
 	ErrWarehouseServiceForeignKey = errors.New("warehouse service: warehouse foreign key constraint")
 	// ErrWarehouseServiceNothingToUpdate is returned when there is nothing to update.
 	ErrWarehouseServiceNothingToUpdate = errors.New("warehouse service: nothing to update")
+	// ErrWarehouseServiceInvalidField is returned when a parameter is invalid.
+	ErrWarehouseServiceInvalidField = errors.New("warehouse service: invalid field")
+	// ErrWarehouseServiceVersionConflict is returned when the warehouse was modified since it was read.
+	ErrWarehouseServiceVersionConflict = errors.New("warehouse service: version conflict")
 )
 
 // WarehouseService is an interface that contains the methods that the warehouse service should support
 type WarehouseService interface {
-	// GetAll returns all the warehouses
-	GetAll() ([]Warehouse, error)
+	// GetAll returns all the warehouses, the deleted ones only if includeDeleted
+	GetAll(includeDeleted bool) ([]Warehouse, error)
+	// ForEach calls fn with every warehouse (the deleted ones only if includeDeleted) without loading all of them in memory, it stops at the first error returned by fn
+	ForEach(includeDeleted bool, fn func(wh Warehouse) error) error
 	// Get returns the warehouse with the given ID
 	Get(id int) (Warehouse, error)
 	// Save saves the given warehouse
-	Save(warehouse *Warehouse) (Warehouse, error)
+	Save(ctx context.Context, warehouse *Warehouse) (Warehouse, error)
+	// Validate checks the given warehouse as Save does, without saving it
+	Validate(warehouse *Warehouse) error
 	// Update updates the given warehouse
-	Update(warehouse *Warehouse) error
-	// Delete deletes the warehouse with the given ID
-	Delete(id int) error
+	Update(ctx context.Context, warehouse *Warehouse) error
+	// Delete marks the warehouse with the given ID as deleted, at the given version (any if zero)
+	Delete(ctx context.Context, id int, version int) error
+	// Restore unmarks the deleted warehouse with the given ID
+	Restore(ctx context.Context, id int) error
+	// Purge deletes the warehouse with the given ID permanently, deleted or not, at the given version (any if zero).
+	// The rows that depend on it are deleted or nulled with it only if the context confirms the cascade (WithCascadeConfirmed)
+	Purge(ctx context.Context, id int, version int) error
+	// GetSummary returns the utilisation of the warehouse with the given ID, counting the batches that expire within
+	// the given number of days and the temperature excursions of the last 24 hours
+	GetSummary(id int, days int) (WarehouseSummary, error)
 }
@@ -0,0 +1,146 @@
+package tracking
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultFormat is the format of the tracking codes when none is configured
+	DefaultFormat = "TRK-{date}-{random}"
+	// MaxLength is the maximum length of a tracking code (the size of the column that stores it)
+	MaxLength = 25
+	// randomLength is the number of characters of the {random} placeholder
+	randomLength = 8
+	// alphabet are the characters of the {random} placeholder (without the ones that are easy to mistake: 0/O, 1/I)
+	alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+var (
+	// ErrFormatInvalid is returned when the format has unbalanced braces, lacks the {random} placeholder
+	// or has a placeholder without value
+	ErrFormatInvalid = errors.New("tracking: invalid format")
+	// ErrCodeTooLong is returned when the generated code is longer than MaxLength
+	ErrCodeTooLong = errors.New("tracking: code too long")
+)
+
+// NewGenerator creates a new generator of tracking codes with the given format. The placeholders are:
+// - {date}: the current date (YYYYMMDD, UTC)
+// - {random}: 8 random characters, required so the codes are unique
+// - {name}: any other name is replaced with the value given to Generate
+func NewGenerator(format string) (g *Generator, err error) {
+	if format == "" {
+		format = DefaultFormat
+	}
+
+	names, err := placeholders(format)
+	if err != nil {
+		return
+	}
+	hasRandom := false
+	for _, name := range names {
+		hasRandom = hasRandom || name == "random"
+	}
+	if !hasRandom {
+		err = fmt.Errorf("%w: missing {random}", ErrFormatInvalid)
+		return
+	}
+
+	g = &Generator{
+		format: format,
+		now:    time.Now,
+	}
+	return
+}
+
+// Generator generates tracking codes from a format
+type Generator struct {
+	// format is the format of the codes
+	format string
+	// now returns the current time
+	now func() time.Time
+}
+
+// Generate returns a new tracking code, replacing the placeholders of the format with the given values
+func (g *Generator) Generate(values map[string]string) (code string, err error) {
+	var sb strings.Builder
+	rest := g.format
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			sb.WriteString(rest)
+			break
+		}
+		end := strings.IndexByte(rest, '}')
+		sb.WriteString(rest[:start])
+		name := rest[start+1 : end]
+		rest = rest[end+1:]
+
+		switch name {
+		case "date":
+			sb.WriteString(g.now().UTC().Format("20060102"))
+		case "random":
+			var random string
+			if random, err = randomString(randomLength); err != nil {
+				return
+			}
+			sb.WriteString(random)
+		default:
+			value, ok := values[name]
+			if !ok {
+				err = fmt.Errorf("%w: no value for {%s}", ErrFormatInvalid, name)
+				return
+			}
+			sb.WriteString(value)
+		}
+	}
+
+	if sb.Len() > MaxLength {
+		err = fmt.Errorf("%w: %d characters, at most %d", ErrCodeTooLong, sb.Len(), MaxLength)
+		return
+	}
+	code = sb.String()
+
+	return
+}
+
+// placeholders returns the names of the placeholders of the format
+func placeholders(format string) (names []string, err error) {
+	rest := format
+	for {
+		start := strings.IndexAny(rest, "{}")
+		if start < 0 {
+			return
+		}
+		if rest[start] == '}' {
+			err = fmt.Errorf("%w: unbalanced braces", ErrFormatInvalid)
+			return
+		}
+		end := strings.IndexAny(rest[start+1:], "{}")
+		if end < 0 || rest[start+1+end] == '{' || end == 0 {
+			err = fmt.Errorf("%w: unbalanced braces", ErrFormatInvalid)
+			return
+		}
+		names = append(names, rest[start+1:start+1+end])
+		rest = rest[start+1+end+1:]
+	}
+}
+
+// randomString returns n random characters of the alphabet
+func randomString(n int) (s string, err error) {
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(alphabet)))
+	for i := range b {
+		var idx *big.Int
+		if idx, err = rand.Int(rand.Reader, max); err != nil {
+			return
+		}
+		b[i] = alphabet[idx.Int64()]
+	}
+	s = string(b)
+	return
+}
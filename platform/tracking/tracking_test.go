@@ -0,0 +1,91 @@
+package tracking_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/manuelfirman/go-API/platform/tracking"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Tests for Generator
+func TestGenerator_Generate(t *testing.T) {
+	t.Run("success - default format", func(t *testing.T) {
+		// arrange
+		g, err := tracking.NewGenerator("")
+		require.NoError(t, err)
+
+		// act
+		code1, err1 := g.Generate(nil)
+		code2, err2 := g.Generate(nil)
+
+		// assert
+		require.NoError(t, err1)
+		require.NoError(t, err2)
+		require.Regexp(t, regexp.MustCompile(`^TRK-\d{8}-[A-Z2-9]{8}$`), code1)
+		require.NotEqual(t, code1, code2)
+	})
+
+	t.Run("success - custom placeholders", func(t *testing.T) {
+		// arrange
+		g, err := tracking.NewGenerator("{carrier}-{order}-{random}")
+		require.NoError(t, err)
+
+		// act
+		code, err := g.Generate(map[string]string{"carrier": "7", "order": "42"})
+
+		// assert
+		require.NoError(t, err)
+		require.Regexp(t, regexp.MustCompile(`^7-42-[A-Z2-9]{8}$`), code)
+	})
+
+	t.Run("error - missing value", func(t *testing.T) {
+		// arrange
+		g, err := tracking.NewGenerator("{carrier}-{random}")
+		require.NoError(t, err)
+
+		// act
+		code, err := g.Generate(nil)
+
+		// assert
+		require.ErrorIs(t, err, tracking.ErrFormatInvalid)
+		require.Empty(t, code)
+	})
+
+	t.Run("error - code too long", func(t *testing.T) {
+		// arrange
+		g, err := tracking.NewGenerator("TRACKING-{date}-{random}-{order}")
+		require.NoError(t, err)
+
+		// act
+		code, err := g.Generate(map[string]string{"order": "123"})
+
+		// assert
+		require.ErrorIs(t, err, tracking.ErrCodeTooLong)
+		require.Empty(t, code)
+	})
+}
+
+// Tests for NewGenerator
+func TestNewGenerator(t *testing.T) {
+	t.Run("error - missing random", func(t *testing.T) {
+		// act
+		g, err := tracking.NewGenerator("TRK-{date}")
+
+		// assert
+		require.ErrorIs(t, err, tracking.ErrFormatInvalid)
+		require.Nil(t, g)
+	})
+
+	t.Run("error - unbalanced braces", func(t *testing.T) {
+		for _, format := range []string{"TRK-{random", "TRK-random}", "{ {random}", "{}{random}"} {
+			// act
+			g, err := tracking.NewGenerator(format)
+
+			// assert
+			require.ErrorIs(t, err, tracking.ErrFormatInvalid, format)
+			require.Nil(t, g)
+		}
+	})
+}
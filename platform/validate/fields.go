@@ -21,13 +21,16 @@ func (f *FieldError) Error() string {
 	return fmt.Sprintf("field %s: %s", f.Field, f.Msg)
 }
 
-// CheckCompleteFields is a function that checks if all fields are complete
+// CheckCompleteFields is a function that checks if all fields are complete (the ones tagged omitempty are optional)
 func CheckFieldExistance(s interface{}, data map[string]any) error {
 	//get type of s
 	t := reflect.TypeOf(s)
 	//get fields of s
 	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i).Tag.Get("json")
+		field, options, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if slices.Contains(strings.Split(options, ","), "omitempty") {
+			continue
+		}
 		//fmt.Println(strings.ToLower(field))
 		//check if field exists in data
 		if _, ok := data[field]; !ok {
@@ -48,7 +51,8 @@ func CheckCorrectsFields(s interface{}, data map[string]any) error {
 	t := reflect.TypeOf(s)
 	//get fields of s
 	for i := 0; i < t.NumField(); i++ {
-		fields = append(fields, t.Field(i).Tag.Get("json"))
+		field, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		fields = append(fields, field)
 	}
 
 	//check data fields and validate if exists in fields slice
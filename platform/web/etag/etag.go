@@ -0,0 +1,56 @@
+package etag
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Format returns the entity tag of the given resource version
+func Format(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// Set writes the ETag header of the given resource version
+func Set(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", Format(version))
+}
+
+// Matches reports whether a list of entity tags (as sent in If-Match or If-None-Match) matches the version.
+// The wildcard "*" matches any version and weak tags are compared by their opaque value.
+func Matches(header string, version int) bool {
+	tag := Format(version)
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" {
+			return true
+		}
+		if strings.TrimPrefix(t, "W/") == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IfMatch reports whether the request can be applied to the given version:
+// true when the request has no If-Match header or one of its tags matches the version
+func IfMatch(r *http.Request, version int) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return true
+	}
+
+	return Matches(header, version)
+}
+
+// IfNoneMatch reports whether the client already holds the given version:
+// true when the request has an If-None-Match header and one of its tags matches the version
+func IfNoneMatch(r *http.Request, version int) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	return Matches(header, version)
+}
@@ -0,0 +1,73 @@
+package etag_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/manuelfirman/go-API/platform/web/etag"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests for Set function
+func TestSet(t *testing.T) {
+	t.Run("sets the etag header", func(t *testing.T) {
+		// arrange
+		// ...
+
+		// act
+		rr := httptest.NewRecorder()
+		etag.Set(rr, 3)
+
+		// assert
+		expectedHeader := http.Header{"Etag": []string{`"3"`}}
+		require.Equal(t, expectedHeader, rr.Header())
+	})
+}
+
+// Tests for Matches function
+func TestMatches(t *testing.T) {
+	t.Run("single tag", func(t *testing.T) {
+		require.True(t, etag.Matches(`"3"`, 3))
+		require.False(t, etag.Matches(`"3"`, 4))
+	})
+
+	t.Run("list of tags and weak tags", func(t *testing.T) {
+		require.True(t, etag.Matches(`"1", W/"3"`, 3))
+		require.False(t, etag.Matches(`"1", W/"2"`, 3))
+	})
+
+	t.Run("wildcard", func(t *testing.T) {
+		require.True(t, etag.Matches(`*`, 7))
+	})
+}
+
+// Tests for IfMatch and IfNoneMatch functions
+func TestConditionalHeaders(t *testing.T) {
+	t.Run("no headers", func(t *testing.T) {
+		// arrange
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		// act & assert
+		require.True(t, etag.IfMatch(r, 1))
+		require.False(t, etag.IfNoneMatch(r, 1))
+	})
+
+	t.Run("mismatching if-match", func(t *testing.T) {
+		// arrange
+		r := httptest.NewRequest(http.MethodPatch, "/", nil)
+		r.Header.Set("If-Match", `"1"`)
+
+		// act & assert
+		require.False(t, etag.IfMatch(r, 2))
+	})
+
+	t.Run("matching if-none-match", func(t *testing.T) {
+		// arrange
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("If-None-Match", `"2"`)
+
+		// act & assert
+		require.True(t, etag.IfNoneMatch(r, 2))
+	})
+}
@@ -0,0 +1,215 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/manuelfirman/go-API/platform/web/response"
+)
+
+const (
+	// HeaderKey is the request header carrying the idempotency key
+	HeaderKey = "Idempotency-Key"
+	// HeaderReplayed is the response header set when a stored response is replayed
+	HeaderReplayed = "Idempotent-Replayed"
+	// maxKeyLength is the maximum length accepted for an idempotency key
+	maxKeyLength = 255
+	// pendingTTL is how long a key stays reserved for a request in progress,
+	// after which it can be reserved again if the instance processing it went away
+	pendingTTL = 5 * time.Minute
+)
+
+// Middleware returns a middleware that makes POST requests carrying an Idempotency-Key header idempotent:
+// - the keys are scoped to the client that sends them (the one returned by client, all the same if nil)
+// - the first request reserves its key in the store, is processed and its response stored for ttl
+// (server errors are not stored and release the key, so the client can retry them)
+// - a repeated key with the same request replays the stored status, ETag and body
+// - a repeated key with a different request is rejected with 422
+// - a repeated key while the first request is in progress waits for it within an instance and is rejected
+// with 409 across instances
+func Middleware(st Store, ttl time.Duration, client func(r *http.Request) string) func(http.Handler) http.Handler {
+	locks := &keyLocks{locks: make(map[string]*keyLock)}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(HeaderKey)
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxKeyLength {
+				response.Error(w, http.StatusBadRequest, "idempotency key too long")
+				return
+			}
+
+			// request
+			// - read the body to fingerprint it and restore it for the handler
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				response.Error(w, http.StatusBadRequest, "invalid body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			fingerprint := Fingerprint(r, body)
+			// - scope the key to the client
+			if client != nil {
+				key = ScopedKey(client(r), key)
+			}
+
+			// process
+			// - serialize requests with the same key so retries in flight on this instance wait for the first one
+			unlock := locks.lock(key)
+			defer unlock()
+
+			// - reserve the key, or replay the stored response if any
+			err = st.Reserve(Record{Key: key, Fingerprint: fingerprint, ExpiresAt: time.Now().Add(pendingTTL)})
+			switch {
+			case err == nil:
+			case errors.Is(err, ErrStoreKeyExists):
+				replay(w, st, key, fingerprint)
+				return
+			default:
+				response.Error(w, http.StatusInternalServerError, "idempotency store error")
+				return
+			}
+
+			// - process the request capturing its response
+			rw := &recorder{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(rw, r)
+
+			// - store the response (server errors are left out so the client can retry them)
+			if rw.statusCode >= http.StatusInternalServerError {
+				_ = st.Release(key)
+				return
+			}
+			_ = st.Save(Record{
+				Key:         key,
+				Fingerprint: fingerprint,
+				StatusCode:  rw.statusCode,
+				ContentType: w.Header().Get("Content-Type"),
+				ETag:        w.Header().Get("ETag"),
+				Body:        rw.body.Bytes(),
+				ExpiresAt:   time.Now().Add(ttl),
+			})
+		})
+	}
+}
+
+// replay writes the stored response of the key, rejecting the request if it is not the one the key was used with
+// or that one is still in progress
+func replay(w http.ResponseWriter, st Store, key string, fingerprint string) {
+	rec, err := st.Get(key)
+	switch {
+	case err != nil:
+		response.Error(w, http.StatusInternalServerError, "idempotency store error")
+		return
+	case rec.Fingerprint != fingerprint:
+		response.Error(w, http.StatusUnprocessableEntity, "idempotency key already used with a different request")
+		return
+	case rec.Pending():
+		response.Error(w, http.StatusConflict, "a request with the same idempotency key is in progress")
+		return
+	}
+
+	if rec.ContentType != "" {
+		w.Header().Set("Content-Type", rec.ContentType)
+	}
+	if rec.ETag != "" {
+		w.Header().Set("ETag", rec.ETag)
+	}
+	w.Header().Set(HeaderReplayed, "true")
+	w.WriteHeader(rec.StatusCode)
+	w.Write(rec.Body)
+}
+
+// ScopedKey returns the key of the store for an idempotency key sent by a client: a hash of both,
+// so the same key sent by different clients doesn't collide
+func ScopedKey(client string, key string) string {
+	h := sha256.New()
+	h.Write([]byte(client + "\n" + key))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Fingerprint returns the fingerprint of a request: a hash of its method, path and body
+func Fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder is a response writer that writes through while keeping a copy of the response
+type recorder struct {
+	http.ResponseWriter
+	// statusCode is the status code written
+	statusCode int
+	// wroteHeader tells if the status code was already written
+	wroteHeader bool
+	// body is a copy of the body written
+	body bytes.Buffer
+}
+
+// WriteHeader writes and records the status code
+func (r *recorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.statusCode = code
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Write writes and records the body
+func (r *recorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush flushes the underlying response writer if it supports it
+func (r *recorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// keyLock is a mutex shared by the requests in flight for a key
+type keyLock struct {
+	mu sync.Mutex
+	// refs is the number of requests holding or waiting for the lock
+	refs int
+}
+
+// keyLocks is a set of per key locks, entries are removed once no request uses them
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+// lock locks the given key and returns the function that unlocks it
+func (k *keyLocks) lock(key string) (unlock func()) {
+	k.mu.Lock()
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyLock{}
+		k.locks[key] = l
+	}
+	l.refs++
+	k.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+
+		k.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
@@ -0,0 +1,206 @@
+package idempotency_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/manuelfirman/go-API/platform/web/idempotency"
+	"github.com/stretchr/testify/require"
+)
+
+// newHandler returns a handler that counts its calls and echoes the request body with 201
+func newHandler(calls *int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"call":` + strconv.Itoa(*calls) + `}`))
+	})
+}
+
+// Tests for Middleware
+func TestMiddleware(t *testing.T) {
+	t.Run("replays the stored response for a repeated key", func(t *testing.T) {
+		// arrange
+		calls := 0
+		hd := idempotency.Middleware(idempotency.NewStoreMemory(), time.Hour, nil)(newHandler(&calls))
+
+		// act
+		var responses []*httptest.ResponseRecorder
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/buyers", strings.NewReader(`{"a":1}`))
+			req.Header.Set(idempotency.HeaderKey, "key-1")
+			rr := httptest.NewRecorder()
+			hd.ServeHTTP(rr, req)
+			responses = append(responses, rr)
+		}
+
+		// assert
+		require.Equal(t, 1, calls)
+		require.Equal(t, http.StatusCreated, responses[1].Code)
+		require.Equal(t, responses[0].Body.String(), responses[1].Body.String())
+		require.Equal(t, "application/json", responses[1].Header().Get("Content-Type"))
+		require.Equal(t, "true", responses[1].Header().Get(idempotency.HeaderReplayed))
+	})
+
+	t.Run("rejects a repeated key with a different body", func(t *testing.T) {
+		// arrange
+		calls := 0
+		hd := idempotency.Middleware(idempotency.NewStoreMemory(), time.Hour, nil)(newHandler(&calls))
+
+		// act
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/buyers", strings.NewReader(`{"a":1}`))
+		req.Header.Set(idempotency.HeaderKey, "key-1")
+		hd.ServeHTTP(httptest.NewRecorder(), req)
+
+		req = httptest.NewRequest(http.MethodPost, "/api/v1/buyers", strings.NewReader(`{"a":2}`))
+		req.Header.Set(idempotency.HeaderKey, "key-1")
+		rr := httptest.NewRecorder()
+		hd.ServeHTTP(rr, req)
+
+		// assert
+		require.Equal(t, 1, calls)
+		require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("requests without key or not POST are not stored", func(t *testing.T) {
+		// arrange
+		calls := 0
+		hd := idempotency.Middleware(idempotency.NewStoreMemory(), time.Hour, nil)(newHandler(&calls))
+
+		// act
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/buyers", strings.NewReader(`{}`))
+			hd.ServeHTTP(httptest.NewRecorder(), req)
+		}
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest(http.MethodPatch, "/api/v1/buyers/1", strings.NewReader(`{}`))
+			req.Header.Set(idempotency.HeaderKey, "key-1")
+			hd.ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		// assert
+		require.Equal(t, 4, calls)
+	})
+
+	t.Run("server errors are not stored", func(t *testing.T) {
+		// arrange
+		calls := 0
+		hd := idempotency.Middleware(idempotency.NewStoreMemory(), time.Hour, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+
+		// act
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/buyers", strings.NewReader(`{}`))
+			req.Header.Set(idempotency.HeaderKey, "key-1")
+			hd.ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		// assert
+		require.Equal(t, 2, calls)
+	})
+	t.Run("replays the stored ETag", func(t *testing.T) {
+		// arrange
+		hd := idempotency.Middleware(idempotency.NewStoreMemory(), time.Hour, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `"1"`)
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		// act
+		var rr *httptest.ResponseRecorder
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/buyers", strings.NewReader(`{}`))
+			req.Header.Set(idempotency.HeaderKey, "key-1")
+			rr = httptest.NewRecorder()
+			hd.ServeHTTP(rr, req)
+		}
+
+		// assert
+		require.Equal(t, "true", rr.Header().Get(idempotency.HeaderReplayed))
+		require.Equal(t, `"1"`, rr.Header().Get("ETag"))
+	})
+
+	t.Run("rejects a key reserved by a request in progress on another instance", func(t *testing.T) {
+		// arrange
+		calls := 0
+		st := idempotency.NewStoreMemory()
+		hd := idempotency.Middleware(st, time.Hour, nil)(newHandler(&calls))
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/buyers", strings.NewReader(`{"a":1}`))
+		_ = st.Reserve(idempotency.Record{Key: "key-1", Fingerprint: idempotency.Fingerprint(req, []byte(`{"a":1}`)), ExpiresAt: time.Now().Add(time.Minute)})
+
+		// act
+		req.Header.Set(idempotency.HeaderKey, "key-1")
+		rr := httptest.NewRecorder()
+		hd.ServeHTTP(rr, req)
+
+		// assert
+		require.Equal(t, 0, calls)
+		require.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("keys are scoped to the client", func(t *testing.T) {
+		// arrange
+		calls := 0
+		client := func(r *http.Request) string { return r.Header.Get("X-Client") }
+		hd := idempotency.Middleware(idempotency.NewStoreMemory(), time.Hour, client)(newHandler(&calls))
+
+		// act
+		for _, c := range []string{"a", "b", "a"} {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/buyers", strings.NewReader(`{}`))
+			req.Header.Set(idempotency.HeaderKey, "key-1")
+			req.Header.Set("X-Client", c)
+			hd.ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		// assert
+		require.Equal(t, 2, calls)
+	})
+}
+
+// Tests for StoreMemory
+func TestStoreMemory(t *testing.T) {
+	t.Run("expired records are not returned", func(t *testing.T) {
+		// arrange
+		st := idempotency.NewStoreMemory()
+		_ = st.Save(idempotency.Record{Key: "key-1", ExpiresAt: time.Now().Add(-time.Second)})
+
+		// act
+		_, err := st.Get("key-1")
+
+		// assert
+		require.ErrorIs(t, err, idempotency.ErrStoreNotFound)
+	})
+
+	t.Run("unexpired records are returned", func(t *testing.T) {
+		// arrange
+		st := idempotency.NewStoreMemory()
+		_ = st.Save(idempotency.Record{Key: "key-1", StatusCode: http.StatusCreated, ExpiresAt: time.Now().Add(time.Hour)})
+
+		// act
+		rec, err := st.Get("key-1")
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, rec.StatusCode)
+	})
+	t.Run("a key with an unexpired record can't be reserved until it is released", func(t *testing.T) {
+		// arrange
+		st := idempotency.NewStoreMemory()
+		_ = st.Reserve(idempotency.Record{Key: "key-1", ExpiresAt: time.Now().Add(time.Hour)})
+
+		// act
+		errReserved := st.Reserve(idempotency.Record{Key: "key-1", ExpiresAt: time.Now().Add(time.Hour)})
+		_ = st.Release("key-1")
+		errReleased := st.Reserve(idempotency.Record{Key: "key-1", ExpiresAt: time.Now().Add(time.Hour)})
+
+		// assert
+		require.ErrorIs(t, errReserved, idempotency.ErrStoreKeyExists)
+		require.NoError(t, errReleased)
+	})
+}
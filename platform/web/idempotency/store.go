@@ -0,0 +1,123 @@
+package idempotency
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrStoreNotFound is returned when there is no (unexpired) record for a key
+	ErrStoreNotFound = errors.New("idempotency store: key not found")
+	// ErrStoreKeyExists is returned when a key is reserved while it has an unexpired record
+	ErrStoreKeyExists = errors.New("idempotency store: key already exists")
+	// ErrStoreInternal is returned when the store fails
+	ErrStoreInternal = errors.New("idempotency store: internal error")
+)
+
+// Record is the response stored for an idempotency key
+type Record struct {
+	// Key is the idempotency key, scoped to the client that sent it
+	Key string
+	// Fingerprint identifies the request the key was first used with
+	Fingerprint string
+	// StatusCode is the status code of the stored response (0 while the request is in progress)
+	StatusCode int
+	// ContentType is the content type of the stored response
+	ContentType string
+	// ETag is the entity tag of the stored response
+	ETag string
+	// Body is the body of the stored response
+	Body []byte
+	// ExpiresAt is the moment after which the record is no longer replayed
+	ExpiresAt time.Time
+}
+
+// Pending reports whether the record is a reservation of a request still in progress, without a response yet
+func (r Record) Pending() bool {
+	return r.StatusCode == 0
+}
+
+// Store is an interface that contains the methods that an idempotency store should support.
+// The store is shared by the instances of the API, so a key is reserved in it before its request is processed.
+type Store interface {
+	// Get returns the unexpired record of the given key
+	Get(key string) (Record, error)
+	// Reserve saves the given pending record unless the key has an unexpired record (ErrStoreKeyExists)
+	Reserve(record Record) error
+	// Save saves the given record, replacing the reservation or any expired record with the same key
+	Save(record Record) error
+	// Release deletes the pending record of the given key, so the request can be retried
+	Release(key string) error
+}
+
+// NewStoreMemory creates a new instance of the in-memory idempotency store
+func NewStoreMemory() *StoreMemory {
+	return &StoreMemory{
+		records: make(map[string]Record),
+	}
+}
+
+// StoreMemory is an in-memory implementation of the idempotency store.
+// Records are lost on restart and are not shared between instances.
+type StoreMemory struct {
+	// mu guards records
+	mu sync.Mutex
+	// records are the stored records by key
+	records map[string]Record
+}
+
+// Get returns the unexpired record of the given key
+func (s *StoreMemory) Get(key string) (r Record, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.get(key)
+	if !ok {
+		err = ErrStoreNotFound
+	}
+	return
+}
+
+// Reserve saves the given pending record unless the key has an unexpired record
+func (s *StoreMemory) Reserve(r Record) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.get(r.Key); ok {
+		err = ErrStoreKeyExists
+		return
+	}
+	s.records[r.Key] = r
+	return
+}
+
+// Save saves the given record
+func (s *StoreMemory) Save(r Record) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[r.Key] = r
+	return
+}
+
+// Release deletes the pending record of the given key
+func (s *StoreMemory) Release(key string) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.records[key]; ok && r.Pending() {
+		delete(s.records, key)
+	}
+	return
+}
+
+// get returns the unexpired record of the given key, expired records are dropped lazily. s.mu must be held.
+func (s *StoreMemory) get(key string) (r Record, ok bool) {
+	r, ok = s.records[key]
+	if ok && !time.Now().Before(r.ExpiresAt) {
+		delete(s.records, key)
+		r, ok = Record{}, false
+	}
+	return
+}
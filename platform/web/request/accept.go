@@ -0,0 +1,37 @@
+package request
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ContentTypeNDJSON is the media type of a newline delimited json document
+const ContentTypeNDJSON = "application/x-ndjson"
+
+// WantsCSV returns whether the client asked for a csv response, either with ?format=csv or an Accept: text/csv header
+func WantsCSV(r *http.Request) bool {
+	return wants(r, "csv", ContentTypeCSV)
+}
+
+// WantsNDJSON returns whether the client asked for a newline delimited json response,
+// either with ?format=ndjson or an Accept: application/x-ndjson header
+func WantsNDJSON(r *http.Request) bool {
+	return wants(r, "ndjson", ContentTypeNDJSON)
+}
+
+// wants returns whether the client asked for the given format in the format query param or the Accept header
+func wants(r *http.Request, format string, mediaType string) bool {
+	if r.URL != nil && strings.EqualFold(r.URL.Query().Get("format"), format) {
+		return true
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mt, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mt == mediaType {
+			return true
+		}
+	}
+
+	return false
+}
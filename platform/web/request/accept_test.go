@@ -0,0 +1,83 @@
+package request_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/manuelfirman/go-API/platform/web/request"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Tests for WantsCSV function
+func TestRequestWantsCSV(t *testing.T) {
+	t.Run("format query param", func(t *testing.T) {
+		// arrange
+		inputRequest := http.Request{URL: &url.URL{RawQuery: "format=csv"}, Header: http.Header{}}
+
+		// act
+		ok := request.WantsCSV(&inputRequest)
+
+		// assert
+		require.True(t, ok)
+	})
+
+	t.Run("accept header", func(t *testing.T) {
+		// arrange
+		inputRequest := http.Request{URL: &url.URL{}, Header: http.Header{"Accept": []string{"application/json;q=0.5, text/csv"}}}
+
+		// act
+		ok := request.WantsCSV(&inputRequest)
+
+		// assert
+		require.True(t, ok)
+	})
+
+	t.Run("json by default", func(t *testing.T) {
+		// arrange
+		inputRequest := http.Request{URL: &url.URL{}, Header: http.Header{"Accept": []string{"*/*"}}}
+
+		// act
+		ok := request.WantsCSV(&inputRequest)
+
+		// assert
+		require.False(t, ok)
+	})
+}
+
+// Tests for WantsNDJSON function
+func TestRequestWantsNDJSON(t *testing.T) {
+	t.Run("format query param", func(t *testing.T) {
+		// arrange
+		inputRequest := http.Request{URL: &url.URL{RawQuery: "format=ndjson"}, Header: http.Header{}}
+
+		// act
+		ok := request.WantsNDJSON(&inputRequest)
+
+		// assert
+		require.True(t, ok)
+	})
+
+	t.Run("accept header", func(t *testing.T) {
+		// arrange
+		inputRequest := http.Request{URL: &url.URL{}, Header: http.Header{"Accept": []string{"application/x-ndjson"}}}
+
+		// act
+		ok := request.WantsNDJSON(&inputRequest)
+
+		// assert
+		require.True(t, ok)
+	})
+
+	t.Run("csv is not ndjson", func(t *testing.T) {
+		// arrange
+		inputRequest := http.Request{URL: &url.URL{RawQuery: "format=csv"}, Header: http.Header{}}
+
+		// act
+		ok := request.WantsNDJSON(&inputRequest)
+
+		// assert
+		require.False(t, ok)
+	})
+}
@@ -0,0 +1,152 @@
+package request
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrRequestContentTypeNotCSV is used when the request content type is not text/csv.
+	ErrRequestContentTypeNotCSV = errors.New("request content type is not text/csv")
+	// ErrRequestCSVInvalid is used when the request csv (or one of its rows) is invalid.
+	ErrRequestCSVInvalid = errors.New("request csv invalid")
+)
+
+// ContentTypeCSV is the media type of a csv document
+const ContentTypeCSV = "text/csv"
+
+// CSVRecord is a row of a csv request converted to a json object
+type CSVRecord struct {
+	// Line is the line of the row in the csv document
+	Line int
+	// Body is the json object of the row, keyed by json tag names
+	Body json.RawMessage
+	// Err is set when the row could not be converted
+	Err error
+}
+
+// ParseCSVMapping parses a header mapping of the form "column:field,column:field"
+func ParseCSVMapping(s string) (mapping map[string]string, err error) {
+	mapping = make(map[string]string)
+	if strings.TrimSpace(s) == "" {
+		return
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		column, field, ok := strings.Cut(pair, ":")
+		column, field = strings.TrimSpace(column), strings.TrimSpace(field)
+		if !ok || column == "" || field == "" {
+			err = fmt.Errorf("%w. invalid mapping %q", ErrRequestCSVInvalid, pair)
+			return
+		}
+		mapping[column] = field
+	}
+
+	return
+}
+
+// CSV reads the csv request body and converts every row to a json object with the fields of schema (a struct).
+// The first row is the header: each column is renamed with mapping (if present) and must match a json tag of schema,
+// columns that don't match any are ignored. Cells are converted to the kind of the field, empty cells are left out.
+// Rows that cannot be converted are returned with Err set, err is only set when the document itself is invalid.
+func CSV(r *http.Request, schema any, mapping map[string]string) (records []CSVRecord, err error) {
+	// check content type
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != ContentTypeCSV {
+		err = ErrRequestContentTypeNotCSV
+		return
+	}
+
+	// fields of the schema by json tag name
+	t := reflect.TypeOf(schema)
+	kinds := make(map[string]reflect.Kind)
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			kinds[name] = t.Field(i).Type.Kind()
+		}
+	}
+
+	// header
+	reader := csv.NewReader(r.Body)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		err = fmt.Errorf("%w. %v", ErrRequestCSVInvalid, err)
+		return
+	}
+	columns := make([]string, len(header))
+	for i, column := range header {
+		column = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+		if field, ok := mapping[column]; ok {
+			column = field
+		}
+		if _, ok := kinds[column]; ok {
+			columns[i] = column
+		}
+	}
+
+	// rows
+	for {
+		row, rdErr := reader.Read()
+		if rdErr == io.EOF {
+			break
+		}
+		if rdErr != nil && !errors.Is(rdErr, csv.ErrFieldCount) {
+			err = fmt.Errorf("%w. %v", ErrRequestCSVInvalid, rdErr)
+			return
+		}
+		line, _ := reader.FieldPos(0)
+		if rdErr != nil {
+			// a row with a wrong number of fields only invalidates itself
+			records = append(records, CSVRecord{Line: line, Err: fmt.Errorf("%w. %v", ErrRequestCSVInvalid, csv.ErrFieldCount)})
+			continue
+		}
+
+		records = append(records, csvRecord(line, columns, kinds, row))
+	}
+
+	return
+}
+
+// csvRecord converts a csv row to a json object
+func csvRecord(line int, columns []string, kinds map[string]reflect.Kind, row []string) (record CSVRecord) {
+	record.Line = line
+
+	object := make(map[string]any)
+	for i, cell := range row {
+		column := columns[i]
+		cell = strings.TrimSpace(cell)
+		if column == "" || cell == "" {
+			continue
+		}
+
+		var err error
+		switch kinds[column] {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			object[column], err = strconv.ParseInt(cell, 10, 64)
+		case reflect.Float32, reflect.Float64:
+			object[column], err = strconv.ParseFloat(cell, 64)
+		case reflect.Bool:
+			object[column], err = strconv.ParseBool(cell)
+		default:
+			object[column] = cell
+		}
+		if err != nil {
+			record.Err = fmt.Errorf("%w. column %s: invalid value %q", ErrRequestCSVInvalid, column, cell)
+			return
+		}
+	}
+
+	record.Body, record.Err = json.Marshal(object)
+	return
+}
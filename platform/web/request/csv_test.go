@@ -0,0 +1,106 @@
+package request_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/manuelfirman/go-API/platform/web/request"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Tests for CSV function
+func TestRequestCSV(t *testing.T) {
+	type schema struct {
+		ID    int     `json:"id"`
+		Name  string  `json:"name"`
+		Price float64 `json:"price"`
+	}
+
+	t.Run("success", func(t *testing.T) {
+		// arrange
+		inputRequest := http.Request{
+			Header: http.Header{"Content-Type": []string{"text/csv"}},
+			Body:   io.NopCloser(strings.NewReader("name,price,other\napple,1.5,x\n\"pear, green\",,y\n")),
+		}
+
+		// act
+		records, err := request.CSV(&inputRequest, schema{}, nil)
+
+		// assert
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		require.Equal(t, 2, records[0].Line)
+		require.NoError(t, records[0].Err)
+		require.JSONEq(t, `{"name":"apple","price":1.5}`, string(records[0].Body))
+		require.Equal(t, 3, records[1].Line)
+		require.NoError(t, records[1].Err)
+		require.JSONEq(t, `{"name":"pear, green"}`, string(records[1].Body))
+	})
+
+	t.Run("success - header mapping", func(t *testing.T) {
+		// arrange
+		mapping, err := request.ParseCSVMapping("Product Name:name, Cost:price")
+		require.NoError(t, err)
+		inputRequest := http.Request{
+			Header: http.Header{"Content-Type": []string{"text/csv; charset=utf-8"}},
+			Body:   io.NopCloser(strings.NewReader("Product Name,Cost\napple,2\n")),
+		}
+
+		// act
+		records, err := request.CSV(&inputRequest, schema{}, mapping)
+
+		// assert
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		require.JSONEq(t, `{"name":"apple","price":2}`, string(records[0].Body))
+	})
+
+	t.Run("error - invalid rows are reported by line", func(t *testing.T) {
+		// arrange
+		inputRequest := http.Request{
+			Header: http.Header{"Content-Type": []string{"text/csv"}},
+			Body:   io.NopCloser(strings.NewReader("id,name\nabc,apple\n1\n2,pear\n")),
+		}
+
+		// act
+		records, err := request.CSV(&inputRequest, schema{}, nil)
+
+		// assert
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		require.ErrorIs(t, records[0].Err, request.ErrRequestCSVInvalid)
+		require.Equal(t, 3, records[1].Line)
+		require.ErrorIs(t, records[1].Err, request.ErrRequestCSVInvalid)
+		require.NoError(t, records[2].Err)
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(records[2].Body, &body))
+		require.Equal(t, map[string]any{"id": float64(2), "name": "pear"}, body)
+	})
+
+	t.Run("error - content-type", func(t *testing.T) {
+		// arrange
+		inputRequest := http.Request{
+			Header: http.Header{"Content-Type": []string{"application/json"}},
+			Body:   io.NopCloser(strings.NewReader(`{"name":"test"}`)),
+		}
+
+		// act
+		records, err := request.CSV(&inputRequest, schema{}, nil)
+
+		// assert
+		require.ErrorIs(t, err, request.ErrRequestContentTypeNotCSV)
+		require.Nil(t, records)
+	})
+
+	t.Run("error - invalid mapping", func(t *testing.T) {
+		// act
+		_, err := request.ParseCSVMapping("name")
+
+		// assert
+		require.ErrorIs(t, err, request.ErrRequestCSVInvalid)
+	})
+}
@@ -0,0 +1,335 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrRequestContentTypeNotPatch is used when the request content type is not a supported patch format.
+	ErrRequestContentTypeNotPatch = errors.New("request content type is not a supported patch format")
+	// ErrRequestPatchInvalid is used when the request patch document is invalid or cannot be applied.
+	ErrRequestPatchInvalid = errors.New("request patch invalid")
+	// ErrRequestPatchTestFailed is used when a json patch test operation does not match the current document.
+	ErrRequestPatchTestFailed = errors.New("request patch test failed")
+)
+
+const (
+	// ContentTypeJSONPatch is the media type of a json patch document (RFC 6902)
+	ContentTypeJSONPatch = "application/json-patch+json"
+	// ContentTypeMergePatch is the media type of a json merge patch document (RFC 7396)
+	ContentTypeMergePatch = "application/merge-patch+json"
+)
+
+// PatchOperation is a single operation of a json patch document
+type PatchOperation struct {
+	// Op is the operation to perform: add, remove, replace, move, copy or test
+	Op string `json:"op"`
+	// Path is the json pointer to the target location
+	Path string `json:"path"`
+	// From is the json pointer to the source location (move and copy)
+	From string `json:"from,omitempty"`
+	// Value is the value used by add, replace and test
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Patch applies the request body to ptr, which must hold the current representation of the resource.
+// The body is interpreted according to the request content type:
+// - application/json and application/merge-patch+json: the body is merged into ptr
+// - application/json-patch+json: the operations are applied to the json representation of ptr
+func Patch(r *http.Request, ptr any) (err error) {
+	// check content type
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		err = ErrRequestContentTypeNotPatch
+		return
+	}
+
+	switch mediaType {
+	case "application/json", ContentTypeMergePatch:
+		err = json.NewDecoder(r.Body).Decode(ptr)
+		if err != nil {
+			err = fmt.Errorf("%w. %v", ErrRequestJSONInvalid, err)
+		}
+	case ContentTypeJSONPatch:
+		var ops []PatchOperation
+		err = json.NewDecoder(r.Body).Decode(&ops)
+		if err != nil {
+			err = fmt.Errorf("%w. %v", ErrRequestPatchInvalid, err)
+			return
+		}
+		err = ApplyPatch(ptr, ops)
+	default:
+		err = ErrRequestContentTypeNotPatch
+	}
+
+	return
+}
+
+// ApplyPatch applies the json patch operations to the json representation of ptr.
+// ptr is left untouched if any of the operations fails.
+func ApplyPatch(ptr any, ops []PatchOperation) (err error) {
+	// get the current document
+	bytes, err := json.Marshal(ptr)
+	if err != nil {
+		err = fmt.Errorf("%w. %v", ErrRequestPatchInvalid, err)
+		return
+	}
+	var doc any
+	if err = json.Unmarshal(bytes, &doc); err != nil {
+		err = fmt.Errorf("%w. %v", ErrRequestPatchInvalid, err)
+		return
+	}
+
+	// apply the operations in order
+	for i, op := range ops {
+		doc, err = applyOperation(doc, op)
+		if err != nil {
+			err = fmt.Errorf("%w: operation %d (%s %s)", err, i, op.Op, op.Path)
+			return
+		}
+	}
+
+	// write the patched document back to ptr (reset first so removed members end up as zero values)
+	bytes, err = json.Marshal(doc)
+	if err != nil {
+		err = fmt.Errorf("%w. %v", ErrRequestPatchInvalid, err)
+		return
+	}
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		err = fmt.Errorf("%w. target must be a non nil pointer", ErrRequestPatchInvalid)
+		return
+	}
+	patched := reflect.New(v.Elem().Type())
+	if err = json.Unmarshal(bytes, patched.Interface()); err != nil {
+		err = fmt.Errorf("%w. %v", ErrRequestPatchInvalid, err)
+		return
+	}
+	v.Elem().Set(patched.Elem())
+
+	return
+}
+
+// applyOperation applies a single operation to the document and returns the resulting document
+func applyOperation(doc any, op PatchOperation) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w. missing value", ErrRequestPatchInvalid)
+		}
+		var value any
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, fmt.Errorf("%w. %v", ErrRequestPatchInvalid, err)
+		}
+		switch op.Op {
+		case "add":
+			return addValue(doc, path, value)
+		case "replace":
+			if _, err := getValue(doc, path); err != nil {
+				return nil, err
+			}
+			if doc, err = removeValue(doc, path); err != nil {
+				return nil, err
+			}
+			return addValue(doc, path, value)
+		default:
+			current, err := getValue(doc, path)
+			if err != nil {
+				return nil, ErrRequestPatchTestFailed
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, ErrRequestPatchTestFailed
+			}
+			return doc, nil
+		}
+	case "remove":
+		return removeValue(doc, path)
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := getValue(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "move" {
+			if strings.HasPrefix(op.Path+"/", op.From+"/") && op.Path != op.From {
+				return nil, fmt.Errorf("%w. cannot move a value into one of its children", ErrRequestPatchInvalid)
+			}
+			if doc, err = removeValue(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			// deep copy so later operations don't alias the source
+			bytes, _ := json.Marshal(value)
+			_ = json.Unmarshal(bytes, &value)
+		}
+		return addValue(doc, path, value)
+	default:
+		return nil, fmt.Errorf("%w. unknown operation %q", ErrRequestPatchInvalid, op.Op)
+	}
+}
+
+// parsePointer splits a json pointer (RFC 6901) into its unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w. invalid pointer %q", ErrRequestPatchInvalid, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		t = strings.ReplaceAll(t, "~1", "/")
+		tokens[i] = strings.ReplaceAll(t, "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+// arrayIndex parses a reference token as an index of an array of the given length
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("%w. invalid array index %q", ErrRequestPatchInvalid, token)
+	}
+	last := length - 1
+	if allowEnd {
+		last = length
+	}
+	if idx > last {
+		return 0, fmt.Errorf("%w. array index %d out of range", ErrRequestPatchInvalid, idx)
+	}
+
+	return idx, nil
+}
+
+// getValue returns the value referenced by path
+func getValue(doc any, path []string) (any, error) {
+	current := doc
+	for _, token := range path {
+		switch node := current.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%w. member %q not found", ErrRequestPatchInvalid, token)
+			}
+			current = value
+		case []any:
+			idx, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			current = node[idx]
+		default:
+			return nil, fmt.Errorf("%w. path not found", ErrRequestPatchInvalid)
+		}
+	}
+
+	return current, nil
+}
+
+// addValue adds value at path and returns the resulting document
+func addValue(doc any, path []string, value any) (any, error) {
+	// the whole document is replaced
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := getValue(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]any:
+		node[token] = value
+		return doc, nil
+	case []any:
+		idx, err := arrayIndex(token, len(node), true)
+		if err != nil {
+			return nil, err
+		}
+		node = append(node, nil)
+		copy(node[idx+1:], node[idx:])
+		node[idx] = value
+		return setValue(doc, path[:len(path)-1], node)
+	default:
+		return nil, fmt.Errorf("%w. path not found", ErrRequestPatchInvalid)
+	}
+}
+
+// removeValue removes the value at path and returns the resulting document
+func removeValue(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w. cannot remove the whole document", ErrRequestPatchInvalid)
+	}
+
+	parent, err := getValue(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]any:
+		if _, ok := node[token]; !ok {
+			return nil, fmt.Errorf("%w. member %q not found", ErrRequestPatchInvalid, token)
+		}
+		delete(node, token)
+		return doc, nil
+	case []any:
+		idx, err := arrayIndex(token, len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		node = append(node[:idx:idx], node[idx+1:]...)
+		return setValue(doc, path[:len(path)-1], node)
+	default:
+		return nil, fmt.Errorf("%w. path not found", ErrRequestPatchInvalid)
+	}
+}
+
+// setValue replaces the value at path (used when an array changes its length)
+func setValue(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := getValue(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]any:
+		node[token] = value
+	case []any:
+		idx, err := arrayIndex(token, len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		node[idx] = value
+	}
+
+	return doc, nil
+}
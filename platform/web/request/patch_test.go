@@ -0,0 +1,149 @@
+package request_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/manuelfirman/go-API/platform/web/request"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Tests for Patch function
+func TestRequestPatch(t *testing.T) {
+	type schema struct {
+		ID    int      `json:"id"`
+		Name  string   `json:"name"`
+		Code  string   `json:"code"`
+		Tags  []string `json:"tags"`
+		Price float64  `json:"price"`
+	}
+
+	t.Run("success - merge patch", func(t *testing.T) {
+		// arrange
+		inputSchema := schema{ID: 1, Name: "old", Code: "A1"}
+		inputRequest := http.Request{
+			Header: http.Header{"Content-Type": []string{"application/merge-patch+json"}},
+			Body:   io.NopCloser(strings.NewReader(`{"name":"new"}`)),
+		}
+
+		// act
+		err := request.Patch(&inputRequest, &inputSchema)
+
+		// assert
+		expectedSchema := schema{ID: 1, Name: "new", Code: "A1"}
+		require.NoError(t, err)
+		require.Equal(t, expectedSchema, inputSchema)
+	})
+
+	t.Run("success - json patch", func(t *testing.T) {
+		// arrange
+		inputSchema := schema{ID: 1, Name: "old", Code: "A1", Tags: []string{"a", "c"}, Price: 10}
+		inputRequest := http.Request{
+			Header: http.Header{"Content-Type": []string{"application/json-patch+json"}},
+			Body: io.NopCloser(strings.NewReader(`[
+				{"op":"test","path":"/price","value":10},
+				{"op":"replace","path":"/name","value":"new"},
+				{"op":"add","path":"/tags/1","value":"b"},
+				{"op":"add","path":"/tags/-","value":"d"},
+				{"op":"remove","path":"/code"}
+			]`)),
+		}
+
+		// act
+		err := request.Patch(&inputRequest, &inputSchema)
+
+		// assert
+		expectedSchema := schema{ID: 1, Name: "new", Tags: []string{"a", "b", "c", "d"}, Price: 10}
+		require.NoError(t, err)
+		require.Equal(t, expectedSchema, inputSchema)
+	})
+
+	t.Run("success - json patch move and copy", func(t *testing.T) {
+		// arrange
+		inputSchema := schema{Name: "old", Code: "A1"}
+		inputRequest := http.Request{
+			Header: http.Header{"Content-Type": []string{"application/json-patch+json; charset=utf-8"}},
+			Body: io.NopCloser(strings.NewReader(`[
+				{"op":"copy","from":"/code","path":"/name"},
+				{"op":"move","from":"/tags","path":"/tags"}
+			]`)),
+		}
+
+		// act
+		err := request.Patch(&inputRequest, &inputSchema)
+
+		// assert
+		expectedSchema := schema{Name: "A1", Code: "A1"}
+		require.NoError(t, err)
+		require.Equal(t, expectedSchema, inputSchema)
+	})
+
+	t.Run("error - json patch test failed", func(t *testing.T) {
+		// arrange
+		inputSchema := schema{ID: 1, Name: "old"}
+		inputRequest := http.Request{
+			Header: http.Header{"Content-Type": []string{"application/json-patch+json"}},
+			Body: io.NopCloser(strings.NewReader(`[
+				{"op":"replace","path":"/name","value":"new"},
+				{"op":"test","path":"/name","value":"old"}
+			]`)),
+		}
+
+		// act
+		err := request.Patch(&inputRequest, &inputSchema)
+
+		// assert
+		expectedSchema := schema{ID: 1, Name: "old"}
+		require.ErrorIs(t, err, request.ErrRequestPatchTestFailed)
+		require.Equal(t, expectedSchema, inputSchema)
+	})
+
+	t.Run("error - json patch replace missing member", func(t *testing.T) {
+		// arrange
+		inputSchema := schema{ID: 1}
+		inputRequest := http.Request{
+			Header: http.Header{"Content-Type": []string{"application/json-patch+json"}},
+			Body:   io.NopCloser(strings.NewReader(`[{"op":"replace","path":"/unknown","value":1}]`)),
+		}
+
+		// act
+		err := request.Patch(&inputRequest, &inputSchema)
+
+		// assert
+		require.ErrorIs(t, err, request.ErrRequestPatchInvalid)
+	})
+
+	t.Run("error - json patch unknown operation", func(t *testing.T) {
+		// arrange
+		inputSchema := schema{ID: 1}
+		inputRequest := http.Request{
+			Header: http.Header{"Content-Type": []string{"application/json-patch+json"}},
+			Body:   io.NopCloser(strings.NewReader(`[{"op":"increment","path":"/id"}]`)),
+		}
+
+		// act
+		err := request.Patch(&inputRequest, &inputSchema)
+
+		// assert
+		require.ErrorIs(t, err, request.ErrRequestPatchInvalid)
+	})
+
+	t.Run("error - content-type", func(t *testing.T) {
+		// arrange
+		inputSchema := schema{}
+		inputRequest := http.Request{
+			Header: http.Header{"Content-Type": []string{"application/xml"}},
+			Body:   io.NopCloser(strings.NewReader(`{"name":"test"}`)),
+		}
+
+		// act
+		err := request.Patch(&inputRequest, &inputSchema)
+
+		// assert
+		require.ErrorIs(t, err, request.ErrRequestContentTypeNotPatch)
+		require.Equal(t, schema{}, inputSchema)
+	})
+}
@@ -0,0 +1,145 @@
+package response
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// CSVWriter writes a csv response row by row, flushing it to the client as it goes.
+// The header is made of the json tag names of the row struct.
+type CSVWriter struct {
+	stream
+	// cw is the csv encoder
+	cw *csv.Writer
+	// header are the column names
+	header []string
+	// fields are the indexes of the exported struct fields written as columns
+	fields []int
+}
+
+// NewCSVWriter creates a new csv response whose rows are values of the same type as schema (a struct)
+func NewCSVWriter(w http.ResponseWriter, code int, schema any) *CSVWriter {
+	cw := &CSVWriter{
+		stream: stream{w: w, code: code, contentType: "text/csv; charset=utf-8"},
+		cw:     csv.NewWriter(w),
+	}
+
+	// columns
+	t := reflect.TypeOf(schema)
+	for i := 0; i < t.NumField(); i++ {
+		name, ok := csvColumn(t.Field(i))
+		if !ok {
+			continue
+		}
+		cw.fields = append(cw.fields, i)
+		cw.header = append(cw.header, name)
+	}
+
+	return cw
+}
+
+// begin sends the headers and the header row
+func (cw *CSVWriter) begin() error {
+	if cw.started {
+		return nil
+	}
+	cw.start()
+
+	return cw.cw.Write(cw.header)
+}
+
+// Write writes a row, row must be of the same type as the schema
+func (cw *CSVWriter) Write(row any) (err error) {
+	v := reflect.ValueOf(row)
+	record := make([]string, len(cw.fields))
+	for i, f := range cw.fields {
+		record[i], err = csvValue(v.Field(f))
+		if err != nil {
+			return
+		}
+	}
+
+	if err = cw.begin(); err != nil {
+		return
+	}
+	if err = cw.cw.Write(record); err != nil {
+		return
+	}
+
+	// flush every streamFlushEvery rows so the client receives them while they are written
+	cw.items++
+	if cw.items%streamFlushEvery == 0 {
+		cw.cw.Flush()
+		cw.flush()
+		err = cw.cw.Error()
+	}
+	return
+}
+
+// Close sends the buffered rows to the client
+func (cw *CSVWriter) Close() (err error) {
+	if err = cw.begin(); err != nil {
+		return
+	}
+	cw.cw.Flush()
+	cw.flush()
+	return cw.cw.Error()
+}
+
+// CSV writes a csv response, rows must be a slice of structs
+func CSV(w http.ResponseWriter, code int, rows any) {
+	v := reflect.ValueOf(rows)
+	cw := NewCSVWriter(w, code, reflect.Zero(v.Type().Elem()).Interface())
+	for i := 0; i < v.Len(); i++ {
+		if err := cw.Write(v.Index(i).Interface()); err != nil {
+			return
+		}
+	}
+	cw.Close()
+}
+
+// csvColumn returns the column name of a struct field (its json tag name)
+func csvColumn(f reflect.StructField) (name string, ok bool) {
+	if !f.IsExported() {
+		return
+	}
+
+	name, _, _ = strings.Cut(f.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return "", false
+	case "":
+		name = f.Name
+	}
+
+	return name, true
+}
+
+// csvValue formats a field value as a csv cell
+func csvValue(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return "", nil
+		}
+		return csvValue(v.Elem())
+	default:
+		// anything else is written as json
+		bytes, err := json.Marshal(v.Interface())
+		return string(bytes), err
+	}
+}
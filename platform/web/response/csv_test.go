@@ -0,0 +1,55 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/manuelfirman/go-API/platform/web/response"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests for CSV function
+func TestCSV(t *testing.T) {
+	type schema struct {
+		ID     int     `json:"id"`
+		Name   string  `json:"name"`
+		Price  float64 `json:"price,omitempty"`
+		Hidden string  `json:"-"`
+	}
+
+	t.Run("200 - status ok", func(t *testing.T) {
+		// arrange
+		rows := []schema{
+			{ID: 1, Name: "apple", Price: 1.5, Hidden: "x"},
+			{ID: 2, Name: "pear, green", Price: 2},
+		}
+
+		// act
+		rr := httptest.NewRecorder()
+		response.CSV(rr, http.StatusOK, rows)
+
+		// assert
+		expectedHeader := http.Header{"Content-Type": []string{"text/csv; charset=utf-8"}}
+		expectedCode := http.StatusOK
+		expectedBody := "id,name,price\n1,apple,1.5\n2,\"pear, green\",2\n"
+		require.Equal(t, expectedHeader, rr.Header())
+		require.Equal(t, expectedCode, rr.Code)
+		require.Equal(t, expectedBody, rr.Body.String())
+	})
+
+	t.Run("200 - empty slice writes the header only", func(t *testing.T) {
+		// arrange
+		rows := []schema{}
+
+		// act
+		rr := httptest.NewRecorder()
+		response.CSV(rr, http.StatusOK, rows)
+
+		// assert
+		expectedCode := http.StatusOK
+		expectedBody := "id,name,price\n"
+		require.Equal(t, expectedCode, rr.Code)
+		require.Equal(t, expectedBody, rr.Body.String())
+	})
+}
@@ -0,0 +1,163 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// streamFlushEvery is the number of items written between flushes of a streamed response
+const streamFlushEvery = 100
+
+// StreamWriter writes a list response item by item, so the list is never held in memory.
+// Nothing is sent until the first item is written (or the writer is closed), so an error found
+// before that can still be answered with a regular error response.
+type StreamWriter interface {
+	// Write writes an item of the list
+	Write(item any) error
+	// Started returns whether the status code and headers have been sent
+	Started() bool
+	// Close writes the end of the list and flushes the response
+	Close() error
+}
+
+// stream holds the state shared by the streamed responses
+type stream struct {
+	// w is the response writer
+	w http.ResponseWriter
+	// code is the status code sent with the first item
+	code int
+	// contentType is the content type of the response
+	contentType string
+	// started is set once the status code has been sent
+	started bool
+	// items is the number of items written
+	items int
+}
+
+// start sends the headers and the status code
+func (s *stream) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+
+	// set header (before code due to it sets by default "text/plain")
+	s.w.Header().Set("Content-Type", s.contentType)
+	s.w.WriteHeader(s.code)
+}
+
+// wrote counts a written item and flushes the response every streamFlushEvery items
+func (s *stream) wrote() {
+	s.items++
+	if s.items%streamFlushEvery == 0 {
+		s.flush()
+	}
+}
+
+// flush sends the buffered data to the client
+func (s *stream) flush() {
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Started returns whether the status code and headers have been sent
+func (s *stream) Started() bool {
+	return s.started
+}
+
+// NDJSONWriter writes a newline delimited json response (application/x-ndjson), one item per line
+type NDJSONWriter struct {
+	stream
+	// enc is the json encoder of the response
+	enc *json.Encoder
+}
+
+// NewNDJSONWriter creates a new newline delimited json response
+func NewNDJSONWriter(w http.ResponseWriter, code int) *NDJSONWriter {
+	return &NDJSONWriter{
+		stream: stream{w: w, code: code, contentType: "application/x-ndjson"},
+		enc:    json.NewEncoder(w),
+	}
+}
+
+// Write writes an item in its own line
+func (s *NDJSONWriter) Write(item any) (err error) {
+	s.start()
+	if err = s.enc.Encode(item); err != nil {
+		return
+	}
+	s.wrote()
+	return
+}
+
+// Close flushes the response
+func (s *NDJSONWriter) Close() error {
+	s.start()
+	s.flush()
+	return nil
+}
+
+// JSONArrayWriter writes a json response with the same body as JSON(w, code, {message, data}),
+// where data is an array sent item by item (chunked transfer encoding)
+type JSONArrayWriter struct {
+	stream
+	// message is the message of the response
+	message string
+}
+
+// NewJSONArrayWriter creates a new json response whose data is streamed
+func NewJSONArrayWriter(w http.ResponseWriter, code int, message string) *JSONArrayWriter {
+	return &JSONArrayWriter{
+		stream:  stream{w: w, code: code, contentType: "application/json"},
+		message: message,
+	}
+}
+
+// begin sends the headers and the beginning of the body
+func (s *JSONArrayWriter) begin() (err error) {
+	if s.started {
+		return
+	}
+	s.start()
+
+	message, err := json.Marshal(s.message)
+	if err != nil {
+		return
+	}
+	_, err = s.w.Write([]byte(`{"message":` + string(message) + `,"data":[`))
+	return
+}
+
+// Write writes an item of the data array
+func (s *JSONArrayWriter) Write(item any) (err error) {
+	bytes, err := json.Marshal(item)
+	if err != nil {
+		return
+	}
+	if err = s.begin(); err != nil {
+		return
+	}
+
+	// items are separated by commas
+	if s.items > 0 {
+		bytes = append([]byte{','}, bytes...)
+	}
+	if _, err = s.w.Write(bytes); err != nil {
+		return
+	}
+	s.wrote()
+	return
+}
+
+// Close writes the end of the data array and flushes the response
+func (s *JSONArrayWriter) Close() (err error) {
+	if err = s.begin(); err != nil {
+		return
+	}
+	if _, err = s.w.Write([]byte("]}")); err != nil {
+		return
+	}
+	s.flush()
+	return
+}
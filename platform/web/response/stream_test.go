@@ -0,0 +1,81 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/manuelfirman/go-API/platform/web/response"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests for NDJSONWriter
+func TestNDJSONWriter(t *testing.T) {
+	t.Run("200 - one item per line", func(t *testing.T) {
+		// arrange
+		rr := httptest.NewRecorder()
+		sw := response.NewNDJSONWriter(rr, http.StatusOK)
+
+		// act
+		require.NoError(t, sw.Write(map[string]int{"id": 1}))
+		require.NoError(t, sw.Write(map[string]int{"id": 2}))
+		require.NoError(t, sw.Close())
+
+		// assert
+		expectedHeader := http.Header{"Content-Type": []string{"application/x-ndjson"}}
+		expectedBody := "{\"id\":1}\n{\"id\":2}\n"
+		require.Equal(t, expectedHeader, rr.Header())
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, expectedBody, rr.Body.String())
+		require.True(t, rr.Flushed)
+	})
+
+	t.Run("nothing is sent before the first item", func(t *testing.T) {
+		// arrange
+		rr := httptest.NewRecorder()
+		sw := response.NewNDJSONWriter(rr, http.StatusOK)
+
+		// act
+		started := sw.Started()
+		response.Error(rr, http.StatusInternalServerError, "failed")
+
+		// assert
+		require.False(t, started)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
+
+// Tests for JSONArrayWriter
+func TestJSONArrayWriter(t *testing.T) {
+	t.Run("200 - same body as JSON", func(t *testing.T) {
+		// arrange
+		rr := httptest.NewRecorder()
+		sw := response.NewJSONArrayWriter(rr, http.StatusOK, "success")
+
+		// act
+		require.NoError(t, sw.Write(map[string]int{"id": 1}))
+		require.NoError(t, sw.Write(map[string]int{"id": 2}))
+		require.NoError(t, sw.Close())
+
+		// assert
+		expectedHeader := http.Header{"Content-Type": []string{"application/json"}}
+		expectedBody := `{"message":"success","data":[{"id":1},{"id":2}]}`
+		require.Equal(t, expectedHeader, rr.Header())
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, expectedBody, rr.Body.String())
+	})
+
+	t.Run("200 - empty list", func(t *testing.T) {
+		// arrange
+		rr := httptest.NewRecorder()
+		sw := response.NewJSONArrayWriter(rr, http.StatusOK, "success")
+
+		// act
+		require.NoError(t, sw.Close())
+
+		// assert
+		expectedBody := `{"message":"success","data":[]}`
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, expectedBody, rr.Body.String())
+	})
+}